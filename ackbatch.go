@@ -0,0 +1,160 @@
+package rabbids
+
+import (
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+// ackBatcher delay the acks sent to the broker and send them using multiple=true,
+// reducing the ack traffic for high-throughput consumers.
+// The delivery tags are sequential per channel, so the batcher keeps track of the highest
+// contiguous settled tag and only acks up to it, never acking messages still being handled.
+type ackBatcher struct {
+	amqp.Acknowledger
+	mutex   sync.Mutex
+	size    int
+	next    uint64
+	settled map[uint64]bool
+	lastAck uint64
+	flushed uint64
+	pending int
+}
+
+func newAckBatcher(ack amqp.Acknowledger, size int) *ackBatcher {
+	return &ackBatcher{
+		Acknowledger: ack,
+		size:         size,
+		next:         1,
+		settled:      map[uint64]bool{},
+	}
+}
+
+// reset bind the batcher to a new channel, dropping the state of the old one.
+func (b *ackBatcher) reset(ack amqp.Acknowledger) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.Acknowledger = ack
+	b.next = 1
+	b.settled = map[uint64]bool{}
+	b.lastAck = 0
+	b.flushed = 0
+	b.pending = 0
+}
+
+func (b *ackBatcher) Ack(tag uint64, multiple bool) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if multiple {
+		err := b.Acknowledger.Ack(tag, true)
+		b.settleUntil(tag)
+
+		return err
+	}
+
+	b.settle(tag, true)
+
+	if b.pending >= b.size {
+		return b.flushLocked()
+	}
+
+	return nil
+}
+
+func (b *ackBatcher) Nack(tag uint64, multiple bool, requeue bool) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if multiple {
+		// ack the contiguous messages before rejecting all the others
+		if err := b.flushLocked(); err != nil {
+			return err
+		}
+
+		err := b.Acknowledger.Nack(tag, true, requeue)
+		b.settleUntil(tag)
+
+		return err
+	}
+
+	err := b.Acknowledger.Nack(tag, false, requeue)
+	b.settle(tag, false)
+
+	return err
+}
+
+func (b *ackBatcher) Reject(tag uint64, requeue bool) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	err := b.Acknowledger.Reject(tag, requeue)
+	b.settle(tag, false)
+
+	return err
+}
+
+// flush send the ack for all the contiguous acked messages.
+func (b *ackBatcher) flush() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.flushLocked()
+}
+
+func (b *ackBatcher) flushLocked() error {
+	if b.lastAck <= b.flushed {
+		return nil
+	}
+
+	err := b.Acknowledger.Ack(b.lastAck, true)
+	b.flushed = b.lastAck
+	b.pending = 0
+
+	return err
+}
+
+// settle mark the tag as acked or rejected and advance over the contiguous settled tags.
+func (b *ackBatcher) settle(tag uint64, acked bool) {
+	if tag < b.next {
+		return
+	}
+
+	b.settled[tag] = acked
+	b.advance()
+}
+
+// advance the next tag over the contiguous settled tags.
+func (b *ackBatcher) advance() {
+	for {
+		acked, ok := b.settled[b.next]
+		if !ok {
+			return
+		}
+
+		delete(b.settled, b.next)
+
+		if acked {
+			b.lastAck = b.next
+			b.pending++
+		}
+
+		b.next++
+	}
+}
+
+// settleUntil is used after an operation with multiple=true, all the tags until the tag are settled.
+func (b *ackBatcher) settleUntil(tag uint64) {
+	for ; b.next <= tag; b.next++ {
+		delete(b.settled, b.next)
+	}
+
+	if b.lastAck < tag {
+		b.lastAck = tag
+		b.flushed = tag
+		b.pending = 0
+	}
+
+	b.advance()
+}
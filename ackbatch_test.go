@@ -0,0 +1,84 @@
+package rabbids
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordedAck struct {
+	tag      uint64
+	multiple bool
+}
+
+type recordAcknowledger struct {
+	acks  []recordedAck
+	nacks []recordedAck
+}
+
+func (a *recordAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acks = append(a.acks, recordedAck{tag, multiple})
+
+	return nil
+}
+
+func (a *recordAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.nacks = append(a.nacks, recordedAck{tag, multiple})
+
+	return nil
+}
+
+func (a *recordAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func Test_ackBatcher(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ack only the contiguous tags", func(t *testing.T) {
+		t.Parallel()
+
+		ack := &recordAcknowledger{}
+		b := newAckBatcher(ack, 3)
+
+		require.NoError(t, b.Ack(2, false))
+		require.NoError(t, b.Ack(3, false))
+		require.NoError(t, b.Ack(4, false))
+		require.Empty(t, ack.acks, "tag 1 is still in flight")
+
+		require.NoError(t, b.Ack(1, false))
+		require.Equal(t, []recordedAck{{4, true}}, ack.acks)
+	})
+
+	t.Run("rejected tags are skipped", func(t *testing.T) {
+		t.Parallel()
+
+		ack := &recordAcknowledger{}
+		b := newAckBatcher(ack, 10)
+
+		require.NoError(t, b.Ack(1, false))
+		require.NoError(t, b.Reject(2, true))
+		require.NoError(t, b.Ack(3, false))
+		require.NoError(t, b.Ack(5, false))
+		require.Equal(t, []recordedAck{{2, false}}, ack.nacks)
+
+		require.NoError(t, b.flush())
+		require.Equal(t, []recordedAck{{3, true}}, ack.acks)
+
+		require.NoError(t, b.flush())
+		require.Len(t, ack.acks, 1, "nothing new to flush")
+	})
+
+	t.Run("multiple acks settle all the previous tags", func(t *testing.T) {
+		t.Parallel()
+
+		ack := &recordAcknowledger{}
+		b := newAckBatcher(ack, 10)
+
+		require.NoError(t, b.Ack(5, false))
+		require.NoError(t, b.Ack(3, true))
+		require.NoError(t, b.Ack(4, false))
+		require.NoError(t, b.flush())
+		require.Equal(t, []recordedAck{{3, true}, {5, true}}, ack.acks)
+	})
+}
@@ -0,0 +1,49 @@
+package rabbids
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Start run all the consumers with a supervisor, using the DefaultSupervisorInterval, until the context
+// is done and then stop the client using Shutdown, bounded by the DefaultDrainTimeout.
+// It blocks until the client is stopped and returns an error when the consumers can't be created
+// or the shutdown fails, so it can be used inside an errgroup.
+func (r *Rabbids) Start(ctx context.Context, opts ...SupervisorOption) error {
+	return r.start(ctx, DefaultSupervisorInterval, DefaultDrainTimeout, opts...)
+}
+
+// Actor returns the execute and interrupt functions used by github.com/oklog/run.Group.Add:
+// execute runs the client like Start and interrupt stops it.
+func (r *Rabbids) Actor(opts ...SupervisorOption) (execute func() error, interrupt func(error)) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	execute = func() error {
+		defer cancel()
+
+		return r.Start(ctx, opts...)
+	}
+	interrupt = func(error) {
+		cancel()
+	}
+
+	return execute, interrupt
+}
+
+func (r *Rabbids) start(ctx context.Context, interval, drainTimeout time.Duration, opts ...SupervisorOption) error {
+	_, err := StartSupervisor(r, interval, opts...)
+	if err != nil {
+		_ = r.Close()
+
+		return fmt.Errorf("failed to start the consumers: %w", err)
+	}
+
+	<-ctx.Done()
+	r.log.Info("shutting down the consumers", Fields{"drain-timeout": drainTimeout})
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	return r.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,56 @@
+package rabbids
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRabbids_Start(t *testing.T) {
+	t.Parallel()
+
+	r, err := New(&Config{}, NoOPLoggerFN)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- r.Start(ctx, WithRestartLimit(1, 0))
+	}()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after the context was done")
+	}
+}
+
+func TestRabbids_Actor(t *testing.T) {
+	t.Parallel()
+
+	r, err := New(&Config{}, NoOPLoggerFN)
+	require.NoError(t, err)
+
+	execute, interrupt := r.Actor()
+	done := make(chan error, 1)
+
+	go func() {
+		done <- execute()
+	}()
+
+	interrupt(errors.New("another actor stopped"))
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the actor did not stop after the interrupt")
+	}
+}
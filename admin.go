@@ -0,0 +1,62 @@
+package rabbids
+
+import "fmt"
+
+// KillConsumer stop one consumer. When the consumers are running with a supervisor
+// the killed consumer is not restarted until RestartConsumer is called.
+// The handler is kept open for the restart and closed by Shutdown or when the supervisor stops.
+func (r *Rabbids) KillConsumer(name string) error {
+	if ok, err := r.sendSupervisor(name, false); ok {
+		return err
+	}
+
+	r.mutex.RLock()
+	c, ok := r.consumers[name]
+	r.mutex.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("consumer \"%s\" is not running", name)
+	}
+
+	c.stop()
+
+	return nil
+}
+
+// RestartConsumer kill one consumer and start a new one using the current config.
+// It can be used to start again a consumer stopped by KillConsumer or given up by the supervisor.
+func (r *Rabbids) RestartConsumer(name string) error {
+	if ok, err := r.sendSupervisor(name, true); ok {
+		return err
+	}
+
+	r.mutex.RLock()
+	c, ok := r.consumers[name]
+	r.mutex.RUnlock()
+
+	if ok {
+		c.stop()
+	}
+
+	nc, err := r.CreateConsumer(name)
+	if err != nil {
+		return err
+	}
+
+	nc.Run()
+
+	return nil
+}
+
+// sendSupervisor send the command to the supervisor, returning false when it's not running.
+func (r *Rabbids) sendSupervisor(name string, restart bool) (bool, error) {
+	r.mutex.RLock()
+	s := r.supervisor
+	r.mutex.RUnlock()
+
+	if s == nil {
+		return false, nil
+	}
+
+	return s.send(name, restart)
+}
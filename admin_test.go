@@ -0,0 +1,51 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRabbids_KillConsumer(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{config: &Config{}, log: NoOPLogger{}, consumers: map[string]*Consumer{}}
+	r.registerConsumer(&Consumer{name: "idle", log: NoOPLogger{}})
+
+	require.EqualError(t, r.KillConsumer("unknown"), "consumer \"unknown\" is not running")
+	require.NoError(t, r.KillConsumer("idle"))
+	require.EqualError(t, r.RestartConsumer("idle"), "consumer \"idle\" did not exist")
+}
+
+func TestSupervisor_handleCommand(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{config: &Config{}, log: NoOPLogger{}, consumers: map[string]*Consumer{}, metrics: NoOPMetrics{}}
+	s := &supervisor{
+		rabbids:   r,
+		consumers: map[string]*Consumer{"idle": {name: "idle", log: NoOPLogger{}}},
+		restarts:  map[string]*restartState{},
+	}
+
+	require.EqualError(t, s.handleCommand(consumerCommand{name: "unknown"}), "consumer \"unknown\" is not running")
+	require.NoError(t, s.handleCommand(consumerCommand{name: "idle"}))
+	require.True(t, s.state("idle").killed)
+	require.EqualError(t, s.handleCommand(consumerCommand{name: "idle", restart: true}), "consumer \"idle\" did not exist")
+	require.True(t, s.state("idle").killed)
+}
+
+func TestRabbids_KillConsumerWithSupervisor(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{config: &Config{}, log: NoOPLogger{}, consumers: map[string]*Consumer{}, metrics: NoOPMetrics{}}
+	stop, err := StartSupervisor(r, time.Millisecond)
+	require.NoError(t, err)
+
+	require.EqualError(t, r.KillConsumer("unknown"), "consumer \"unknown\" is not running")
+	require.EqualError(t, r.RestartConsumer("unknown"), "consumer \"unknown\" did not exist")
+
+	stop()
+	// after the stop the commands are handled by the client
+	require.EqualError(t, r.KillConsumer("unknown"), "consumer \"unknown\" is not running")
+}
@@ -0,0 +1,66 @@
+package rabbids
+
+import (
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// The headers set on the audit copies with the data of the original message.
+const (
+	AuditExchangeHeader    = "x-audit-exchange"
+	AuditRoutingKeyHeader  = "x-audit-routing-key"
+	AuditProducerHeader    = "x-audit-producer"
+	AuditPublishedAtHeader = "x-audit-published-at"
+)
+
+// WithAuditExchange copy every message published by the producer to the audit exchange, with the same
+// routing key, properties and body and the Audit headers with the original exchange and routing key,
+// so a compliance consumer can archive everything without the broker tracing.
+// The copies are published after the message, the failures are logged and don't fail the Send.
+func WithAuditExchange(name string) ProducerOption {
+	return func(p *Producer) error {
+		p.auditExchange = name
+
+		return nil
+	}
+}
+
+// audit publish the copy of the message published to the audit exchange.
+func (p *Producer) audit(m Publishing) {
+	if p.auditExchange == "" {
+		return
+	}
+
+	p.mutex.RLock()
+	p.tryToDeclareTopic(p.auditExchange)
+
+	err := p.ch.Publish(p.auditExchange, m.Key, false, false, auditCopy(m, p.name, time.Now()))
+	p.mutex.RUnlock()
+
+	if err != nil {
+		p.log.Error("failed to publish the audit copy of the message", Fields{
+			"producer":       p.name,
+			"audit-exchange": p.auditExchange,
+			"message-id":     m.MessageId,
+			"error":          err,
+		})
+	}
+}
+
+// auditCopy returns the message with the Audit headers, without changing the headers of the original message.
+func auditCopy(m Publishing, producer string, now time.Time) amqp.Publishing {
+	pub := m.Publishing
+	pub.Headers = make(amqp.Table, len(m.Headers)+4)
+
+	for k, v := range m.Headers {
+		pub.Headers[k] = v
+	}
+
+	pub.Headers[AuditExchangeHeader] = m.Exchange
+	pub.Headers[AuditRoutingKeyHeader] = m.Key
+	pub.Headers[AuditProducerHeader] = producer
+	pub.Headers[AuditPublishedAtHeader] = now
+
+	return pub
+}
@@ -0,0 +1,34 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditCopy(t *testing.T) {
+	t.Parallel()
+
+	p := &Producer{}
+	require.NoError(t, WithAuditExchange("audit")(p))
+	require.Equal(t, "audit", p.auditExchange)
+
+	m := NewPublishing("events", "user.created", nil)
+	m.Headers = amqp.Table{"tenant": "foo"}
+	m.Body = []byte(`{"name":"bar"}`)
+	now := time.Now()
+
+	pub := auditCopy(m, "events-producer", now)
+	require.Equal(t, m.Body, pub.Body)
+	require.Equal(t, m.MessageId, pub.MessageId)
+	require.Equal(t, amqp.Table{
+		"tenant":               "foo",
+		AuditExchangeHeader:    "events",
+		AuditRoutingKeyHeader:  "user.created",
+		AuditProducerHeader:    "events-producer",
+		AuditPublishedAtHeader: now,
+	}, pub.Headers)
+	require.Equal(t, amqp.Table{"tenant": "foo"}, m.Headers, "the original headers must not change")
+}
@@ -0,0 +1,86 @@
+package rabbids
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBackpressureThreshold is the time one message waits for a free worker before the consumer is saturated.
+const DefaultBackpressureThreshold = time.Second
+
+// BackpressureFunc is called when the consumer is saturated, with all the workers busy
+// for longer than the threshold, and again when the message waiting is dispatched.
+// It's called by the consumer goroutine, so it should return fast.
+type BackpressureFunc func(consumer string, saturated bool, blocked time.Duration)
+
+type backpressureConfig struct {
+	threshold time.Duration
+	fn        BackpressureFunc
+}
+
+// WithBackpressure set the threshold and the function called when the consumer can't keep up with the messages,
+// so the application can shed load or alert.
+func WithBackpressure(threshold time.Duration, fn BackpressureFunc) ConsumerOption {
+	return func(c *Consumer) error {
+		c.backpressure = backpressureConfig{threshold: threshold, fn: fn}
+
+		return nil
+	}
+}
+
+// Saturated return true when the consumer is waiting for a free worker for longer than the backpressure threshold.
+func (c *Consumer) Saturated() bool {
+	return atomic.LoadInt32(&c.saturated) == 1
+}
+
+// dispatch send the job to the worker pool, tracking how long the consumer waits for a free worker.
+func (c *Consumer) dispatch(job func()) {
+	select {
+	case c.workerPool.JobQueue <- job:
+		return
+	default:
+	}
+
+	if c.backpressure.threshold <= 0 {
+		c.workerPool.JobQueue <- job
+
+		return
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(c.backpressure.threshold)
+
+	select {
+	case c.workerPool.JobQueue <- job:
+		timer.Stop()
+
+		return
+	case <-timer.C:
+	}
+
+	c.setSaturated(true, time.Since(start))
+	c.workerPool.JobQueue <- job
+	c.setSaturated(false, time.Since(start))
+}
+
+func (c *Consumer) setSaturated(saturated bool, blocked time.Duration) {
+	var v int32
+	if saturated {
+		v = 1
+	}
+
+	atomic.StoreInt32(&c.saturated, v)
+	c.metrics.ConsumerSaturated(c.name, saturated)
+
+	if saturated {
+		c.log.Warn("the consumer is saturated, all the workers are busy", Fields{
+			"consumer": c.name,
+			"workers":  c.workers,
+			"blocked":  blocked,
+		})
+	}
+
+	if c.backpressure.fn != nil {
+		c.backpressure.fn(c.name, saturated, blocked)
+	}
+}
@@ -0,0 +1,60 @@
+package rabbids
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ivpusic/grpool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumer_dispatchBackpressure(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mutex  sync.Mutex
+		events []bool
+	)
+
+	c := &Consumer{
+		name:       "backpressure",
+		log:        NoOPLogger{},
+		metrics:    NoOPMetrics{},
+		workerPool: grpool.NewPool(1, 0),
+	}
+
+	require.NoError(t, WithBackpressure(10*time.Millisecond, func(_ string, saturated bool, _ time.Duration) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		events = append(events, saturated)
+	})(c))
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	c.dispatch(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	// the pool dispatcher holds one job while waiting for a free worker
+	c.dispatch(func() {})
+
+	saturated := make(chan bool, 1)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		saturated <- c.Saturated()
+		close(release)
+	}()
+
+	c.dispatch(func() {})
+	require.True(t, <-saturated)
+	require.False(t, c.Saturated())
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Equal(t, []bool{true, false}, events)
+}
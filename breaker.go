@@ -0,0 +1,120 @@
+package rabbids
+
+import (
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// CircuitBreakerConfig describe when the consumer stops handling messages because of handler errors.
+type CircuitBreakerConfig struct {
+	// Threshold is the number of consecutive handler errors that opens the circuit, zero disables the breaker.
+	Threshold int `mapstructure:"threshold"`
+	// CoolDown is the time the consumer waits with the circuit open before trying again.
+	CoolDown time.Duration `mapstructure:"cool_down"`
+}
+
+// DefaultCircuitBreakerCoolDown is the cool down used when the circuit breaker is enabled without one.
+const DefaultCircuitBreakerCoolDown = 30 * time.Second
+
+// circuitBreaker count the consecutive handler errors. When the threshold is reached the circuit
+// opens and the consumer stops dispatching messages until the cool down ends. After the cool down
+// one more error opens the circuit again and one success closes it.
+type circuitBreaker struct {
+	threshold int
+	coolDown  time.Duration
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+func newCircuitBreaker(cfg CircuitBreakerConfig) *circuitBreaker {
+	if cfg.Threshold <= 0 {
+		return nil
+	}
+
+	return &circuitBreaker{threshold: cfg.Threshold, coolDown: cfg.CoolDown}
+}
+
+// record the handler result, returning true when the circuit changed to open or closed.
+func (b *circuitBreaker) record(err error, now time.Time) (changed, open bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err == nil {
+		closed := b.failures >= b.threshold
+		b.failures = 0
+
+		return closed, false
+	}
+
+	b.failures++
+	if b.failures < b.threshold {
+		return false, false
+	}
+
+	reopen := !b.openUntil.After(now)
+	b.openUntil = now.Add(b.coolDown)
+
+	return reopen, true
+}
+
+// wait return how long the consumer must wait before dispatching the next message.
+func (b *circuitBreaker) wait(now time.Time) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.openUntil.Sub(now)
+}
+
+// recordCircuit update the circuit breaker with the handler result.
+func (c *Consumer) recordCircuit(err error) {
+	if c.breaker == nil {
+		return
+	}
+
+	changed, open := c.breaker.record(err, time.Now())
+	if !changed {
+		return
+	}
+
+	if open {
+		c.log.Warn("too many handler errors, the consumer circuit is open", Fields{
+			"consumer":  c.name,
+			"cool-down": c.breaker.coolDown,
+			"error":     err,
+		})
+		c.emit(Event{Type: EventCircuitOpened, Consumer: c.name, Queue: c.queue, Err: err})
+
+		return
+	}
+
+	c.log.Info("the consumer circuit is closed", Fields{"consumer": c.name})
+	c.emit(Event{Type: EventCircuitClosed, Consumer: c.name, Queue: c.queue})
+}
+
+// waitCircuit block while the circuit is open. It returns false when the consumer is dying
+// or the channel was closed during the cool down, in the last case with the channel error.
+func (c *Consumer) waitCircuit(closed <-chan *amqp.Error) (bool, error) {
+	if c.breaker == nil {
+		return true, nil
+	}
+
+	wait := c.breaker.wait(time.Now())
+	if wait <= 0 {
+		return true, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-c.t.Dying():
+		return false, nil
+	case err := <-closed:
+		return false, c.channelClosed(err)
+	}
+}
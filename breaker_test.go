@@ -0,0 +1,107 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_circuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, newCircuitBreaker(CircuitBreakerConfig{}))
+
+	b := newCircuitBreaker(CircuitBreakerConfig{Threshold: 2, CoolDown: time.Minute})
+	now := time.Now()
+	errHandler := errors.New("failed")
+
+	changed, open := b.record(errHandler, now)
+	require.False(t, changed)
+	require.False(t, open)
+	require.True(t, b.wait(now) <= 0)
+
+	changed, open = b.record(errHandler, now)
+	require.True(t, changed)
+	require.True(t, open)
+	require.Equal(t, time.Minute, b.wait(now))
+
+	// after the cool down one error opens the circuit again
+	later := now.Add(2 * time.Minute)
+	require.True(t, b.wait(later) <= 0)
+	changed, open = b.record(errHandler, later)
+	require.True(t, changed)
+	require.True(t, open)
+
+	changed, open = b.record(nil, later)
+	require.True(t, changed)
+	require.False(t, open)
+
+	changed, open = b.record(nil, later)
+	require.False(t, changed)
+	require.False(t, open)
+}
+
+func TestConsumer_circuitBreakerEvents(t *testing.T) {
+	t.Parallel()
+
+	var events []EventType
+
+	c := &Consumer{
+		name:    "breaker",
+		log:     NoOPLogger{},
+		breaker: newCircuitBreaker(CircuitBreakerConfig{Threshold: 1, CoolDown: time.Millisecond}),
+		events: func(e Event) {
+			events = append(events, e.Type)
+		},
+	}
+
+	c.recordCircuit(errors.New("failed"))
+	ok, err := c.waitCircuit(nil)
+	require.True(t, ok)
+	require.NoError(t, err)
+	c.recordCircuit(nil)
+
+	require.Equal(t, []EventType{EventCircuitOpened, EventCircuitClosed}, events)
+}
+
+func TestConsumer_waitCircuitChannelClosed(t *testing.T) {
+	t.Parallel()
+
+	c := &Consumer{
+		name:    "breaker",
+		log:     NoOPLogger{},
+		breaker: newCircuitBreaker(CircuitBreakerConfig{Threshold: 1, CoolDown: time.Hour}),
+		events:  func(Event) {},
+	}
+	c.recordCircuit(errors.New("failed"))
+
+	closed := make(chan *amqp.Error, 1)
+	closed <- amqp.ErrClosed
+
+	ok, err := c.waitCircuit(closed)
+	require.False(t, ok, "the cool down must be interrupted by the channel closed")
+	require.Equal(t, amqp.ErrClosed, err)
+
+	c.t.Kill(nil)
+
+	ok, err = c.waitCircuit(nil)
+	require.False(t, ok)
+	require.NoError(t, err)
+}
+
+func TestRabbids_circuitBreakerWithAutoAck(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{}
+	_, err := r.newConsumer("events", ConsumerConfig{
+		NackStrategy:    NackStrategyRequeue,
+		OnError:         OnErrorRequeue,
+		TimeoutStrategy: TimeoutStrategyWarn,
+		CircuitBreaker:  CircuitBreakerConfig{Threshold: 3},
+		Options:         Options{AutoAck: true},
+	})
+	require.EqualError(t, err, `the circuit_breaker can't be used with auto_ack for consumer "events"`)
+}
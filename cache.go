@@ -0,0 +1,136 @@
+package rabbids
+
+import (
+	"sync"
+	"time"
+)
+
+// ResponseCache keeps the replies sent by RPC-style consumers.
+// The implementations MUST be safe for concurrent use.
+type ResponseCache interface {
+	Get(key string) (Publishing, bool)
+	Set(key string, reply Publishing, ttl time.Duration)
+}
+
+// ResponseCacheMiddleware cache the replies sent with Message.Reply using the CorrelationId,
+// or the MessageId when empty, as the key. When a request already answered arrives again,
+// the cached reply is sent and the message is acked without calling the handler,
+// so the side effects are not executed twice.
+func ResponseCacheMiddleware(cache ResponseCache, ttl time.Duration) Middleware {
+	return func(next MessageHandler) MessageHandler {
+		return &responseCacheHandler{next: next, cache: cache, ttl: ttl}
+	}
+}
+
+type responseCacheHandler struct {
+	next  MessageHandler
+	cache ResponseCache
+	ttl   time.Duration
+}
+
+func (h *responseCacheHandler) Handle(m Message) {
+	_ = h.HandleErr(m)
+}
+
+func (h *responseCacheHandler) HandleErr(m Message) error {
+	key := m.CorrelationId
+	if key == "" {
+		key = m.MessageId
+	}
+
+	if key == "" || m.ReplyTo == "" {
+		return handlerErr(h.next, m)
+	}
+
+	if reply, ok := h.cache.Get(key); ok {
+		reply.Key = m.ReplyTo
+		if err := m.sendReply(reply); err != nil {
+			return err
+		}
+
+		return m.Ack(false)
+	}
+
+	previous := m.onReply
+	m.onReply = func(reply Publishing) {
+		h.cache.Set(key, reply, h.ttl)
+
+		if previous != nil {
+			previous(reply)
+		}
+	}
+
+	return handlerErr(h.next, m)
+}
+
+func (h *responseCacheHandler) Close() {
+	h.next.Close()
+}
+
+type cachedResponse struct {
+	reply   Publishing
+	expires time.Time
+}
+
+// memoryCacheMinSweep is the min number of responses before the MemoryResponseCache removes the expired ones.
+const memoryCacheMinSweep = 64
+
+// MemoryResponseCache is a ResponseCache keeping the replies in memory.
+// The expired replies are removed by Get and, to keep the memory bounded by the ttl,
+// by one sweep every time the number of replies doubles.
+type MemoryResponseCache struct {
+	mutex     sync.Mutex
+	responses map[string]cachedResponse
+	sweepAt   int
+}
+
+// NewMemoryResponseCache create an empty MemoryResponseCache.
+func NewMemoryResponseCache() *MemoryResponseCache {
+	return &MemoryResponseCache{responses: map[string]cachedResponse{}, sweepAt: memoryCacheMinSweep}
+}
+
+func (c *MemoryResponseCache) Get(key string) (Publishing, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	r, ok := c.responses[key]
+	if !ok {
+		return Publishing{}, false
+	}
+
+	if time.Now().After(r.expires) {
+		delete(c.responses, key)
+
+		return Publishing{}, false
+	}
+
+	return r.reply, true
+}
+
+func (c *MemoryResponseCache) Set(key string, reply Publishing, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+
+	if len(c.responses) >= c.sweepAt {
+		c.sweep(now)
+	}
+
+	c.responses[key] = cachedResponse{reply: reply, expires: now.Add(ttl)}
+}
+
+// sweep remove the expired responses. The next sweep happens when the responses left double,
+// so the cost of the sweeps is amortized between the calls to Set.
+func (c *MemoryResponseCache) sweep(now time.Time) {
+	for k, r := range c.responses {
+		if now.After(r.expires) {
+			delete(c.responses, k)
+		}
+	}
+
+	c.sweepAt = 2 * len(c.responses)
+	if c.sweepAt < memoryCacheMinSweep {
+		c.sweepAt = memoryCacheMinSweep
+	}
+}
@@ -0,0 +1,82 @@
+package rabbids
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryResponseCache(t *testing.T) {
+	t.Parallel()
+
+	cache := NewMemoryResponseCache()
+	cache.Set("expired", NewPublishing("", "reply", "old"), -time.Second)
+	cache.Set("valid", NewPublishing("", "reply", "new"), time.Minute)
+
+	_, ok := cache.Get("expired")
+	require.False(t, ok)
+
+	reply, ok := cache.Get("valid")
+	require.True(t, ok)
+	require.Equal(t, "new", reply.Data)
+}
+
+func TestMemoryResponseCache_sweep(t *testing.T) {
+	t.Parallel()
+
+	cache := NewMemoryResponseCache()
+	for i := 0; i < memoryCacheMinSweep; i++ {
+		cache.Set(fmt.Sprintf("expired-%d", i), NewPublishing("", "reply", i), -time.Second)
+	}
+
+	require.Len(t, cache.responses, memoryCacheMinSweep, "the expired responses are kept until the next sweep")
+
+	for i := 0; i < memoryCacheMinSweep; i++ {
+		cache.Set(fmt.Sprintf("valid-%d", i), NewPublishing("", "reply", i), time.Minute)
+	}
+
+	require.Len(t, cache.responses, memoryCacheMinSweep, "the sweep should remove the expired responses")
+	require.Equal(t, memoryCacheMinSweep, cache.sweepAt)
+
+	cache.Set("one-more", NewPublishing("", "reply", "data"), time.Minute)
+	require.Len(t, cache.responses, memoryCacheMinSweep+1)
+	require.Equal(t, 2*memoryCacheMinSweep, cache.sweepAt, "the next sweep waits the valid responses double")
+}
+
+func TestResponseCacheMiddleware(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	cache := NewMemoryResponseCache()
+	handler := ResponseCacheMiddleware(cache, time.Minute)(MessageHandlerFunc(func(m Message) {
+		calls++
+		// simulate the Reply without a broker
+		if m.onReply != nil {
+			m.onReply(NewPublishing("", m.ReplyTo, "response"))
+		}
+	}))
+
+	m := Message{
+		Delivery: amqp.Delivery{CorrelationId: "abc", ReplyTo: "reply-queue", Acknowledger: &fakeAcknowledger{}},
+		producer: func() (*Producer, error) { return nil, errors.New("no broker") },
+	}
+
+	handler.Handle(m)
+	require.Equal(t, 1, calls)
+
+	reply, ok := cache.Get("abc")
+	require.True(t, ok)
+	require.Equal(t, "response", reply.Data)
+
+	err := handler.(ErrorHandler).HandleErr(m)
+	require.EqualError(t, err, "failed to get the producer for the reply: no broker", "should send the cached reply")
+	require.Equal(t, 1, calls, "the handler should not be called for requests already answered")
+
+	m.CorrelationId = ""
+	handler.Handle(m)
+	require.Equal(t, 2, calls, "messages without ids are not cached")
+}
@@ -19,6 +19,39 @@ const (
 	DefaultTimeout = 2 * time.Second
 	DefaultSleep   = 500 * time.Millisecond
 	DefaultRetries = 5
+	// DefaultConfirmTimeout is the max time SendWithConfirm waits for the broker confirmation.
+	DefaultConfirmTimeout = 5 * time.Second
+	// DefaultRequeueDelay is the delay used by the delayed-requeue nack strategy.
+	DefaultRequeueDelay = 5 * time.Second
+	// DefaultRequeueMaxDelay is the max delay used by the delayed-requeue with requeue_backoff.
+	DefaultRequeueMaxDelay = time.Hour
+	// DefaultAckBatchInterval is the interval used to flush the acks when the ack batching is enabled.
+	DefaultAckBatchInterval = time.Second
+	// DefaultConsumerTag is the template used to create the consumer tags.
+	DefaultConsumerTag = "rabbitmq-{name}-{worker}"
+)
+
+// Nack strategies available to the consumers.
+const (
+	// NackStrategyRequeue put the message back on the queue immediately (the AMQP behavior).
+	NackStrategyRequeue = "requeue"
+	// NackStrategyDelayedRequeue acks the message and publish it again using the delay
+	// infrastructure, so the message arrives the queue after the requeue_delay plus a random jitter.
+	NackStrategyDelayedRequeue = "delayed-requeue"
+)
+
+// Strategies used by the consumers when the handler returns an error or panics
+// without acknowledging the message.
+const (
+	// OnErrorRequeue reject the message with requeue, the requeue follows the nack_strategy.
+	OnErrorRequeue = "requeue"
+	// OnErrorDeadLetter reject the message without requeue, sending it to the queue dead letter.
+	OnErrorDeadLetter = "dead_letter"
+	// OnErrorDelayedRetry publish the message again using the delay infrastructure,
+	// using the requeue_delay, requeue_jitter and requeue_backoff.
+	OnErrorDelayedRetry = "delayed_retry"
+	// OnErrorDiscard acks the message.
+	OnErrorDiscard = "discard"
 )
 
 // File represents the file operations needed to works with our config loader.
@@ -42,6 +75,22 @@ type Config struct {
 	Consumers map[string]ConsumerConfig `mapstructure:"consumers"`
 	// Registered Message handlers used by consumers
 	Handlers map[string]MessageHandler
+	// Registered options used by consumers
+	ConsumerOptions map[string][]ConsumerOption
+	// Producers describes the producers created by the rabbids client.
+	Producers map[string]ProducerConfig `mapstructure:"producers"`
+	// Registered options used by the producers
+	ProducerOptions map[string][]ProducerOption
+	// Schedules describes the messages published on a cron schedule by the Scheduler.
+	Schedules map[string]ScheduleConfig `mapstructure:"schedules"`
+	// Registered functions creating the messages of the schedules
+	SchedulePublishings map[string]func() Publishing
+}
+
+// ProducerConfig describes the configuration of one producer created by the rabbids client.
+type ProducerConfig struct {
+	// Connection is the name of the connection used by the producer.
+	Connection string `mapstructure:"connection"`
 }
 
 // Connection describe a config for one connection.
@@ -50,16 +99,102 @@ type Connection struct {
 	Timeout time.Duration `mapstructure:"timeout"`
 	Sleep   time.Duration `mapstructure:"sleep"`
 	Retries int           `mapstructure:"retries"`
+	// ChannelsPerConnection is the max number of consumer channels sharing one TCP connection,
+	// more connections are opened when the number of consumers is greater. Zero means unlimited.
+	ChannelsPerConnection int `mapstructure:"channels_per_connection"`
+	// ConnectionName is the template of the name shown by the broker management UI, with the placeholders
+	// {name} (the name used by rabbids, like rabbids.default), {hostname} and {pid}.
+	ConnectionName string `mapstructure:"connection_name"`
+	// DelayStrategy is the strategy used by the producers of this connection to send the messages
+	// with delay: levels (default), plugin or ttl. See DelayStrategy.
+	DelayStrategy string `mapstructure:"delay_strategy"`
+	// DelayPrefix is the prefix of the exchanges and queues created by the delay strategy,
+	// the default is rabbids.delay.
+	DelayPrefix string `mapstructure:"delay_prefix"`
+	// MaxDelay is the max delay of the messages sent by the producers of this connection,
+	// reducing the number of levels declared by the levels strategy. The default is rabbids.MaxDelay.
+	MaxDelay time.Duration `mapstructure:"max_delay"`
+	// DelayQueueType is the type of the delay queues: classic (default) or quorum.
+	DelayQueueType string `mapstructure:"delay_queue_type"`
+	// DeclareDelay declare the delay infrastructure when the client starts, instead of
+	// on the first message sent with a delay.
+	DeclareDelay bool `mapstructure:"declare_delay"`
+	// DelayResolution is the smallest delay of the levels strategy, between 1ms and 1s (default).
+	DelayResolution time.Duration `mapstructure:"delay_resolution"`
+	// DelayBypass skip the delay infrastructure for the delays less than this threshold, see WithDelayBypass.
+	DelayBypass time.Duration `mapstructure:"delay_bypass"`
+	// DelayBypassMode is the mode used to bypass the delay: immediate (default) or hold.
+	DelayBypassMode string `mapstructure:"delay_bypass_mode"`
 }
 
 // ConsumerConfig describes consumer's configuration.
 type ConsumerConfig struct {
-	Connection    string      `mapstructure:"connection"`
-	Workers       int         `mapstructure:"workers"`
-	PrefetchCount int         `mapstructure:"prefetch_count"`
-	DeadLetter    string      `mapstructure:"dead_letter"`
-	Queue         QueueConfig `mapstructure:"queue"`
-	Options       Options     `mapstructure:"options"`
+	// Type of the consumer, empty for consumers with registered handlers or "reprocessor".
+	Type          string `mapstructure:"type"`
+	Connection    string `mapstructure:"connection"`
+	Workers       int    `mapstructure:"workers"`
+	PrefetchCount int    `mapstructure:"prefetch_count"`
+	// PrefetchSize is the max size in bytes of the unacknowledged messages, zero means unlimited.
+	PrefetchSize int `mapstructure:"prefetch_size"`
+	// QosGlobal apply the prefetch limits to all the consumers of the channel instead of per consumer
+	// (for quorum queues global QoS is not supported).
+	QosGlobal  bool        `mapstructure:"qos_global"`
+	DeadLetter string      `mapstructure:"dead_letter"`
+	Queue      QueueConfig `mapstructure:"queue"`
+	Options    Options     `mapstructure:"options"`
+	// CheckInterval is the interval used by the supervisor to check if the consumer is alive,
+	// by default the interval passed to the supervisor.
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+	// DedicatedConnection make the consumer use a TCP connection only for itself,
+	// so other consumers can't starve it.
+	DedicatedConnection bool `mapstructure:"dedicated_connection"`
+	// OnError defines what happens when the handler returns an error or panics without acknowledging the message.
+	OnError string `mapstructure:"on_error"`
+	// NackStrategy defines what happens when the handler rejects a message with requeue.
+	NackStrategy string `mapstructure:"nack_strategy"`
+	// RequeueDelay is the minimum delay used by the delayed-requeue strategy.
+	RequeueDelay time.Duration `mapstructure:"requeue_delay"`
+	// RequeueJitter is the max random duration added to the RequeueDelay.
+	RequeueJitter time.Duration `mapstructure:"requeue_jitter"`
+	// RequeueBackoff is the factor multiplying the RequeueDelay for every time the message was requeued,
+	// creating an exponential backoff when greater than one.
+	RequeueBackoff float64 `mapstructure:"requeue_backoff"`
+	// RequeueMaxDelay is the max delay of the exponential backoff, default 1h.
+	RequeueMaxDelay time.Duration `mapstructure:"requeue_max_delay"`
+	// RequeueMaxAttempts is the max number of delayed requeues of one message, after that the message is
+	// rejected without requeue, going to the queue dead letter. Zero means unlimited.
+	RequeueMaxAttempts int `mapstructure:"requeue_max_attempts"`
+	// AckBatchSize enable the ack batching when greater than one, the acks are sent to the broker
+	// using multiple=true after the number of contiguous acked messages reach this size.
+	AckBatchSize int `mapstructure:"ack_batch_size"`
+	// AckBatchInterval is the max time one ack is kept waiting for the batch.
+	AckBatchInterval time.Duration `mapstructure:"ack_batch_interval"`
+	// MaxAge is the max age of a message, based on the message timestamp.
+	// Older messages are acked without being passed to the handler.
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// ConsumerTag is the template of the consumer tag, with the placeholders {name} (the consumer name),
+	// {worker} (a sequence incremented for every consumer created), {hostname} and {pid}.
+	ConsumerTag string `mapstructure:"consumer_tag"`
+	// Singleton run the consumer in only one instance across a fleet, see Singleton.
+	Singleton bool `mapstructure:"singleton"`
+	// CircuitBreaker pause the consumer after consecutive handler errors.
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+	// Stream is the config used by the consumers of stream queues.
+	Stream StreamConfig `mapstructure:"stream"`
+	// Reprocess is the config used by the reprocessor consumers.
+	Reprocess ReprocessConfig `mapstructure:"reprocess"`
+	// SkipExpired ack without passing to the handler the messages with the timestamp plus the
+	// expiration property in the past.
+	SkipExpired bool `mapstructure:"skip_expired"`
+	// ConsumerTimeout is the broker delivery acknowledgement timeout, by default the
+	// x-consumer-timeout queue argument or the RabbitMQ default.
+	ConsumerTimeout time.Duration `mapstructure:"consumer_timeout"`
+	// TimeoutStrategy defines what happens when the handler is close to the consumer timeout.
+	// The handlers are not watched when it's empty.
+	TimeoutStrategy string `mapstructure:"timeout_strategy"`
+	// RoutingKeyMetrics enable the outcome metrics by routing key (Metrics.RoutingKeyHandled) for the
+	// first routing keys received, up to this number, the other keys are reported as RoutingKeyOther.
+	RoutingKeyMetrics int `mapstructure:"routing_key_metrics"`
 }
 
 // ExchangeConfig describes exchange's configuration.
@@ -129,6 +264,51 @@ func setConfigDefaults(config *Config) {
 			cfg.PrefetchCount = cfg.Workers + 2
 		}
 
+		if cfg.NackStrategy == "" {
+			cfg.NackStrategy = NackStrategyRequeue
+		}
+
+		if cfg.AckBatchSize > 1 && cfg.AckBatchInterval <= 0 {
+			cfg.AckBatchInterval = DefaultAckBatchInterval
+		}
+
+		if cfg.OnError == "" {
+			cfg.OnError = OnErrorRequeue
+		}
+
+		if cfg.ConsumerTag == "" {
+			cfg.ConsumerTag = DefaultConsumerTag
+		}
+
+		if cfg.CircuitBreaker.Threshold > 0 && cfg.CircuitBreaker.CoolDown <= 0 {
+			cfg.CircuitBreaker.CoolDown = DefaultCircuitBreakerCoolDown
+		}
+
+		if cfg.Stream.CheckpointEvery <= 0 {
+			cfg.Stream.CheckpointEvery = 1
+		}
+
+		if cfg.TimeoutStrategy != "" && cfg.ConsumerTimeout == 0 {
+			cfg.ConsumerTimeout = DefaultConsumerTimeout
+			if timeout, ok := queueConsumerTimeout(cfg.Queue.Options.Args); ok {
+				cfg.ConsumerTimeout = timeout
+			}
+		}
+
+		if cfg.usesDelay() {
+			if cfg.RequeueDelay <= 0 {
+				cfg.RequeueDelay = DefaultRequeueDelay
+			}
+
+			if cfg.RequeueJitter <= 0 {
+				cfg.RequeueJitter = cfg.RequeueDelay / 2
+			}
+
+			if cfg.RequeueBackoff > 1 && cfg.RequeueMaxDelay <= 0 {
+				cfg.RequeueMaxDelay = DefaultRequeueMaxDelay
+			}
+		}
+
 		config.Consumers[k] = cfg
 	}
 }
@@ -136,12 +316,43 @@ func setConfigDefaults(config *Config) {
 // RegisterHandler is used to set the MessageHandler used by one Consumer.
 // The consumerName MUST be equal as the name used by the Consumer
 // (the key inside the map of consumers).
-func (c *Config) RegisterHandler(consumerName string, h MessageHandler) {
+// The options are applied every time the consumer is created.
+func (c *Config) RegisterHandler(consumerName string, h MessageHandler, opts ...ConsumerOption) {
 	if c.Handlers == nil {
 		c.Handlers = map[string]MessageHandler{}
 	}
 
 	c.Handlers[consumerName] = h
+
+	if len(opts) == 0 {
+		return
+	}
+
+	if c.ConsumerOptions == nil {
+		c.ConsumerOptions = map[string][]ConsumerOption{}
+	}
+
+	c.ConsumerOptions[consumerName] = append(c.ConsumerOptions[consumerName], opts...)
+}
+
+// RegisterProducer register the options used to create one producer from the config.
+// The producerName MUST be equal as the name used inside the map of producers.
+func (c *Config) RegisterProducer(producerName string, opts ...ProducerOption) {
+	if c.ProducerOptions == nil {
+		c.ProducerOptions = map[string][]ProducerOption{}
+	}
+
+	c.ProducerOptions[producerName] = append(c.ProducerOptions[producerName], opts...)
+}
+
+// RegisterSchedule register the function creating the message published by one schedule,
+// instead of the exchange, routing key and data from the schedule config.
+func (c *Config) RegisterSchedule(scheduleName string, fn func() Publishing) {
+	if c.SchedulePublishings == nil {
+		c.SchedulePublishings = map[string]func() Publishing{}
+	}
+
+	c.SchedulePublishings[scheduleName] = fn
 }
 
 // ConfigFromFilename is a wrapper to open the file and pass to ConfigFromFile.
@@ -219,3 +430,18 @@ func getConfigType(file string) string {
 
 	return ""
 }
+
+// delayOptions return the options of the delay strategy used by the producers of the connection.
+func (c Connection) delayOptions() DelayOptions {
+	return DelayOptions{
+		Prefix:     c.DelayPrefix,
+		MaxDelay:   c.MaxDelay,
+		QueueType:  c.DelayQueueType,
+		Resolution: c.DelayResolution,
+	}
+}
+
+// usesDelay return true when the consumer publish the messages again using the delay infrastructure.
+func (c ConsumerConfig) usesDelay() bool {
+	return c.NackStrategy == NackStrategyDelayedRequeue || c.OnError == OnErrorDelayedRetry
+}
@@ -17,6 +17,23 @@ func Test_withDefaults(t *testing.T) {
 				Connection: "server1",
 				Queue:      QueueConfig{Name: "fooo"},
 			},
+			"consumer2": {
+				Connection:   "server1",
+				Queue:        QueueConfig{Name: "baar"},
+				NackStrategy: NackStrategyDelayedRequeue,
+				RequeueDelay: 10 * time.Second,
+			},
+			"consumer3": {
+				Connection:     "server1",
+				Queue:          QueueConfig{Name: "baaz"},
+				OnError:        OnErrorDelayedRetry,
+				RequeueBackoff: 2,
+			},
+			"consumer4": {
+				Connection:      "server1",
+				Queue:           QueueConfig{Name: "quux"},
+				TimeoutStrategy: TimeoutStrategyWarn,
+			},
 		},
 	}
 
@@ -26,4 +43,11 @@ func Test_withDefaults(t *testing.T) {
 	require.Equal(t, 500*time.Millisecond, config.Connections["de"].Sleep)
 	require.Equal(t, 1, config.Consumers["consumer1"].Workers)
 	require.Equal(t, 3, config.Consumers["consumer1"].PrefetchCount)
+	require.Equal(t, NackStrategyRequeue, config.Consumers["consumer1"].NackStrategy)
+	require.Equal(t, 10*time.Second, config.Consumers["consumer2"].RequeueDelay)
+	require.Equal(t, 5*time.Second, config.Consumers["consumer2"].RequeueJitter)
+	require.Equal(t, time.Duration(0), config.Consumers["consumer2"].RequeueMaxDelay)
+	require.Equal(t, DefaultRequeueMaxDelay, config.Consumers["consumer3"].RequeueMaxDelay)
+	require.Equal(t, time.Duration(0), config.Consumers["consumer1"].ConsumerTimeout)
+	require.Equal(t, DefaultConsumerTimeout, config.Consumers["consumer4"].ConsumerTimeout)
 }
@@ -0,0 +1,79 @@
+package rabbids
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// The states of the connections reported by Connections.
+const (
+	ConnectionConnected    = "connected"
+	ConnectionReconnecting = "reconnecting"
+)
+
+// connectionStats track one connection of the client, the counters are updated atomically because
+// the channels are closed by the amqp goroutines.
+type connectionStats struct {
+	// openedAt is the time of the last connection opened, in unix nano.
+	openedAt int64
+	attempts int64
+	channels int64
+}
+
+// stats returns the stats of the connection, the connsMutex MUST be held by the caller.
+func (r *Rabbids) stats(key string) *connectionStats {
+	if r.connStats == nil {
+		r.connStats = map[string]*connectionStats{}
+	}
+
+	s, ok := r.connStats[key]
+	if !ok {
+		s = &connectionStats{}
+		r.connStats[key] = s
+	}
+
+	return s
+}
+
+func (s *connectionStats) opened(now time.Time) {
+	atomic.StoreInt64(&s.openedAt, now.UnixNano())
+}
+
+// track count the channel as open until it is closed, by the client or together with the connection.
+func (s *connectionStats) track(ch *amqp.Channel) {
+	atomic.AddInt64(&s.channels, 1)
+
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	go func() {
+		for range closed {
+		}
+
+		atomic.AddInt64(&s.channels, -1)
+	}()
+}
+
+// status fill the state of the connection, open is the state of the amqp.Connection.
+func (s *connectionStats) status(c *ConnectionStatus) {
+	c.ReconnectAttempts = int(atomic.LoadInt64(&s.attempts))
+	c.Channels = int(atomic.LoadInt64(&s.channels))
+
+	if openedAt := atomic.LoadInt64(&s.openedAt); openedAt > 0 {
+		t := time.Unix(0, openedAt)
+		c.OpenedAt = &t
+	}
+}
+
+// checkConnections report the state of the connections of the client to the Metrics.
+func (s *supervisor) checkConnections(now time.Time) {
+	for _, c := range s.rabbids.Connections() {
+		var since time.Duration
+		if c.OpenedAt != nil {
+			since = now.Sub(*c.OpenedAt)
+		}
+
+		s.rabbids.metrics.ConnectionState(c.Name, c.Open, since, c.Channels)
+	}
+}
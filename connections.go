@@ -0,0 +1,37 @@
+package rabbids
+
+import (
+	"fmt"
+	"sort"
+)
+
+// consumerConnectionKey return the key of the TCP connection used by one consumer.
+// Dedicated consumers have their own connection and, when the channels_per_connection is set,
+// the other consumers are split in groups by name, so one consumer always uses the same connection.
+func (r *Rabbids) consumerConnectionKey(name string, cfg ConsumerConfig) string {
+	if cfg.DedicatedConnection {
+		return fmt.Sprintf("%s.%s", cfg.Connection, name)
+	}
+
+	limit := r.config.Connections[cfg.Connection].ChannelsPerConnection
+	if limit <= 0 {
+		return cfg.Connection
+	}
+
+	names := []string{}
+
+	for n, c := range r.config.Consumers {
+		if c.Connection == cfg.Connection && !c.DedicatedConnection {
+			names = append(names, n)
+		}
+	}
+
+	sort.Strings(names)
+
+	group := sort.SearchStrings(names, name) / limit
+	if group == 0 {
+		return cfg.Connection
+	}
+
+	return fmt.Sprintf("%s.%d", cfg.Connection, group)
+}
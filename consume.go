@@ -0,0 +1,96 @@
+package rabbids
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ConsumeCheckInterval is the interval used by the supervisor started by Consume to check the consumer.
+const ConsumeCheckInterval = time.Second
+
+// Consume start the consumer with the consumerName from the config and return a channel
+// with all the messages received, for callers who want to run their own loop instead of using a MessageHandler.
+// The consumer is recreated by a supervisor when the channel or the connection is closed,
+// so reconnections are handled internally.
+// The messages MUST be acknowledged by the caller, unless the consumer uses auto_ack.
+// The stop function stops the consumer, closes the connections and the messages channel.
+func Consume(config *Config, consumerName string, opts ...Option) (<-chan Message, func(), error) {
+	cfg, ok := config.Consumers[consumerName]
+	if !ok {
+		return nil, nil, fmt.Errorf("consumer \"%s\" did not exist", consumerName)
+	}
+
+	var once sync.Once
+
+	ch := newConsumeChannel()
+
+	c := *config
+	c.Consumers = map[string]ConsumerConfig{consumerName: cfg}
+	c.Handlers = map[string]MessageHandler{consumerName: MessageHandlerFunc(ch.send)}
+
+	rab, err := New(&c, NoOPLoggerFN, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stopSupervisor, err := StartSupervisor(rab, ConsumeCheckInterval)
+	if err != nil {
+		_ = rab.Close()
+
+		return nil, nil, err
+	}
+
+	stop := func() {
+		once.Do(func() {
+			ch.stop(func() {
+				stopSupervisor()
+
+				if err := rab.Close(); err != nil {
+					rab.log.Error("failed to close the rabbids client", Fields{"error": err})
+				}
+			})
+		})
+	}
+
+	return ch.msgs, stop, nil
+}
+
+// consumeChannel send the messages received by the consumer started with Consume to the caller.
+type consumeChannel struct {
+	mutex  sync.RWMutex
+	closed bool
+	msgs   chan Message
+	done   chan struct{}
+}
+
+func newConsumeChannel() *consumeChannel {
+	return &consumeChannel{msgs: make(chan Message), done: make(chan struct{})}
+}
+
+// send block until the caller receives the message or the channel is stopped.
+func (c *consumeChannel) send(m Message) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.closed {
+		return
+	}
+
+	select {
+	case c.msgs <- m:
+	case <-c.done:
+	}
+}
+
+// stop release the handlers waiting the caller, stop the consumer and close the messages channel.
+func (c *consumeChannel) stop(stopConsumer func()) {
+	close(c.done)
+	stopConsumer()
+
+	// wait for any handler still sending before closing the channel
+	c.mutex.Lock()
+	c.closed = true
+	close(c.msgs)
+	c.mutex.Unlock()
+}
@@ -0,0 +1,57 @@
+package rabbids
+
+import (
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsume_unknownConsumer(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := Consume(&Config{}, "events")
+	require.EqualError(t, err, `consumer "events" did not exist`)
+}
+
+func TestConsumeChannel(t *testing.T) {
+	t.Parallel()
+
+	ch := newConsumeChannel()
+	ack := &fakeAcknowledger{}
+	handler := MessageHandlerFunc(ch.send)
+
+	go func() {
+		for i := uint64(1); i <= 3; i++ {
+			handler.Handle(Message{Delivery: amqp.Delivery{Acknowledger: ack, DeliveryTag: i}})
+		}
+	}()
+
+	for i := uint64(1); i <= 3; i++ {
+		m := <-ch.msgs
+		require.Equal(t, i, m.DeliveryTag)
+		require.NoError(t, m.Ack(false))
+	}
+
+	require.Equal(t, 3, ack.acks, "the messages are acknowledged by the caller")
+
+	// one handler blocked because the caller stopped receiving
+	sent := make(chan struct{})
+	go func() {
+		handler.Handle(Message{Delivery: amqp.Delivery{Acknowledger: ack, DeliveryTag: 4}})
+		close(sent)
+	}()
+
+	stopped := false
+	ch.stop(func() { stopped = true })
+	<-sent
+
+	require.True(t, stopped)
+
+	_, ok := <-ch.msgs
+	require.False(t, ok, "the messages channel should be closed")
+
+	// the handlers called after the stop return without sending
+	handler.Handle(Message{Delivery: amqp.Delivery{Acknowledger: ack, DeliveryTag: 5}})
+	require.Equal(t, 3, ack.acks)
+}
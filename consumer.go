@@ -1,8 +1,15 @@
 package rabbids
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"runtime/debug"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"gopkg.in/tomb.v2"
 
@@ -12,73 +19,395 @@ import (
 
 // Consumer is a high level rabbitMQ consumer.
 type Consumer struct {
-	handler    MessageHandler
-	number     int64
-	name       string
-	queue      string
-	workerPool *grpool.Pool
-	opts       Options
-	channel    *amqp.Channel
-	t          tomb.Tomb
-	log        LoggerFN
+	handler      MessageHandler
+	handlerOnce  sync.Once
+	number       int64
+	name         string
+	tag          string
+	queue        string
+	workers      int
+	prefetch     int64
+	prefetchSize int
+	qosGlobal    bool
+	inFlight     int64
+	lastProgress int64
+	restarts     int
+	lastErr      error
+	draining     int32
+	started      int32
+	reloaded     int32
+	recycled     int32
+	drained      chan struct{}
+	workerPool   *grpool.Pool
+	opts         Options
+	channel      *amqp.Channel
+	// channelMutex guard the channel, replaced by the consume goroutine when the channel is recovered.
+	channelMutex sync.RWMutex
+	t            tomb.Tomb
+	ctx          context.Context
+	cancel       context.CancelFunc
+	log          Logger
+	producer     func() (*Producer, error)
+	// redeclare open a new channel for the consumer declaring the topology again,
+	// used when the queue is not found and to recover the channel closed by the broker.
+	redeclare       func() (*amqp.Channel, error)
+	lastRecovery    time.Time
+	auditHooks      []AuditHook
+	nack            nackConfig
+	onErrorStrategy string
+	validators      []Validator
+	transformers    []Transformer
+	sampling        *samplingConfig
+	timeout         consumerTimeoutConfig
+	stream          streamConsumer
+	backpressure    backpressureConfig
+	breaker         *circuitBreaker
+	// lock is the exclusive queue held by group and singleton consumers.
+	lock          string
+	singleton     bool
+	saturated     int32
+	createdAt     time.Time
+	events        func(Event)
+	deadLetterEx  string
+	deadLetterKey string
+	ackBatch      ackBatchConfig
+	acks          *ackBatcher
+	metrics       Metrics
+	tracer        Tracer
+	hooks         hookSet
+	reportErr     ErrorReporter
+	routingKeys   *routingKeyLimiter
+	maxAge        time.Duration
+	skipExpired   bool
+}
+
+// ackBatchConfig describe how the acks are batched, the batching is disabled when the size is lower than 2.
+type ackBatchConfig struct {
+	size     int
+	interval time.Duration
+}
+
+// nackConfig describe what the consumer does when the handler rejects a message with requeue.
+type nackConfig struct {
+	strategy    string
+	delay       time.Duration
+	jitter      time.Duration
+	backoff     float64
+	maxDelay    time.Duration
+	maxAttempts int64
+}
+
+// ConsumerStatus is a snapshot of the consumer state at the time it was requested.
+type ConsumerStatus struct {
+	// Name of the consumer (the key used inside the config).
+	Name string
+	// Queue consumed by the consumer.
+	Queue string
+	// Alive is true while the consumer is running.
+	Alive bool
+	// Workers is the max number of messages handled concurrently.
+	Workers int
+	// Prefetch is the prefetch count applied on the consumer channel.
+	Prefetch int
+	// InFlight is the number of messages being handled right now.
+	InFlight int64
+	// LastError is the last error that stopped this consumer or a previous one with the same name.
+	LastError error
+	// Restarts is the number of times the consumer was recreated.
+	Restarts int
+	// Saturated is true while all the workers are busy for longer than the backpressure threshold.
+	Saturated bool
+	// CreatedAt is the time the consumer was created, the last restart time when Restarts > 0.
+	CreatedAt time.Time
 }
 
 // Run start a goroutine to consume messages from a queue and pass to one runner.
 func (c *Consumer) Run() {
+	atomic.StoreInt32(&c.started, 1)
 	c.t.Go(func() error {
-		defer func() {
-			if c.channel == nil {
-				return
-			}
-			err := c.channel.Close()
-			if err != nil {
-				c.log("Error closing the consumer channel", Fields{"error": err, "name": c.name})
-			}
-		}()
-		d, err := c.channel.Consume(c.queue, fmt.Sprintf("rabbitmq-%s-%d", c.name, c.number),
-			c.opts.AutoAck,
-			c.opts.Exclusive,
-			c.opts.NoLocal,
-			c.opts.NoWait,
-			c.opts.Args)
+		if c.singleton {
+			c.emit(Event{Type: EventLeadershipAcquired, Consumer: c.name, Queue: c.queue})
+		}
+
+		err := c.consume()
+		if err != nil {
+			c.emit(Event{Type: EventConsumerDied, Consumer: c.name, Queue: c.queue, Err: err})
+		}
+
+		if c.singleton {
+			c.emit(Event{Type: EventLeadershipLost, Consumer: c.name, Queue: c.queue, Err: err})
+		}
+
+		return err
+	})
+}
+
+// consume start consuming the queue and block until the consumer stops.
+func (c *Consumer) consume() error {
+	defer func() {
+		ch := c.amqpChannel()
+		if ch == nil {
+			return
+		}
+		err := ch.Close()
+		if err != nil {
+			c.log.Error("Error closing the consumer channel", Fields{"error": err, "name": c.name})
+		}
+	}()
+	args, err := c.consumeArgs()
+	if err != nil {
+		c.log.Error("Failed to start consume", Fields{"error": err, "name": c.name})
+		return err
+	}
+	d, closed, err := c.subscribe(args)
+	if err != nil {
+		return err
+	}
+
+	if !c.opts.AutoAck && c.ackBatch.size > 1 {
+		c.acks = newAckBatcher(c.amqpChannel(), c.ackBatch.size)
+		c.t.Go(c.flushAcksLoop)
+	}
+
+	return c.consumeLoop(d, closed)
+}
+
+// consumeLoop run the consume loop until the consumer stops, opening a new channel
+// when the channel closed by the broker can be recovered.
+func (c *Consumer) consumeLoop(d <-chan amqp.Delivery, closed <-chan *amqp.Error) error {
+	var err error
+
+	for {
+		if c.opts.AutoAck {
+			err = c.autoAckLoop(d, closed)
+		} else {
+			err = c.loop(d, closed)
+		}
+
+		if c.isRecycled() {
+			return ErrConsumerStuck
+		}
+
+		if !c.canRecoverChannel(err) {
+			return err
+		}
+
+		d, closed, err = c.recoverChannel(err)
 		if err != nil {
-			c.log("Failed to start consume", Fields{"error": err, "name": c.name})
 			return err
 		}
-		dying := c.t.Dying()
-		closed := c.channel.NotifyClose(make(chan *amqp.Error))
-		for {
-			select {
-			case <-dying:
-				// When dying we wait for any remaining worker to finish and close the handler
-				c.workerPool.WaitAll()
-				c.handler.Close()
-				return nil
-			case err := <-closed:
-				return err
-			case msg, ok := <-d:
-				if !ok {
-					return errors.New("internal channel closed")
+	}
+}
+
+// subscribe start consuming the queue with the current channel.
+func (c *Consumer) subscribe(args amqp.Table) (<-chan amqp.Delivery, <-chan *amqp.Error, error) {
+	d, err := c.startConsume(args)
+	if err != nil {
+		c.log.Error("Failed to start consume", Fields{"error": err, "name": c.name})
+		return nil, nil, err
+	}
+
+	closed := c.amqpChannel().NotifyClose(make(chan *amqp.Error))
+	c.emit(Event{Type: EventConsumerStarted, Consumer: c.name, Queue: c.queue})
+
+	return d, closed, nil
+}
+
+// loop receive the deliveries and send them to the worker pool.
+func (c *Consumer) loop(d <-chan amqp.Delivery, closed <-chan *amqp.Error) error {
+	dying := c.t.Dying()
+
+	for {
+		select {
+		case <-dying:
+			c.shutdown(c.workerPool.WaitAll)
+
+			return nil
+		case err := <-closed:
+			return c.channelClosed(err)
+		case msg, ok := <-d:
+			if !ok {
+				if c.isDraining() {
+					close(c.drained)
+					d = nil
+
+					continue
+				}
+
+				return errors.New("internal channel closed")
+			}
+
+			// the message is returned to the queue by the broker when the channel is closed
+			if ok, err := c.waitCircuit(closed); !ok {
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			received := time.Now()
+
+			c.workerPool.WaitCount(1)
+			c.startJob()
+			c.receiveOffset(msg)
+			fn := func(msg amqp.Delivery) func() {
+				return func() {
+					c.handle(msg, received)
+					c.trackOffset(msg)
+					c.finishJob()
+					c.workerPool.JobDone()
 				}
-				c.workerPool.WaitCount(1)
-				fn := func(msg amqp.Delivery) func() {
-					return func() {
-						c.handler.Handle(Message{msg})
-						c.workerPool.JobDone()
+			}(msg)
+			// When Workers goroutines are in flight, Send a Job blocks until one of the
+			// workers finishes.
+			c.dispatch(fn)
+		}
+	}
+}
+
+// shutdown is the end of both consume loops: it waits for any remaining worker to finish with the wait function,
+// then flush the acks, save the stream offset and release the consumer lock.
+func (c *Consumer) shutdown(wait func()) {
+	wait()
+	c.flushAcks()
+	c.checkpoint()
+	c.releaseLock()
+}
+
+// channelClosed log the reason of the channel closed by the broker, returning the error to the consume loop.
+func (c *Consumer) channelClosed(err *amqp.Error) error {
+	// the channel closed by the client
+	if err == nil {
+		return errors.New("internal channel closed")
+	}
+
+	if isConsumerTimeout(err) {
+		c.log.Error("the channel was closed by the broker consumer timeout, increase the consumer_timeout or use the abort timeout_strategy", Fields{
+			"consumer": c.name,
+			"error":    err,
+		})
+	}
+
+	if isNotFound(err) {
+		c.log.Warn("the consumer topology was not found, it will be declared again when the consumer is recreated", Fields{
+			"consumer": c.name,
+			"error":    err,
+		})
+	}
+
+	return err
+}
+
+// flushAcksLoop send the batched acks to the broker on every interval.
+func (c *Consumer) flushAcksLoop() error {
+	ticker := time.NewTicker(c.ackBatch.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.t.Dying():
+			return nil
+		case <-ticker.C:
+			c.flushAcks()
+		}
+	}
+}
+
+// flushAcks send the batched acks to the broker, when the batching is enabled.
+func (c *Consumer) flushAcks() {
+	if c.acks == nil {
+		return
+	}
+
+	if err := c.acks.flush(); err != nil {
+		c.log.Error("failed to flush the acks", Fields{"consumer": c.name, "error": err})
+	}
+}
+
+// autoAckLoop is used when the messages are acknowledged by the broker on delivery.
+// There is nothing to track for each message so, instead of using the worker pool,
+// every worker receives the deliveries directly.
+func (c *Consumer) autoAckLoop(d <-chan amqp.Delivery, closed <-chan *amqp.Error) error {
+	var wg sync.WaitGroup
+
+	dying := c.t.Dying()
+	finished := make(chan struct{})
+
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-dying:
+					return
+				case msg, ok := <-d:
+					if !ok {
+						return
 					}
-				}(msg)
-				// When Workers goroutines are in flight, Send a Job blocks until one of the
-				// workers finishes.
-				c.workerPool.JobQueue <- fn
+
+					c.startJob()
+					c.receiveOffset(msg)
+					c.handle(msg, time.Now())
+					c.trackOffset(msg)
+					c.finishJob()
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	for {
+		select {
+		case <-dying:
+			c.shutdown(wg.Wait)
+
+			return nil
+		case err := <-closed:
+			// the deliveries channel is closed with the amqp channel, the old workers
+			// must finish before a recovered channel starts new ones.
+			wg.Wait()
+
+			return c.channelClosed(err)
+		case <-finished:
+			if !c.isDraining() {
+				return errors.New("internal channel closed")
 			}
+
+			close(c.drained)
+
+			finished = nil
 		}
-	})
+	}
 }
 
-// Kill will try to stop the internal work.
+// Kill will try to stop the internal work and close the handler.
 func (c *Consumer) Kill() {
+	if c.stop() {
+		c.handlerOnce.Do(c.handler.Close)
+	}
+}
+
+// stop the internal work keeping the handler open, used when a new consumer with
+// the same handler replaces this one. It returns false when the consumer never ran.
+func (c *Consumer) stop() bool {
 	c.t.Kill(nil)
+
+	// the tomb of a consumer that never ran is never dead
+	if atomic.LoadInt32(&c.started) == 0 {
+		return false
+	}
+
 	<-c.t.Dead()
+
+	return true
 }
 
 // Alive returns true if the tomb is not in a dying or dead state.
@@ -86,7 +415,360 @@ func (c *Consumer) Alive() bool {
 	return c.t.Alive()
 }
 
+// running returns true if the consumer was started and is still alive.
+func (c *Consumer) running() bool {
+	return atomic.LoadInt32(&c.started) == 1 && c.Alive()
+}
+
 // Name return the consumer name.
 func (c *Consumer) Name() string {
 	return c.name
 }
+
+// Status returns a snapshot of the current consumer state.
+func (c *Consumer) Status() ConsumerStatus {
+	return ConsumerStatus{
+		Name:      c.name,
+		Queue:     c.queue,
+		Alive:     c.Alive(),
+		Workers:   c.workers,
+		Prefetch:  int(atomic.LoadInt64(&c.prefetch)),
+		InFlight:  atomic.LoadInt64(&c.inFlight),
+		LastError: c.LastError(),
+		Restarts:  c.restarts,
+		Saturated: c.Saturated(),
+		CreatedAt: c.createdAt,
+	}
+}
+
+// handle pass one delivery, received from the broker at the given time, to the handler
+// and call the audit hooks with the result.
+func (c *Consumer) handle(d amqp.Delivery, received time.Time) {
+	if c.acks != nil {
+		d.Acknowledger = c.acks
+	}
+
+	now := time.Now()
+	c.recordLag(d, now)
+
+	if c.expired(d, now) {
+		c.dropExpired(d)
+
+		return
+	}
+
+	if c.skipSample(d) {
+		return
+	}
+
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if deadline, ok := MessageDeadline(d.Headers); ok {
+		if !deadline.After(now) {
+			c.dropExpired(d)
+
+			return
+		}
+
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	ctx, stop := c.watchTimeout(ctx, d, received)
+	defer stop()
+
+	if c.nack.strategy == NackStrategyDelayedRequeue && !c.opts.AutoAck {
+		d.Acknowledger = c.delayedRequeue(d)
+	}
+
+	recorder := &outcomeRecorder{Acknowledger: d.Acknowledger, outcome: OutcomeUnacked}
+	if c.opts.AutoAck {
+		recorder.outcome = OutcomeAck
+	}
+
+	d.Acknowledger = recorder
+	m, finish := c.trace(c.newMessage(ctx, d))
+	c.hooks.consumed(c.name, m)
+	start := time.Now()
+
+	m, err := c.transform(m)
+	if err == nil {
+		err = c.validate(m)
+	}
+
+	if err != nil {
+		c.deadLetter(m, err)
+	} else {
+		err = c.invoke(m)
+		c.recordCircuit(err)
+	}
+
+	duration := time.Since(start)
+
+	if err != nil {
+		c.onError(m, recorder, err)
+	}
+
+	outcome, ackErr := recorder.result()
+	if err == nil {
+		err = ackErr
+	}
+
+	c.metrics.MessageHandled(c.name, outcome, duration)
+	c.recordRoutingKey(d.RoutingKey, outcome)
+	finish(outcome, err)
+	c.hooks.handled(c.name, m, outcome)
+
+	for _, h := range c.auditHooks {
+		h(m, outcome, duration, err)
+	}
+}
+
+// recordLag report the age of the messages with Timestamp, the clock skew between the hosts
+// can make the timestamp greater than now, reported as zero.
+func (c *Consumer) recordLag(d amqp.Delivery, now time.Time) {
+	if d.Timestamp.IsZero() {
+		return
+	}
+
+	lag := now.Sub(d.Timestamp)
+	if lag < 0 {
+		lag = 0
+	}
+
+	c.metrics.MessageLag(c.name, lag)
+}
+
+// The pprof labels set while the handler is running, so the CPU profiles attribute the time to the consumers.
+const (
+	ProfileConsumerLabel   = "rabbids.consumer"
+	ProfileRoutingKeyLabel = "rabbids.routing_key"
+)
+
+// invoke call the handler with the pprof labels, recovering from panics.
+// The message context carries the labels, for the goroutines started by the handler.
+func (c *Consumer) invoke(m Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+			fields := Fields{
+				"consumer":   c.name,
+				"message-id": m.MessageId,
+				"panic":      r,
+				"stack":      string(debug.Stack()),
+			}
+
+			c.log.Error("recovered from a panic inside the handler", fields)
+			c.reportErr.report(err, fields)
+		}
+	}()
+
+	labels := pprof.Labels(ProfileConsumerLabel, c.name, ProfileRoutingKeyLabel, m.RoutingKey)
+	pprof.Do(m.Context(), labels, func(ctx context.Context) {
+		err = handlerErr(c.handler, m.WithContext(ctx))
+	})
+
+	return err
+}
+
+// onError apply the on_error strategy when the handler fails without acknowledging the message.
+func (c *Consumer) onError(m Message, recorder *outcomeRecorder, err error) {
+	c.log.Error("failed to handle the message", Fields{
+		"consumer":   c.name,
+		"message-id": m.MessageId,
+		"error":      err,
+		"on-error":   c.onErrorStrategy,
+	})
+
+	if outcome, _ := recorder.result(); c.opts.AutoAck || outcome != OutcomeUnacked {
+		return
+	}
+
+	var ackErr error
+
+	switch c.onErrorStrategy {
+	case OnErrorDeadLetter:
+		ackErr = m.Nack(false, false)
+	case OnErrorDiscard:
+		ackErr = m.Ack(false)
+	case OnErrorDelayedRetry:
+		ackErr = c.delayedRequeue(m.Delivery).Nack(m.DeliveryTag, false, true)
+		recorder.record(OutcomeRequeue, ackErr)
+	default:
+		ackErr = m.Nack(false, true)
+	}
+
+	if ackErr != nil {
+		c.log.Error("failed to apply the on_error strategy", Fields{
+			"consumer":   c.name,
+			"message-id": m.MessageId,
+			"error":      ackErr,
+		})
+	}
+}
+
+// delayedRequeue wraps the delivery acknowledger to requeue the message using the delay infrastructure.
+func (c *Consumer) delayedRequeue(d amqp.Delivery) *delayedRequeueAcknowledger {
+	ack := d.Acknowledger
+	if r, ok := ack.(*outcomeRecorder); ok {
+		ack = r.Acknowledger
+	}
+
+	return &delayedRequeueAcknowledger{
+		Acknowledger: ack,
+		delivery:     d,
+		queue:        c.queue,
+		nack:         c.nack,
+		producer:     c.producer,
+		log:          c.log,
+	}
+}
+
+// expired check if the message is older than the max age or if the message expiration is passed.
+func (c *Consumer) expired(d amqp.Delivery, now time.Time) bool {
+	if d.Timestamp.IsZero() {
+		return false
+	}
+
+	age := now.Sub(d.Timestamp)
+	if c.maxAge > 0 && age > c.maxAge {
+		return true
+	}
+
+	if !c.skipExpired || d.Expiration == "" {
+		return false
+	}
+
+	ttl, err := strconv.ParseInt(d.Expiration, 10, 64)
+
+	return err == nil && age > time.Duration(ttl)*time.Millisecond
+}
+
+func (c *Consumer) dropExpired(d amqp.Delivery) {
+	c.log.Warn("dropping an expired message", Fields{
+		"consumer":   c.name,
+		"message-id": d.MessageId,
+		"timestamp":  d.Timestamp,
+	})
+	c.metrics.MessageExpired(c.name)
+
+	if c.opts.AutoAck {
+		return
+	}
+
+	if err := d.Ack(false); err != nil {
+		c.log.Error("failed to ack an expired message", Fields{"consumer": c.name, "error": err})
+	}
+}
+
+// newMessage wraps the delivery with the helpers bound to this consumer.
+func (c *Consumer) newMessage(ctx context.Context, d amqp.Delivery) Message {
+	return Message{Delivery: d, ctx: ctx, producer: c.producer}
+}
+
+// Drain cancel the consumer subscription, so no new deliveries arrive, and wait
+// until all the deliveries already received are handled or the context is done.
+// The drained consumer is kept alive, without consuming, until it is killed.
+func (c *Consumer) Drain(ctx context.Context) error {
+	select {
+	case <-c.t.Dying():
+		return fmt.Errorf("consumer %s is not running: %w", c.name, c.t.Err())
+	default:
+	}
+
+	if !atomic.CompareAndSwapInt32(&c.draining, 0, 1) {
+		return fmt.Errorf("consumer %s is already draining", c.name)
+	}
+
+	c.log.Info("draining consumer", Fields{"consumer": c.name})
+
+	// without a channel there is no subscription to cancel, only the deliveries in flight to wait.
+	if ch := c.amqpChannel(); ch != nil {
+		if err := ch.Cancel(c.tag, false); err != nil {
+			return fmt.Errorf("failed to cancel the consumer %s: %w", c.name, err)
+		}
+	}
+
+	select {
+	case <-c.drained:
+	case <-c.t.Dying():
+		return fmt.Errorf("consumer %s stopped while draining: %w", c.name, c.t.Err())
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for atomic.LoadInt64(&c.inFlight) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	c.flushAcks()
+
+	return nil
+}
+
+func (c *Consumer) isDraining() bool {
+	return atomic.LoadInt32(&c.draining) == 1
+}
+
+// isReloaded returns true when the consumer was stopped by a reload changing its config.
+func (c *Consumer) isReloaded() bool {
+	return atomic.LoadInt32(&c.reloaded) == 1
+}
+
+// amqpChannel returns the current channel of the consumer.
+func (c *Consumer) amqpChannel() *amqp.Channel {
+	c.channelMutex.RLock()
+	defer c.channelMutex.RUnlock()
+
+	return c.channel
+}
+
+// setChannel replace the channel of the consumer, after the channel is opened again.
+func (c *Consumer) setChannel(ch *amqp.Channel) {
+	c.channelMutex.Lock()
+	c.channel = ch
+	c.channelMutex.Unlock()
+}
+
+// SetPrefetch change the prefetch count of the running consumer channel.
+// The new value is valid until the consumer is recreated, the recreated consumer will use
+// the prefetch_count from the config again.
+func (c *Consumer) SetPrefetch(n int) error {
+	if n < 0 {
+		return fmt.Errorf("invalid prefetch count %d, it must be greater than or equal to zero", n)
+	}
+
+	if err := c.amqpChannel().Qos(n, c.prefetchSize, c.qosGlobal); err != nil {
+		return fmt.Errorf("failed to set QoS: %w", err)
+	}
+
+	atomic.StoreInt64(&c.prefetch, int64(n))
+	c.log.Info("consumer prefetch changed", Fields{"consumer": c.name, "prefetch": n})
+
+	return nil
+}
+
+// LastError returns the error that stopped the consumer.
+// When the consumer is running, it returns the error that stopped the previous consumer
+// with the same name, if any.
+func (c *Consumer) LastError() error {
+	if !c.t.Alive() {
+		if err := c.t.Err(); err != nil && err != tomb.ErrStillAlive {
+			return err
+		}
+	}
+
+	return c.lastErr
+}
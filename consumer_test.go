@@ -0,0 +1,543 @@
+package rabbids
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ivpusic/grpool"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_registerConsumer(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{consumers: map[string]*Consumer{}}
+
+	first := &Consumer{name: "foo", queue: "foo-queue", workers: 2}
+	r.registerConsumer(first)
+	first.t.Kill(errors.New("channel closed"))
+
+	second := &Consumer{name: "foo", queue: "foo-queue", workers: 2}
+	r.registerConsumer(second)
+
+	r.registerConsumer(&Consumer{name: "bar", queue: "bar-queue", workers: 1})
+
+	status := r.Consumers()
+	require.Len(t, status, 2)
+	require.Equal(t, "bar", status[0].Name)
+	require.Equal(t, 0, status[0].Restarts)
+	require.Equal(t, "foo", status[1].Name)
+	require.Equal(t, 1, status[1].Restarts)
+	require.Equal(t, 2, status[1].Workers)
+	require.EqualError(t, status[1].LastError, "channel closed")
+}
+
+func BenchmarkConsumerLoop(b *testing.B) {
+	benchs := []struct {
+		name    string
+		autoAck bool
+	}{
+		{name: "worker pool", autoAck: false},
+		{name: "auto ack", autoAck: true},
+	}
+
+	for _, bb := range benchs {
+		bb := bb
+		b.Run(bb.name, func(b *testing.B) {
+			var wg sync.WaitGroup
+
+			wg.Add(b.N)
+
+			c := &Consumer{
+				name:    "bench",
+				workers: 4,
+				handler: MessageHandlerFunc(func(m Message) { wg.Done() }),
+				log:     NoOPLogger{},
+				metrics: NoOPMetrics{},
+				tracer:  NoOPTracer{},
+			}
+			if !bb.autoAck {
+				c.workerPool = grpool.NewPool(c.workers, 0)
+				defer c.workerPool.Release()
+			}
+
+			d := make(chan amqp.Delivery, 100)
+			closed := make(chan *amqp.Error)
+			loop := c.loop
+
+			if bb.autoAck {
+				loop = c.autoAckLoop
+			}
+
+			c.t.Go(func() error { return loop(d, closed) })
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				d <- amqp.Delivery{DeliveryTag: uint64(i)}
+			}
+
+			wg.Wait()
+			b.StopTimer()
+			c.Kill()
+		})
+	}
+}
+
+type fakeAcknowledger struct {
+	acks, nacks, rejects int
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acks++
+
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.nacks++
+
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	a.rejects++
+
+	return nil
+}
+
+func TestConsumer_auditHook(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		handler MessageHandlerFunc
+		want    Outcome
+	}{
+		{"ack", func(m Message) { _ = m.Ack(false) }, OutcomeAck},
+		{"nack", func(m Message) { _ = m.Nack(false, false) }, OutcomeNack},
+		{"reject with requeue", func(m Message) { _ = m.Reject(true) }, OutcomeRequeue},
+		{"unacked", func(m Message) {}, OutcomeUnacked},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got Outcome
+
+			c := &Consumer{name: "audit", handler: tt.handler, log: NoOPLogger{}, metrics: NoOPMetrics{}}
+			err := WithAuditHook(func(m Message, outcome Outcome, _ time.Duration, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "foo", m.MessageId)
+				got = outcome
+			})(c)
+			require.NoError(t, err)
+
+			c.handle(amqp.Delivery{MessageId: "foo", Acknowledger: &fakeAcknowledger{}}, time.Now())
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConsumer_expired(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	tests := []struct {
+		name     string
+		consumer *Consumer
+		delivery amqp.Delivery
+		want     bool
+	}{
+		{"without timestamp", &Consumer{maxAge: time.Second}, amqp.Delivery{}, false},
+		{"younger than max age", &Consumer{maxAge: time.Minute}, amqp.Delivery{Timestamp: now.Add(-time.Second)}, false},
+		{"older than max age", &Consumer{maxAge: time.Minute}, amqp.Delivery{Timestamp: now.Add(-time.Hour)}, true},
+		{
+			"expiration passed but disabled",
+			&Consumer{},
+			amqp.Delivery{Timestamp: now.Add(-time.Hour), Expiration: "1000"},
+			false,
+		},
+		{
+			"expiration passed",
+			&Consumer{skipExpired: true},
+			amqp.Delivery{Timestamp: now.Add(-time.Hour), Expiration: "1000"},
+			true,
+		},
+		{
+			"expiration not passed",
+			&Consumer{skipExpired: true},
+			amqp.Delivery{Timestamp: now.Add(-time.Hour), Expiration: "7200000"},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, tt.consumer.expired(tt.delivery, now))
+		})
+	}
+}
+
+func TestConsumer_onError(t *testing.T) {
+	t.Parallel()
+
+	failure := ErrorHandlerFunc(func(m Message) error { return errors.New("failed") })
+	tests := []struct {
+		name     string
+		strategy string
+		handler  MessageHandler
+		want     fakeAcknowledger
+		outcome  Outcome
+	}{
+		{"requeue", OnErrorRequeue, failure, fakeAcknowledger{nacks: 1}, OutcomeRequeue},
+		{"dead letter", OnErrorDeadLetter, failure, fakeAcknowledger{nacks: 1}, OutcomeNack},
+		{"discard", OnErrorDiscard, failure, fakeAcknowledger{acks: 1}, OutcomeAck},
+		{
+			"panic",
+			OnErrorDeadLetter,
+			MessageHandlerFunc(func(m Message) { panic("boom") }),
+			fakeAcknowledger{nacks: 1},
+			OutcomeNack,
+		},
+		{
+			"acked by the handler",
+			OnErrorRequeue,
+			ErrorHandlerFunc(func(m Message) error {
+				_ = m.Ack(false)
+
+				return errors.New("failed after ack")
+			}),
+			fakeAcknowledger{acks: 1},
+			OutcomeAck,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				gotOutcome Outcome
+				gotErr     error
+			)
+
+			c := &Consumer{
+				name:            "on-error",
+				handler:         tt.handler,
+				log:             NoOPLogger{},
+				onErrorStrategy: tt.strategy,
+				metrics:         NoOPMetrics{},
+				auditHooks: []AuditHook{func(m Message, outcome Outcome, _ time.Duration, err error) {
+					gotOutcome = outcome
+					gotErr = err
+				}},
+			}
+
+			ack := &fakeAcknowledger{}
+			c.handle(amqp.Delivery{Acknowledger: ack}, time.Now())
+			require.Equal(t, tt.want, *ack)
+			require.Equal(t, tt.outcome, gotOutcome)
+			require.Error(t, gotErr)
+		})
+	}
+}
+
+func TestConsumer_profileLabels(t *testing.T) {
+	t.Parallel()
+
+	var consumer, key string
+
+	c := &Consumer{name: "users", log: NoOPLogger{}, metrics: NoOPMetrics{}}
+	c.handler = MessageHandlerFunc(func(m Message) {
+		consumer, _ = pprof.Label(m.Context(), ProfileConsumerLabel)
+		key, _ = pprof.Label(m.Context(), ProfileRoutingKeyLabel)
+
+		panic("boom")
+	})
+
+	err := c.invoke(Message{Delivery: amqp.Delivery{RoutingKey: "user.created"}})
+	require.EqualError(t, err, "handler panic: boom")
+	require.Equal(t, "users", consumer)
+	require.Equal(t, "user.created", key)
+}
+
+func TestConsumer_recordLag(t *testing.T) {
+	t.Parallel()
+
+	m := &lagMetrics{}
+	c := &Consumer{name: "users", metrics: m}
+	now := time.Now()
+
+	c.recordLag(amqp.Delivery{}, now)
+	c.recordLag(amqp.Delivery{Timestamp: now.Add(-3 * time.Second)}, now)
+	c.recordLag(amqp.Delivery{Timestamp: now.Add(time.Second)}, now)
+
+	require.Equal(t, []time.Duration{3 * time.Second, 0}, m.lags, "the messages without timestamp are ignored")
+}
+
+type lagMetrics struct {
+	NoOPMetrics
+	lags []time.Duration
+}
+
+func (m *lagMetrics) MessageLag(_ string, lag time.Duration) {
+	m.lags = append(m.lags, lag)
+}
+
+type closeRecorder struct {
+	MessageHandlerFunc
+	closed int32
+}
+
+func (h *closeRecorder) Close() {
+	atomic.StoreInt32(&h.closed, 1)
+}
+
+func TestConsumer_loopsShutdown(t *testing.T) {
+	t.Parallel()
+
+	for _, autoAck := range []bool{false, true} {
+		autoAck := autoAck
+		t.Run(fmt.Sprintf("auto ack %v", autoAck), func(t *testing.T) {
+			t.Parallel()
+
+			store := NewMemoryOffsetStore()
+			cfg := ConsumerConfig{
+				Queue:  QueueConfig{Options: Options{Args: amqp.Table{"x-queue-type": "stream"}}},
+				Stream: StreamConfig{CheckpointEvery: 100},
+			}
+			handled := make(chan struct{}, 3)
+			handler := &closeRecorder{MessageHandlerFunc: func(m Message) { handled <- struct{}{} }}
+			c := &Consumer{
+				name:    "stream",
+				workers: 2,
+				opts:    Options{AutoAck: autoAck},
+				handler: handler,
+				log:     NoOPLogger{},
+				metrics: NoOPMetrics{},
+				tracer:  NoOPTracer{},
+				stream:  newStreamConsumer(cfg),
+			}
+			require.NoError(t, WithOffsetStore(store)(c))
+
+			loop := c.autoAckLoop
+			if !autoAck {
+				c.workerPool = grpool.NewPool(c.workers, 0)
+				defer c.workerPool.Release()
+
+				loop = c.loop
+			}
+
+			d := make(chan amqp.Delivery, 3)
+			c.started = 1
+			c.t.Go(func() error { return loop(d, make(chan *amqp.Error)) })
+
+			for i := int64(0); i < 3; i++ {
+				d <- amqp.Delivery{Headers: amqp.Table{StreamOffsetHeader: i}}
+			}
+
+			for i := 0; i < 3; i++ {
+				<-handled
+			}
+
+			c.Kill()
+			require.NoError(t, c.t.Wait())
+
+			offset, ok, err := store.Offset("stream")
+			require.NoError(t, err)
+			require.True(t, ok, "the offset should be saved on shutdown")
+			require.Equal(t, int64(2), offset)
+			require.Equal(t, int32(1), atomic.LoadInt32(&handler.closed))
+		})
+	}
+}
+
+func TestConsumer_autoAckLoopChannelClosed(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c := &Consumer{
+		name:    "auto-ack",
+		workers: 2,
+		opts:    Options{AutoAck: true},
+		handler: MessageHandlerFunc(func(m Message) {
+			close(started)
+			<-release
+		}),
+		log:     NoOPLogger{},
+		metrics: NoOPMetrics{},
+		tracer:  NoOPTracer{},
+	}
+
+	d := make(chan amqp.Delivery, 1)
+	closed := make(chan *amqp.Error, 1)
+	returned := make(chan error, 1)
+
+	go func() { returned <- c.autoAckLoop(d, closed) }()
+
+	d <- amqp.Delivery{}
+	<-started
+
+	closed <- &amqp.Error{Code: amqp.PreconditionFailed, Reason: "delivery acknowledgement timed out", Recover: true}
+	close(d)
+
+	select {
+	case <-returned:
+		t.Fatal("the loop should wait the workers in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	err := <-returned
+	require.True(t, errors.As(err, new(*amqp.Error)), "the amqp error should be returned")
+	require.EqualError(t, err, `Exception (406) Reason: "delivery acknowledgement timed out"`)
+}
+
+// drainingConsumer start the loop of one consumer blocking in the handler until the release is closed.
+func drainingConsumer(name string, release chan struct{}) (*Consumer, chan amqp.Delivery, chan struct{}) {
+	started := make(chan struct{}, 1)
+	c := &Consumer{
+		name:    name,
+		workers: 1,
+		drained: make(chan struct{}),
+		handler: MessageHandlerFunc(func(m Message) {
+			started <- struct{}{}
+			<-release
+		}),
+		workerPool: grpool.NewPool(1, 0),
+		log:        NoOPLogger{},
+		metrics:    NoOPMetrics{},
+		tracer:     NoOPTracer{},
+	}
+	d := make(chan amqp.Delivery, 1)
+	c.started = 1
+	c.t.Go(func() error { return c.loop(d, make(chan *amqp.Error)) })
+
+	return c, d, started
+}
+
+func TestConsumer_Drain(t *testing.T) {
+	t.Parallel()
+
+	t.Run("wait the messages in flight", func(t *testing.T) {
+		t.Parallel()
+
+		release := make(chan struct{})
+		c, d, started := drainingConsumer("drain", release)
+		defer c.workerPool.Release()
+
+		d <- amqp.Delivery{}
+		<-started
+
+		done := make(chan error, 1)
+		go func() { done <- c.Drain(context.Background()) }()
+
+		// the broker close the deliveries after the subscription is canceled
+		require.Eventually(t, c.isDraining, time.Second, time.Millisecond)
+		close(d)
+
+		select {
+		case <-done:
+			t.Fatal("the drain should wait the message in flight")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		require.NoError(t, <-done)
+		require.Equal(t, int64(0), c.Status().InFlight)
+		require.True(t, c.Alive(), "the drained consumer is kept alive until killed")
+		require.EqualError(t, c.Drain(context.Background()), "consumer drain is already draining")
+
+		c.Kill()
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		t.Parallel()
+
+		release := make(chan struct{})
+		c, d, started := drainingConsumer("drain", release)
+		defer c.workerPool.Release()
+
+		d <- amqp.Delivery{}
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		require.Equal(t, context.DeadlineExceeded, c.Drain(ctx))
+		require.Equal(t, int64(1), c.Status().InFlight)
+
+		close(release)
+		c.Kill()
+	})
+
+	t.Run("dead consumer", func(t *testing.T) {
+		t.Parallel()
+
+		c := &Consumer{name: "dead", log: NoOPLogger{}}
+		c.t.Kill(errors.New("channel closed"))
+
+		require.EqualError(t, c.Drain(context.Background()), "consumer dead is not running: channel closed")
+		require.False(t, c.isDraining())
+	})
+}
+
+func TestConsumer_stopKeepsTheHandler(t *testing.T) {
+	t.Parallel()
+
+	handled := make(chan struct{}, 1)
+	handler := &closeRecorder{MessageHandlerFunc: func(m Message) {
+		handled <- struct{}{}
+	}}
+	start := func() (*Consumer, chan amqp.Delivery) {
+		c := &Consumer{
+			name:    "restart",
+			workers: 1,
+			opts:    Options{AutoAck: true},
+			handler: handler,
+			log:     NoOPLogger{},
+			metrics: NoOPMetrics{},
+			tracer:  NoOPTracer{},
+		}
+		d := make(chan amqp.Delivery, 1)
+		c.started = 1
+		c.t.Go(func() error { return c.autoAckLoop(d, make(chan *amqp.Error)) })
+
+		return c, d
+	}
+
+	first, _ := start()
+	require.True(t, first.stop())
+	require.Equal(t, int32(0), atomic.LoadInt32(&handler.closed), "the handler is used by the next consumer")
+
+	// the consumer recreated with the same handler
+	second, d := start()
+	d <- amqp.Delivery{}
+	<-handled
+
+	second.Kill()
+	require.Equal(t, int32(1), atomic.LoadInt32(&handler.closed))
+
+	require.False(t, (&Consumer{handler: handler}).stop(), "the consumer never ran")
+}
+
+func TestConsumer_SetPrefetchInvalid(t *testing.T) {
+	t.Parallel()
+
+	c := &Consumer{name: "prefetch", log: NoOPLogger{}, prefetch: 4}
+
+	require.EqualError(t, c.SetPrefetch(-1), "invalid prefetch count -1, it must be greater than or equal to zero")
+	require.Equal(t, 4, c.Status().Prefetch, "the prefetch should not change")
+}
@@ -0,0 +1,93 @@
+package rabbids
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// CorrelationIDHeader is the header used to propagate the correlation ID between the messages.
+const CorrelationIDHeader = "x-correlation-id"
+
+type correlationIDKey struct{}
+
+// ContextWithCorrelationID returns a copy of the context with the correlation ID.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID stored inside the context by the CorrelationMiddleware.
+func CorrelationID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDKey{}).(string)
+
+	return id, ok && id != ""
+}
+
+// CorrelationMiddleware store the correlation ID of the message inside the handler context, read from the
+// CorrelationIDHeader or the CorrelationId property, or a new one when the message has none.
+// The messages sent with the handler context, using Producer.SendContext or WithPublishingContext(m.Context()),
+// and the replies receive the same correlation ID, tracing one request across the queues.
+func CorrelationMiddleware() Middleware {
+	return func(next MessageHandler) MessageHandler {
+		return &correlationHandler{next: next}
+	}
+}
+
+type correlationHandler struct {
+	next MessageHandler
+}
+
+func (h *correlationHandler) Handle(m Message) {
+	_ = h.HandleErr(m)
+}
+
+func (h *correlationHandler) HandleErr(m Message) error {
+	id, _ := m.Headers[CorrelationIDHeader].(string)
+	if id == "" {
+		id = m.CorrelationId
+	}
+
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	return handlerErr(h.next, m.WithContext(ContextWithCorrelationID(m.Context(), id)))
+}
+
+func (h *correlationHandler) Close() {
+	h.next.Close()
+}
+
+// stampCorrelationID set the correlation ID from the Publishing context on the message,
+// without replacing the CorrelationId already set, like the ones of the replies.
+func stampCorrelationID(m *Publishing) {
+	if m.ctx == nil {
+		return
+	}
+
+	id, ok := CorrelationID(m.ctx)
+	if !ok {
+		return
+	}
+
+	if m.CorrelationId == "" {
+		m.CorrelationId = id
+	}
+
+	if m.Headers == nil {
+		m.Headers = amqp.Table{}
+	}
+
+	if _, exists := m.Headers[CorrelationIDHeader]; !exists {
+		m.Headers[CorrelationIDHeader] = id
+	}
+}
+
+// SendContext send a message to rabbitMQ using the context as the Publishing context,
+// propagating the correlation ID and the trace of the handler.
+func (p *Producer) SendContext(ctx context.Context, m Publishing) error {
+	m.ctx = ctx
+
+	return p.Send(m)
+}
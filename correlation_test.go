@@ -0,0 +1,76 @@
+package rabbids
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrelationMiddleware(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		delivery amqp.Delivery
+		want     string
+	}{
+		{"header", amqp.Delivery{Headers: amqp.Table{CorrelationIDHeader: "from-header"}, CorrelationId: "property"}, "from-header"},
+		{"property", amqp.Delivery{CorrelationId: "property"}, "property"},
+		{"generated", amqp.Delivery{}, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+
+			h := CorrelationMiddleware()(ErrorHandlerFunc(func(m Message) error {
+				id, ok := CorrelationID(m.Context())
+				require.True(t, ok)
+				got = id
+
+				return nil
+			}))
+
+			require.NoError(t, handlerErr(h, Message{Delivery: tt.delivery}))
+
+			if tt.want == "" {
+				require.NotEmpty(t, got)
+				return
+			}
+
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStampCorrelationID(t *testing.T) {
+	t.Parallel()
+
+	ctx := ContextWithCorrelationID(context.Background(), "abc")
+
+	pub := NewPublishing("events", "created", nil, WithPublishingContext(ctx))
+	for _, op := range pub.options {
+		op(&pub)
+	}
+
+	stampCorrelationID(&pub)
+	require.Equal(t, "abc", pub.CorrelationId)
+	require.Equal(t, "abc", pub.Headers[CorrelationIDHeader])
+
+	reply := NewPublishing("", "reply-queue", nil)
+	reply.CorrelationId = "request-id"
+	reply.ctx = ctx
+
+	stampCorrelationID(&reply)
+	require.Equal(t, "request-id", reply.CorrelationId, "the reply keeps the id of the request")
+	require.Equal(t, "abc", reply.Headers[CorrelationIDHeader])
+
+	plain := NewPublishing("events", "created", nil)
+	stampCorrelationID(&plain)
+	require.Empty(t, plain.CorrelationId)
+	require.NotContains(t, plain.Headers, CorrelationIDHeader)
+}
@@ -0,0 +1,203 @@
+package rabbids
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed cron expression with the fields minute, hour, day of month, month and day of week,
+// every field is a bit set of the allowed values.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// every is used by the @every descriptor instead of the fields.
+	every time.Duration
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// starBit mark the fields defined with "*", used to combine the day of month and the day of week.
+const starBit = 1 << 63
+
+// parseCron parse a standard cron expression with five fields (minute hour day-of-month month day-of-week),
+// supporting "*", lists, ranges and steps like "*/15" or "1-5/2", or one of the descriptors
+// @yearly, @monthly, @weekly, @daily, @hourly and @every <duration>.
+func parseCron(spec string) (*cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil || every < time.Second {
+			return nil, fmt.Errorf("invalid cron expression \"%s\": the @every duration must be at least 1s", spec)
+		}
+
+		return &cronSchedule{every: every}, nil
+	}
+
+	if expanded, ok := cronDescriptors[spec]; ok {
+		spec = expanded
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression \"%s\": expected 5 fields", spec)
+	}
+
+	values := make([]uint64, len(cronFields))
+
+	for i, part := range parts {
+		bits, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression \"%s\": %w", spec, err)
+		}
+
+		values[i] = bits
+	}
+
+	return &cronSchedule{
+		minute: values[0],
+		hour:   values[1],
+		dom:    values[2],
+		month:  values[3],
+		dow:    values[4],
+	}, nil
+}
+
+func parseCronField(value string, field cronField) (uint64, error) {
+	var bits uint64
+
+	for _, item := range strings.Split(value, ",") {
+		rangeValue, step := item, 1
+
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step \"%s\" in the %s", item, field.name)
+			}
+
+			rangeValue, step = item[:i], n
+		}
+
+		start, end := field.min, field.max
+
+		switch {
+		case rangeValue == "*":
+			if step == 1 {
+				bits |= starBit
+			}
+		case strings.Contains(rangeValue, "-"):
+			bounds := strings.SplitN(rangeValue, "-", 2)
+
+			var err error
+			if start, err = cronNumber(bounds[0], field); err != nil {
+				return 0, err
+			}
+
+			if end, err = cronNumber(bounds[1], field); err != nil {
+				return 0, err
+			}
+
+			if start > end {
+				return 0, fmt.Errorf("invalid range \"%s\" in the %s", rangeValue, field.name)
+			}
+		default:
+			n, err := cronNumber(rangeValue, field)
+			if err != nil {
+				return 0, err
+			}
+
+			start = n
+			if step == 1 {
+				end = n
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func cronNumber(value string, field cronField) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < field.min || n > field.max {
+		return 0, fmt.Errorf("invalid value \"%s\" in the %s, expected a number between %d and %d",
+			value, field.name, field.min, field.max)
+	}
+
+	return n, nil
+}
+
+// next return the first time matching the schedule after t, or the zero time when the schedule never matches.
+func (s *cronSchedule) next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follow the cron rule: when both the day of month and the day of week are restricted,
+// the day matches if any of them matches.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.dom&starBit != 0 || s.dow&starBit != 0 {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
@@ -0,0 +1,66 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseCron(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		spec string
+		err  string
+	}{
+		{"* * * * *", ""},
+		{"*/15 9-18 * * 1-5", ""},
+		{"0 0 1,15 * *", ""},
+		{"@daily", ""},
+		{"@every 30s", ""},
+		{"* * * *", `invalid cron expression "* * * *": expected 5 fields`},
+		{"60 * * * *", `invalid cron expression "60 * * * *": invalid value "60" in the minute, expected a number between 0 and 59`},
+		{"* 5-1 * * *", `invalid cron expression "* 5-1 * * *": invalid range "5-1" in the hour`},
+		{"*/0 * * * *", `invalid cron expression "*/0 * * * *": invalid step "*/0" in the minute`},
+		{"@every 10ms", `invalid cron expression "@every 10ms": the @every duration must be at least 1s`},
+	}
+	for _, tt := range tests {
+		_, err := parseCron(tt.spec)
+		if tt.err == "" {
+			require.NoError(t, err, tt.spec)
+			continue
+		}
+
+		require.EqualError(t, err, tt.err)
+	}
+}
+
+func Test_cronSchedule_next(t *testing.T) {
+	t.Parallel()
+
+	// Monday
+	now := time.Date(2020, 10, 12, 10, 32, 15, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2020, 10, 12, 10, 33, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, 10, 12, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2020, 10, 13, 9, 0, 0, 0, time.UTC)},
+		{"30 8 * * 6", time.Date(2020, 10, 17, 8, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// day of month OR day of week when both are restricted
+		{"0 0 20 * 3", time.Date(2020, 10, 14, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2020, 10, 12, 10, 33, 45, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := parseCron(tt.spec)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, s.next(now), tt.spec)
+	}
+}
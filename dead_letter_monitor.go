@@ -0,0 +1,131 @@
+package rabbids
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DeadLetterAlert is passed to the callback of WithDeadLetterMonitor when one dead letter queue grows
+// beyond the threshold.
+type DeadLetterAlert struct {
+	Queue string
+	// Messages is the number of messages inside the queue and Previous the number in the previous check,
+	// -1 when the queue was not checked before.
+	Messages  int
+	Previous  int
+	Threshold int
+}
+
+type deadLetterMonitorConfig struct {
+	interval  time.Duration
+	threshold int
+	fn        func(DeadLetterAlert)
+	lastCheck time.Time
+	last      map[string]int
+}
+
+// WithDeadLetterMonitor make the supervisor check the number of messages inside every dead letter queue
+// from the config, at most once per interval, and report it with Metrics.DeadLetterDepth.
+// The fn is called when one queue has more messages than the threshold and it grew since the previous check,
+// so a buildup is noticed without alerting again while the queue is being emptied.
+func WithDeadLetterMonitor(interval time.Duration, threshold int, fn func(DeadLetterAlert)) SupervisorOption {
+	return func(s *supervisor) {
+		s.deadLetterMonitor = deadLetterMonitorConfig{
+			interval:  interval,
+			threshold: threshold,
+			fn:        fn,
+			last:      map[string]int{},
+		}
+	}
+}
+
+// checkDeadLetters report the depth of the dead letter queues when the interval is passed.
+func (s *supervisor) checkDeadLetters(now time.Time) {
+	m := &s.deadLetterMonitor
+	if m.interval <= 0 || now.Sub(m.lastCheck) < m.interval {
+		return
+	}
+
+	m.lastCheck = now
+
+	depths, err := s.rabbids.DeadLetterDepth()
+	if err != nil {
+		s.rabbids.log.Error("failed to check the dead letter queues", Fields{"error": err})
+	}
+
+	for queue, messages := range depths {
+		s.rabbids.metrics.DeadLetterDepth(queue, messages)
+	}
+
+	for _, alert := range m.alerts(depths) {
+		s.rabbids.log.Warn("the dead letter queue is growing", Fields{
+			"queue":     alert.Queue,
+			"messages":  alert.Messages,
+			"previous":  alert.Previous,
+			"threshold": alert.Threshold,
+		})
+
+		if m.fn != nil {
+			m.fn(alert)
+		}
+	}
+}
+
+// alerts return the queues above the threshold that grew since the previous check, saving the depths.
+func (m *deadLetterMonitorConfig) alerts(depths map[string]int) []DeadLetterAlert {
+	var alerts []DeadLetterAlert
+
+	for queue, messages := range depths {
+		previous, ok := m.last[queue]
+		if !ok {
+			previous = -1
+		}
+
+		m.last[queue] = messages
+
+		if messages > m.threshold && messages > previous {
+			alerts = append(alerts, DeadLetterAlert{
+				Queue:     queue,
+				Messages:  messages,
+				Previous:  previous,
+				Threshold: m.threshold,
+			})
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Queue < alerts[j].Queue })
+
+	return alerts
+}
+
+// DeadLetterDepth return the number of messages inside each dead letter queue used by the consumers,
+// using passive declarations. The queues not declared yet are skipped.
+func (r *Rabbids) DeadLetterDepth() (map[string]int, error) {
+	r.configMutex.RLock()
+	queues := map[string]bool{}
+
+	for _, cfg := range r.config.Consumers {
+		if dead, ok := r.config.DeadLetters[cfg.DeadLetter]; ok && dead.Queue.Name != "" {
+			queues[dead.Queue.Name] = true
+		}
+	}
+	r.configMutex.RUnlock()
+
+	depths := map[string]int{}
+
+	for queue := range queues {
+		stats, err := r.QueueStats(queue)
+		if isNotFound(err) {
+			continue
+		}
+
+		if err != nil {
+			return depths, fmt.Errorf("failed to check the dead letter queue \"%s\": %w", queue, err)
+		}
+
+		depths[queue] = stats.Messages
+	}
+
+	return depths, nil
+}
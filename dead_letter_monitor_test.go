@@ -0,0 +1,50 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetterMonitor_alerts(t *testing.T) {
+	t.Parallel()
+
+	m := &deadLetterMonitorConfig{threshold: 10, last: map[string]int{}}
+
+	alerts := m.alerts(map[string]int{"orders-dlq": 20, "users-dlq": 5})
+	require.Equal(t, []DeadLetterAlert{{Queue: "orders-dlq", Messages: 20, Previous: -1, Threshold: 10}}, alerts)
+
+	alerts = m.alerts(map[string]int{"orders-dlq": 20, "users-dlq": 11})
+	require.Equal(t, []DeadLetterAlert{{Queue: "users-dlq", Messages: 11, Previous: 5, Threshold: 10}}, alerts,
+		"the queues not growing since the last check are not alerted again")
+
+	alerts = m.alerts(map[string]int{"orders-dlq": 15, "users-dlq": 30})
+	require.Equal(t, []DeadLetterAlert{{Queue: "users-dlq", Messages: 30, Previous: 11, Threshold: 10}}, alerts)
+}
+
+func TestSupervisor_checkDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	r, err := New(&Config{}, NoOPLoggerFN)
+	require.NoError(t, err)
+
+	now := time.Now()
+	s := &supervisor{rabbids: r}
+
+	s.checkDeadLetters(now)
+	require.True(t, s.deadLetterMonitor.lastCheck.IsZero(), "the dead letters are not checked by default")
+
+	called := false
+	WithDeadLetterMonitor(time.Minute, 0, func(DeadLetterAlert) { called = true })(s)
+	s.checkDeadLetters(now)
+	require.Equal(t, now, s.deadLetterMonitor.lastCheck)
+	require.False(t, called, "the config didn't have dead letters")
+
+	s.checkDeadLetters(now.Add(time.Second))
+	require.Equal(t, now, s.deadLetterMonitor.lastCheck, "the dead letters are checked once per interval")
+
+	depths, err := r.DeadLetterDepth()
+	require.NoError(t, err)
+	require.Empty(t, depths)
+}
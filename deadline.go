@@ -0,0 +1,42 @@
+package rabbids
+
+import (
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// DeadlineHeader is the header used to propagate the deadline of one message.
+// The consumers use the deadline inside the handler context and drop the messages
+// with the deadline already passed without calling the handler.
+const DeadlineHeader = "x-deadline"
+
+// WithDeadline set the DeadlineHeader of the Publishing.
+func WithDeadline(deadline time.Time) PublishingOption {
+	return func(p *Publishing) {
+		if p.Headers == nil {
+			p.Headers = amqp.Table{}
+		}
+
+		p.Headers[DeadlineHeader] = deadline.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// MessageDeadline return the deadline from the headers.
+// The deadline can be a RFC3339 string, a timestamp or an integer with the unix time in milliseconds.
+func MessageDeadline(headers amqp.Table) (time.Time, bool) {
+	switch v := headers[DeadlineHeader].(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+
+		return t, err == nil
+	case time.Time:
+		return v, !v.IsZero()
+	case int64:
+		return time.Unix(0, v*int64(time.Millisecond)), true
+	case int32:
+		return time.Unix(0, int64(v)*int64(time.Millisecond)), true
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,70 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageDeadline(t *testing.T) {
+	t.Parallel()
+
+	deadline := time.Date(2020, 10, 12, 10, 30, 0, 500, time.UTC)
+	p := NewPublishing("ex", "key", nil, WithDeadline(deadline))
+	p.options[0](&p)
+
+	tests := []struct {
+		name    string
+		headers amqp.Table
+		want    time.Time
+		wantOk  bool
+	}{
+		{"without header", amqp.Table{}, time.Time{}, false},
+		{"from WithDeadline", p.Headers, deadline, true},
+		{"timestamp", amqp.Table{DeadlineHeader: deadline}, deadline, true},
+		{"unix milliseconds", amqp.Table{DeadlineHeader: int64(1602498600000)}, deadline.Truncate(time.Second), true},
+		{"invalid string", amqp.Table{DeadlineHeader: "tomorrow"}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := MessageDeadline(tt.headers)
+			require.Equal(t, tt.wantOk, ok)
+			require.True(t, tt.want.Equal(got), "expected %s, got %s", tt.want, got)
+		})
+	}
+}
+
+func TestConsumer_deadline(t *testing.T) {
+	t.Parallel()
+
+	called := false
+	c := &Consumer{
+		name:    "deadline",
+		log:     NoOPLogger{},
+		metrics: NoOPMetrics{},
+		handler: MessageHandlerFunc(func(m Message) {
+			called = true
+			_, ok := m.Context().Deadline()
+			require.True(t, ok)
+		}),
+	}
+
+	ack := &fakeAcknowledger{}
+	c.handle(amqp.Delivery{
+		Acknowledger: ack,
+		Headers:      amqp.Table{DeadlineHeader: time.Now().Add(-time.Minute)},
+	}, time.Now())
+	require.False(t, called, "the handler should not be called after the deadline")
+	require.Equal(t, 1, ack.acks)
+
+	c.handle(amqp.Delivery{
+		Acknowledger: ack,
+		Headers:      amqp.Table{DeadlineHeader: time.Now().Add(time.Minute)},
+	}, time.Now())
+	require.True(t, called)
+}
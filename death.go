@@ -0,0 +1,103 @@
+package rabbids
+
+import (
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Death is one entry of the x-death header added by RabbitMQ every time a message is dead lettered.
+type Death struct {
+	Queue       string
+	Reason      string
+	Exchange    string
+	RoutingKeys []string
+	Count       int64
+	Time        time.Time
+}
+
+// Deaths return the entries from the x-death header, the most recent first.
+func (m Message) Deaths() []Death {
+	return deaths(m.Headers)
+}
+
+// DeathCount return how many times the message was dead lettered from the queue.
+// With an empty queue name all the queues are counted.
+func (m Message) DeathCount(queue string) int64 {
+	var count int64
+
+	for _, d := range m.Deaths() {
+		if queue == "" || d.Queue == queue {
+			count += d.Count
+		}
+	}
+
+	return count
+}
+
+// FirstDeathReason return the reason (rejected, expired, maxlen or delivery_limit) of the
+// first time the message was dead lettered, or an empty string when it never was.
+func (m Message) FirstDeathReason() string {
+	if reason, ok := m.Headers["x-first-death-reason"].(string); ok {
+		return reason
+	}
+
+	ds := m.Deaths()
+	if len(ds) == 0 {
+		return ""
+	}
+
+	return ds[len(ds)-1].Reason
+}
+
+// Redelivered return true when the message was delivered before, redelivered by the broker,
+// dead lettered or requeued by the delayed-requeue strategy.
+// The broker flag alone is available in m.Delivery.Redelivered.
+func (m Message) Redelivered() bool {
+	return m.Delivery.Redelivered || m.DeathCount("") > 0 || requeueCount(m.Headers) > 0
+}
+
+func deaths(headers amqp.Table) []Death {
+	entries, _ := headers["x-death"].([]interface{})
+	ds := make([]Death, 0, len(entries))
+
+	for _, e := range entries {
+		table, ok := e.(amqp.Table)
+		if !ok {
+			continue
+		}
+
+		d := Death{}
+		d.Queue, _ = table["queue"].(string)
+		d.Reason, _ = table["reason"].(string)
+		d.Exchange, _ = table["exchange"].(string)
+		d.Time, _ = table["time"].(time.Time)
+		d.Count = int64Value(table["count"])
+
+		keys, _ := table["routing-keys"].([]interface{})
+		for _, k := range keys {
+			if key, ok := k.(string); ok {
+				d.RoutingKeys = append(d.RoutingKeys, key)
+			}
+		}
+
+		ds = append(ds, d)
+	}
+
+	return ds
+}
+
+func int64Value(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	default:
+		return 0
+	}
+}
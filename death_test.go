@@ -0,0 +1,89 @@
+package rabbids
+
+import (
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessage_deaths(t *testing.T) {
+	t.Parallel()
+
+	xdeath := []interface{}{
+		amqp.Table{
+			"queue":        "retry",
+			"reason":       "expired",
+			"exchange":     "retry-ex",
+			"routing-keys": []interface{}{"retry.key"},
+			"count":        int64(3),
+		},
+		amqp.Table{
+			"queue":        "work",
+			"reason":       "rejected",
+			"exchange":     "event_bus",
+			"routing-keys": []interface{}{"work.key"},
+			"count":        int64(2),
+		},
+	}
+
+	tests := []struct {
+		name        string
+		delivery    amqp.Delivery
+		deaths      int
+		workCount   int64
+		totalCount  int64
+		firstReason string
+		redelivered bool
+	}{
+		{
+			name:     "without deaths",
+			delivery: amqp.Delivery{Headers: amqp.Table{}},
+		},
+		{
+			name:        "redelivered by the broker",
+			delivery:    amqp.Delivery{Redelivered: true},
+			redelivered: true,
+		},
+		{
+			name:        "requeued by the delayed-requeue strategy",
+			delivery:    amqp.Delivery{Headers: amqp.Table{RequeueCountHeader: int64(1)}},
+			redelivered: true,
+		},
+		{
+			name:        "with the x-death header",
+			delivery:    amqp.Delivery{Headers: amqp.Table{"x-death": xdeath}},
+			deaths:      2,
+			workCount:   2,
+			totalCount:  5,
+			firstReason: "rejected",
+			redelivered: true,
+		},
+		{
+			name: "with the x-first-death-reason header",
+			delivery: amqp.Delivery{Headers: amqp.Table{
+				"x-death":              xdeath,
+				"x-first-death-reason": "maxlen",
+			}},
+			deaths:      2,
+			workCount:   2,
+			totalCount:  5,
+			firstReason: "maxlen",
+			redelivered: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := Message{Delivery: tt.delivery}
+			require.Len(t, m.Deaths(), tt.deaths)
+			require.Equal(t, tt.workCount, m.DeathCount("work"))
+			require.Equal(t, tt.totalCount, m.DeathCount(""))
+			require.Equal(t, tt.firstReason, m.FirstDeathReason())
+			require.Equal(t, tt.redelivered, m.Redelivered())
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package rabbids
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// StateSnapshot is the internal state of the client returned by DebugState.
+type StateSnapshot struct {
+	Time        time.Time            `json:"time"`
+	Draining    bool                 `json:"draining"`
+	Supervisor  SupervisorState      `json:"supervisor"`
+	Connections []ConnectionStatus   `json:"connections"`
+	Consumers   []ConsumerDebugState `json:"consumers"`
+	Producers   []ProducerDebugState `json:"producers"`
+	// Exchanges and Queues are the names declared by the client since it was created.
+	Exchanges []string `json:"declared_exchanges"`
+	Queues    []string `json:"declared_queues"`
+}
+
+// SupervisorState is the state of the supervisor loop inside the StateSnapshot.
+type SupervisorState struct {
+	Running   bool          `json:"running"`
+	Interval  time.Duration `json:"interval"`
+	LastCheck *time.Time    `json:"last_check,omitempty"`
+}
+
+// ConsumerDebugState is the state of one consumer and its worker pool inside the StateSnapshot.
+type ConsumerDebugState struct {
+	Name      string    `json:"name"`
+	Queue     string    `json:"queue"`
+	Tag       string    `json:"tag"`
+	Alive     bool      `json:"alive"`
+	Workers   int       `json:"workers"`
+	Prefetch  int       `json:"prefetch"`
+	InFlight  int64     `json:"in_flight"`
+	Saturated bool      `json:"saturated"`
+	Restarts  int       `json:"restarts"`
+	CreatedAt time.Time `json:"created_at"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// ProducerDebugState is the state of one producer inside the StateSnapshot.
+type ProducerDebugState struct {
+	Name     string `json:"name"`
+	Alive    bool   `json:"alive"`
+	Channels int    `json:"channels"`
+	// Pending is the number of messages waiting inside the Emit channel, with the Capacity of the channel.
+	Pending  int `json:"pending"`
+	Capacity int `json:"capacity"`
+}
+
+// DebugState returns a snapshot of the connections, consumers, producers and the topology declared,
+// to be inspected during incidents. See DebugStateHandler to expose it in an admin port.
+func (r *Rabbids) DebugState() StateSnapshot {
+	now := time.Now()
+	state := StateSnapshot{
+		Time:        now,
+		Draining:    r.isDraining(),
+		Connections: r.Connections(),
+		Consumers:   []ConsumerDebugState{},
+		Producers:   r.producersState(now),
+	}
+
+	if interval := time.Duration(atomic.LoadInt64(&r.supervisorInterval)); interval > 0 {
+		last := time.Unix(0, atomic.LoadInt64(&r.supervisorHeartbeat))
+		state.Supervisor = SupervisorState{Running: true, Interval: interval, LastCheck: &last}
+	}
+
+	r.mutex.RLock()
+	for _, c := range r.consumers {
+		s := c.Status()
+		consumer := ConsumerDebugState{
+			Name:      s.Name,
+			Queue:     s.Queue,
+			Tag:       c.tag,
+			Alive:     s.Alive,
+			Workers:   s.Workers,
+			Prefetch:  s.Prefetch,
+			InFlight:  s.InFlight,
+			Saturated: s.Saturated,
+			Restarts:  s.Restarts,
+			CreatedAt: s.CreatedAt,
+		}
+
+		if s.LastError != nil {
+			consumer.LastError = s.LastError.Error()
+		}
+
+		state.Consumers = append(state.Consumers, consumer)
+	}
+	r.mutex.RUnlock()
+
+	sort.Slice(state.Consumers, func(i, j int) bool {
+		return state.Consumers[i].Name < state.Consumers[j].Name
+	})
+
+	state.Exchanges, state.Queues = r.declaredTopology()
+
+	return state
+}
+
+// producersState returns the state of the producers created by the client and used by the consumers.
+func (r *Rabbids) producersState(now time.Time) []ProducerDebugState {
+	r.producersMutex.Lock()
+	producers := append([]*Producer{}, r.producers...)
+	r.producersMutex.Unlock()
+
+	r.connProducersMutex.Lock()
+	for _, p := range r.connProducers {
+		producers = append(producers, p)
+	}
+	r.connProducersMutex.Unlock()
+
+	states := make([]ProducerDebugState, 0, len(producers))
+
+	for _, p := range producers {
+		_, channels := p.connectionState(now)
+		states = append(states, ProducerDebugState{
+			Name:     p.name,
+			Alive:    p.Alive(),
+			Channels: channels,
+			Pending:  len(p.emit),
+			Capacity: cap(p.emit),
+		})
+	}
+
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+
+	return states
+}
+
+// recordDeclared keep the names of the exchanges and queues declared, it is the OnDeclare hook of the client.
+func (r *Rabbids) recordDeclared(kind, name string) {
+	r.declaredMutex.Lock()
+	defer r.declaredMutex.Unlock()
+
+	if r.declared == nil {
+		r.declared = map[string]map[string]struct{}{}
+	}
+
+	if r.declared[kind] == nil {
+		r.declared[kind] = map[string]struct{}{}
+	}
+
+	r.declared[kind][name] = struct{}{}
+}
+
+func (r *Rabbids) declaredTopology() (exchanges, queues []string) {
+	r.declaredMutex.Lock()
+	defer r.declaredMutex.Unlock()
+
+	names := func(kind string) []string {
+		list := make([]string, 0, len(r.declared[kind]))
+		for name := range r.declared[kind] {
+			list = append(list, name)
+		}
+
+		sort.Strings(list)
+
+		return list
+	}
+
+	return names(DeclareExchange), names(DeclareQueue)
+}
+
+// DebugStateHandler returns an http.Handler reporting the DebugState as JSON.
+func DebugStateHandler(r *Rabbids) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		_ = json.NewEncoder(w).Encode(r.DebugState())
+	})
+}
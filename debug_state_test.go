@@ -0,0 +1,54 @@
+package rabbids
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugStateHandler(t *testing.T) {
+	t.Parallel()
+
+	dead := &Consumer{name: "dead", queue: "orders", tag: "dead-1", workers: 5}
+	dead.t.Kill(errors.New("channel closed"))
+
+	p := &Producer{name: "rabbids.events", emit: make(chan Publishing, 10)}
+	p.emit <- NewPublishing("events", "user.created", nil)
+
+	r := &Rabbids{
+		conns:         map[string]*amqp.Connection{},
+		consumers:     map[string]*Consumer{"dead": dead, "alive": {name: "alive", queue: "users"}},
+		producers:     []*Producer{p},
+		connProducers: map[string]*Producer{},
+	}
+	r.recordDeclared(DeclareQueue, "users")
+	r.recordDeclared(DeclareExchange, "events")
+	r.recordDeclared(DeclareQueue, "orders")
+	r.recordDeclared(DeclareQueue, "users")
+
+	rec := httptest.NewRecorder()
+	DebugStateHandler(r).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/rabbids/state", nil))
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var state StateSnapshot
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state))
+
+	require.False(t, state.Supervisor.Running)
+	require.Equal(t, []string{"events"}, state.Exchanges)
+	require.Equal(t, []string{"orders", "users"}, state.Queues)
+	require.Len(t, state.Consumers, 2)
+	require.Equal(t, "alive", state.Consumers[0].Name)
+	require.Equal(t, ConsumerDebugState{
+		Name:      "dead",
+		Queue:     "orders",
+		Tag:       "dead-1",
+		Workers:   5,
+		LastError: "channel closed",
+		CreatedAt: state.Consumers[1].CreatedAt,
+	}, state.Consumers[1])
+	require.Equal(t, []ProducerDebugState{{Name: "rabbids.events", Pending: 1, Capacity: 10}}, state.Producers)
+}
@@ -2,6 +2,7 @@ package rabbids
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/streadway/amqp"
@@ -10,7 +11,8 @@ import (
 // declarations is the block responsible for create consumers and restart the rabbitMQ connections.
 type declarations struct {
 	config *Config
-	log    LoggerFN
+	log    Logger
+	hooks  hookSet
 }
 
 func (f *declarations) declareExchange(ch *amqp.Channel, name string) error {
@@ -20,11 +22,11 @@ func (f *declarations) declareExchange(ch *amqp.Channel, name string) error {
 
 	ex, ok := f.config.Exchanges[name]
 	if !ok {
-		f.log("exchange config didn't exist, we will try to continue", Fields{"name": name})
+		f.log.Warn("exchange config didn't exist, we will try to continue", Fields{"name": name})
 		return nil
 	}
 
-	f.log("declaring exchange", Fields{
+	f.log.Debug("declaring exchange", Fields{
 		"ex":      name,
 		"type":    ex.Type,
 		"options": ex.Options,
@@ -42,11 +44,13 @@ func (f *declarations) declareExchange(ch *amqp.Channel, name string) error {
 		return fmt.Errorf("failed to declare the exchange %s, err: %w", name, err)
 	}
 
+	f.hooks.declared(DeclareExchange, name)
+
 	return nil
 }
 
 func (f *declarations) declareQueue(ch *amqp.Channel, queue QueueConfig) error {
-	f.log("declaring queue", Fields{
+	f.log.Debug("declaring queue", Fields{
 		"queue":   queue.Name,
 		"options": queue.Options,
 	})
@@ -62,8 +66,10 @@ func (f *declarations) declareQueue(ch *amqp.Channel, queue QueueConfig) error {
 		return fmt.Errorf("failed to declare the queue \"%s\"", queue.Name)
 	}
 
+	f.hooks.declared(DeclareQueue, queue.Name)
+
 	for _, b := range queue.Bindings {
-		f.log("declaring queue bind", Fields{
+		f.log.Debug("declaring queue bind", Fields{
 			"queue":    queue.Name,
 			"exchange": b.Exchange,
 		})
@@ -86,11 +92,11 @@ func (f *declarations) declareQueue(ch *amqp.Channel, queue QueueConfig) error {
 }
 
 func (f *declarations) declareDeadLetters(ch *amqp.Channel, name string) error {
-	f.log("declaring deadletter", Fields{"dlx": name})
+	f.log.Debug("declaring deadletter", Fields{"dlx": name})
 
 	dead, ok := f.config.DeadLetters[name]
 	if !ok {
-		f.log("deadletter config didn't exist, we will try to continue", Fields{"dlx": name})
+		f.log.Warn("deadletter config didn't exist, we will try to continue", Fields{"dlx": name})
 		return nil
 	}
 
@@ -113,3 +119,47 @@ func assertRightTableTypes(args amqp.Table) amqp.Table {
 
 	return nArgs
 }
+
+// argString return one string argument from the table or an empty string.
+func argString(args amqp.Table, name string) string {
+	v, _ := args[name].(string)
+
+	return v
+}
+
+// argMilliseconds return one duration argument, in milliseconds, from the table.
+func argMilliseconds(args amqp.Table, name string) (time.Duration, bool) {
+	var ms int64
+
+	switch v := args[name].(type) {
+	case int:
+		ms = int64(v)
+	case int32:
+		ms = int64(v)
+	case int64:
+		ms = v
+	case float64:
+		ms = int64(v)
+	default:
+		return 0, false
+	}
+
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// queueTTL return the x-message-ttl of one queue from the consumers and dead letters config.
+func (f *declarations) queueTTL(name string) (time.Duration, bool) {
+	for _, c := range f.config.Consumers {
+		if c.Queue.Name == name {
+			return argMilliseconds(c.Queue.Options.Args, "x-message-ttl")
+		}
+	}
+
+	for _, d := range f.config.DeadLetters {
+		if d.Queue.Name == name {
+			return argMilliseconds(d.Queue.Options.Args, "x-message-ttl")
+		}
+	}
+
+	return 0, false
+}
@@ -1,7 +1,11 @@
 package rabbids
 
 import (
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/streadway/amqp"
 	"github.com/stretchr/testify/require"
@@ -31,3 +35,50 @@ func Test_assertRightArgsTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestProducerCheckQueueTTL(t *testing.T) {
+	t.Parallel()
+
+	p := &Producer{declarations: &declarations{config: &Config{
+		Consumers: map[string]ConsumerConfig{
+			"billing": {Queue: QueueConfig{Name: "billing", Options: Options{Args: amqp.Table{"x-message-ttl": 60000}}}},
+			"mailer":  {Queue: QueueConfig{Name: "mailer"}},
+		},
+	}}}
+
+	err := p.checkQueueTTL(NewDelayedPublishing("billing", 2*time.Minute, nil))
+	require.True(t, errors.Is(err, ErrDelayExceeded))
+	require.EqualError(t, err, `the delay exceeds the max delay: 2m0s is greater than the x-message-ttl 1m0s of the queue "billing"`)
+
+	require.NoError(t, p.checkQueueTTL(NewDelayedPublishing("billing", time.Minute, nil)))
+	require.NoError(t, p.checkQueueTTL(NewDelayedPublishing("mailer", time.Hour, nil)))
+	require.NoError(t, p.checkQueueTTL(NewDelayedPublishing("unknown", time.Hour, nil)))
+	require.NoError(t, (&Producer{}).checkQueueTTL(NewDelayedPublishing("billing", time.Hour, nil)))
+}
+
+func TestProducerTryToDeclareTopicConcurrently(t *testing.T) {
+	t.Parallel()
+
+	p := &Producer{
+		log:          NoOPLogger{},
+		exDeclared:   map[string]struct{}{},
+		declarations: &declarations{config: &Config{}, log: NoOPLogger{}},
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			p.mutex.RLock()
+			p.tryToDeclareTopic(fmt.Sprintf("exchange-%d", i%3))
+			p.mutex.RUnlock()
+		}(i)
+	}
+
+	wg.Wait()
+	require.Len(t, p.exDeclared, 3)
+}
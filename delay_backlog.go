@@ -0,0 +1,103 @@
+package rabbids
+
+import (
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+type delayBacklogConfig struct {
+	interval  time.Duration
+	lastCheck time.Time
+}
+
+// WithDelayBacklog make the supervisor report the number of messages inside every delay level queue,
+// with Metrics.DelayBacklog, at most once per interval. These messages are the traffic "in the future"
+// and a growing backlog shows the delay pipeline is backing up.
+func WithDelayBacklog(interval time.Duration) SupervisorOption {
+	return func(s *supervisor) {
+		s.delayBacklog = delayBacklogConfig{interval: interval}
+	}
+}
+
+// checkDelayBacklog report the delay backlog when the interval is passed.
+func (s *supervisor) checkDelayBacklog(now time.Time) {
+	if s.delayBacklog.interval <= 0 || now.Sub(s.delayBacklog.lastCheck) < s.delayBacklog.interval {
+		return
+	}
+
+	s.delayBacklog.lastCheck = now
+
+	backlog, err := s.rabbids.DelayBacklog()
+	if err != nil {
+		s.rabbids.log.Error("failed to check the delay backlog", Fields{"error": err})
+	}
+
+	for queue, messages := range backlog {
+		s.rabbids.metrics.DelayBacklog(queue, messages)
+	}
+}
+
+// DelayBacklog return the number of messages inside each queue of the levels delay strategy used by the
+// connections, using passive declarations. The levels not declared yet are skipped.
+func (r *Rabbids) DelayBacklog() (map[string]int, error) {
+	backlog := map[string]int{}
+
+	for _, name := range r.connectionNames() {
+		r.configMutex.RLock()
+		conn := r.config.Connections[name]
+		r.configMutex.RUnlock()
+
+		if conn.DelayStrategy != "" && conn.DelayStrategy != DelayStrategyLevels {
+			continue
+		}
+
+		if err := r.levelsBacklog(name, conn.delayOptions(), backlog); err != nil {
+			return backlog, err
+		}
+	}
+
+	return backlog, nil
+}
+
+func (r *Rabbids) levelsBacklog(connectionName string, opts DelayOptions, backlog map[string]int) error {
+	var ch *amqp.Channel
+
+	defer func() {
+		if ch != nil {
+			_ = ch.Close()
+		}
+	}()
+
+	for level := opts.levels() - 1; level >= 0; level-- {
+		queue := delayedLevelName(opts.levelPrefix(), level)
+		if _, ok := backlog[queue]; ok {
+			continue
+		}
+
+		if ch == nil {
+			var err error
+
+			ch, err = r.getChannel(connectionName)
+			if err != nil {
+				return err
+			}
+		}
+
+		q, err := ch.QueueInspect(queue)
+		if isNotFound(err) {
+			// the broker closes the channel when the queue is not found
+			ch = nil
+
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		backlog[queue] = q.Messages
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRabbids_DelayBacklog(t *testing.T) {
+	t.Parallel()
+
+	r, err := New(&Config{}, NoOPLoggerFN)
+	require.NoError(t, err)
+
+	r.config.Connections = map[string]Connection{
+		"plugin": {DelayStrategy: DelayStrategyPlugin},
+		"ttl":    {DelayStrategy: DelayStrategyTTL},
+	}
+
+	// only the levels strategy has fixed queues to inspect
+	backlog, err := r.DelayBacklog()
+	require.NoError(t, err)
+	require.Empty(t, backlog)
+}
+
+func TestSupervisor_checkDelayBacklog(t *testing.T) {
+	t.Parallel()
+
+	r, err := New(&Config{}, NoOPLoggerFN)
+	require.NoError(t, err)
+
+	now := time.Now()
+	s := &supervisor{rabbids: r}
+
+	s.checkDelayBacklog(now)
+	require.True(t, s.delayBacklog.lastCheck.IsZero(), "the backlog is not checked by default")
+
+	WithDelayBacklog(time.Minute)(s)
+	s.checkDelayBacklog(now)
+	require.Equal(t, now, s.delayBacklog.lastCheck)
+
+	s.checkDelayBacklog(now.Add(time.Second))
+	require.Equal(t, now, s.delayBacklog.lastCheck, "the backlog is checked once per interval")
+
+	s.checkDelayBacklog(now.Add(time.Minute))
+	require.Equal(t, now.Add(time.Minute), s.delayBacklog.lastCheck)
+}
@@ -0,0 +1,43 @@
+package rabbids
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+// queueBinder is the part of the amqp.Channel used to bind the queues to the delay infrastructure.
+type queueBinder interface {
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+}
+
+// boundQueues keep the queues already bound to the delay infrastructure, like the exDeclared of the
+// Producer does with the exchanges, so the delayed messages don't need one QueueBind for every Send.
+type boundQueues struct {
+	mutex  sync.Mutex
+	queues map[string]bool
+}
+
+// bind the queue to the exchange, only when the queue wasn't bound before.
+// The failed bindings are not cached, so the next message tries again.
+func (b *boundQueues) bind(ch queueBinder, queue, key, exchange string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.queues[queue] {
+		return nil
+	}
+
+	if err := ch.QueueBind(queue, key, exchange, false, amqp.Table{}); err != nil {
+		return fmt.Errorf("failed to bind the queue \"%s\" to the delay infrastructure: %w", queue, err)
+	}
+
+	if b.queues == nil {
+		b.queues = map[string]bool{}
+	}
+
+	b.queues[queue] = true
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+type binding struct {
+	queue, key, exchange string
+}
+
+type fakeBinder struct {
+	bindings []binding
+	err      error
+}
+
+func (f *fakeBinder) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	f.bindings = append(f.bindings, binding{queue: name, key: key, exchange: exchange})
+
+	return f.err
+}
+
+func TestDelayStrategies_bindQueuesOnce(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		strategy interface {
+			bindQueues(ch queueBinder, queues ...string) error
+		}
+		expected binding
+	}{
+		{
+			name:     "levels",
+			strategy: NewLevelsDelayStrategy(DelayOptions{}).(*delayDelivery),
+			expected: binding{queue: "billing", key: "#.billing", exchange: "rabbids.delay-delivery"},
+		},
+		{
+			name:     "plugin",
+			strategy: NewPluginDelayStrategy(DelayOptions{}).(*pluginDelay),
+			expected: binding{queue: "billing", key: "billing", exchange: "rabbids.delay-message"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ch := &fakeBinder{err: errors.New("channel closed")}
+			require.EqualError(t, tt.strategy.bindQueues(ch, "billing"),
+				`failed to bind the queue "billing" to the delay infrastructure: channel closed`)
+
+			ch.err = nil
+			for i := 0; i < 3; i++ {
+				require.NoError(t, tt.strategy.bindQueues(ch, "billing"))
+			}
+
+			require.Equal(t, []binding{tt.expected, tt.expected}, ch.bindings, "expecting one failed and one successful bind")
+
+			require.NoError(t, tt.strategy.bindQueues(ch, "orders"))
+			require.Len(t, ch.bindings, 3, "the other queues are bound once too")
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package rabbids
+
+import (
+	"fmt"
+	"time"
+)
+
+// The modes used to bypass the delay infrastructure for tiny delays.
+const (
+	// DelayBypassImmediate publish the message directly to the queue, ignoring the delay.
+	DelayBypassImmediate = "immediate"
+	// DelayBypassHold keep the message inside the process during the delay and then publish it
+	// directly to the queue. The messages held are lost if the process dies.
+	DelayBypassHold = "hold"
+)
+
+// delayBypass skip the delay infrastructure for delays less than the threshold.
+type delayBypass struct {
+	threshold time.Duration
+	hold      bool
+}
+
+// WithDelayBypass skip the delay infrastructure for the messages with a delay less than the threshold,
+// avoiding the traversal of the delay levels for near-zero delays. The mode is DelayBypassImmediate, to publish
+// the message right away, or DelayBypassHold, to wait the delay inside the process. Send blocks during
+// the delay with DelayBypassHold, the messages from Emit are published in background.
+func WithDelayBypass(threshold time.Duration, mode string) ProducerOption {
+	return func(p *Producer) error {
+		switch mode {
+		case "", DelayBypassImmediate:
+			p.delayBypass = delayBypass{threshold: threshold}
+		case DelayBypassHold:
+			p.delayBypass = delayBypass{threshold: threshold, hold: true}
+		default:
+			return fmt.Errorf("invalid delay bypass mode \"%s\"", mode)
+		}
+
+		return nil
+	}
+}
+
+// applies return true for the delayed messages with a known queue and a delay less than the threshold.
+func (b delayBypass) applies(m Publishing) bool {
+	return m.Delay > 0 && m.Delay < b.threshold && m.delayQueue != ""
+}
+
+// holds return true when the message must be held inside the process.
+func (b delayBypass) holds(m Publishing) bool {
+	return b.hold && b.applies(m)
+}
+
+// bypassDelay route the message directly to the queue, using the default exchange.
+func bypassDelay(m *Publishing) {
+	m.Exchange = ""
+	m.Key = m.delayQueue
+	m.Delay = 0
+}
+
+// holdEmit publish one message from Emit after the delay, without blocking the producer loop.
+// Close waits for the messages held.
+func (p *Producer) holdEmit(m Publishing) {
+	p.held.Add(1)
+
+	time.AfterFunc(m.Delay, func() {
+		defer p.held.Done()
+
+		bypassDelay(&m)
+
+		if err := p.Send(m); err != nil {
+			p.tryToEmitErr(m, err)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDelayBypass(t *testing.T) {
+	t.Parallel()
+
+	p := &Producer{}
+	require.NoError(t, WithDelayBypass(2*time.Second, "")(p))
+	require.Equal(t, delayBypass{threshold: 2 * time.Second}, p.delayBypass)
+
+	require.NoError(t, WithDelayBypass(2*time.Second, DelayBypassHold)(p))
+	require.Equal(t, delayBypass{threshold: 2 * time.Second, hold: true}, p.delayBypass)
+
+	require.EqualError(t, WithDelayBypass(time.Second, "drop")(p), `invalid delay bypass mode "drop"`)
+}
+
+func Test_delayBypass(t *testing.T) {
+	t.Parallel()
+
+	b := delayBypass{threshold: 2 * time.Second, hold: true}
+
+	tiny := NewDelayedPublishing("billing", 500*time.Millisecond, nil)
+	require.True(t, b.applies(tiny))
+	require.True(t, b.holds(tiny))
+	require.False(t, delayBypass{threshold: 2 * time.Second}.holds(tiny))
+
+	require.False(t, b.applies(NewDelayedPublishing("billing", 2*time.Second, nil)))
+	require.False(t, b.applies(NewPublishing("ex", "key", nil)))
+	require.False(t, delayBypass{}.applies(tiny))
+
+	bypassDelay(&tiny)
+	require.Equal(t, "", tiny.Exchange)
+	require.Equal(t, "billing", tiny.Key)
+	require.Equal(t, time.Duration(0), tiny.Delay)
+}
@@ -0,0 +1,65 @@
+package rabbids
+
+import (
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Headers added by the producers to the messages sent with delay, used to measure the actual delay.
+const (
+	// DelayPublishedAtHeader is the time the delayed message was published, in the RFC3339 format.
+	DelayPublishedAtHeader = "x-rabbids-delay-published-at"
+	// DelayRequestedHeader is the requested delay in milliseconds.
+	DelayRequestedHeader = "x-rabbids-delay-requested"
+	// DelayRoutingKeyHeader is the routing key computed by the DelayStrategy.
+	DelayRoutingKeyHeader = "x-rabbids-delay-routing-key"
+)
+
+// DelayInfo is the delay metadata of one message sent with delay.
+type DelayInfo struct {
+	PublishedAt time.Time
+	Requested   time.Duration
+	RoutingKey  string
+}
+
+// Actual return the delay between the publishing and the time informed, like the time the message was received.
+func (i DelayInfo) Actual(receivedAt time.Time) time.Duration {
+	return receivedAt.Sub(i.PublishedAt)
+}
+
+// MessageDelay return the delay metadata from the headers of a message sent with delay.
+func MessageDelay(headers amqp.Table) (DelayInfo, bool) {
+	published, ok := headers[DelayPublishedAtHeader].(string)
+	if !ok {
+		return DelayInfo{}, false
+	}
+
+	publishedAt, err := time.Parse(time.RFC3339Nano, published)
+	if err != nil {
+		return DelayInfo{}, false
+	}
+
+	info := DelayInfo{PublishedAt: publishedAt}
+	info.RoutingKey, _ = headers[DelayRoutingKeyHeader].(string)
+
+	switch v := headers[DelayRequestedHeader].(type) {
+	case int64:
+		info.Requested = time.Duration(v) * time.Millisecond
+	case int32:
+		info.Requested = time.Duration(v) * time.Millisecond
+	}
+
+	return info, true
+}
+
+// stampDelay add the delay metadata headers to a message prepared by the DelayStrategy.
+func stampDelay(m *Publishing, now time.Time) {
+	if m.Headers == nil {
+		m.Headers = amqp.Table{}
+	}
+
+	m.Headers[DelayPublishedAtHeader] = now.UTC().Format(time.RFC3339Nano)
+	m.Headers[DelayRequestedHeader] = m.Delay.Milliseconds()
+	m.Headers[DelayRoutingKeyHeader] = m.Key
+}
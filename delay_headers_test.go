@@ -0,0 +1,48 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageDelay(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2020, 10, 12, 10, 30, 0, 500, time.UTC)
+	p := NewDelayedPublishing("billing", 3*time.Second, nil)
+	p.Key = "0.1.1.billing"
+	stampDelay(&p, now)
+
+	tests := []struct {
+		name    string
+		headers amqp.Table
+		want    DelayInfo
+		wantOk  bool
+	}{
+		{"without header", amqp.Table{}, DelayInfo{}, false},
+		{"from stampDelay", p.Headers, DelayInfo{PublishedAt: now, Requested: 3 * time.Second, RoutingKey: "0.1.1.billing"}, true},
+		{"int32 delay", amqp.Table{
+			DelayPublishedAtHeader: "2020-10-12T10:30:00.0000005Z",
+			DelayRequestedHeader:   int32(500),
+		}, DelayInfo{PublishedAt: now, Requested: 500 * time.Millisecond}, true},
+		{"invalid time", amqp.Table{DelayPublishedAtHeader: "yesterday"}, DelayInfo{}, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := MessageDelay(tt.headers)
+			require.Equal(t, tt.wantOk, ok)
+			require.True(t, tt.want.PublishedAt.Equal(got.PublishedAt))
+			require.Equal(t, tt.want.Requested, got.Requested)
+			require.Equal(t, tt.want.RoutingKey, got.RoutingKey)
+		})
+	}
+
+	info, _ := MessageDelay(p.Headers)
+	require.Equal(t, 3500*time.Millisecond, info.Actual(now.Add(3500*time.Millisecond)))
+}
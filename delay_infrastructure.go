@@ -0,0 +1,120 @@
+package rabbids
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DeclareDelayInfrastructure declare the delay infrastructure of every connection, binding the queues of the
+// consumers using the delayed-requeue nack_strategy or the delayed_retry on_error strategy. The producers
+// created by the client share the delay strategy of the connection, so they skip these declarations
+// when sending the messages.
+func (r *Rabbids) DeclareDelayInfrastructure() error {
+	for _, name := range r.connectionNames() {
+		if err := r.declareDelay(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// declareDelayOnStart declare the delay infrastructure of the connections with declare_delay.
+func (r *Rabbids) declareDelayOnStart() error {
+	for _, name := range r.connectionNames() {
+		r.configMutex.RLock()
+		declare := r.config.Connections[name].DeclareDelay
+		r.configMutex.RUnlock()
+
+		if !declare {
+			continue
+		}
+
+		if err := r.declareDelay(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Rabbids) declareDelay(connectionName string) error {
+	r.configMutex.RLock()
+
+	conn := r.config.Connections[connectionName]
+
+	var queues []string
+
+	for _, cfg := range r.config.Consumers {
+		if cfg.Connection == connectionName && cfg.usesDelay() {
+			queues = append(queues, cfg.Queue.Name)
+		}
+	}
+
+	r.configMutex.RUnlock()
+
+	strategy, err := r.delayStrategy(connectionName, conn)
+	if err != nil {
+		return err
+	}
+
+	declarer, ok := strategy.(DelayDeclarer)
+	if !ok {
+		return nil
+	}
+
+	ch, err := r.getChannel(connectionName)
+	if err != nil {
+		return fmt.Errorf("failed to open a channel to declare the delay infrastructure: %w", err)
+	}
+
+	defer ch.Close()
+
+	sort.Strings(queues)
+
+	r.log.Info("declaring the delay infrastructure", Fields{"connection": connectionName, "queues": queues})
+
+	if err := declarer.Declare(ch, queues...); err != nil {
+		return fmt.Errorf("failed to declare the delay infrastructure for connection \"%s\": %w", connectionName, err)
+	}
+
+	return nil
+}
+
+// delayStrategy return the delay strategy shared by the producers of one connection.
+func (r *Rabbids) delayStrategy(connectionName string, conn Connection) (DelayStrategy, error) {
+	r.delayMutex.Lock()
+	defer r.delayMutex.Unlock()
+
+	if s, ok := r.delayStrategies[connectionName]; ok {
+		return s, nil
+	}
+
+	s, err := newDelayStrategy(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.delayStrategies == nil {
+		r.delayStrategies = map[string]DelayStrategy{}
+	}
+
+	r.delayStrategies[connectionName] = s
+
+	return s, nil
+}
+
+// connectionNames return the names of the connections from the config, sorted.
+func (r *Rabbids) connectionNames() []string {
+	r.configMutex.RLock()
+	defer r.configMutex.RUnlock()
+
+	names := make([]string, 0, len(r.config.Connections))
+	for name := range r.config.Connections {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,39 @@
+package rabbids
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRabbids_delayStrategy(t *testing.T) {
+	t.Parallel()
+
+	r, err := New(&Config{}, NoOPLoggerFN)
+	require.NoError(t, err)
+
+	s1, err := r.delayStrategy("default", Connection{})
+	require.NoError(t, err)
+	s2, err := r.delayStrategy("default", Connection{})
+	require.NoError(t, err)
+	require.Same(t, s1, s2, "the producers of one connection must share the strategy")
+
+	s3, err := r.delayStrategy("billing", Connection{DelayStrategy: DelayStrategyPlugin})
+	require.NoError(t, err)
+	require.IsType(t, &pluginDelay{}, s3)
+
+	_, err = r.delayStrategy("events", Connection{DelayStrategy: "foo"})
+	require.EqualError(t, err, `invalid delay strategy "foo"`)
+}
+
+func TestRabbids_DeclareDelayInfrastructureWithTTL(t *testing.T) {
+	t.Parallel()
+
+	r, err := New(&Config{}, NoOPLoggerFN)
+	require.NoError(t, err)
+
+	r.config.Connections = map[string]Connection{"default": {DelayStrategy: DelayStrategyTTL}}
+
+	// the ttl queues depend on the delays, nothing is declared before sending
+	require.NoError(t, r.DeclareDelayInfrastructure())
+}
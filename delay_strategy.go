@@ -0,0 +1,355 @@
+package rabbids
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// The delay strategies available in the connection config.
+const (
+	// DelayStrategyLevels use NewLevelsDelayStrategy, the default.
+	DelayStrategyLevels = "levels"
+	// DelayStrategyPlugin use NewPluginDelayStrategy.
+	DelayStrategyPlugin = "plugin"
+	// DelayStrategyTTL use NewTTLDelayStrategy.
+	DelayStrategyTTL = "ttl"
+	// DelayQueueClassic declare the delay queues as classic lazy queues, the default.
+	DelayQueueClassic = "classic"
+	// DelayQueueQuorum declare the delay queues as quorum queues, replicated between the cluster nodes.
+	DelayQueueQuorum = "quorum"
+	// DelayedMessageExchange is the exchange used by the plugin delay strategy with the default prefix.
+	DelayedMessageExchange = DefaultDelayPrefix + "-message"
+)
+
+// DelayOptions are the options used to create the delay strategies.
+type DelayOptions struct {
+	// Prefix is the prefix used in the name of all the exchanges and queues created by the strategy,
+	// DefaultDelayPrefix is used when empty. Different prefixes allow applications with different
+	// delay settings to share the same vhost.
+	Prefix string
+	// MaxDelay is the max delay accepted by the strategy, the messages with a longer delay are not sent
+	// and ErrDelayExceeded is returned. With the levels strategy it also reduces the number of levels declared.
+	// The default and the max value is MaxDelay.
+	MaxDelay time.Duration
+	// QueueType is the type of the queues declared by the levels and ttl strategies: DelayQueueClassic (default)
+	// or DelayQueueQuorum. Quorum queues survive node failures and don't use the deprecated lazy mode.
+	QueueType string
+	// Resolution is the smallest delay of the levels strategy, between one millisecond and one second (default).
+	// The levels with a resolution less than one second are named <prefix>-<resolution in ms>ms-level-<n>,
+	// so they don't conflict with the levels using seconds.
+	Resolution time.Duration
+}
+
+// ErrDelayExceeded is returned when sending a message with a delay greater than the max delay of the strategy.
+var ErrDelayExceeded = errors.New("the delay exceeds the max delay")
+
+func (o DelayOptions) maxDelay() time.Duration {
+	if o.MaxDelay <= 0 || o.MaxDelay > MaxDelay {
+		return MaxDelay
+	}
+
+	return o.MaxDelay
+}
+
+// resolution return the resolution of the levels, truncated to milliseconds.
+func (o DelayOptions) resolution() time.Duration {
+	if o.Resolution <= 0 || o.Resolution >= time.Second {
+		return time.Second
+	}
+
+	if o.Resolution < time.Millisecond {
+		return time.Millisecond
+	}
+
+	return o.Resolution.Truncate(time.Millisecond)
+}
+
+// levelPrefix return the prefix of the names of the levels.
+func (o DelayOptions) levelPrefix() string {
+	if res := o.resolution(); res < time.Second {
+		return fmt.Sprintf("%s-%dms", o.prefix(), res.Milliseconds())
+	}
+
+	return o.prefix()
+}
+
+// levels return the number of delay levels needed for the max delay.
+func (o DelayOptions) levels() int {
+	units := uint64(math.Ceil(float64(o.maxDelay()) / float64(o.resolution())))
+
+	return bits.Len64(units)
+}
+
+// delayQueueArgs set the type of the delay queue in the args.
+func delayQueueArgs(queueType string, args amqp.Table) amqp.Table {
+	if queueType == DelayQueueQuorum {
+		delete(args, "x-queue-mode")
+		args["x-queue-type"] = DelayQueueQuorum
+	}
+
+	return args
+}
+
+// RoutingKey return the routing key and the first exchange used by the levels delay strategy,
+// created with these options, to deliver a message to the queue after the delay.
+func (o DelayOptions) RoutingKey(delay time.Duration, queue string) (routingKey, exchange string) {
+	return levelsRoutingKey(delay, o.resolution(), queue, o.levelPrefix(), o.levels())
+}
+
+// EffectiveDelay return the delay applied by the levels delay strategy, created with these options:
+// the delay truncated to the resolution, at least one resolution, and at most the max delay supported by the levels.
+func (o DelayOptions) EffectiveDelay(delay time.Duration) time.Duration {
+	res := o.resolution()
+
+	if max := levelsMaxDelay(o.levels(), res); delay > max {
+		delay = max
+	}
+
+	if delay < res {
+		return res
+	}
+
+	return delay.Truncate(res)
+}
+
+// ValidateDelay return ErrDelayExceeded when the delay is greater than the max delay of the options.
+func (o DelayOptions) ValidateDelay(delay time.Duration) error {
+	return checkDelay(delay, o.maxDelay())
+}
+
+// ValidateDelay return ErrDelayExceeded when the delay is greater than MaxDelay.
+func ValidateDelay(delay time.Duration) error {
+	return DelayOptions{}.ValidateDelay(delay)
+}
+
+// checkDelay return ErrDelayExceeded for delays greater than the max.
+func checkDelay(delay, max time.Duration) error {
+	if delay > max {
+		return fmt.Errorf("%w: %s is greater than %s", ErrDelayExceeded, delay, max)
+	}
+
+	return nil
+}
+
+func (o DelayOptions) prefix() string {
+	if o.Prefix == "" {
+		return DefaultDelayPrefix
+	}
+
+	return o.Prefix
+}
+
+// DelayStrategy declares the infrastructure used to delay the messages and routes the delayed messages through it.
+// The strategy is used by the producers to send the messages with Delay, like the ones created by NewDelayedPublishing.
+type DelayStrategy interface {
+	// Prepare declare the infrastructure needed to deliver the message to the queue after the
+	// message Delay and change the Exchange, Key and properties of the message to be routed through it.
+	Prepare(ch *amqp.Channel, queue string, m *Publishing) error
+}
+
+// DelayDeclarer is implemented by the delay strategies able to declare the delay infrastructure
+// before the first message is sent, removing the declarations from the publish path.
+type DelayDeclarer interface {
+	// Declare the infrastructure used to deliver delayed messages to the queues.
+	Declare(ch *amqp.Channel, queues ...string) error
+}
+
+// WithDelayStrategy set the strategy used by the producer to send the messages with delay.
+func WithDelayStrategy(s DelayStrategy) ProducerOption {
+	return func(p *Producer) error {
+		p.delayStrategy = s
+
+		return nil
+	}
+}
+
+// newDelayStrategy return the strategy from the connection config.
+func newDelayStrategy(conn Connection) (DelayStrategy, error) {
+	opts := conn.delayOptions()
+
+	switch opts.QueueType {
+	case "", DelayQueueClassic, DelayQueueQuorum:
+	default:
+		return nil, fmt.Errorf("invalid delay queue type \"%s\"", opts.QueueType)
+	}
+
+	switch conn.DelayStrategy {
+	case "", DelayStrategyLevels:
+		return NewLevelsDelayStrategy(opts), nil
+	case DelayStrategyPlugin:
+		return NewPluginDelayStrategy(opts), nil
+	case DelayStrategyTTL:
+		return NewTTLDelayStrategy(opts), nil
+	}
+
+	return nil, fmt.Errorf("invalid delay strategy \"%s\"", conn.DelayStrategy)
+}
+
+type pluginDelay struct {
+	exchange    string
+	maxDelay    time.Duration
+	declareOnce sync.Once
+	declareErr  error
+	bound       boundQueues
+}
+
+// NewPluginDelayStrategy return a DelayStrategy using the rabbitmq_delayed_message_exchange plugin:
+// the messages are published to one x-delayed-message exchange, named <prefix>-message, with the x-delay header.
+// The plugin MUST be enabled in the broker.
+func NewPluginDelayStrategy(opts DelayOptions) DelayStrategy {
+	return &pluginDelay{
+		exchange: delayedMessageExchange(opts.prefix()),
+		maxDelay: opts.maxDelay(),
+	}
+}
+
+func (d *pluginDelay) Prepare(ch *amqp.Channel, queue string, m *Publishing) error {
+	if queue == "" {
+		return errors.New("the queue of the delayed message is unknown, use NewDelayedPublishing")
+	}
+
+	err := checkDelay(m.Delay, d.maxDelay)
+	if err != nil {
+		return err
+	}
+
+	err = d.Declare(ch, queue)
+	if err != nil {
+		return err
+	}
+
+	if m.Headers == nil {
+		m.Headers = amqp.Table{}
+	}
+
+	m.Exchange = d.exchange
+	m.Key = queue
+	m.Headers["x-delay"] = m.Delay.Milliseconds()
+
+	return nil
+}
+
+// Declare the x-delayed-message exchange, only once, and bind the queues to it.
+func (d *pluginDelay) Declare(ch *amqp.Channel, queues ...string) error {
+	d.declareOnce.Do(func() {
+		err := ch.ExchangeDeclare(d.exchange, "x-delayed-message", true, false, false, false, amqp.Table{
+			"x-delayed-type": amqp.ExchangeDirect,
+		})
+		if err != nil {
+			d.declareErr = fmt.Errorf("failed to declare exchange \"%s\": %w", d.exchange, err)
+		}
+	})
+
+	if d.declareErr != nil {
+		return d.declareErr
+	}
+
+	return d.bindQueues(ch, queues...)
+}
+
+// bindQueues bind the queues to the x-delayed-message exchange, using the queue name as the key.
+func (d *pluginDelay) bindQueues(ch queueBinder, queues ...string) error {
+	for _, queue := range queues {
+		if err := d.bound.bind(ch, queue, queue, d.exchange); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type ttlDelay struct {
+	prefix    string
+	maxDelay  time.Duration
+	queueType string
+}
+
+// NewTTLDelayStrategy return a DelayStrategy using one queue for each destination queue and delay,
+// named <prefix>.<queue>.<delay in ms>, with the message TTL and dead lettering the messages to the
+// destination queue. It's the simplest infrastructure but a new queue is created for every different delay,
+// so it should be used only with a few fixed delays.
+func NewTTLDelayStrategy(opts DelayOptions) DelayStrategy {
+	return ttlDelay{prefix: opts.prefix(), maxDelay: opts.maxDelay(), queueType: opts.QueueType}
+}
+
+func (d ttlDelay) Prepare(ch *amqp.Channel, queue string, m *Publishing) error {
+	if queue == "" {
+		return errors.New("the queue of the delayed message is unknown, use NewDelayedPublishing")
+	}
+
+	err := checkDelay(m.Delay, d.maxDelay)
+	if err != nil {
+		return err
+	}
+
+	ttl := m.Delay.Milliseconds()
+	name := ttlDelayQueueName(d.prefix, queue, m.Delay)
+
+	_, err = ch.QueueDeclare(name, true, false, false, false, delayQueueArgs(d.queueType, amqp.Table{
+		"x-message-ttl":             ttl,
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queue,
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to declare queue \"%s\": %w", name, err)
+	}
+
+	m.Exchange = ""
+	m.Key = name
+
+	return nil
+}
+
+func ttlDelayQueueName(prefix, queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.%s.%d", prefix, queue, delay.Milliseconds())
+}
+
+func delayedMessageExchange(prefix string) string {
+	return prefix + "-message"
+}
+
+// RemoveDelayTopology delete all the exchanges and queues declared by the levels and plugin delay strategies
+// with the prefix (DefaultDelayPrefix when empty), removing the bindings with them.
+// The messages waiting inside the delay queues are lost. It's useful to clean up the tests and to
+// decommission the delayed messages in shared vhosts. The queues created by the ttl strategy depend
+// on the delays sent and are not removed. The levels with a resolution less than one second are removed
+// only when the resolution is informed.
+func RemoveDelayTopology(ch *amqp.Channel, prefix string, resolutions ...time.Duration) error {
+	opts := []DelayOptions{{Prefix: prefix}}
+	for _, res := range resolutions {
+		opts = append(opts, DelayOptions{Prefix: prefix, Resolution: res})
+	}
+
+	for _, o := range opts {
+		for level := o.levels() - 1; level >= 0; level-- {
+			name := delayedLevelName(o.levelPrefix(), level)
+
+			_, err := ch.QueueDelete(name, false, false, false)
+			if err != nil {
+				return fmt.Errorf("failed to delete queue \"%s\": %w", name, err)
+			}
+
+			err = ch.ExchangeDelete(name, false, false)
+			if err != nil {
+				return fmt.Errorf("failed to delete exchange \"%s\": %w", name, err)
+			}
+		}
+	}
+
+	prefix = DelayOptions{Prefix: prefix}.prefix()
+
+	for _, name := range []string{delayDeliveryExchange(prefix), delayedMessageExchange(prefix)} {
+		err := ch.ExchangeDelete(name, false, false)
+		if err != nil {
+			return fmt.Errorf("failed to delete exchange \"%s\": %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newDelayStrategy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		expected DelayStrategy
+		err      string
+	}{
+		{"", &delayDelivery{}, ""},
+		{DelayStrategyLevels, &delayDelivery{}, ""},
+		{DelayStrategyPlugin, &pluginDelay{}, ""},
+		{DelayStrategyTTL, ttlDelay{}, ""},
+		{"foo", nil, `invalid delay strategy "foo"`},
+	}
+
+	for _, test := range tests {
+		s, err := newDelayStrategy(Connection{DelayStrategy: test.name})
+		if test.err != "" {
+			require.EqualError(t, err, test.err)
+			continue
+		}
+
+		require.NoError(t, err)
+		require.IsType(t, test.expected, s)
+	}
+}
+
+func TestDelayStrategiesWithoutQueue(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []DelayStrategy{NewLevelsDelayStrategy(DelayOptions{}), NewPluginDelayStrategy(DelayOptions{}), NewTTLDelayStrategy(DelayOptions{})} {
+		m := NewPublishing("", "key", "data")
+		m.Delay = time.Second
+		require.Error(t, s.Prepare(nil, "", &m))
+	}
+}
+
+func Test_ttlDelayQueueName(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "rabbids.delay.billing.1500", ttlDelayQueueName(DefaultDelayPrefix, "billing", 1500*time.Millisecond))
+}
+
+func TestDelayStrategiesWithPrefix(t *testing.T) {
+	t.Parallel()
+
+	opts := DelayOptions{Prefix: "billing.delay"}
+	require.Equal(t, "billing.delay", NewLevelsDelayStrategy(opts).(*delayDelivery).prefix)
+	require.Equal(t, "billing.delay-message", NewPluginDelayStrategy(opts).(*pluginDelay).exchange)
+	require.Equal(t, "billing.delay", NewTTLDelayStrategy(opts).(ttlDelay).prefix)
+
+	key, ex := opts.RoutingKey(3*time.Second, "billing")
+	require.Equal(t, "billing.delay-level-1", ex)
+	require.Equal(t, "0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.1.1.billing", key)
+}
+
+func TestDelayOptions_levels(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		maxDelay time.Duration
+		levels   int
+	}{
+		{0, maxNumberOfBitsToUse},
+		{MaxDelay + time.Hour, maxNumberOfBitsToUse},
+		{time.Second, 1},
+		{time.Minute, 6},
+		{time.Hour, 12},
+		{1500 * time.Millisecond, 2},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.levels, DelayOptions{MaxDelay: test.maxDelay}.levels(), test.maxDelay.String())
+	}
+}
+
+func TestDelayStrategiesWithMaxDelay(t *testing.T) {
+	t.Parallel()
+
+	opts := DelayOptions{MaxDelay: time.Hour}
+
+	for _, s := range []DelayStrategy{NewLevelsDelayStrategy(opts), NewPluginDelayStrategy(opts), NewTTLDelayStrategy(opts)} {
+		m := NewDelayedPublishing("billing", 2*time.Hour, "data")
+		err := s.Prepare(nil, "billing", &m)
+		require.True(t, errors.Is(err, ErrDelayExceeded))
+		require.EqualError(t, err, "the delay exceeds the max delay: 2h0m0s is greater than 1h0m0s")
+	}
+
+	key, ex := opts.RoutingKey(time.Hour, "billing")
+	require.Equal(t, "rabbids.delay-level-11", ex)
+	require.Equal(t, "1.1.1.0.0.0.0.1.0.0.0.0.billing", key)
+}
+
+func Test_delayLevelArgs(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, amqp.Table{
+		"x-queue-mode":           "lazy",
+		"x-message-ttl":          int64(4000),
+		"x-dead-letter-exchange": "rabbids.delay-level-1",
+	}, delayLevelArgs("", 2, time.Second, "rabbids.delay-level-1"))
+	require.Equal(t, amqp.Table{
+		"x-queue-type":           DelayQueueQuorum,
+		"x-message-ttl":          int64(4000),
+		"x-dead-letter-exchange": "rabbids.delay-level-1",
+	}, delayLevelArgs(DelayQueueQuorum, 2, time.Second, "rabbids.delay-level-1"))
+
+	_, err := newDelayStrategy(Connection{DelayQueueType: "stream"})
+	require.EqualError(t, err, `invalid delay queue type "stream"`)
+}
+
+func TestDelayOptionsWithResolution(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		resolution  time.Duration
+		expected    time.Duration
+		levelPrefix string
+	}{
+		{0, time.Second, "rabbids.delay"},
+		{2 * time.Second, time.Second, "rabbids.delay"},
+		{100 * time.Millisecond, 100 * time.Millisecond, "rabbids.delay-100ms"},
+		{time.Microsecond, time.Millisecond, "rabbids.delay-1ms"},
+		{1500 * time.Microsecond, time.Millisecond, "rabbids.delay-1ms"},
+	}
+
+	for _, test := range tests {
+		opts := DelayOptions{Resolution: test.resolution}
+		require.Equal(t, test.expected, opts.resolution())
+		require.Equal(t, test.levelPrefix, opts.levelPrefix())
+	}
+
+	// one minute needs 600 units of 100ms
+	require.Equal(t, 10, DelayOptions{Resolution: 100 * time.Millisecond, MaxDelay: time.Minute}.levels())
+}
+
+func Test_levelsRoutingKey(t *testing.T) {
+	t.Parallel()
+
+	key, ex := levelsRoutingKey(300*time.Millisecond, 100*time.Millisecond, "billing", "rabbids.delay-100ms", 4)
+	require.Equal(t, "0.0.1.1.billing", key)
+	require.Equal(t, "rabbids.delay-100ms-level-1", ex)
+
+	// delays less than the resolution use the resolution
+	key, ex = levelsRoutingKey(200*time.Millisecond, time.Second, "billing", DefaultDelayPrefix, 4)
+	require.Equal(t, "0.0.0.1.billing", key)
+	require.Equal(t, "rabbids.delay-level-0", ex)
+
+	require.Equal(t, amqp.Table{
+		"x-queue-mode":           "lazy",
+		"x-message-ttl":          int64(400),
+		"x-dead-letter-exchange": "rabbids.delay-100ms-level-1",
+	}, delayLevelArgs("", 2, 100*time.Millisecond, "rabbids.delay-100ms-level-1"))
+}
+
+func TestDelayOptions_EffectiveDelay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		opts  DelayOptions
+		delay time.Duration
+		want  time.Duration
+	}{
+		{DelayOptions{}, 200 * time.Millisecond, time.Second},
+		{DelayOptions{}, 2500 * time.Millisecond, 2 * time.Second},
+		{DelayOptions{}, MaxDelay + time.Hour, MaxDelay},
+		{DelayOptions{Resolution: 100 * time.Millisecond}, 250 * time.Millisecond, 200 * time.Millisecond},
+		{DelayOptions{MaxDelay: time.Minute}, 2 * time.Minute, 63 * time.Second},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, tt.opts.EffectiveDelay(tt.delay), tt.delay.String())
+	}
+}
+
+func TestValidateDelay(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidateDelay(time.Hour))
+	require.True(t, errors.Is(ValidateDelay(MaxDelay+time.Second), ErrDelayExceeded))
+	require.True(t, errors.Is(DelayOptions{MaxDelay: time.Minute}.ValidateDelay(2*time.Minute), ErrDelayExceeded))
+}
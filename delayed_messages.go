@@ -2,6 +2,7 @@ package rabbids
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"math"
 	"sync"
@@ -12,63 +13,111 @@ import (
 
 const (
 	maxNumberOfBitsToUse int = 28
-	maxLevel             int = maxNumberOfBitsToUse - 1
 
 	MaxDelay              time.Duration = ((1 << maxNumberOfBitsToUse) - 1) * time.Second
-	DelayDeliveryExchange string        = "rabbids.delay-delivery"
+	DefaultDelayPrefix    string        = "rabbids.delay"
+	DelayDeliveryExchange string        = DefaultDelayPrefix + "-delivery"
 )
 
 // delayDelivery is based on the setup of delay messages created by the NServiceBus project.
 // For more information go to the docs on https://docs.particular.net/transports/rabbitmq/delayed-delivery.
 type delayDelivery struct {
+	prefix            string
+	levelPrefix       string
+	resolution        time.Duration
+	levels            int
+	maxDelay          time.Duration
+	queueType         string
 	delayDeclaredOnce sync.Once
+	declaredErr       error
+	bound             boundQueues
 }
 
-// Declare create all the layers of exchanges and queues on rabbitMQ
-// and declare the bind between the last rabbids.delay-delivery ex and the queue.
-func (d *delayDelivery) Declare(ch *amqp.Channel, key string) error {
-	var declaredErr error
+// NewLevelsDelayStrategy return the default DelayStrategy, using levels of exchanges and queues
+// with TTLs of powers of two seconds. It's a fixed infrastructure for any delay, up to the DelayOptions.MaxDelay,
+// without broker plugins. Only the levels needed for the MaxDelay are declared, up to 28 levels.
+// With a DelayOptions.Resolution less than one second, the TTLs are powers of two of the resolution.
+func NewLevelsDelayStrategy(opts DelayOptions) DelayStrategy {
+	return &delayDelivery{
+		prefix:      opts.prefix(),
+		levelPrefix: opts.levelPrefix(),
+		resolution:  opts.resolution(),
+		levels:      opts.levels(),
+		maxDelay:    opts.maxDelay(),
+		queueType:   opts.QueueType,
+	}
+}
+
+// Prepare create all the layers of exchanges and queues on rabbitMQ
+// and declare the bind between the last <prefix>-delivery ex and the queue.
+func (d *delayDelivery) Prepare(ch *amqp.Channel, queue string, m *Publishing) error {
+	if queue == "" {
+		queue, _ = getQueueFromRoutingKey(m.Key, d.levels)
+	}
+
+	if queue == "" {
+		return errors.New("the queue of the delayed message is unknown, use NewDelayedPublishing")
+	}
 
-	queue := getQueueFromRoutingKey(key)
+	err := checkDelay(m.Delay, d.maxDelay)
+	if err != nil {
+		return err
+	}
 
+	err = d.Declare(ch, queue)
+	if err != nil {
+		return err
+	}
+
+	m.Key, m.Exchange = levelsRoutingKey(m.Delay, d.resolution, queue, d.levelPrefix, d.levels)
+
+	return nil
+}
+
+// Declare create all the layers of exchanges and queues, only once,
+// and bind the queues to the last <prefix>-delivery exchange.
+func (d *delayDelivery) Declare(ch *amqp.Channel, queues ...string) error {
 	d.delayDeclaredOnce.Do(func() {
-		declaredErr = d.build(ch)
+		d.declaredErr = d.build(ch)
 	})
 
-	if declaredErr != nil {
-		return declaredErr
+	if d.declaredErr != nil {
+		return d.declaredErr
+	}
+
+	return d.bindQueues(ch, queues...)
+}
+
+// bindQueues bind the queues to the last <prefix>-delivery exchange.
+func (d *delayDelivery) bindQueues(ch queueBinder, queues ...string) error {
+	for _, queue := range queues {
+		if err := d.bound.bind(ch, queue, fmt.Sprintf("#.%s", queue), delayDeliveryExchange(d.prefix)); err != nil {
+			return err
+		}
 	}
 
-	return ch.QueueBind(queue, fmt.Sprintf("#.%s", queue), DelayDeliveryExchange, false, amqp.Table{})
+	return nil
 }
 
 //nolint:funlen
 func (d *delayDelivery) build(ch *amqp.Channel) error {
+	delivery := delayDeliveryExchange(d.prefix)
 	bindingKey := "1.#"
 
-	for level := maxLevel; level >= 0; level-- {
-		currentLevel := delayedLevelName(level)
-		nextLevel := delayedLevelName(level - 1)
+	for level := d.levels - 1; level >= 0; level-- {
+		currentLevel := delayedLevelName(d.levelPrefix, level)
+		nextLevel := delayedLevelName(d.levelPrefix, level-1)
 
 		if level == 0 {
-			nextLevel = DelayDeliveryExchange
+			nextLevel = delivery
 		}
 
-		err := ch.ExchangeDeclare("fooo", amqp.ExchangeTopic, true, false, false, false, amqp.Table{})
-		if err != nil {
-			return fmt.Errorf("failed to declare exchange \"%s\": %v", "foo", err)
-		}
-
-		err = ch.ExchangeDeclare(currentLevel, amqp.ExchangeTopic, true, false, false, false, amqp.Table{})
+		err := ch.ExchangeDeclare(currentLevel, amqp.ExchangeTopic, true, false, false, false, amqp.Table{})
 		if err != nil {
 			return fmt.Errorf("failed to declare exchange \"%s\": %v", currentLevel, err)
 		}
 
-		_, err = ch.QueueDeclare(currentLevel, true, false, false, false, amqp.Table{
-			"x-queue-mode":           "lazy",
-			"x-message-ttl":          int64(math.Pow(2, float64(level)) * 1000),
-			"x-dead-letter-exchange": nextLevel,
-		})
+		_, err = ch.QueueDeclare(currentLevel, true, false, false, false, delayLevelArgs(d.queueType, level, d.resolution, nextLevel))
 		if err != nil {
 			return fmt.Errorf("failed to declare queue \"%s\": %v", currentLevel, err)
 		}
@@ -83,9 +132,9 @@ func (d *delayDelivery) build(ch *amqp.Channel) error {
 
 	bindingKey = "0.#"
 
-	for level := maxLevel; level >= 0; level-- {
-		currentLevel := delayedLevelName(level)
-		nextLevel := delayedLevelName(level - 1)
+	for level := d.levels - 1; level >= 0; level-- {
+		currentLevel := delayedLevelName(d.levelPrefix, level)
+		nextLevel := delayedLevelName(d.levelPrefix, level-1)
 
 		if level == 0 {
 			break
@@ -99,34 +148,46 @@ func (d *delayDelivery) build(ch *amqp.Channel) error {
 		bindingKey = "*." + bindingKey
 	}
 
-	err := ch.ExchangeDeclare(DelayDeliveryExchange, amqp.ExchangeTopic, true, false, false, false, amqp.Table{})
+	err := ch.ExchangeDeclare(delivery, amqp.ExchangeTopic, true, false, false, false, amqp.Table{})
 	if err != nil {
-		return fmt.Errorf("failed to declare exchange %s: %v", DelayDeliveryExchange, err)
+		return fmt.Errorf("failed to declare exchange %s: %v", delivery, err)
 	}
 
-	err = ch.ExchangeBind(DelayDeliveryExchange, bindingKey, delayedLevelName(0), false, amqp.Table{})
+	err = ch.ExchangeBind(delivery, bindingKey, delayedLevelName(d.levelPrefix, 0), false, amqp.Table{})
 
 	return err
 }
 
-// calculateRoutingKey return the routingkey and the first applicable exchange
-// to avoid unnecessary traversal through the delay infrastructure.
-func calculateRoutingKey(delay time.Duration, queue string) (string, string) {
-	if delay > MaxDelay {
-		delay = MaxDelay
+// CalculateRoutingKey return the routing key and the first exchange of the default levels delay strategy
+// used to deliver a message to the queue after the delay, skipping the unnecessary levels. The delays greater
+// than MaxDelay are reduced to MaxDelay, use ValidateDelay to check it. Use DelayOptions.RoutingKey for
+// strategies created with other options.
+func CalculateRoutingKey(delay time.Duration, queue string) (routingKey, exchange string) {
+	return DelayOptions{}.RoutingKey(delay, queue)
+}
+
+// levelsRoutingKey return the routingkey and the first applicable exchange for levels with any resolution.
+// Delays less than the resolution are rounded up to the resolution.
+func levelsRoutingKey(delay, resolution time.Duration, queue, prefix string, levels int) (string, string) {
+	if max := levelsMaxDelay(levels, resolution); delay > max {
+		delay = max
 	}
 
 	var buf bytes.Buffer
 
-	sec := uint(delay.Seconds())
+	units := uint64(delay / resolution)
+	if units == 0 {
+		units = 1
+	}
+
 	firstLevel := 0
 
-	for level := maxLevel; level >= 0; level-- {
-		if firstLevel == 0 && sec&(1<<uint(level)) != 0 {
+	for level := levels - 1; level >= 0; level-- {
+		if firstLevel == 0 && units&(1<<uint(level)) != 0 {
 			firstLevel = level
 		}
 
-		if sec&(1<<uint(level)) != 0 {
+		if units&(1<<uint(level)) != 0 {
 			buf.WriteString("1.")
 		} else {
 			buf.WriteString("0.")
@@ -135,15 +196,44 @@ func calculateRoutingKey(delay time.Duration, queue string) (string, string) {
 
 	buf.WriteString(queue)
 
-	return buf.String(), delayedLevelName(firstLevel)
+	return buf.String(), delayedLevelName(prefix, firstLevel)
 }
 
-// getQueueFromKey return the original queue name
-// used to generate the delay routing key.
-func getQueueFromRoutingKey(key string) string {
-	return key[maxNumberOfBitsToUse*2:]
+// getQueueFromRoutingKey return the original queue name used to generate the delay routing key
+// with the number of levels, false when the key doesn't start with one token for each level.
+func getQueueFromRoutingKey(key string, levels int) (string, bool) {
+	if len(key) <= levels*2 {
+		return "", false
+	}
+
+	for i := 0; i < levels*2; i += 2 {
+		if (key[i] != '0' && key[i] != '1') || key[i+1] != '.' {
+			return "", false
+		}
+	}
+
+	return key[levels*2:], true
+}
+
+func delayedLevelName(prefix string, level int) string {
+	return fmt.Sprintf("%s-level-%d", prefix, level)
+}
+
+// levelsMaxDelay return the max delay supported by the number of levels.
+func levelsMaxDelay(levels int, resolution time.Duration) time.Duration {
+	return time.Duration((1<<uint(levels))-1) * resolution
+}
+
+// delayLevelArgs return the arguments of the queue of one delay level, sending the messages
+// to the next level after the TTL of the level.
+func delayLevelArgs(queueType string, level int, resolution time.Duration, next string) amqp.Table {
+	return delayQueueArgs(queueType, amqp.Table{
+		"x-queue-mode":           "lazy",
+		"x-message-ttl":          int64(math.Pow(2, float64(level))) * resolution.Milliseconds(),
+		"x-dead-letter-exchange": next,
+	})
 }
 
-func delayedLevelName(level int) string {
-	return fmt.Sprintf("rabbids.delay-level-%d", level)
+func delayDeliveryExchange(prefix string) string {
+	return prefix + "-delivery"
 }
@@ -3,9 +3,11 @@ package rabbids
 import (
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/require"
 )
 
-func Test_calculateRoutingKey(t *testing.T) {
+func TestCalculateRoutingKey(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
@@ -54,7 +56,7 @@ func Test_calculateRoutingKey(t *testing.T) {
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			topic, ex := calculateRoutingKey(tt.delay, tt.address)
+			topic, ex := CalculateRoutingKey(tt.delay, tt.address)
 			if topic != tt.wantTopic {
 				t.Errorf("returned wrong topic = %v, want %v", topic, tt.wantTopic)
 			}
@@ -69,27 +71,67 @@ func Test_getQueueFromRoutingKey(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
-		name string
-		key  string
-		want string
+		name   string
+		key    string
+		levels int
+		want   string
+		ok     bool
 	}{
 		{
-			name: "single word",
-			key:  "0.0.0.0.0.0.0.0.1.1.1.1.1.1.0.1.0.0.1.0.0.0.0.0.0.0.0.0.test",
-			want: "test",
+			name:   "single word",
+			key:    "0.0.0.0.0.0.0.0.1.1.1.1.1.1.0.1.0.0.1.0.0.0.0.0.0.0.0.0.test",
+			levels: maxNumberOfBitsToUse,
+			want:   "test",
+			ok:     true,
+		},
+		{
+			name:   "2 words separated by dot",
+			key:    "0.0.0.0.0.0.0.0.1.1.1.1.1.1.0.1.0.0.1.0.0.0.0.0.0.0.0.0.test.foo",
+			levels: maxNumberOfBitsToUse,
+			want:   "test.foo",
+			ok:     true,
+		},
+		{
+			name:   "less levels",
+			key:    "1.0.1.0.1.test",
+			levels: 5,
+			want:   "test",
+			ok:     true,
+		},
+		{
+			name:   "not a delay routing key",
+			key:    "billing.invoice.created",
+			levels: 5,
 		},
 		{
-			name: "2 words separated by dot",
-			key:  "0.0.0.0.0.0.0.0.1.1.1.1.1.1.0.1.0.0.1.0.0.0.0.0.0.0.0.0.test.foo",
-			want: "test.foo",
+			name:   "too short",
+			key:    "1.0.1.",
+			levels: 5,
 		},
 	}
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			if got := getQueueFromRoutingKey(tt.key); got != tt.want {
-				t.Errorf("getQueueFromRoutingKey() = %v, want %v", got, tt.want)
+			got, ok := getQueueFromRoutingKey(tt.key, tt.levels)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("getQueueFromRoutingKey() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
 			}
 		})
 	}
 }
+
+func Test_getQueueFromRoutingKeyWithDelayOptions(t *testing.T) {
+	t.Parallel()
+
+	opts := DelayOptions{MaxDelay: time.Hour, Resolution: 100 * time.Millisecond}
+	d := NewLevelsDelayStrategy(opts).(*delayDelivery)
+	require.Equal(t, 16, d.levels)
+
+	for _, delay := range []time.Duration{time.Second, 30 * time.Minute, time.Hour} {
+		key, _ := levelsRoutingKey(delay, d.resolution, "billing.retry", d.levelPrefix, d.levels)
+
+		queue, ok := getQueueFromRoutingKey(key, d.levels)
+		require.True(t, ok)
+		require.Equal(t, "billing.retry", queue)
+	}
+}
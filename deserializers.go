@@ -0,0 +1,78 @@
+package rabbids
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/leveeml/rabbids/serialization"
+)
+
+// Deserializer decode the message bodies of one content type.
+type Deserializer interface {
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// HeadersDeserializer is implemented by the deserializers that need the message headers,
+// like the Avro serializer with the schema ID header. Message.Decode uses UnmarshalWithHeaders when available.
+type HeadersDeserializer interface {
+	Deserializer
+	UnmarshalWithHeaders(data []byte, headers map[string]interface{}, v interface{}) error
+}
+
+var deserializers = struct {
+	sync.RWMutex
+	byType map[string]Deserializer
+	keys   serialization.KeyProvider
+}{
+	byType: map[string]Deserializer{
+		"":                 &serialization.JSON{},
+		"application/json": &serialization.JSON{},
+		"text/plain":       &serialization.Text{},
+	},
+}
+
+// RegisterDeserializer register the deserializer used by Message.Decode for the messages with the content type,
+// replacing the previous one. The content type parameters, like the charset, are ignored.
+// JSON and plain text are registered by default, JSON is used for the messages without content type.
+func RegisterDeserializer(contentType string, d Deserializer) {
+	deserializers.Lock()
+	defer deserializers.Unlock()
+
+	deserializers.byType[mediaType(contentType)] = d
+}
+
+// RegisterKeyProvider set the keys used by Message.Decode to decrypt the messages
+// encrypted by the serialization.Encrypted serializer.
+func RegisterKeyProvider(keys serialization.KeyProvider) {
+	deserializers.Lock()
+	defer deserializers.Unlock()
+
+	deserializers.keys = keys
+}
+
+// keyProvider return the keys registered with RegisterKeyProvider.
+func keyProvider() serialization.KeyProvider {
+	deserializers.RLock()
+	defer deserializers.RUnlock()
+
+	return deserializers.keys
+}
+
+// deserializerFor return the deserializer registered for the content type.
+func deserializerFor(contentType string) (Deserializer, bool) {
+	deserializers.RLock()
+	defer deserializers.RUnlock()
+
+	d, ok := deserializers.byType[mediaType(contentType)]
+
+	return d, ok
+}
+
+// mediaType return the content type without the parameters, in lower case.
+func mediaType(contentType string) string {
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
@@ -0,0 +1,64 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMsgpack struct{}
+
+func (fakeMsgpack) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*string)) = "msgpack:" + string(data)
+
+	return nil
+}
+
+type fakeHeadersDeserializer struct{ fakeMsgpack }
+
+func (fakeHeadersDeserializer) UnmarshalWithHeaders(data []byte, headers map[string]interface{}, v interface{}) error {
+	if headers["x-schema"] == nil {
+		return errors.New("missing the schema header")
+	}
+
+	*(v.(*string)) = "headers:" + string(data)
+
+	return nil
+}
+
+func TestRegisterDeserializer(t *testing.T) {
+	t.Parallel()
+
+	RegisterDeserializer("Application/X-Test-Msgpack", fakeMsgpack{})
+	RegisterDeserializer("application/x-test-headers", fakeHeadersDeserializer{})
+
+	var v string
+
+	m := Message{Delivery: amqp.Delivery{Body: []byte("data"), ContentType: "application/x-test-msgpack; charset=utf-8"}}
+	require.NoError(t, m.Decode(&v))
+	require.Equal(t, "msgpack:data", v)
+
+	m.ContentType = "application/json"
+	m.Body = []byte(`"json"`)
+	require.NoError(t, m.Decode(&v))
+	require.Equal(t, "json", v)
+
+	m.ContentType = "application/x-test-headers"
+	require.EqualError(t, m.Decode(&v), "missing the schema header")
+
+	m.Headers = amqp.Table{"x-schema": int32(1)}
+	require.NoError(t, m.Decode(&v))
+	require.Equal(t, `headers:"json"`, v)
+}
+
+func TestDecodeText(t *testing.T) {
+	t.Parallel()
+
+	var v string
+
+	m := Message{Delivery: amqp.Delivery{Body: []byte("disk full"), ContentType: "text/plain; charset=utf-8"}}
+	require.NoError(t, m.Decode(&v))
+	require.Equal(t, "disk full", v)
+}
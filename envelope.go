@@ -0,0 +1,231 @@
+package rabbids
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+// The headers of the message envelope, describing the type and the version of the data.
+const (
+	MessageTypeHeader    = "x-message-type"
+	MessageVersionHeader = "x-message-version"
+)
+
+// ErrUnknownMessageType is returned by the Dispatcher for messages without a registered handler.
+var ErrUnknownMessageType = errors.New("no handler registered for the message type")
+
+// TypedMessage is implemented by the data types describing their message type and version.
+// The producers stamp the envelope headers of the messages with a TypedMessage data.
+type TypedMessage interface {
+	MessageType() (msgType string, version int)
+}
+
+// WithMessageType set the envelope headers with the type and the version of the message.
+func WithMessageType(msgType string, version int) PublishingOption {
+	return func(p *Publishing) {
+		stampMessageType(p, msgType, version)
+	}
+}
+
+func stampMessageType(p *Publishing, msgType string, version int) {
+	if p.Headers == nil {
+		p.Headers = amqp.Table{}
+	}
+
+	p.Headers[MessageTypeHeader] = msgType
+	p.Headers[MessageVersionHeader] = int32(version)
+}
+
+// stampTypedMessage set the envelope headers using the TypedMessage data, unless they are already set.
+func stampTypedMessage(p *Publishing) {
+	typed, ok := p.Data.(TypedMessage)
+	if !ok {
+		return
+	}
+
+	if _, exists := p.Headers[MessageTypeHeader]; exists {
+		return
+	}
+
+	msgType, version := typed.MessageType()
+	stampMessageType(p, msgType, version)
+}
+
+// MessageType return the type and the version from the envelope headers.
+// The version is zero when the header is missing.
+func MessageType(headers map[string]interface{}) (string, int, bool) {
+	msgType, ok := headers[MessageTypeHeader].(string)
+	if !ok {
+		return "", 0, false
+	}
+
+	var version int
+
+	switch v := headers[MessageVersionHeader].(type) {
+	case int:
+		version = v
+	case int16:
+		version = int(v)
+	case int32:
+		version = int(v)
+	case int64:
+		version = int(v)
+	}
+
+	return msgType, version, true
+}
+
+var messageTypes = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+}{byName: map[string]reflect.Type{}}
+
+// RegisterType register the Go type of the data of one message type, used by Message.DecodeRegistered.
+// The prototype is a value of the type, like OrderCreated{}, or a pointer to receive pointers.
+func RegisterType(msgType string, prototype interface{}) {
+	messageTypes.Lock()
+	defer messageTypes.Unlock()
+
+	messageTypes.byName[msgType] = reflect.TypeOf(prototype)
+}
+
+// DecodeRegistered decode the message body into a new value of the type registered with RegisterType
+// for the message type from the envelope headers. The value has the same type as the registered prototype.
+func (m Message) DecodeRegistered() (interface{}, error) {
+	msgType, _, ok := MessageType(m.Headers)
+	if !ok {
+		return nil, errors.New("the message didn't have the message type header")
+	}
+
+	messageTypes.RLock()
+	t, ok := messageTypes.byName[msgType]
+	messageTypes.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: \"%s\" is not registered", ErrUnknownMessageType, msgType)
+	}
+
+	if t.Kind() == reflect.Ptr {
+		v := reflect.New(t.Elem())
+		if err := m.Decode(v.Interface()); err != nil {
+			return nil, err
+		}
+
+		return v.Interface(), nil
+	}
+
+	v := reflect.New(t)
+	if err := m.Decode(v.Interface()); err != nil {
+		return nil, err
+	}
+
+	return v.Elem().Interface(), nil
+}
+
+// Dispatcher is a MessageHandler routing the messages to the handlers registered by the type and the version
+// from the envelope headers, so the consumers can handle many versions of the messages during a schema change.
+// Messages without a handler are passed to the default handler or ErrUnknownMessageType is returned,
+// applying the consumer on_error strategy.
+type Dispatcher struct {
+	mutex    sync.RWMutex
+	handlers map[dispatchKey]MessageHandler
+	fallback MessageHandler
+}
+
+type dispatchKey struct {
+	msgType string
+	version int
+}
+
+// NewDispatcher create an empty Dispatcher.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: map[dispatchKey]MessageHandler{}}
+}
+
+// Register set the handler of one type and version. The version zero handles all the versions
+// of the type without a specific handler.
+func (d *Dispatcher) Register(msgType string, version int, h MessageHandler) *Dispatcher {
+	d.mutex.Lock()
+	d.handlers[dispatchKey{msgType, version}] = h
+	d.mutex.Unlock()
+
+	return d
+}
+
+// Default set the handler of the messages without a registered handler, including the messages without envelope.
+func (d *Dispatcher) Default(h MessageHandler) *Dispatcher {
+	d.mutex.Lock()
+	d.fallback = h
+	d.mutex.Unlock()
+
+	return d
+}
+
+// Handle implements the MessageHandler interface.
+func (d *Dispatcher) Handle(m Message) {
+	_ = d.HandleErr(m)
+}
+
+// HandleErr implements the ErrorHandler interface.
+func (d *Dispatcher) HandleErr(m Message) error {
+	h := d.handler(m)
+	if h == nil {
+		msgType, version, _ := MessageType(m.Headers)
+
+		return fmt.Errorf("%w: \"%s\" version %d", ErrUnknownMessageType, msgType, version)
+	}
+
+	return handlerErr(h, m)
+}
+
+func (d *Dispatcher) handler(m Message) MessageHandler {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if msgType, version, ok := MessageType(m.Headers); ok {
+		if h, ok := d.handlers[dispatchKey{msgType, version}]; ok {
+			return h
+		}
+
+		if h, ok := d.handlers[dispatchKey{msgType, 0}]; ok {
+			return h
+		}
+	}
+
+	return d.fallback
+}
+
+// Close closes all the registered handlers, once for each handler, even when the handler
+// is registered for many types or is also the default handler.
+// The handlers that can't be compared, like the MessageHandlerFunc, are closed for each registration.
+func (d *Dispatcher) Close() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	handlers := make([]MessageHandler, 0, len(d.handlers)+1)
+	for _, h := range d.handlers {
+		handlers = append(handlers, h)
+	}
+
+	if d.fallback != nil {
+		handlers = append(handlers, d.fallback)
+	}
+
+	closed := make(map[MessageHandler]struct{}, len(handlers))
+
+	for _, h := range handlers {
+		if reflect.TypeOf(h).Comparable() {
+			if _, ok := closed[h]; ok {
+				continue
+			}
+
+			closed[h] = struct{}{}
+		}
+
+		h.Close()
+	}
+}
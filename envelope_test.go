@@ -0,0 +1,135 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/leveeml/rabbids/serialization"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+type orderCreatedV2 struct {
+	ID string `json:"id"`
+}
+
+func (orderCreatedV2) MessageType() (string, int) {
+	return "order.created", 2
+}
+
+func TestProducerStampMessageType(t *testing.T) {
+	t.Parallel()
+
+	p := &Producer{serializer: &serialization.JSON{}}
+
+	m, err := p.prepare(NewPublishing("ex", "key", orderCreatedV2{ID: "1"}))
+	require.NoError(t, err)
+	require.Equal(t, "order.created", m.Headers[MessageTypeHeader])
+	require.Equal(t, int32(2), m.Headers[MessageVersionHeader])
+
+	m, err = p.prepare(NewPublishing("ex", "key", orderCreatedV2{}, WithMessageType("order.imported", 1)))
+	require.NoError(t, err)
+
+	msgType, version, ok := MessageType(m.Headers)
+	require.True(t, ok)
+	require.Equal(t, "order.imported", msgType)
+	require.Equal(t, 1, version)
+
+	_, _, ok = MessageType(amqp.Table{})
+	require.False(t, ok)
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+
+	handler := func(name string) MessageHandler {
+		return MessageHandlerFunc(func(m Message) {
+			calls = append(calls, name)
+		})
+	}
+
+	d := NewDispatcher().
+		Register("order.created", 1, handler("v1")).
+		Register("order.created", 2, handler("v2")).
+		Register("order.paid", 0, handler("paid"))
+
+	message := func(headers amqp.Table) Message {
+		return Message{Delivery: amqp.Delivery{Headers: headers}}
+	}
+
+	require.NoError(t, d.HandleErr(message(amqp.Table{MessageTypeHeader: "order.created", MessageVersionHeader: int32(2)})))
+	require.NoError(t, d.HandleErr(message(amqp.Table{MessageTypeHeader: "order.created", MessageVersionHeader: int64(1)})))
+	require.NoError(t, d.HandleErr(message(amqp.Table{MessageTypeHeader: "order.paid", MessageVersionHeader: int32(7)})))
+	require.Equal(t, []string{"v2", "v1", "paid"}, calls)
+
+	err := d.HandleErr(message(amqp.Table{MessageTypeHeader: "order.created", MessageVersionHeader: int32(3)}))
+	require.True(t, errors.Is(err, ErrUnknownMessageType))
+	require.EqualError(t, err, `no handler registered for the message type: "order.created" version 3`)
+
+	d.Default(handler("default"))
+	require.NoError(t, d.HandleErr(message(nil)))
+	require.Equal(t, "default", calls[3])
+}
+
+type closeCounter struct {
+	MessageHandlerFunc
+	closes int
+}
+
+func (h *closeCounter) Close() {
+	h.closes++
+}
+
+func TestDispatcherClose(t *testing.T) {
+	t.Parallel()
+
+	shared := &closeCounter{}
+	other := &closeCounter{}
+
+	d := NewDispatcher().
+		Register("order.created", 1, shared).
+		Register("order.created", 2, shared).
+		Register("order.paid", 0, other).
+		Register("order.canceled", 0, MessageHandlerFunc(func(m Message) {})).
+		Default(shared)
+
+	d.Close()
+	require.Equal(t, 1, shared.closes)
+	require.Equal(t, 1, other.closes)
+}
+
+func TestMessageDecodeRegistered(t *testing.T) {
+	t.Parallel()
+
+	RegisterType("test.order.created", orderCreatedV2{})
+	RegisterType("test.order.paid", &orderCreatedV2{})
+
+	m := Message{Delivery: amqp.Delivery{
+		Headers: amqp.Table{MessageTypeHeader: "test.order.created"},
+		Body:    []byte(`{"id":"1"}`),
+	}}
+
+	v, err := m.DecodeRegistered()
+	require.NoError(t, err)
+	require.Equal(t, orderCreatedV2{ID: "1"}, v)
+
+	m.Headers[MessageTypeHeader] = "test.order.paid"
+	v, err = m.DecodeRegistered()
+	require.NoError(t, err)
+	require.Equal(t, &orderCreatedV2{ID: "1"}, v)
+
+	m.Headers[MessageTypeHeader] = "test.unknown"
+	_, err = m.DecodeRegistered()
+	require.True(t, errors.Is(err, ErrUnknownMessageType))
+
+	m.Headers = nil
+	_, err = m.DecodeRegistered()
+	require.EqualError(t, err, "the message didn't have the message type header")
+
+	m.Headers = amqp.Table{MessageTypeHeader: "test.order.created"}
+	m.Body = []byte("{")
+	_, err = m.DecodeRegistered()
+	require.Error(t, err)
+}
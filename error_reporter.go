@@ -0,0 +1,32 @@
+package rabbids
+
+// ErrorReporter receives the failures that are not returned to the application, like the handler panics,
+// the errors dropped because the EmitErr channel is full and the consumers the supervisor failed to restart,
+// to send them to an error tracker like Sentry or Bugsnag.
+// It is called by the goroutine where the failure happens, so it MUST be safe for concurrent use.
+type ErrorReporter func(err error, fields Fields)
+
+// WithErrorReporter set the ErrorReporter of the client, its consumers and producers.
+func WithErrorReporter(fn ErrorReporter) Option {
+	return func(r *Rabbids) error {
+		r.reportErr = fn
+
+		return nil
+	}
+}
+
+// WithProducerErrorReporter set the ErrorReporter called with the errors dropped by the producer.
+func WithProducerErrorReporter(fn ErrorReporter) ProducerOption {
+	return func(p *Producer) error {
+		p.reportErr = fn
+
+		return nil
+	}
+}
+
+// report call the function, when set.
+func (fn ErrorReporter) report(err error, fields Fields) {
+	if fn != nil {
+		fn(err, fields)
+	}
+}
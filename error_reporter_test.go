@@ -0,0 +1,55 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+type reported struct {
+	err    error
+	fields Fields
+}
+
+func TestErrorReporter(t *testing.T) {
+	t.Parallel()
+
+	var got []reported
+
+	reporter := ErrorReporter(func(err error, fields Fields) {
+		got = append(got, reported{err: err, fields: fields})
+	})
+
+	r := &Rabbids{log: NoOPLogger{}}
+	require.NoError(t, WithErrorReporter(reporter)(r))
+
+	c := &Consumer{name: "users", log: NoOPLogger{}, metrics: NoOPMetrics{}, reportErr: r.reportErr}
+	c.handler = MessageHandlerFunc(func(m Message) { panic("boom") })
+	require.EqualError(t, c.invoke(Message{Delivery: amqp.Delivery{MessageId: "foo"}}), "handler panic: boom")
+
+	p := &Producer{name: "events", emitErr: make(chan PublishingError)}
+	require.NoError(t, WithProducerErrorReporter(r.reportErr)(p))
+	p.tryToEmitErr(NewPublishing("events", "user.created", nil), errors.New("connection closed"))
+
+	s := &supervisor{rabbids: r, restarts: map[string]*restartState{}}
+	WithRestartPolicy(RestartPolicy{MaxRestarts: 1, Window: time.Minute})(s)
+
+	dead := &Consumer{name: "dead"}
+	dead.t.Kill(errors.New("channel closed"))
+
+	now := time.Now()
+	require.True(t, s.allowRestart("dead", dead, now))
+	require.False(t, s.allowRestart("dead", dead, now))
+
+	require.Len(t, got, 3)
+	require.EqualError(t, got[0].err, "handler panic: boom")
+	require.Equal(t, "users", got[0].fields["consumer"])
+	require.Equal(t, "foo", got[0].fields["message-id"])
+	require.EqualError(t, got[1].err, "the EmitErr channel is full, dropping the error: connection closed")
+	require.Equal(t, "events", got[1].fields["producer"])
+	require.EqualError(t, got[2].err, "gave up restarting the consumer after 1 restarts: channel closed")
+	require.Equal(t, transitionGaveUp, got[2].fields["transition"])
+}
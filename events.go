@@ -0,0 +1,85 @@
+package rabbids
+
+import (
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// EventsBufferSize is the size of the buffer of the Events channel.
+// When the buffer is full new events are dropped, so a slow reader never blocks the consumers.
+const EventsBufferSize = 100
+
+// EventType is the type of the Events emitted by the Rabbids client.
+type EventType string
+
+const (
+	// EventConsumerStarted is emitted when one consumer starts consuming the queue.
+	EventConsumerStarted EventType = "consumer_started"
+	// EventConsumerDied is emitted when one consumer stops with an error.
+	EventConsumerDied EventType = "consumer_died"
+	// EventConsumerRestarted is emitted when one consumer is created again after the first time.
+	EventConsumerRestarted EventType = "consumer_restarted"
+	// EventConnectionLost is emitted when one connection with rabbitMQ is closed.
+	EventConnectionLost EventType = "connection_lost"
+	// EventConnectionRestored is emitted when one connection closed is opened again.
+	EventConnectionRestored EventType = "connection_restored"
+	// EventTopologyDeclared is emitted when the queue, exchanges and dead letters of one consumer are declared.
+	EventTopologyDeclared EventType = "topology_declared"
+	// EventCircuitOpened is emitted when one consumer stops handling messages because of consecutive handler errors.
+	EventCircuitOpened EventType = "circuit_opened"
+	// EventCircuitClosed is emitted when one consumer handles a message with success after the circuit was open.
+	EventCircuitClosed EventType = "circuit_closed"
+	// EventLeadershipAcquired is emitted when one singleton consumer holds the lock and starts consuming.
+	EventLeadershipAcquired EventType = "leadership_acquired"
+	// EventLeadershipLost is emitted when one singleton consumer stops consuming.
+	EventLeadershipLost EventType = "leadership_lost"
+	// EventChannelRecovered is emitted when one consumer opens a new channel after the broker closed it.
+	EventChannelRecovered EventType = "channel_recovered"
+)
+
+// Event is something that happened inside the Rabbids client.
+type Event struct {
+	Type       EventType
+	Time       time.Time
+	Consumer   string
+	Queue      string
+	Connection string
+	Err        error
+}
+
+// Events returns the channel with the events emitted by the client, consumers and supervisor.
+func (r *Rabbids) Events() <-chan Event {
+	return r.events
+}
+
+// emit send the event without blocking, dropping it when the buffer is full.
+func (r *Rabbids) emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	r.lifecycle.call(e)
+
+	select {
+	case r.events <- e:
+	default:
+	}
+}
+
+func (c *Consumer) emit(e Event) {
+	if c.events != nil {
+		c.events(e)
+	}
+}
+
+// watchConnection emit the EventConnectionLost when the connection is closed.
+func (r *Rabbids) watchConnection(name string, conn *amqp.Connection) {
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	go func() {
+		if err, ok := <-closed; ok && err != nil {
+			r.emit(Event{Type: EventConnectionLost, Connection: name, Err: err})
+		}
+	}()
+}
@@ -0,0 +1,63 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRabbids_Events(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{consumers: map[string]*Consumer{}, events: make(chan Event, 1)}
+
+	old := &Consumer{name: "consumer", queue: "queue"}
+	old.t.Kill(errors.New("channel closed"))
+	r.registerConsumer(old)
+	require.Empty(t, r.Events(), "the first consumer is not a restart")
+
+	r.registerConsumer(&Consumer{name: "consumer", queue: "queue"})
+	e := <-r.Events()
+	require.Equal(t, EventConsumerRestarted, e.Type)
+	require.Equal(t, "consumer", e.Consumer)
+	require.EqualError(t, e.Err, "channel closed")
+	require.False(t, e.Time.IsZero())
+
+	// a full buffer drops the events without blocking
+	r.emit(Event{Type: EventTopologyDeclared})
+	r.emit(Event{Type: EventTopologyDeclared})
+	require.Len(t, r.Events(), 1)
+}
+
+func TestRabbids_lifecycleHooks(t *testing.T) {
+	t.Parallel()
+
+	var (
+		restarted []string
+		lost      []string
+		shutdown  bool
+	)
+
+	r := &Rabbids{consumers: map[string]*Consumer{}, conns: map[string]*amqp.Connection{}}
+	require.NoError(t, WithOnConsumerRestart(func(consumer string, err error) {
+		// the hooks can call the client without deadlocks
+		require.Len(t, r.Consumers(), 1)
+		restarted = append(restarted, consumer)
+	})(r))
+	require.NoError(t, WithOnShutdown(func() { shutdown = true })(r))
+	require.NoError(t, WithOnConnectionLost(func(connection string, err error) {
+		lost = append(lost, connection)
+	})(r))
+
+	r.registerConsumer(&Consumer{name: "consumer"})
+	r.registerConsumer(&Consumer{name: "consumer"})
+	require.Equal(t, []string{"consumer"}, restarted)
+
+	r.emit(Event{Type: EventConnectionLost, Connection: "default", Err: amqp.ErrClosed})
+	require.Equal(t, []string{"default"}, lost)
+
+	require.NoError(t, r.Close())
+	require.True(t, shutdown)
+}
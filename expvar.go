@@ -0,0 +1,90 @@
+package rabbids
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+var (
+	expvarOnce  sync.Once
+	expvarStats *expvarMetrics
+)
+
+// ExpvarMetrics returns the Metrics publishing the basic runtime stats with expvar, so the /debug/vars
+// handler gives a quick visibility without a metrics stack. The stats are maps keyed by the producer,
+// consumer or connection name, published with the names:
+//
+//	rabbids.messages_published, rabbids.publish_errors,
+//	rabbids.messages_consumed, rabbids.messages_rejected,
+//	rabbids.reconnects, rabbids.consumer_restarts,
+//	rabbids.consumer_workers, rabbids.consumer_in_flight
+//
+// expvar is global, so all the clients using it share the same stats.
+// Use MultiMetrics to use it with other Metrics.
+func ExpvarMetrics() Metrics {
+	expvarOnce.Do(func() {
+		expvarStats = &expvarMetrics{
+			published: expvar.NewMap("rabbids.messages_published"),
+			pubErrors: expvar.NewMap("rabbids.publish_errors"),
+			consumed:  expvar.NewMap("rabbids.messages_consumed"),
+			rejected:  expvar.NewMap("rabbids.messages_rejected"),
+			reconnect: expvar.NewMap("rabbids.reconnects"),
+			restarts:  expvar.NewMap("rabbids.consumer_restarts"),
+			workers:   expvar.NewMap("rabbids.consumer_workers"),
+			inFlight:  expvar.NewMap("rabbids.consumer_in_flight"),
+		}
+	})
+
+	return expvarStats
+}
+
+type expvarMetrics struct {
+	NoOPMetrics
+	published *expvar.Map
+	pubErrors *expvar.Map
+	consumed  *expvar.Map
+	rejected  *expvar.Map
+	reconnect *expvar.Map
+	restarts  *expvar.Map
+	workers   *expvar.Map
+	inFlight  *expvar.Map
+}
+
+func (e *expvarMetrics) MessagePublished(producer, exchange string, err error) {
+	if err != nil {
+		e.pubErrors.Add(producer, 1)
+
+		return
+	}
+
+	e.published.Add(producer, 1)
+}
+
+func (e *expvarMetrics) MessageHandled(consumer string, outcome Outcome, duration time.Duration) {
+	e.consumed.Add(consumer, 1)
+
+	if outcome == OutcomeNack || outcome == OutcomeRequeue {
+		e.rejected.Add(consumer, 1)
+	}
+}
+
+func (e *expvarMetrics) Reconnected(connection string) {
+	e.reconnect.Add(connection, 1)
+}
+
+func (e *expvarMetrics) ConsumerRestarted(consumer string, timeToRecover time.Duration) {
+	e.restarts.Add(consumer, 1)
+}
+
+func (e *expvarMetrics) ConsumerWorkers(consumer string, inFlight, workers int) {
+	e.inFlight.Set(consumer, intVar(inFlight))
+	e.workers.Set(consumer, intVar(workers))
+}
+
+func intVar(v int) *expvar.Int {
+	i := new(expvar.Int)
+	i.Set(int64(v))
+
+	return i
+}
@@ -0,0 +1,46 @@
+package rabbids
+
+import (
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpvarMetrics(t *testing.T) {
+	m := ExpvarMetrics()
+	require.Same(t, m, ExpvarMetrics(), "the stats are published only once")
+
+	var handled []Outcome
+
+	multi := MultiMetrics(m, &outcomeMetrics{outcomes: &handled})
+
+	multi.MessagePublished("expvar-producer", "events", nil)
+	multi.MessagePublished("expvar-producer", "events", errors.New("closed"))
+	multi.MessageHandled("expvar-consumer", OutcomeAck, 0)
+	multi.MessageHandled("expvar-consumer", OutcomeRequeue, 0)
+	multi.ConsumerWorkers("expvar-consumer", 2, 10)
+
+	stat := func(name, key string) string {
+		return expvar.Get(name).(*expvar.Map).Get(key).String()
+	}
+
+	require.Equal(t, "1", stat("rabbids.messages_published", "expvar-producer"))
+	require.Equal(t, "1", stat("rabbids.publish_errors", "expvar-producer"))
+	require.Equal(t, "2", stat("rabbids.messages_consumed", "expvar-consumer"))
+	require.Equal(t, "1", stat("rabbids.messages_rejected", "expvar-consumer"))
+	require.Equal(t, "10", stat("rabbids.consumer_workers", "expvar-consumer"))
+	require.Equal(t, "2", stat("rabbids.consumer_in_flight", "expvar-consumer"))
+	require.Equal(t, []Outcome{OutcomeAck, OutcomeRequeue}, handled)
+}
+
+type outcomeMetrics struct {
+	NoOPMetrics
+	outcomes *[]Outcome
+}
+
+func (m *outcomeMetrics) MessageHandled(_ string, outcome Outcome, _ time.Duration) {
+	*m.outcomes = append(*m.outcomes, outcome)
+}
@@ -0,0 +1,94 @@
+package rabbids
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/streadway/amqp"
+)
+
+// ErrStandby is returned when creating a consumer from a group, or a singleton consumer, already active
+// in another connection. The supervisor keeps trying to create the consumer, so it takes over when the active one stops.
+var ErrStandby = errors.New("the consumer is active in another connection")
+
+// groupLockName return the name of the exclusive queue used as a lock by one consumer group.
+// Every stream (or partition) of the group has its own lock.
+func groupLockName(group, queue string) string {
+	return fmt.Sprintf("rabbids.group.%s.%s", group, queue)
+}
+
+// consumerLock return the name of the lock queue the consumer must hold before consuming,
+// empty when the consumer is not part of a group or a singleton.
+func (r *Rabbids) consumerLock(name string, cfg ConsumerConfig) string {
+	if cfg.Stream.Group != "" {
+		return groupLockName(cfg.Stream.Group, cfg.Queue.Name)
+	}
+
+	if r.isSingleton(name, cfg) {
+		return singletonLockName(name)
+	}
+
+	return ""
+}
+
+// groupMemberOnConnection return the other consumer from the same group reading the same queue
+// with the same connection. The exclusive lock queue is owned by the connection, so both consumers
+// would hold the lock and consume the stream at the same time.
+func (r *Rabbids) groupMemberOnConnection(name string, cfg ConsumerConfig) (string, bool) {
+	if cfg.Stream.Group == "" {
+		return "", false
+	}
+
+	member := ""
+
+	for other, otherCfg := range r.config.Consumers {
+		if other == name || otherCfg.Stream.Group != cfg.Stream.Group ||
+			otherCfg.Queue.Name != cfg.Queue.Name || otherCfg.Connection != cfg.Connection {
+			continue
+		}
+
+		if member == "" || other < member {
+			member = other
+		}
+	}
+
+	return member, member != ""
+}
+
+// acquireLock declare the exclusive lock queue for the consumer.
+// Exclusive queues are deleted by the broker when the connection closes,
+// so the lock is released even when the process dies.
+func (r *Rabbids) acquireLock(name string, cfg ConsumerConfig, lock string) error {
+	ch, err := r.getConnectionChannel(cfg.Connection, r.consumerConnectionKey(name, cfg))
+	if err != nil {
+		return fmt.Errorf("failed to open the rabbitMQ channel for consumer %s: %w", name, err)
+	}
+
+	_, err = ch.QueueDeclare(lock, false, false, true, false, nil)
+
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.ResourceLocked {
+		return fmt.Errorf("failed to create the \"%s\" consumer: %w", name, ErrStandby)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to declare the lock \"%s\" for consumer \"%s\": %w", lock, name, err)
+	}
+
+	return ch.Close()
+}
+
+// releaseLock delete the lock queue, allowing a standby consumer to take over.
+func (c *Consumer) releaseLock() {
+	if c.lock == "" {
+		return
+	}
+
+	if _, err := c.amqpChannel().QueueDelete(c.lock, false, false, false); err != nil {
+		c.log.Error("failed to release the consumer lock", Fields{
+			"consumer": c.name,
+			"lock":     c.lock,
+			"error":    err,
+		})
+	}
+}
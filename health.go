@@ -0,0 +1,120 @@
+package rabbids
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ConnectionStatus is the state of one TCP connection with rabbitMQ.
+type ConnectionStatus struct {
+	// Name of the connection, consumers with dedicated connections use the connection and consumer names.
+	Name string `json:"name"`
+	Open bool   `json:"open"`
+	// State is ConnectionConnected or ConnectionReconnecting, when the connection is closed
+	// and waiting to be opened again by the next channel requested.
+	State string `json:"state"`
+	// OpenedAt is the time the connection was opened, or opened again after being lost.
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	// ReconnectAttempts is the number of dials made to open the connection again after being lost.
+	ReconnectAttempts int `json:"reconnect_attempts"`
+	// Channels is the number of channels open on the connection.
+	Channels int `json:"channels"`
+}
+
+// Connections returns the state of all the connections opened by this client, sorted by name.
+func (r *Rabbids) Connections() []ConnectionStatus {
+	r.connsMutex.Lock()
+	defer r.connsMutex.Unlock()
+
+	statuses := make([]ConnectionStatus, 0, len(r.conns))
+	for name, conn := range r.conns {
+		status := ConnectionStatus{Name: name, Open: !conn.IsClosed(), State: ConnectionConnected}
+		if !status.Open {
+			status.State = ConnectionReconnecting
+		}
+
+		if s, ok := r.connStats[name]; ok {
+			s.status(&status)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
+
+// Health is the report returned by the HealthHandler.
+type Health struct {
+	Healthy     bool               `json:"healthy"`
+	Connections []ConnectionStatus `json:"connections"`
+	Consumers   []ConsumerHealth   `json:"consumers"`
+}
+
+// ConsumerHealth is the state of one consumer inside the Health report.
+type ConsumerHealth struct {
+	Name        string     `json:"name"`
+	Queue       string     `json:"queue"`
+	Alive       bool       `json:"alive"`
+	InFlight    int64      `json:"in_flight"`
+	Saturated   bool       `json:"saturated"`
+	Restarts    int        `json:"restarts"`
+	LastRestart *time.Time `json:"last_restart,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// Health returns the state of the connections and consumers.
+// The client is healthy when all the connections are open and all the consumers are alive.
+func (r *Rabbids) Health() Health {
+	h := Health{Healthy: true, Connections: r.Connections()}
+
+	for _, c := range h.Connections {
+		h.Healthy = h.Healthy && c.Open
+	}
+
+	for _, s := range r.Consumers() {
+		c := ConsumerHealth{
+			Name:      s.Name,
+			Queue:     s.Queue,
+			Alive:     s.Alive,
+			InFlight:  s.InFlight,
+			Saturated: s.Saturated,
+			Restarts:  s.Restarts,
+		}
+
+		if s.Restarts > 0 {
+			createdAt := s.CreatedAt
+			c.LastRestart = &createdAt
+		}
+
+		if s.LastError != nil {
+			c.LastError = s.LastError.Error()
+		}
+
+		h.Healthy = h.Healthy && s.Alive
+		h.Consumers = append(h.Consumers, c)
+	}
+
+	return h
+}
+
+// HealthHandler returns an http.Handler reporting the Health as JSON,
+// with the status 503 when the client is unhealthy.
+func HealthHandler(r *Rabbids) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		h := r.Health()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if !h.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		_ = json.NewEncoder(w).Encode(h)
+	})
+}
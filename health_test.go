@@ -0,0 +1,95 @@
+package rabbids
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler(t *testing.T) {
+	t.Parallel()
+
+	alive := &Consumer{name: "alive", queue: "queue-alive"}
+	dead := &Consumer{name: "dead", queue: "queue-dead", restarts: 2}
+	dead.t.Kill(errors.New("channel closed"))
+
+	tests := []struct {
+		name      string
+		consumers map[string]*Consumer
+		status    int
+		healthy   bool
+	}{
+		{"all consumers alive", map[string]*Consumer{"alive": alive}, http.StatusOK, true},
+		{"one consumer dead", map[string]*Consumer{"alive": alive, "dead": dead}, http.StatusServiceUnavailable, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &Rabbids{conns: map[string]*amqp.Connection{}, consumers: tt.consumers}
+			rec := httptest.NewRecorder()
+			HealthHandler(r).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			require.Equal(t, tt.status, rec.Code)
+			require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var h Health
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &h))
+			require.Equal(t, tt.healthy, h.Healthy)
+			require.Len(t, h.Consumers, len(tt.consumers))
+
+			for _, c := range h.Consumers {
+				if c.Name == "dead" {
+					require.Equal(t, "channel closed", c.LastError)
+					require.NotNil(t, c.LastRestart)
+				}
+			}
+		})
+	}
+}
+
+func TestRabbids_Ready(t *testing.T) {
+	t.Parallel()
+
+	dead := &Consumer{name: "dead"}
+	dead.t.Kill(errors.New("channel closed"))
+
+	r := &Rabbids{conns: map[string]*amqp.Connection{}, consumers: map[string]*Consumer{"alive": {name: "alive"}}}
+	require.NoError(t, r.Ready())
+
+	r.consumers["dead"] = dead
+	require.EqualError(t, r.Ready(), "the consumer \"dead\" is not running: channel closed")
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	dead := &Consumer{name: "dead"}
+	dead.t.Kill(errors.New("channel closed"))
+
+	r := &Rabbids{config: &Config{}, log: NoOPLogger{}, conns: map[string]*amqp.Connection{}, consumers: map[string]*Consumer{}}
+	require.EqualError(t, r.Check(context.Background()), "the supervisor is not running")
+
+	stop, err := StartSupervisor(r, time.Millisecond)
+	require.NoError(t, err)
+
+	defer stop()
+
+	require.Eventually(t, func() bool { return r.Check(context.Background()) == nil }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, r.Check(ctx))
+
+	p := &Producer{name: "events"}
+	require.EqualError(t, p.Check(context.Background()), "the producer \"events\" connection is closed")
+}
@@ -0,0 +1,145 @@
+package rabbids
+
+// lifecycleHooks are the callbacks called when the Rabbids client changes state.
+type lifecycleHooks struct {
+	onConsumerRestart []func(consumer string, err error)
+	onConnectionLost  []func(connection string, err error)
+	onShutdown        []func()
+}
+
+// WithOnConsumerRestart add a function called when one consumer is recreated,
+// with the error that stopped the previous one.
+func WithOnConsumerRestart(fn func(consumer string, err error)) Option {
+	return func(r *Rabbids) error {
+		r.lifecycle.onConsumerRestart = append(r.lifecycle.onConsumerRestart, fn)
+
+		return nil
+	}
+}
+
+// WithOnConnectionLost add a function called when one connection with rabbitMQ is closed by an error.
+func WithOnConnectionLost(fn func(connection string, err error)) Option {
+	return func(r *Rabbids) error {
+		r.lifecycle.onConnectionLost = append(r.lifecycle.onConnectionLost, fn)
+
+		return nil
+	}
+}
+
+// WithOnShutdown add a function called when the client is closed, before closing the connections.
+func WithOnShutdown(fn func()) Option {
+	return func(r *Rabbids) error {
+		r.lifecycle.onShutdown = append(r.lifecycle.onShutdown, fn)
+
+		return nil
+	}
+}
+
+// call the hooks registered for the event. The hooks are called by the goroutine emitting the event.
+func (h lifecycleHooks) call(e Event) {
+	switch e.Type {
+	case EventConsumerRestarted:
+		for _, fn := range h.onConsumerRestart {
+			fn(e.Consumer, e.Err)
+		}
+	case EventConnectionLost:
+		for _, fn := range h.onConnectionLost {
+			fn(e.Connection, e.Err)
+		}
+	}
+}
+
+// The kinds of the declarations passed to Hooks.OnDeclare.
+const (
+	DeclareExchange = "exchange"
+	DeclareQueue    = "queue"
+)
+
+// Hooks are the functions called on the internal events of the producers and consumers,
+// to attach observability or business auditing without wrapping the handlers and producers.
+// All the functions are optional and are called by the goroutine where the event happens,
+// so they MUST be fast and safe for concurrent use.
+type Hooks struct {
+	// OnPublish is called after one message is published.
+	OnPublish func(producer string, p Publishing)
+	// OnPublishError is called when one message fails to be encoded or published.
+	OnPublishError func(producer string, p Publishing, err error)
+	// OnConsume is called when one message is received, before the handler.
+	OnConsume func(consumer string, m Message)
+	// OnAck is called after the message is handled and acknowledged.
+	OnAck func(consumer string, m Message)
+	// OnNack is called after the message is handled and rejected, requeued or not.
+	OnNack func(consumer string, m Message, requeue bool)
+	// OnReconnect is called when one connection of the client, or of a producer, is opened again.
+	OnReconnect func(connection string)
+	// OnDeclare is called after one exchange or queue is declared, the kind is DeclareExchange or DeclareQueue.
+	OnDeclare func(kind, name string)
+}
+
+// WithHooks add the hooks called by the client, its consumers, producers and declarations.
+func WithHooks(h Hooks) Option {
+	return func(r *Rabbids) error {
+		r.hooks = append(r.hooks, h)
+
+		return nil
+	}
+}
+
+// WithProducerHooks add the hooks called by the producer.
+func WithProducerHooks(h Hooks) ProducerOption {
+	return func(p *Producer) error {
+		p.hooks = append(p.hooks, h)
+
+		return nil
+	}
+}
+
+// hookSet call the functions set in all the Hooks added.
+type hookSet []Hooks
+
+func (hs hookSet) published(producer string, p Publishing, err error) {
+	for _, h := range hs {
+		if err == nil && h.OnPublish != nil {
+			h.OnPublish(producer, p)
+		}
+
+		if err != nil && h.OnPublishError != nil {
+			h.OnPublishError(producer, p, err)
+		}
+	}
+}
+
+func (hs hookSet) consumed(consumer string, m Message) {
+	for _, h := range hs {
+		if h.OnConsume != nil {
+			h.OnConsume(consumer, m)
+		}
+	}
+}
+
+func (hs hookSet) handled(consumer string, m Message, outcome Outcome) {
+	for _, h := range hs {
+		switch {
+		case outcome == OutcomeAck && h.OnAck != nil:
+			h.OnAck(consumer, m)
+		case (outcome == OutcomeNack || outcome == OutcomeRequeue) && h.OnNack != nil:
+			h.OnNack(consumer, m, outcome == OutcomeRequeue)
+		}
+	}
+}
+
+func (hs hookSet) reconnected(connection string) {
+	for _, h := range hs {
+		if h.OnReconnect != nil {
+			h.OnReconnect(connection)
+		}
+	}
+}
+
+func (hs hookSet) declared(kind, name string) {
+	for _, h := range hs {
+		if h.OnDeclare != nil {
+			h.OnDeclare(kind, name)
+		}
+	}
+}
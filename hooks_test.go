@@ -0,0 +1,79 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks_consumer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		handler MessageHandlerFunc
+		want    []string
+	}{
+		{"ack", func(m Message) { _ = m.Ack(false) }, []string{"consume", "ack"}},
+		{"nack", func(m Message) { _ = m.Nack(false, false) }, []string{"consume", "nack"}},
+		{"requeue", func(m Message) { _ = m.Reject(true) }, []string{"consume", "requeue"}},
+		{"unacked", func(m Message) {}, []string{"consume"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+
+			c := &Consumer{name: "hooks", handler: tt.handler, log: NoOPLogger{}, metrics: NoOPMetrics{}}
+			c.hooks = hookSet{{
+				OnConsume: func(consumer string, m Message) {
+					require.Equal(t, "hooks", consumer)
+					got = append(got, "consume")
+				},
+				OnAck: func(_ string, m Message) { got = append(got, "ack") },
+				OnNack: func(_ string, m Message, requeue bool) {
+					if requeue {
+						got = append(got, "requeue")
+						return
+					}
+					got = append(got, "nack")
+				},
+			}}
+
+			c.handle(amqp.Delivery{MessageId: "foo", Acknowledger: &fakeAcknowledger{}}, time.Now())
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHooks_published(t *testing.T) {
+	t.Parallel()
+
+	var published, failed []string
+
+	hooks := Hooks{
+		OnPublish:      func(producer string, p Publishing) { published = append(published, p.MessageId) },
+		OnPublishError: func(producer string, p Publishing, err error) { failed = append(failed, p.MessageId) },
+	}
+	p := &Producer{name: "events", serializer: AdaptSerializer(failingSerializer{})}
+	require.NoError(t, WithProducerHooks(hooks)(p))
+
+	pub := NewPublishing("events", "key", "data")
+	require.Error(t, p.Send(pub))
+	require.Empty(t, published)
+	require.Equal(t, []string{pub.MessageId}, failed)
+
+	hookSet{hooks, {}}.published("events", pub, nil)
+	require.Equal(t, []string{pub.MessageId}, published)
+}
+
+type failingSerializer struct{}
+
+func (failingSerializer) Marshal(interface{}) ([]byte, error) { return nil, errors.New("invalid data") }
+
+func (failingSerializer) Name() string { return "application/json" }
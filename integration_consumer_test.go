@@ -1,6 +1,7 @@
 package rabbids_test
 
 import (
+	"context"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -32,6 +33,18 @@ func TestIntegrationConsumerSuite(t *testing.T) {
 			scenario: "validate that all the consumers will restart without problems",
 			method:   testConsumerReconnect,
 		},
+		{
+			scenario: "validate the replay of the messages from one dead letter queue",
+			method:   testReplayDeadLetter,
+		},
+		{
+			scenario: "validate the prefetch change of one consumer",
+			method:   testConsumerSetPrefetch,
+		},
+		{
+			scenario: "validate the restart of one consumer keeps the handler open",
+			method:   testConsumerRestart,
+		},
 	}
 	// -> Setup
 	dockerPool, err := dockertest.NewPool("")
@@ -164,6 +177,173 @@ func testConsumerReconnect(t *testing.T, resource *dockertest.Resource) {
 	require.Len(t, received, 9, "consumer should be processed 9 messages")
 }
 
+func testReplayDeadLetter(t *testing.T, resource *dockertest.Resource) {
+	t.Parallel()
+
+	// use queues without bindings to not receive the messages from the other scenarios
+	config := getConfigHelper(t, "valid_queue_and_exchange_config.yml")
+	config.Connections["default"] = setDSN(resource, config.Connections["default"])
+
+	dead := config.DeadLetters["fallback"]
+	dead.Queue.Name = "replay_fallback"
+	dead.Queue.Bindings = nil
+	config.DeadLetters["fallback"] = dead
+
+	consumer := config.Consumers["messaging_consumer"]
+	consumer.Queue.Name = "replay_send"
+	consumer.Queue.Bindings = nil
+	config.Consumers = map[string]rabbids.ConsumerConfig{"replay_consumer": consumer}
+	config.RegisterHandler("replay_consumer", &mockHandler{ack: true, tb: t})
+
+	rab, err := rabbids.New(config, logFNHelper(t))
+	require.NoError(t, err, "failed to initialize the rabbids client")
+
+	defer rab.Close()
+
+	// the consumer is not started, it only declares the queues
+	_, err = rab.CreateConsumer("replay_consumer")
+	require.NoError(t, err)
+
+	ch := getChannelHelper(t, resource)
+
+	for _, tenant := range []string{"acme", "globex", "acme", "globex"} {
+		err = ch.Publish("", "replay_fallback", false, false, amqp.Publishing{
+			Headers: amqp.Table{
+				rabbids.OriginalExchangeHeader:   "",
+				rabbids.OriginalRoutingKeyHeader: "replay_send",
+				"tenant":                         tenant,
+			},
+			Body: []byte(tenant),
+		})
+		require.NoError(t, err, "error publishing to rabbitMQ")
+	}
+
+	ctx := context.Background()
+
+	replayed, err := rab.ReplayDeadLetter(ctx, "replay_fallback", rabbids.ReplayOptions{Limit: 1, RateLimit: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, replayed, "the limit should stop the replay")
+
+	replayed, err = rab.ReplayDeadLetter(ctx, "replay_fallback", rabbids.ReplayOptions{
+		RateLimit: 10,
+		Filter: func(m rabbids.Message) bool {
+			return m.Headers["tenant"] == "acme"
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, replayed, "only the acme messages should be replayed")
+
+	client := getRabbitClient(t, resource)
+	require.Equal(t, 2, getQueueLength(t, client, "replay_send", 5*time.Second))
+	require.Equal(t, 2, getQueueLength(t, client, "replay_fallback", 5*time.Second), "the filtered messages go back to the queue")
+
+	for _, queue := range []string{"replay_send", "replay_fallback"} {
+		_, err := ch.QueueDelete(queue, false, false, false)
+		require.NoError(t, err)
+	}
+}
+
+func testConsumerSetPrefetch(t *testing.T, resource *dockertest.Resource) {
+	t.Parallel()
+
+	config := getConfigHelper(t, "valid_queue_and_exchange_config.yml")
+	config.Connections["default"] = setDSN(resource, config.Connections["default"])
+
+	consumerCfg := config.Consumers["messaging_consumer"]
+	consumerCfg.Queue.Name = "prefetch_send"
+	consumerCfg.Queue.Bindings = nil
+	consumerCfg.PrefetchCount = 2
+	config.Consumers = map[string]rabbids.ConsumerConfig{"prefetch_consumer": consumerCfg}
+	config.RegisterHandler("prefetch_consumer", &mockHandler{ack: true, tb: t})
+
+	rab, err := rabbids.New(config, logFNHelper(t))
+	require.NoError(t, err, "failed to initialize the rabbids client")
+
+	defer rab.Close()
+
+	consumer, err := rab.CreateConsumer("prefetch_consumer")
+	require.NoError(t, err)
+
+	consumer.Run()
+	defer consumer.Kill()
+
+	require.Equal(t, 2, consumer.Status().Prefetch)
+	require.NoError(t, consumer.SetPrefetch(10))
+	require.Equal(t, 10, consumer.Status().Prefetch)
+
+	require.EqualError(t, consumer.SetPrefetch(-1), "invalid prefetch count -1, it must be greater than or equal to zero")
+	require.Equal(t, 10, consumer.Status().Prefetch)
+
+	ch := getChannelHelper(t, resource)
+	_, err = ch.QueueDelete("prefetch_send", false, false, false)
+	require.NoError(t, err)
+}
+
+func testConsumerRestart(t *testing.T, resource *dockertest.Resource) {
+	t.Parallel()
+
+	config := getConfigHelper(t, "valid_queue_and_exchange_config.yml")
+	config.Connections["default"] = setDSN(resource, config.Connections["default"])
+
+	consumerCfg := config.Consumers["messaging_consumer"]
+	consumerCfg.Queue.Name = "restart_send"
+	consumerCfg.Queue.Bindings = nil
+	config.Consumers = map[string]rabbids.ConsumerConfig{"restart_consumer": consumerCfg}
+
+	handler := &closableHandler{mockHandler: mockHandler{ack: true, tb: t}}
+	config.RegisterHandler("restart_consumer", handler)
+
+	rab, err := rabbids.New(config, logFNHelper(t))
+	require.NoError(t, err, "failed to initialize the rabbids client")
+
+	defer rab.Close()
+
+	stop, err := rabbids.StartSupervisor(rab, 10*time.Millisecond)
+	require.NoError(t, err, "Failed to create the Supervisor")
+
+	require.NoError(t, rab.RestartConsumer("restart_consumer"))
+	require.NoError(t, rab.KillConsumer("restart_consumer"))
+	require.NoError(t, rab.RestartConsumer("restart_consumer"))
+	require.False(t, handler.isClosed(), "the handler is used by the recreated consumer")
+
+	ch := getChannelHelper(t, resource)
+	err = ch.Publish("", "restart_send", false, false, amqp.Publishing{Body: []byte(`{"fooo": "bazzz"}`)})
+	require.NoError(t, err, "error publishing to rabbitMQ")
+
+	<-time.After(400 * time.Millisecond)
+	require.EqualValues(t, 1, handler.messagesProcessed())
+
+	stop()
+	require.True(t, handler.isClosed(), "the handler should be closed when the supervisor stops")
+
+	_, err = ch.QueueDelete("restart_send", false, false, false)
+	require.NoError(t, err)
+}
+
+// closableHandler fail the test when a message is handled after the Close.
+type closableHandler struct {
+	mockHandler
+	closed int32
+}
+
+func (h *closableHandler) Handle(msg rabbids.Message) {
+	if h.isClosed() {
+		h.tb.Errorf("the message %d was handled after the handler was closed", msg.DeliveryTag)
+
+		return
+	}
+
+	h.mockHandler.Handle(msg)
+}
+
+func (h *closableHandler) Close() {
+	atomic.StoreInt32(&h.closed, 1)
+}
+
+func (h *closableHandler) isClosed() bool {
+	return atomic.LoadInt32(&h.closed) == 1
+}
+
 type mockHandler struct {
 	count int64
 	ack   bool
@@ -27,6 +27,10 @@ func TestBasicIntegrationProducer(t *testing.T) {
 			scenario: "test send delay messages",
 			method:   testPublishWithDelay,
 		},
+		{
+			scenario: "test remove the delay topology",
+			method:   testRemoveDelayTopology,
+		},
 	}
 	// -> Setup
 	dockerPool, err := dockertest.NewPool("")
@@ -121,3 +125,38 @@ func testPublishWithDelay(t *testing.T, resource *dockertest.Resource) {
 	count := getQueueLength(t, adminClient, "testPublishWithDelay", 10*time.Second)
 	require.Equal(t, 1, count, "expecting the message inside the queue")
 }
+
+func testRemoveDelayTopology(t *testing.T, resource *dockertest.Resource) {
+	t.Parallel()
+
+	adminClient := getRabbitClient(t, resource)
+	producer, err := rabbids.NewProducer(getDSN(resource), rabbids.WithDelayStrategy(
+		rabbids.NewLevelsDelayStrategy(rabbids.DelayOptions{Prefix: "test.remove", MaxDelay: time.Minute}),
+	))
+	require.NoError(t, err, "could not connect to: ", getDSN(resource))
+
+	ch := producer.GetAMQPChannel()
+
+	_, err = ch.QueueDeclare("testRemoveDelayTopology", true, false, false, false, amqp.Table{})
+	require.NoError(t, err)
+
+	err = producer.Send(rabbids.NewDelayedPublishing("testRemoveDelayTopology", 10*time.Second, "fooo"))
+	require.NoError(t, err, "error on rab.Send")
+
+	_, err = adminClient.GetExchange("/", "test.remove-delivery")
+	require.NoError(t, err, "expecting the delay topology declared")
+
+	err = rabbids.RemoveDelayTopology(ch, "test.remove")
+	require.NoError(t, err)
+
+	for _, name := range []string{"test.remove-delivery", "test.remove-level-0", "test.remove-level-5"} {
+		_, err = adminClient.GetExchange("/", name)
+		require.Error(t, err, "expecting the exchange %s removed", name)
+	}
+
+	_, err = adminClient.GetQueue("/", "test.remove-level-3")
+	require.Error(t, err, "expecting the delay queue removed")
+
+	err = producer.Close()
+	require.NoError(t, err, "error closing the connection")
+}
@@ -0,0 +1,148 @@
+package rabbids
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/streadway/amqp"
+)
+
+// ErrIteratorClosed is returned by Iterator.Next after the iterator is closed.
+var ErrIteratorClosed = errors.New("iterator closed")
+
+// Iterator is a pull based consumer, useful for batch jobs and tests that need
+// to receive a fixed number of messages and exit.
+// The channel is opened on the first call to Next and reopened on the next call
+// if the channel or the connection is closed.
+type Iterator struct {
+	rabbids    *Rabbids
+	name       string
+	mutex      sync.Mutex
+	channel    *amqp.Channel
+	deliveries <-chan amqp.Delivery
+	closed     <-chan *amqp.Error
+	connection string
+	done       bool
+}
+
+// Iterator create a pull based consumer using the consumer config with the same name.
+// The messages received MUST be acknowledged by the caller, unless the consumer uses auto_ack,
+// the messages not acknowledged are requeued by the broker when the iterator is closed.
+func (r *Rabbids) Iterator(consumerName string) *Iterator {
+	return &Iterator{rabbids: r, name: consumerName}
+}
+
+// Next blocks until one message is received, the context is done or the channel is closed.
+// It is safe to call Next from multiple goroutines.
+func (it *Iterator) Next(ctx context.Context) (Message, error) {
+	it.mutex.Lock()
+
+	if it.done {
+		it.mutex.Unlock()
+
+		return Message{}, ErrIteratorClosed
+	}
+
+	if it.channel == nil {
+		if err := it.open(); err != nil {
+			it.mutex.Unlock()
+
+			return Message{}, err
+		}
+	}
+
+	ch, deliveries, closed, connection := it.channel, it.deliveries, it.closed, it.connection
+	it.mutex.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	case err := <-closed:
+		it.reset(ch)
+
+		return Message{}, fmt.Errorf("the iterator channel was closed: %v", err)
+	case d, ok := <-deliveries:
+		if !ok {
+			it.reset(ch)
+
+			return Message{}, errors.New("the iterator channel was closed")
+		}
+
+		return Message{Delivery: d, producer: func() (*Producer, error) {
+			return it.rabbids.connectionProducer(connection)
+		}}, nil
+	}
+}
+
+// reset mark the channel to be opened again in the next call.
+func (it *Iterator) reset(ch *amqp.Channel) {
+	it.mutex.Lock()
+	defer it.mutex.Unlock()
+
+	if it.channel == ch {
+		it.channel = nil
+	}
+}
+
+// Close the iterator channel, the messages not acknowledged are requeued by the broker.
+func (it *Iterator) Close() error {
+	it.mutex.Lock()
+	defer it.mutex.Unlock()
+
+	it.done = true
+
+	if it.channel == nil {
+		return nil
+	}
+
+	err := it.channel.Close()
+	it.channel = nil
+
+	return err
+}
+
+func (it *Iterator) open() error {
+	cfg, ch, err := it.openChannel()
+	if err != nil {
+		return err
+	}
+
+	tag := fmt.Sprintf("rabbitmq-%s-%d", it.name, atomic.AddInt64(&it.rabbids.number, 1))
+
+	d, err := ch.Consume(cfg.Queue.Name, tag,
+		cfg.Options.AutoAck,
+		cfg.Options.Exclusive,
+		cfg.Options.NoLocal,
+		cfg.Options.NoWait,
+		cfg.Options.Args)
+	if err != nil {
+		_ = ch.Close()
+
+		return fmt.Errorf("failed to start consume: %w", err)
+	}
+
+	it.channel = ch
+	it.deliveries = d
+	it.closed = ch.NotifyClose(make(chan *amqp.Error, 1))
+	it.connection = cfg.Connection
+
+	return nil
+}
+
+// openChannel open the consumer channel with the config locked, like the consumers do when the channel is redeclared.
+func (it *Iterator) openChannel() (ConsumerConfig, *amqp.Channel, error) {
+	it.rabbids.configMutex.RLock()
+	defer it.rabbids.configMutex.RUnlock()
+
+	cfg, ok := it.rabbids.config.Consumers[it.name]
+	if !ok {
+		return cfg, nil, fmt.Errorf("consumer \"%s\" did not exist", it.name)
+	}
+
+	ch, err := it.rabbids.openConsumerChannel(it.name, cfg)
+
+	return cfg, ch, err
+}
@@ -0,0 +1,72 @@
+package rabbids
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterator_Next(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{config: &Config{}}
+	it := r.Iterator("events")
+
+	deliveries := make(chan amqp.Delivery, 1)
+	ch := &amqp.Channel{}
+	it.channel, it.deliveries, it.connection = ch, deliveries, "default"
+
+	deliveries <- amqp.Delivery{Body: []byte("data")}
+
+	m, err := it.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), m.Body)
+	require.NotNil(t, m.producer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = it.Next(ctx)
+	require.Equal(t, context.Canceled, err)
+
+	close(deliveries)
+
+	_, err = it.Next(context.Background())
+	require.EqualError(t, err, "the iterator channel was closed")
+	require.Nil(t, it.channel, "the channel should be opened again in the next call")
+
+	_, err = it.Next(context.Background())
+	require.EqualError(t, err, `consumer "events" did not exist`)
+
+	require.NoError(t, it.Close())
+
+	_, err = it.Next(context.Background())
+	require.Equal(t, ErrIteratorClosed, err)
+}
+
+func TestIterator_NextWhileReloading(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{config: &Config{}}
+	it := r.Iterator("events")
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 100; i++ {
+			r.configMutex.Lock()
+			r.config = &Config{Consumers: map[string]ConsumerConfig{}}
+			r.configMutex.Unlock()
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		_, err := it.Next(context.Background())
+		require.EqualError(t, err, `consumer "events" did not exist`)
+	}
+
+	<-done
+}
@@ -2,6 +2,110 @@ package rabbids
 
 type Fields map[string]interface{}
 
+// LoggerFN is the logger function without levels, all the messages are logged with it.
+// It implements the Logger interface and is kept for compatibility, use MinLevel to drop the noise.
 type LoggerFN func(message string, fields Fields)
 
 func NoOPLoggerFN(message string, fields Fields) {}
+
+func (fn LoggerFN) Debug(message string, fields Fields) { fn(message, fields) }
+
+func (fn LoggerFN) Info(message string, fields Fields) { fn(message, fields) }
+
+func (fn LoggerFN) Warn(message string, fields Fields) { fn(message, fields) }
+
+func (fn LoggerFN) Error(message string, fields Fields) { fn(message, fields) }
+
+// Logger is the leveled logger used by rabbids.
+// The rabbidslog package has adapters for zap, zerolog and log/slog.
+type Logger interface {
+	// Debug is used for the details of the normal operation, like the declarations and the supervisor checks.
+	Debug(message string, fields Fields)
+	// Info is used for the changes of state, like the consumers created and the connections opened.
+	Info(message string, fields Fields)
+	// Warn is used for the problems rabbids recovers by itself, like connections lost and expired messages.
+	Warn(message string, fields Fields)
+	// Error is used for the failures losing or delaying messages, like the handler errors and panics.
+	Error(message string, fields Fields)
+}
+
+// NoOPLogger is a Logger implementation that does nothing.
+type NoOPLogger struct{}
+
+func (NoOPLogger) Debug(message string, fields Fields) {}
+
+func (NoOPLogger) Info(message string, fields Fields) {}
+
+func (NoOPLogger) Warn(message string, fields Fields) {}
+
+func (NoOPLogger) Error(message string, fields Fields) {}
+
+// Level is the severity of the log messages.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+// MinLevel returns a Logger dropping the messages with a level lower than the min level.
+func MinLevel(l Logger, min Level) Logger {
+	return levelFilter{Logger: l, min: min}
+}
+
+type levelFilter struct {
+	Logger
+	min Level
+}
+
+func (f levelFilter) Debug(message string, fields Fields) {
+	if f.min <= LevelDebug {
+		f.Logger.Debug(message, fields)
+	}
+}
+
+func (f levelFilter) Info(message string, fields Fields) {
+	if f.min <= LevelInfo {
+		f.Logger.Info(message, fields)
+	}
+}
+
+func (f levelFilter) Warn(message string, fields Fields) {
+	if f.min <= LevelWarn {
+		f.Logger.Warn(message, fields)
+	}
+}
+
+// WithLeveledLogger set the logger of the Rabbids client, replacing the LoggerFN given to New.
+func WithLeveledLogger(l Logger) Option {
+	return func(r *Rabbids) error {
+		r.log = l
+
+		return nil
+	}
+}
+
+// WithProducerLogger set the leveled logger of the producer.
+func WithProducerLogger(l Logger) ProducerOption {
+	return func(p *Producer) error {
+		p.log = l
+
+		return nil
+	}
+}
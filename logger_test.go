@@ -0,0 +1,22 @@
+package rabbids
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinLevel(t *testing.T) {
+	var messages []string
+
+	fn := LoggerFN(func(message string, _ Fields) { messages = append(messages, message) })
+	l := MinLevel(fn, LevelWarn)
+
+	l.Debug("debug", nil)
+	l.Info("info", nil)
+	l.Warn("warn", nil)
+	l.Error("error", nil)
+
+	require.Equal(t, []string{"warn", "error"}, messages)
+	require.Equal(t, "warn", LevelWarn.String())
+}
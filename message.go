@@ -1,9 +1,14 @@
 package rabbids
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/leveeml/rabbids/serialization"
 	"github.com/streadway/amqp"
 )
 
@@ -27,6 +32,11 @@ type Publishing struct {
 	Delay time.Duration
 
 	options []PublishingOption
+	ctx     context.Context
+	// delayQueue is the destination queue of the delayed messages.
+	delayQueue string
+	// encoded is used when the Body and ContentType are already set and the Data is ignored.
+	encoded bool
 	amqp.Publishing
 }
 
@@ -60,11 +70,12 @@ func NewPublishing(exchange, key string, data interface{}, options ...Publishing
 }
 
 // SendWithDelay send a message to arrive the queue only after the time is passed.
-// The minimum delay is one second, if the delay is less than the minimum, the minimum will be used.
+// The minimum delay depends on the DelayStrategy, the levels strategy uses the resolution of the levels,
+// one second by default, if the delay is less than the minimum, the minimum will be used.
 // The max delay period is 268,435,455 seconds, or about 8.5 years.
 func NewDelayedPublishing(queue string, delay time.Duration, data interface{}, options ...PublishingOption) Publishing {
-	if delay < time.Second {
-		delay = time.Second
+	if delay < time.Millisecond {
+		delay = time.Millisecond
 	}
 
 	id, err := uuid.NewRandom()
@@ -72,13 +83,14 @@ func NewDelayedPublishing(queue string, delay time.Duration, data interface{}, o
 		id = uuid.Must(uuid.NewUUID())
 	}
 
-	key, ex := calculateRoutingKey(delay, queue)
+	key, ex := CalculateRoutingKey(delay, queue)
 
 	return Publishing{
-		Exchange: ex,
-		Key:      key,
-		Data:     data,
-		Delay:    delay,
+		Exchange:   ex,
+		Key:        key,
+		Data:       data,
+		Delay:      delay,
+		delayQueue: queue,
 		Publishing: amqp.Publishing{
 			Priority:  0,
 			MessageId: id.String(),
@@ -91,6 +103,157 @@ func NewDelayedPublishing(queue string, delay time.Duration, data interface{}, o
 // Message is an ampq.Delivery with some helper methods used by our systems.
 type Message struct {
 	amqp.Delivery
+	ctx      context.Context
+	producer func() (*Producer, error)
+	// onReply is called after the reply is sent, used by the middlewares to observe the replies.
+	onReply func(Publishing)
+}
+
+// Context returns the message context.
+// When the message have the DeadlineHeader, the context deadline is the same as the message deadline.
+func (m Message) Context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+
+	return m.ctx
+}
+
+// WithContext returns a copy of the message using the new context.
+func (m Message) WithContext(ctx context.Context) Message {
+	m.ctx = ctx
+
+	return m
+}
+
+// Decode parses the message body into the value pointed by v, using the deserializer registered
+// for the content type of the message with RegisterDeserializer.
+// Messages without content type are decoded as JSON. The content encodings are removed before decoding
+// in the reverse order they were applied: the encrypted bodies are decrypted using the keys registered
+// with RegisterKeyProvider and the gzip and deflate bodies are decompressed.
+func (m Message) Decode(v interface{}) error {
+	d, ok := deserializerFor(m.ContentType)
+	if !ok {
+		return fmt.Errorf("content type \"%s\" not supported", m.ContentType)
+	}
+
+	body, err := serialization.DecodeBody(keyProvider(), m.ContentEncoding, m.Body, m.Headers)
+	if err != nil {
+		return err
+	}
+
+	m.Body = body
+
+	if hd, ok := d.(HeadersDeserializer); ok {
+		return hd.UnmarshalWithHeaders(m.Body, m.Headers, v)
+	}
+
+	return d.Unmarshal(m.Body, v)
+}
+
+// Reply send a response to the queue defined in the ReplyTo property of the message,
+// copying the CorrelationId. The response is published in the default exchange using
+// a producer managed by the Rabbids client that created the consumer.
+func (m Message) Reply(data interface{}, options ...PublishingOption) error {
+	if m.ReplyTo == "" {
+		return errors.New("the message didn't have the ReplyTo property")
+	}
+
+	pub := NewPublishing("", m.ReplyTo, data, options...)
+	pub.CorrelationId = m.CorrelationId
+	pub.ctx = m.Context()
+
+	if err := m.sendReply(pub); err != nil {
+		return err
+	}
+
+	if m.onReply != nil {
+		m.onReply(pub)
+	}
+
+	return nil
+}
+
+func (m Message) sendReply(pub Publishing) error {
+	if m.producer == nil {
+		return errors.New("the message was not received by a Rabbids consumer")
+	}
+
+	p, err := m.producer()
+	if err != nil {
+		return fmt.Errorf("failed to get the producer for the reply: %w", err)
+	}
+
+	return p.Send(pub)
+}
+
+// Outcome is the final state of a message after being handled.
+type Outcome string
+
+const (
+	// OutcomeAck is used when the message was acknowledged.
+	OutcomeAck Outcome = "ack"
+	// OutcomeNack is used when the message was rejected without requeue.
+	OutcomeNack Outcome = "nack"
+	// OutcomeRequeue is used when the message was rejected with requeue.
+	OutcomeRequeue Outcome = "requeue"
+	// OutcomeUnacked is used when the handler returned without acknowledging the message.
+	OutcomeUnacked Outcome = "unacked"
+)
+
+// AuditHook is called after one message is handled with the outcome,
+// the time spent handling the message and the error, if any.
+type AuditHook func(m Message, outcome Outcome, duration time.Duration, err error)
+
+// outcomeRecorder wraps the delivery acknowledger to record the outcome of the message.
+type outcomeRecorder struct {
+	amqp.Acknowledger
+	mutex   sync.Mutex
+	outcome Outcome
+	err     error
+}
+
+func (a *outcomeRecorder) Ack(tag uint64, multiple bool) error {
+	err := a.Acknowledger.Ack(tag, multiple)
+	a.record(OutcomeAck, err)
+
+	return err
+}
+
+func (a *outcomeRecorder) Nack(tag uint64, multiple bool, requeue bool) error {
+	err := a.Acknowledger.Nack(tag, multiple, requeue)
+	a.record(rejectOutcome(requeue), err)
+
+	return err
+}
+
+func (a *outcomeRecorder) Reject(tag uint64, requeue bool) error {
+	err := a.Acknowledger.Reject(tag, requeue)
+	a.record(rejectOutcome(requeue), err)
+
+	return err
+}
+
+func (a *outcomeRecorder) record(o Outcome, err error) {
+	a.mutex.Lock()
+	a.outcome = o
+	a.err = err
+	a.mutex.Unlock()
+}
+
+func (a *outcomeRecorder) result() (Outcome, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.outcome, a.err
+}
+
+func rejectOutcome(requeue bool) Outcome {
+	if requeue {
+		return OutcomeRequeue
+	}
+
+	return OutcomeNack
 }
 
 // MessageHandler is the base interface used to consumer AMPQ messages.
@@ -101,6 +264,27 @@ type MessageHandler interface {
 	Close()
 }
 
+// ErrorHandler is a MessageHandler that returns the error from processing the message.
+// The consumer calls HandleErr instead of Handle and, when the handler returns an error
+// without acknowledging the message, the consumer on_error strategy is applied.
+type ErrorHandler interface {
+	MessageHandler
+	HandleErr(m Message) error
+}
+
+// ErrorHandlerFunc implements the ErrorHandler interface.
+type ErrorHandlerFunc func(m Message) error
+
+func (h ErrorHandlerFunc) Handle(m Message) {
+	_ = h(m)
+}
+
+func (h ErrorHandlerFunc) HandleErr(m Message) error {
+	return h(m)
+}
+
+func (h ErrorHandlerFunc) Close() {}
+
 // MessageHandlerFunc implements the MessageHandler interface.
 type MessageHandlerFunc func(m Message)
 
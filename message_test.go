@@ -0,0 +1,169 @@
+package rabbids
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/leveeml/rabbids/serialization"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessage_Reply(t *testing.T) {
+	t.Parallel()
+
+	t.Run("without the ReplyTo property", func(t *testing.T) {
+		t.Parallel()
+
+		m := Message{Delivery: amqp.Delivery{CorrelationId: "123"}}
+		require.EqualError(t, m.Reply("pong"), "the message didn't have the ReplyTo property")
+	})
+
+	t.Run("without a rabbids consumer", func(t *testing.T) {
+		t.Parallel()
+
+		m := Message{Delivery: amqp.Delivery{CorrelationId: "123", ReplyTo: "responses"}}
+		require.EqualError(t, m.Reply("pong"), "the message was not received by a Rabbids consumer")
+	})
+}
+
+func TestMessage_Decode(t *testing.T) {
+	t.Parallel()
+
+	var v struct {
+		Foo string `json:"foo"`
+	}
+
+	m := Message{Delivery: amqp.Delivery{Body: []byte(`{"foo":"bar"}`)}}
+	require.NoError(t, m.Decode(&v))
+	require.Equal(t, "bar", v.Foo)
+
+	m.ContentType = "application/xml"
+	require.EqualError(t, m.Decode(&v), `content type "application/xml" not supported`)
+}
+
+func TestMessage_DecodeEncrypted(t *testing.T) {
+	keys, err := serialization.NewStaticKeyProvider("k1", map[string][]byte{"k1": bytes.Repeat([]byte("k"), 32)})
+	require.NoError(t, err)
+
+	p := &Producer{serializer: serialization.Encrypted(serialization.Compressed(&serialization.JSON{}, serialization.Gzip), keys)}
+
+	pub, err := p.prepare(NewPublishing("ex", "key", map[string]string{"foo": "bar"}))
+	require.NoError(t, err)
+
+	m := Message{Delivery: amqp.Delivery{
+		ContentType:     pub.ContentType,
+		ContentEncoding: pub.ContentEncoding,
+		Headers:         pub.Headers,
+		Body:            pub.Body,
+	}}
+
+	var v map[string]string
+	require.EqualError(t, m.Decode(&v), "the body is encrypted and no key provider was registered")
+
+	RegisterKeyProvider(keys)
+	defer RegisterKeyProvider(nil)
+
+	require.NoError(t, m.Decode(&v))
+	require.Equal(t, map[string]string{"foo": "bar"}, v)
+}
+
+type customSerializer struct{}
+
+func (customSerializer) Marshal(v interface{}) ([]byte, error) { return []byte("body"), nil }
+
+func (customSerializer) Name() string { return "test/custom" }
+
+func TestProducerPrepareWithAdaptedSerializer(t *testing.T) {
+	t.Parallel()
+
+	p := &Producer{}
+	require.NoError(t, WithSerializer(customSerializer{})(p))
+
+	m, err := p.prepare(NewPublishing("ex", "key", "value"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("body"), m.Body)
+	require.Equal(t, "test/custom", m.ContentType)
+	require.Empty(t, m.ContentEncoding)
+	require.Equal(t, amqp.Table{}, m.Headers)
+	require.Equal(t, serializerAdapter{customSerializer{}}, p.serializer)
+}
+
+func TestProducerPrepareWithContentType(t *testing.T) {
+	t.Parallel()
+
+	p := &Producer{serializer: &serialization.Passthrough{}}
+
+	m, err := p.prepare(NewPublishing("ex", "key", "<order/>", WithContentType("application/xml")))
+	require.NoError(t, err)
+	require.Equal(t, []byte("<order/>"), m.Body)
+	require.Equal(t, "application/xml", m.ContentType)
+
+	m, err = p.prepare(NewPublishing("ex", "key", []byte("raw")))
+	require.NoError(t, err)
+	require.Equal(t, []byte("raw"), m.Body)
+	require.Equal(t, "application/octet-stream", m.ContentType)
+
+	_, err = p.prepare(NewPublishing("ex", "key", 10))
+	require.EqualError(t, err, "failed to marshal: the passthrough serializer expects []byte or string, got int")
+}
+
+func TestProducerPrepareWithBytesOnlyPassthrough(t *testing.T) {
+	t.Parallel()
+
+	p := &Producer{serializer: &serialization.Passthrough{BytesOnly: true}}
+
+	m, err := p.prepare(NewPublishing("ex", "key", []byte{0x89, 'P', 'N', 'G'}, WithContentType("image/png")))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x89, 'P', 'N', 'G'}, m.Body)
+	require.Equal(t, "image/png", m.ContentType)
+
+	m, err = p.prepare(NewPublishing("ex", "key", []byte("blob")))
+	require.NoError(t, err)
+	require.Equal(t, "application/octet-stream", m.ContentType)
+
+	_, err = p.prepare(NewPublishing("ex", "key", "text"))
+	require.EqualError(t, err, "failed to marshal: the passthrough serializer expects []byte, got string")
+}
+
+func TestProducerPrepareWithSchemaValidated(t *testing.T) {
+	t.Parallel()
+
+	s := serialization.NewSchemaValidated(&serialization.JSON{})
+	require.NoError(t, s.Add("orders", "", `{"required": ["id"]}`))
+
+	p := &Producer{serializer: s}
+
+	_, err := p.prepare(NewPublishing("orders", "order.created", map[string]string{}))
+	require.True(t, errors.Is(err, serialization.ErrSchemaValidation))
+
+	m, err := p.prepare(NewPublishing("orders", "order.created", map[string]string{"id": "1"}))
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"1"}`, string(m.Body))
+}
+
+func TestProducerPrepareWithSerializerV2(t *testing.T) {
+	t.Parallel()
+
+	p := &Producer{}
+	require.NoError(t, WithSerializerV2(SerializerV2Func(func(exchange, key string, data interface{}) (Serialized, error) {
+		return Serialized{
+			Body:            []byte(exchange + "/" + key),
+			ContentType:     "text/plain",
+			ContentEncoding: "identity",
+			Headers:         map[string]interface{}{"x-format": "v2"},
+		}, nil
+	}))(p))
+
+	m, err := p.prepare(NewPublishing("ex", "key", nil))
+	require.NoError(t, err)
+	require.Equal(t, []byte("ex/key"), m.Body)
+	require.Equal(t, "text/plain", m.ContentType)
+	require.Equal(t, "identity", m.ContentEncoding)
+	require.Equal(t, amqp.Table{"x-format": "v2"}, m.Headers)
+
+	// the serializers of the serialization package implement SerializerV2 and aren't adapted
+	require.NoError(t, WithSerializer(&serialization.JSON{})(p))
+	require.Equal(t, &serialization.JSON{}, p.serializer)
+}
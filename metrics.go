@@ -0,0 +1,203 @@
+package rabbids
+
+import "time"
+
+// Metrics is the interface used by rabbids to record the internal metrics.
+// The methods are called by the consumers and producers goroutines, so they MUST be safe for concurrent use.
+// Embed NoOPMetrics inside your implementation to implement only the metrics you need.
+type Metrics interface {
+	// MessageExpired is called when one consumer drops an expired message.
+	MessageExpired(consumer string)
+	// ConsumerSaturated is called when one consumer becomes saturated, with all the workers
+	// busy for longer than the backpressure threshold, and when it recovers.
+	ConsumerSaturated(consumer string, saturated bool)
+	// ConsumerAlive is called by the supervisor on every check with the state of each consumer.
+	ConsumerAlive(consumer string, alive bool)
+	// ConsumerRestarted is called by the supervisor when one dead consumer is recreated,
+	// with the time since the consumer was found dead.
+	ConsumerRestarted(consumer string, timeToRecover time.Duration)
+	// ConsumerStuck is called by the supervisor watchdog when one consumer gets stuck,
+	// with messages in flight and no progress for longer than the threshold, and when it recovers.
+	ConsumerStuck(consumer string, stuck bool)
+	// ProducerAlive is called by the supervisor on every check with the state of each producer from the config.
+	ProducerAlive(producer string, alive bool)
+	// DelayBacklog is called by the supervisor with the number of messages inside each delay level queue,
+	// see WithDelayBacklog.
+	DelayBacklog(queue string, messages int)
+	// DeadLetterDepth is called by the supervisor with the number of messages inside each dead letter queue,
+	// see WithDeadLetterMonitor.
+	DeadLetterDepth(queue string, messages int)
+	// MessagePublished is called by the producers after every message published, with the error, if any.
+	MessagePublished(producer, exchange string, err error)
+	// MessageHandled is called by the consumers after every message handled,
+	// with the outcome and the time spent inside the handler.
+	MessageHandled(consumer string, outcome Outcome, duration time.Duration)
+	// Reconnected is called when one connection of the client, or of a producer, is opened again.
+	Reconnected(connection string)
+	// ConsumerWorkers is called by the supervisor on every check with the messages in flight
+	// and the number of workers of each consumer.
+	ConsumerWorkers(consumer string, inFlight, workers int)
+	// ProducerBuffer is called by the supervisor on every check with the messages waiting
+	// inside the Emit channel of each producer from the config and the channel capacity.
+	ProducerBuffer(producer string, pending, capacity int)
+	// ReconnectAttempt is called after every dial made to open one connection again after being lost,
+	// with the error, if any.
+	ReconnectAttempt(connection string, err error)
+	// ConnectionState is called by the supervisor on every check with the state of each connection
+	// of the client and of the producers from the config, the time since it was opened, or opened again,
+	// and the number of channels open.
+	ConnectionState(connection string, connected bool, sinceReconnect time.Duration, channels int)
+	// MessageLag is called by the consumers when a message with Timestamp is received,
+	// with the age of the message (the time since it was published).
+	MessageLag(consumer string, lag time.Duration)
+	// RoutingKeyHandled is called by the consumers with routing_key_metrics after every message handled,
+	// with the routing key of the message, or RoutingKeyOther, and the outcome.
+	RoutingKeyHandled(consumer, routingKey string, outcome Outcome)
+}
+
+// NoOPMetrics is a Metrics implementation that does nothing.
+type NoOPMetrics struct{}
+
+func (NoOPMetrics) MessageExpired(consumer string) {}
+
+func (NoOPMetrics) ConsumerSaturated(consumer string, saturated bool) {}
+
+func (NoOPMetrics) ConsumerAlive(consumer string, alive bool) {}
+
+func (NoOPMetrics) ConsumerRestarted(consumer string, timeToRecover time.Duration) {}
+
+func (NoOPMetrics) ConsumerStuck(consumer string, stuck bool) {}
+
+func (NoOPMetrics) ProducerAlive(producer string, alive bool) {}
+
+func (NoOPMetrics) DelayBacklog(queue string, messages int) {}
+
+func (NoOPMetrics) DeadLetterDepth(queue string, messages int) {}
+
+func (NoOPMetrics) MessagePublished(producer, exchange string, err error) {}
+
+func (NoOPMetrics) MessageHandled(consumer string, outcome Outcome, duration time.Duration) {}
+
+func (NoOPMetrics) Reconnected(connection string) {}
+
+func (NoOPMetrics) ConsumerWorkers(consumer string, inFlight, workers int) {}
+
+func (NoOPMetrics) ProducerBuffer(producer string, pending, capacity int) {}
+
+func (NoOPMetrics) ReconnectAttempt(connection string, err error) {}
+
+func (NoOPMetrics) ConnectionState(connection string, connected bool, sinceReconnect time.Duration, channels int) {
+}
+
+func (NoOPMetrics) MessageLag(consumer string, lag time.Duration) {}
+
+func (NoOPMetrics) RoutingKeyHandled(consumer, routingKey string, outcome Outcome) {}
+
+// MultiMetrics returns a Metrics calling all the implementations, like the prometheus collector and ExpvarMetrics.
+func MultiMetrics(metrics ...Metrics) Metrics {
+	return multiMetrics(metrics)
+}
+
+type multiMetrics []Metrics
+
+func (mm multiMetrics) MessageExpired(consumer string) {
+	for _, m := range mm {
+		m.MessageExpired(consumer)
+	}
+}
+
+func (mm multiMetrics) ConsumerSaturated(consumer string, saturated bool) {
+	for _, m := range mm {
+		m.ConsumerSaturated(consumer, saturated)
+	}
+}
+
+func (mm multiMetrics) ConsumerAlive(consumer string, alive bool) {
+	for _, m := range mm {
+		m.ConsumerAlive(consumer, alive)
+	}
+}
+
+func (mm multiMetrics) ConsumerRestarted(consumer string, timeToRecover time.Duration) {
+	for _, m := range mm {
+		m.ConsumerRestarted(consumer, timeToRecover)
+	}
+}
+
+func (mm multiMetrics) ConsumerStuck(consumer string, stuck bool) {
+	for _, m := range mm {
+		m.ConsumerStuck(consumer, stuck)
+	}
+}
+
+func (mm multiMetrics) ProducerAlive(producer string, alive bool) {
+	for _, m := range mm {
+		m.ProducerAlive(producer, alive)
+	}
+}
+
+func (mm multiMetrics) DelayBacklog(queue string, messages int) {
+	for _, m := range mm {
+		m.DelayBacklog(queue, messages)
+	}
+}
+
+func (mm multiMetrics) DeadLetterDepth(queue string, messages int) {
+	for _, m := range mm {
+		m.DeadLetterDepth(queue, messages)
+	}
+}
+
+func (mm multiMetrics) MessagePublished(producer, exchange string, err error) {
+	for _, m := range mm {
+		m.MessagePublished(producer, exchange, err)
+	}
+}
+
+func (mm multiMetrics) MessageHandled(consumer string, outcome Outcome, duration time.Duration) {
+	for _, m := range mm {
+		m.MessageHandled(consumer, outcome, duration)
+	}
+}
+
+func (mm multiMetrics) Reconnected(connection string) {
+	for _, m := range mm {
+		m.Reconnected(connection)
+	}
+}
+
+func (mm multiMetrics) ConsumerWorkers(consumer string, inFlight, workers int) {
+	for _, m := range mm {
+		m.ConsumerWorkers(consumer, inFlight, workers)
+	}
+}
+
+func (mm multiMetrics) ProducerBuffer(producer string, pending, capacity int) {
+	for _, m := range mm {
+		m.ProducerBuffer(producer, pending, capacity)
+	}
+}
+
+func (mm multiMetrics) ReconnectAttempt(connection string, err error) {
+	for _, m := range mm {
+		m.ReconnectAttempt(connection, err)
+	}
+}
+
+func (mm multiMetrics) ConnectionState(connection string, connected bool, sinceReconnect time.Duration, channels int) {
+	for _, m := range mm {
+		m.ConnectionState(connection, connected, sinceReconnect, channels)
+	}
+}
+
+func (mm multiMetrics) MessageLag(consumer string, lag time.Duration) {
+	for _, m := range mm {
+		m.MessageLag(consumer, lag)
+	}
+}
+
+func (mm multiMetrics) RoutingKeyHandled(consumer, routingKey string, outcome Outcome) {
+	for _, m := range mm {
+		m.RoutingKeyHandled(consumer, routingKey, outcome)
+	}
+}
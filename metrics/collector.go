@@ -0,0 +1,256 @@
+// Package metrics exposes the internal metrics of rabbids to prometheus.
+//
+// The Collector implements both rabbids.Metrics and prometheus.Collector,
+// so the same instance is given to the rabbids client and registered on any prometheus.Registerer:
+//
+//	collector := metrics.NewCollector("myapp")
+//	prometheus.MustRegister(collector)
+//	rab, err := rabbids.New(config, logger, rabbids.WithMetrics(collector))
+package metrics
+
+import (
+	"time"
+
+	"github.com/leveeml/rabbids"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	statusSuccess = "success"
+	statusError   = "error"
+)
+
+// Collector records the rabbids metrics for the producers, consumers and the supervisor.
+type Collector struct {
+	published       *prometheus.CounterVec
+	handled         *prometheus.CounterVec
+	handlerDuration *prometheus.HistogramVec
+	expired         *prometheus.CounterVec
+	reconnects      *prometheus.CounterVec
+	attempts        *prometheus.CounterVec
+	restarts        *prometheus.CounterVec
+	recovery        *prometheus.HistogramVec
+	consumerUp      *prometheus.GaugeVec
+	saturated       *prometheus.GaugeVec
+	stuck           *prometheus.GaugeVec
+	inFlight        *prometheus.GaugeVec
+	workers         *prometheus.GaugeVec
+	producerUp      *prometheus.GaugeVec
+	bufferPending   *prometheus.GaugeVec
+	bufferCapacity  *prometheus.GaugeVec
+	delayBacklog    *prometheus.GaugeVec
+	deadLetters     *prometheus.GaugeVec
+	connectionUp    *prometheus.GaugeVec
+	sinceReconnect  *prometheus.GaugeVec
+	channels        *prometheus.GaugeVec
+	lag             *prometheus.HistogramVec
+	byRoutingKey    *prometheus.CounterVec
+}
+
+// NewCollector create a Collector with all the metrics prefixed by the namespace,
+// when the namespace is empty "rabbids" is used.
+func NewCollector(namespace string) *Collector {
+	if namespace == "" {
+		namespace = "rabbids"
+	}
+
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, labels)
+	}
+	gauge := func(name, help string, labels ...string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help}, labels)
+	}
+	histogram := func(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+			Buckets:   buckets,
+		}, labels)
+	}
+
+	return &Collector{
+		published: counter("messages_published_total",
+			"Messages published by the producers, by status.", "producer", "exchange", "status"),
+		handled: counter("messages_handled_total",
+			"Messages handled by the consumers, by outcome.", "consumer", "outcome"),
+		handlerDuration: histogram("handler_duration_seconds",
+			"Time spent inside the consumer handlers.", prometheus.DefBuckets, "consumer"),
+		expired: counter("messages_expired_total",
+			"Expired messages dropped by the consumers.", "consumer"),
+		reconnects: counter("reconnects_total",
+			"Connections opened again after being lost.", "connection"),
+		attempts: counter("reconnect_attempts_total",
+			"Dials made to open the connections again after being lost, by status.", "connection", "status"),
+		restarts: counter("consumer_restarts_total",
+			"Dead consumers recreated by the supervisor.", "consumer"),
+		recovery: histogram("consumer_recovery_seconds",
+			"Time since the consumer was found dead until it was recreated.",
+			prometheus.ExponentialBuckets(0.1, 2, 10), "consumer"),
+		consumerUp: gauge("consumer_up",
+			"1 when the consumer is alive.", "consumer"),
+		saturated: gauge("consumer_saturated",
+			"1 when all the workers of the consumer are busy for longer than the backpressure threshold.", "consumer"),
+		stuck: gauge("consumer_stuck",
+			"1 when the consumer has messages in flight and no progress for longer than the watchdog threshold.",
+			"consumer"),
+		inFlight: gauge("consumer_messages_in_flight",
+			"Messages being handled by the consumer workers.", "consumer"),
+		workers: gauge("consumer_workers",
+			"Number of workers of the consumer.", "consumer"),
+		producerUp: gauge("producer_up",
+			"1 when the producer connection is open.", "producer"),
+		bufferPending: gauge("producer_buffer_pending",
+			"Messages waiting inside the Emit channel of the producer.", "producer"),
+		bufferCapacity: gauge("producer_buffer_capacity",
+			"Capacity of the Emit channel of the producer.", "producer"),
+		delayBacklog: gauge("delay_backlog_messages",
+			"Messages inside each delay level queue.", "queue"),
+		deadLetters: gauge("dead_letter_messages",
+			"Messages inside each dead letter queue.", "queue"),
+		connectionUp: gauge("connection_up",
+			"1 when the connection is open, 0 while reconnecting.", "connection"),
+		sinceReconnect: gauge("connection_seconds_since_reconnect",
+			"Time since the connection was opened, or opened again after being lost.", "connection"),
+		channels: gauge("connection_channels",
+			"Channels open on the connection.", "connection"),
+		lag: histogram("consumer_lag_seconds",
+			"Age of the messages received by the consumers, using the message timestamp.",
+			prometheus.ExponentialBuckets(0.005, 4, 10), "consumer"),
+		byRoutingKey: counter("messages_handled_by_routing_key_total",
+			"Messages handled by the consumers with routing_key_metrics, by routing key and outcome.",
+			"consumer", "routing_key", "outcome"),
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.published, c.handled, c.handlerDuration, c.expired, c.reconnects, c.restarts, c.recovery,
+		c.consumerUp, c.saturated, c.stuck, c.inFlight, c.workers,
+		c.producerUp, c.bufferPending, c.bufferCapacity, c.delayBacklog, c.deadLetters,
+		c.attempts, c.connectionUp, c.sinceReconnect, c.channels, c.lag, c.byRoutingKey,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, col := range c.collectors() {
+		col.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, col := range c.collectors() {
+		col.Collect(ch)
+	}
+}
+
+// MessagePublished implements rabbids.Metrics.
+func (c *Collector) MessagePublished(producer, exchange string, err error) {
+	status := statusSuccess
+	if err != nil {
+		status = statusError
+	}
+
+	c.published.WithLabelValues(producer, exchange, status).Inc()
+}
+
+// MessageHandled implements rabbids.Metrics.
+func (c *Collector) MessageHandled(consumer string, outcome rabbids.Outcome, duration time.Duration) {
+	c.handled.WithLabelValues(consumer, string(outcome)).Inc()
+	c.handlerDuration.WithLabelValues(consumer).Observe(duration.Seconds())
+}
+
+// MessageExpired implements rabbids.Metrics.
+func (c *Collector) MessageExpired(consumer string) {
+	c.expired.WithLabelValues(consumer).Inc()
+}
+
+// Reconnected implements rabbids.Metrics.
+func (c *Collector) Reconnected(connection string) {
+	c.reconnects.WithLabelValues(connection).Inc()
+}
+
+// ConsumerRestarted implements rabbids.Metrics.
+func (c *Collector) ConsumerRestarted(consumer string, timeToRecover time.Duration) {
+	c.restarts.WithLabelValues(consumer).Inc()
+	c.recovery.WithLabelValues(consumer).Observe(timeToRecover.Seconds())
+}
+
+// ConsumerAlive implements rabbids.Metrics.
+func (c *Collector) ConsumerAlive(consumer string, alive bool) {
+	c.consumerUp.WithLabelValues(consumer).Set(boolToFloat(alive))
+}
+
+// ConsumerSaturated implements rabbids.Metrics.
+func (c *Collector) ConsumerSaturated(consumer string, saturated bool) {
+	c.saturated.WithLabelValues(consumer).Set(boolToFloat(saturated))
+}
+
+// ConsumerStuck implements rabbids.Metrics.
+func (c *Collector) ConsumerStuck(consumer string, stuck bool) {
+	c.stuck.WithLabelValues(consumer).Set(boolToFloat(stuck))
+}
+
+// ConsumerWorkers implements rabbids.Metrics.
+func (c *Collector) ConsumerWorkers(consumer string, inFlight, workers int) {
+	c.inFlight.WithLabelValues(consumer).Set(float64(inFlight))
+	c.workers.WithLabelValues(consumer).Set(float64(workers))
+}
+
+// ProducerAlive implements rabbids.Metrics.
+func (c *Collector) ProducerAlive(producer string, alive bool) {
+	c.producerUp.WithLabelValues(producer).Set(boolToFloat(alive))
+}
+
+// ProducerBuffer implements rabbids.Metrics.
+func (c *Collector) ProducerBuffer(producer string, pending, capacity int) {
+	c.bufferPending.WithLabelValues(producer).Set(float64(pending))
+	c.bufferCapacity.WithLabelValues(producer).Set(float64(capacity))
+}
+
+// DelayBacklog implements rabbids.Metrics.
+func (c *Collector) DelayBacklog(queue string, messages int) {
+	c.delayBacklog.WithLabelValues(queue).Set(float64(messages))
+}
+
+// DeadLetterDepth implements rabbids.Metrics.
+func (c *Collector) DeadLetterDepth(queue string, messages int) {
+	c.deadLetters.WithLabelValues(queue).Set(float64(messages))
+}
+
+// ReconnectAttempt implements rabbids.Metrics.
+func (c *Collector) ReconnectAttempt(connection string, err error) {
+	status := statusSuccess
+	if err != nil {
+		status = statusError
+	}
+
+	c.attempts.WithLabelValues(connection, status).Inc()
+}
+
+// ConnectionState implements rabbids.Metrics.
+func (c *Collector) ConnectionState(connection string, connected bool, sinceReconnect time.Duration, channels int) {
+	c.connectionUp.WithLabelValues(connection).Set(boolToFloat(connected))
+	c.sinceReconnect.WithLabelValues(connection).Set(sinceReconnect.Seconds())
+	c.channels.WithLabelValues(connection).Set(float64(channels))
+}
+
+// MessageLag implements rabbids.Metrics.
+func (c *Collector) MessageLag(consumer string, lag time.Duration) {
+	c.lag.WithLabelValues(consumer).Observe(lag.Seconds())
+}
+
+// RoutingKeyHandled implements rabbids.Metrics.
+func (c *Collector) RoutingKeyHandled(consumer, routingKey string, outcome rabbids.Outcome) {
+	c.byRoutingKey.WithLabelValues(consumer, routingKey, string(outcome)).Inc()
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
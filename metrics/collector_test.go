@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leveeml/rabbids"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var _ rabbids.Metrics = &Collector{}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector("")
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(c))
+
+	c.MessagePublished("producer", "events", nil)
+	c.MessagePublished("producer", "events", nil)
+	c.MessagePublished("producer", "events", errors.New("closed"))
+	c.MessageHandled("consumer", rabbids.OutcomeAck, 20*time.Millisecond)
+	c.MessageHandled("consumer", rabbids.OutcomeNack, time.Second)
+	c.Reconnected("default")
+	c.ConsumerAlive("consumer", true)
+	c.ConsumerSaturated("consumer", true)
+	c.ConsumerSaturated("consumer", false)
+	c.ConsumerWorkers("consumer", 3, 5)
+	c.ProducerBuffer("producer", 10, 250)
+	c.ReconnectAttempt("default", errors.New("connection refused"))
+	c.ReconnectAttempt("default", nil)
+	c.ConnectionState("default", true, time.Minute, 4)
+	c.MessageLag("consumer", 2*time.Second)
+	c.RoutingKeyHandled("consumer", "user.created", rabbids.OutcomeRequeue)
+
+	require.Equal(t, float64(2), testutil.ToFloat64(c.published.WithLabelValues("producer", "events", "success")))
+	require.Equal(t, float64(1), testutil.ToFloat64(c.published.WithLabelValues("producer", "events", "error")))
+	require.Equal(t, float64(1), testutil.ToFloat64(c.handled.WithLabelValues("consumer", "nack")))
+	require.Equal(t, float64(1), testutil.ToFloat64(c.reconnects.WithLabelValues("default")))
+	require.Equal(t, float64(1), testutil.ToFloat64(c.consumerUp.WithLabelValues("consumer")))
+	require.Equal(t, float64(0), testutil.ToFloat64(c.saturated.WithLabelValues("consumer")))
+	require.Equal(t, float64(1), testutil.ToFloat64(c.attempts.WithLabelValues("default", "error")))
+	require.Equal(t, float64(60), testutil.ToFloat64(c.sinceReconnect.WithLabelValues("default")))
+	require.Equal(t, float64(4), testutil.ToFloat64(c.channels.WithLabelValues("default")))
+	require.Equal(t, float64(1),
+		testutil.ToFloat64(c.byRoutingKey.WithLabelValues("consumer", "user.created", "requeue")))
+
+	expected := `
+# HELP rabbids_consumer_messages_in_flight Messages being handled by the consumer workers.
+# TYPE rabbids_consumer_messages_in_flight gauge
+rabbids_consumer_messages_in_flight{consumer="consumer"} 3
+# HELP rabbids_producer_buffer_pending Messages waiting inside the Emit channel of the producer.
+# TYPE rabbids_producer_buffer_pending gauge
+rabbids_producer_buffer_pending{producer="producer"} 10
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"rabbids_consumer_messages_in_flight", "rabbids_producer_buffer_pending")
+	require.NoError(t, err)
+
+	count, err := testutil.GatherAndCount(reg, "rabbids_handler_duration_seconds", "rabbids_consumer_lag_seconds")
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+}
+
+func TestCollectorNamespace(t *testing.T) {
+	c := NewCollector("myapp")
+	c.MessageExpired("consumer")
+
+	count := testutil.CollectAndCount(c, "myapp_messages_expired_total")
+	require.Equal(t, 1, count)
+}
@@ -0,0 +1,29 @@
+package rabbids
+
+// Middleware wraps a MessageHandler adding some behavior before or after the message is handled.
+// The handler returned SHOULD implement the ErrorHandler interface when the wrapped handler does,
+// handlerErr can be used to call the next handler in both cases.
+type Middleware func(next MessageHandler) MessageHandler
+
+// WithMiddleware wraps the consumer handler with the middlewares,
+// the first middleware is the first one called when a message arrives.
+func WithMiddleware(middlewares ...Middleware) ConsumerOption {
+	return func(c *Consumer) error {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			c.handler = middlewares[i](c.handler)
+		}
+
+		return nil
+	}
+}
+
+// handlerErr call the handler returning the error when the handler implements ErrorHandler.
+func handlerErr(h MessageHandler, m Message) error {
+	if eh, ok := h.(ErrorHandler); ok {
+		return eh.HandleErr(m)
+	}
+
+	h.Handle(m)
+
+	return nil
+}
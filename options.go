@@ -1,5 +1,7 @@
 package rabbids
 
+import "time"
+
 // PublishingOption represents an option you can pass to setup some data inside the Publishing.
 type PublishingOption func(*Publishing)
 
@@ -7,6 +9,24 @@ type PublishingOption func(*Publishing)
 // state on creation time.
 type ProducerOption func(*Producer) error
 
+// Option represents an option function to add some functionality or change the Rabbids client
+// state on creation time.
+type Option func(*Rabbids) error
+
+// ConsumerOption represents an option function to add some functionality or change the consumer
+// state on creation time. The options are registered with the handler using Config.RegisterHandler.
+type ConsumerOption func(*Consumer) error
+
+// WithMetrics set the Metrics implementation used to record the internal metrics
+// of the Rabbids client and all the consumers created by it.
+func WithMetrics(m Metrics) Option {
+	return func(r *Rabbids) error {
+		r.metrics = m
+
+		return nil
+	}
+}
+
 // WithPriority change the priority of the Publishing message.
 func WithPriority(v int) PublishingOption {
 	return func(p *Publishing) {
@@ -22,6 +42,14 @@ func WithPriority(v int) PublishingOption {
 	}
 }
 
+// WithContentType set the content type of the Publishing, replacing the name of the producer serializer.
+// Use it with the serialization.Passthrough serializer to publish bodies already encoded, like XML documents.
+func WithContentType(contentType string) PublishingOption {
+	return func(p *Publishing) {
+		p.ContentType = contentType
+	}
+}
+
 func WithCustomName(name string) ProducerOption {
 	return func(p *Producer) error {
 		p.name = name
@@ -30,6 +58,16 @@ func WithCustomName(name string) ProducerOption {
 	}
 }
 
+// WithConfirmTimeout set the max time SendWithConfirm waits for the broker confirmation of each attempt,
+// the default is DefaultConfirmTimeout.
+func WithConfirmTimeout(timeout time.Duration) ProducerOption {
+	return func(p *Producer) error {
+		p.confirmWait = timeout
+
+		return nil
+	}
+}
+
 // WithLogger will override the default logger (no Operation Log).
 func WithLogger(log LoggerFN) ProducerOption {
 	return func(p *Producer) error {
@@ -39,6 +77,24 @@ func WithLogger(log LoggerFN) ProducerOption {
 	}
 }
 
+// withMetrics set the Metrics implementation of the Rabbids client used by the producer.
+func withMetrics(m Metrics) ProducerOption {
+	return func(p *Producer) error {
+		p.metrics = m
+
+		return nil
+	}
+}
+
+// withHooks set the hooks of the Rabbids client used by the producer.
+func withHooks(h hookSet) ProducerOption {
+	return func(p *Producer) error {
+		p.hooks = append(p.hooks, h...)
+
+		return nil
+	}
+}
+
 // withDeclarations will add the AMQP declarations and be able to declare the exchanges used.
 func withDeclarations(d *declarations) ProducerOption {
 	return func(p *Producer) error {
@@ -49,8 +105,9 @@ func withDeclarations(d *declarations) ProducerOption {
 }
 
 // withConnection add the connection config to set up the Connection instead the default values.
-func withConnection(conf Connection) ProducerOption {
+func withConnection(name string, conf Connection) ProducerOption {
 	return func(p *Producer) error {
+		p.connection = name
 		p.conf = conf
 
 		return nil
@@ -58,9 +115,30 @@ func withConnection(conf Connection) ProducerOption {
 }
 
 func WithSerializer(s Serializer) ProducerOption {
+	return func(p *Producer) error {
+		p.serializer = AdaptSerializer(s)
+
+		return nil
+	}
+}
+
+// WithSerializerV2 set the serializer used by the producer, able to set the content encoding
+// and the headers of the messages.
+func WithSerializerV2(s SerializerV2) ProducerOption {
 	return func(p *Producer) error {
 		p.serializer = s
 
 		return nil
 	}
 }
+
+// WithAuditHook add a hook called after every message handled by the consumer
+// with the message outcome, the time spent inside the handler and the acknowledgement error, if any.
+// The hook is called by the worker goroutine, so it MUST be safe for concurrent use.
+func WithAuditHook(h AuditHook) ConsumerOption {
+	return func(c *Consumer) error {
+		c.auditHooks = append(c.auditHooks, h)
+
+		return nil
+	}
+}
@@ -0,0 +1,116 @@
+package rabbids
+
+import (
+	"errors"
+	"time"
+)
+
+// Headers added to the messages published by the outcome routing.
+const (
+	ConsumerHeader           = "x-rabbids-consumer"
+	OutcomeHeader            = "x-rabbids-outcome"
+	ErrorHeader              = "x-rabbids-error"
+	OriginalExchangeHeader   = "x-original-exchange"
+	OriginalRoutingKeyHeader = "x-original-routing-key"
+)
+
+// OutcomeRouting describe where the consumer publishes the result of the handled messages.
+// The published message is a copy of the original message with the headers describing the outcome.
+type OutcomeRouting struct {
+	// SuccessExchange receives a completion event for every message acked without errors.
+	// The completion event is not published when empty.
+	SuccessExchange string
+	// SuccessKey is the routing key of the completion event, the original routing key is used when empty.
+	SuccessKey string
+	// FailureExchange receives the messages that failed without being requeued,
+	// with the error inside the ErrorHeader. The failures are not published when empty.
+	FailureExchange string
+	// FailureKey is the routing key of the failures, the original routing key is used when empty.
+	FailureKey string
+}
+
+// route return the exchange and key for one outcome, the exchange is empty when nothing should be published.
+// Messages requeued are not published because they will be handled again.
+func (r OutcomeRouting) route(outcome Outcome, err error) (string, string) {
+	switch {
+	case outcome == OutcomeAck && err == nil:
+		return r.SuccessExchange, r.SuccessKey
+	case outcome == OutcomeNack || (err != nil && outcome != OutcomeRequeue):
+		return r.FailureExchange, r.FailureKey
+	default:
+		return "", ""
+	}
+}
+
+// WithOutcomeRouting publish the outcome of every message handled by the consumer using the routing,
+// the messages are published using the producer managed by the Rabbids client.
+func WithOutcomeRouting(routing OutcomeRouting) ConsumerOption {
+	return func(c *Consumer) error {
+		c.auditHooks = append(c.auditHooks, func(m Message, outcome Outcome, _ time.Duration, err error) {
+			c.routeOutcome(routing, m, outcome, err)
+		})
+
+		return nil
+	}
+}
+
+func (c *Consumer) routeOutcome(routing OutcomeRouting, m Message, outcome Outcome, err error) {
+	exchange, key := routing.route(outcome, err)
+	if exchange == "" {
+		return
+	}
+
+	if key == "" {
+		key = m.RoutingKey
+	}
+
+	pub := NewPublishing(exchange, key, nil)
+	pub.Publishing = publishingFromDelivery(m.Delivery)
+	pub.encoded = true
+	pub.Headers[ConsumerHeader] = c.name
+	pub.Headers[OutcomeHeader] = string(outcome)
+	pub.Headers[OriginalExchangeHeader] = m.Exchange
+	pub.Headers[OriginalRoutingKeyHeader] = m.RoutingKey
+
+	if err != nil {
+		pub.Headers[ErrorHeader] = err.Error()
+	}
+
+	if perr := c.publish(pub); perr != nil {
+		c.log.Error("failed to publish the message outcome", Fields{
+			"consumer":   c.name,
+			"message-id": m.MessageId,
+			"exchange":   exchange,
+			"error":      perr,
+		})
+	}
+}
+
+// publish send one message using the producer managed by the Rabbids client.
+func (c *Consumer) publish(pub Publishing) error {
+	if c.producer == nil {
+		return errors.New("the consumer was not created by a Rabbids client")
+	}
+
+	p, err := c.producer()
+	if err != nil {
+		return err
+	}
+
+	return p.Send(pub)
+}
+
+// publishConfirmed send one message and wait for the broker confirmation,
+// used when the original message is acked after the copy is published.
+func (c *Consumer) publishConfirmed(pub Publishing) error {
+	if c.producer == nil {
+		return errors.New("the consumer was not created by a Rabbids client")
+	}
+
+	p, err := c.producer()
+	if err != nil {
+		return err
+	}
+
+	return p.SendWithConfirm(pub)
+}
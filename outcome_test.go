@@ -0,0 +1,41 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutcomeRouting_route(t *testing.T) {
+	t.Parallel()
+
+	routing := OutcomeRouting{
+		SuccessExchange: "completed",
+		FailureExchange: "failures",
+		FailureKey:      "consumer-x",
+	}
+	tests := []struct {
+		name     string
+		outcome  Outcome
+		err      error
+		exchange string
+		key      string
+	}{
+		{"acked", OutcomeAck, nil, "completed", ""},
+		{"acked with error", OutcomeAck, errors.New("fail"), "failures", "consumer-x"},
+		{"rejected", OutcomeNack, nil, "failures", "consumer-x"},
+		{"requeued", OutcomeRequeue, errors.New("fail"), "", ""},
+		{"unacked", OutcomeUnacked, nil, "", ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			exchange, key := routing.route(tt.outcome, tt.err)
+			require.Equal(t, tt.exchange, exchange)
+			require.Equal(t, tt.key, key)
+		})
+	}
+}
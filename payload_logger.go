@@ -0,0 +1,101 @@
+package rabbids
+
+import (
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// RedactFunc returns the headers and the body written in the payload logs, removing the sensitive data.
+// It receives copies of the headers and body, so they can be changed in place.
+type RedactFunc func(headers amqp.Table, body []byte) (amqp.Table, []byte)
+
+// WithPayloadLogging log a sample of the messages received by the consumers, at most perMinute messages
+// per minute for each consumer, with the headers and body returned by redact, so the real traffic can be
+// seen in the logs without leaking PII. When redact is nil the messages are logged as received.
+func WithPayloadLogging(perMinute int, redact RedactFunc) Option {
+	return func(r *Rabbids) error {
+		p := newPayloadLogger(perMinute, redact, func() Logger { return r.log })
+
+		return WithHooks(p.Hooks())(r)
+	}
+}
+
+// payloadLogger log the messages consumed, limited by a fixed window of one minute per consumer.
+type payloadLogger struct {
+	mutex     sync.Mutex
+	perMinute int
+	redact    RedactFunc
+	windows   map[string]*payloadWindow
+	log       func() Logger
+	now       func() time.Time
+}
+
+type payloadWindow struct {
+	start  time.Time
+	logged int
+}
+
+func newPayloadLogger(perMinute int, redact RedactFunc, log func() Logger) *payloadLogger {
+	return &payloadLogger{
+		perMinute: perMinute,
+		redact:    redact,
+		windows:   map[string]*payloadWindow{},
+		log:       log,
+		now:       time.Now,
+	}
+}
+
+// Hooks returns the hooks logging the messages consumed.
+func (p *payloadLogger) Hooks() Hooks {
+	return Hooks{
+		OnConsume: func(consumer string, m Message) {
+			if !p.sample(consumer) {
+				return
+			}
+
+			headers := make(amqp.Table, len(m.Headers))
+			for k, v := range m.Headers {
+				headers[k] = v
+			}
+
+			body := append([]byte{}, m.Body...)
+			if p.redact != nil {
+				headers, body = p.redact(headers, body)
+			}
+
+			p.log().Info("message payload sample", Fields{
+				"consumer":     consumer,
+				"exchange":     m.Exchange,
+				"routing-key":  m.RoutingKey,
+				"message-id":   m.MessageId,
+				"content-type": m.ContentType,
+				"headers":      headers,
+				"body":         string(body),
+			})
+		},
+	}
+}
+
+// sample returns true while the consumer has not logged perMinute messages in the current minute.
+func (p *payloadLogger) sample(consumer string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := p.now()
+
+	w, ok := p.windows[consumer]
+	if !ok || now.Sub(w.start) >= time.Minute {
+		w = &payloadWindow{start: now}
+		p.windows[consumer] = w
+	}
+
+	if w.logged >= p.perMinute {
+		return false
+	}
+
+	w.logged++
+
+	return true
+}
@@ -0,0 +1,51 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadLogger(t *testing.T) {
+	t.Parallel()
+
+	var logged []Fields
+
+	log := LoggerFN(func(message string, fields Fields) {
+		logged = append(logged, fields)
+	})
+	redact := func(headers amqp.Table, body []byte) (amqp.Table, []byte) {
+		delete(headers, "email")
+
+		return headers, []byte("redacted")
+	}
+
+	p := newPayloadLogger(2, redact, func() Logger { return log })
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	hooks := p.Hooks()
+
+	m := Message{Delivery: amqp.Delivery{
+		RoutingKey: "user.created",
+		Headers:    amqp.Table{"email": "foo@example.com", "tenant": "bar"},
+		Body:       []byte(`{"email":"foo@example.com"}`),
+	}}
+
+	for i := 0; i < 3; i++ {
+		hooks.OnConsume("users", m)
+	}
+
+	hooks.OnConsume("orders", m)
+	require.Len(t, logged, 3, "only 2 messages per minute are logged for each consumer")
+	require.Equal(t, "redacted", logged[0]["body"])
+	require.Equal(t, amqp.Table{"tenant": "bar"}, logged[0]["headers"])
+	require.Equal(t, "orders", logged[2]["consumer"])
+	require.Equal(t, "foo@example.com", m.Headers["email"], "the message headers must not change")
+
+	now = now.Add(time.Minute)
+
+	hooks.OnConsume("users", m)
+	require.Len(t, logged, 4, "a new window starts after one minute")
+}
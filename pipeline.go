@@ -0,0 +1,88 @@
+package rabbids
+
+// PipelineTransform receives the decoded input and the original message and
+// return the data to be published to the next stage.
+type PipelineTransform func(in interface{}, m Message) (interface{}, error)
+
+// PipelineHandler is a MessageHandler used to build processing chains.
+// For every message it decodes the body, calls the transform function and publishes
+// the result to the output exchange. The message is acknowledged only after the broker
+// confirms the publishing of the result:
+//   - decode and transform errors reject the message without requeue (use a dead letter to keep them).
+//   - publishing errors reject the message with requeue.
+type PipelineHandler struct {
+	send      func(Publishing) error
+	log       Logger
+	exchange  string
+	key       string
+	input     func() interface{}
+	transform PipelineTransform
+	options   []PublishingOption
+}
+
+// NewPipelineHandler create a new PipelineHandler publishing the transformed messages using the producer.
+// The input function returns a pointer used to decode the message body, when nil the message is not decoded
+// and the transform receives a nil input.
+// The CorrelationId of the received message is copied to the published message.
+func NewPipelineHandler(
+	producer *Producer,
+	exchange, key string,
+	input func() interface{},
+	transform PipelineTransform,
+	options ...PublishingOption,
+) *PipelineHandler {
+	return &PipelineHandler{
+		send:      producer.SendWithConfirm,
+		log:       producer.log,
+		exchange:  exchange,
+		key:       key,
+		input:     input,
+		transform: transform,
+		options:   options,
+	}
+}
+
+// Handle process one message from the pipeline.
+func (h *PipelineHandler) Handle(m Message) {
+	var in interface{}
+
+	if h.input != nil {
+		in = h.input()
+		if err := m.Decode(in); err != nil {
+			h.reject(m, false, "failed to decode the message", err)
+
+			return
+		}
+	}
+
+	out, err := h.transform(in, m)
+	if err != nil {
+		h.reject(m, false, "failed to transform the message", err)
+
+		return
+	}
+
+	pub := NewPublishing(h.exchange, h.key, out, h.options...)
+	pub.CorrelationId = m.CorrelationId
+
+	if err := h.send(pub); err != nil {
+		h.reject(m, true, "failed to publish the transformed message", err)
+
+		return
+	}
+
+	if err := m.Ack(false); err != nil {
+		h.log.Error("failed to ack the message", Fields{"error": err, "message-id": m.MessageId})
+	}
+}
+
+// Close does nothing, the producer is owned by the caller.
+func (h *PipelineHandler) Close() {}
+
+func (h *PipelineHandler) reject(m Message, requeue bool, reason string, err error) {
+	h.log.Warn(reason, Fields{"error": err, "message-id": m.MessageId, "requeue": requeue})
+
+	if err := m.Nack(false, requeue); err != nil {
+		h.log.Error("failed to nack the message", Fields{"error": err, "message-id": m.MessageId})
+	}
+}
@@ -0,0 +1,125 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+type pipelineAcknowledger struct {
+	acks     int
+	requeues []bool
+}
+
+func (a *pipelineAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acks++
+
+	return nil
+}
+
+func (a *pipelineAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.requeues = append(a.requeues, requeue)
+
+	return nil
+}
+
+func (a *pipelineAcknowledger) Reject(tag uint64, requeue bool) error {
+	a.requeues = append(a.requeues, requeue)
+
+	return nil
+}
+
+func TestPipelineHandler(t *testing.T) {
+	t.Parallel()
+
+	type order struct {
+		ID int `json:"id"`
+	}
+
+	tests := []struct {
+		name      string
+		body      string
+		transform PipelineTransform
+		sendErr   error
+		acks      int
+		requeues  []bool
+		published int
+	}{
+		{
+			name: "publish the transformed message",
+			body: `{"id":42}`,
+			transform: func(in interface{}, m Message) (interface{}, error) {
+				return map[string]int{"order": in.(*order).ID}, nil
+			},
+			acks:      1,
+			published: 1,
+		},
+		{
+			name:     "reject invalid messages without requeue",
+			body:     `{"id":`,
+			requeues: []bool{false},
+		},
+		{
+			name: "reject the transform errors without requeue",
+			body: `{"id":42}`,
+			transform: func(in interface{}, m Message) (interface{}, error) {
+				return nil, errors.New("invalid order")
+			},
+			requeues: []bool{false},
+		},
+		{
+			name: "requeue when the publishing fails",
+			body: `{"id":42}`,
+			transform: func(in interface{}, m Message) (interface{}, error) {
+				return in, nil
+			},
+			sendErr:  errors.New("timeout waiting the confirmation"),
+			requeues: []bool{true},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var published []Publishing
+
+			h := &PipelineHandler{
+				send: func(pub Publishing) error {
+					published = append(published, pub)
+
+					return tt.sendErr
+				},
+				log:       NoOPLogger{},
+				exchange:  "orders",
+				key:       "order.enriched",
+				input:     func() interface{} { return &order{} },
+				transform: tt.transform,
+			}
+			ack := &pipelineAcknowledger{}
+
+			h.Handle(Message{Delivery: amqp.Delivery{
+				Acknowledger:  ack,
+				Body:          []byte(tt.body),
+				ContentType:   "application/json",
+				CorrelationId: "abc",
+			}})
+
+			require.Equal(t, tt.acks, ack.acks)
+			require.Equal(t, tt.requeues, ack.requeues)
+
+			if tt.published == 0 {
+				return
+			}
+
+			require.Len(t, published, tt.published)
+			require.Equal(t, "orders", published[0].Exchange)
+			require.Equal(t, "order.enriched", published[0].Key)
+			require.Equal(t, "abc", published[0].CorrelationId)
+			require.Equal(t, map[string]int{"order": 42}, published[0].Data)
+		})
+	}
+}
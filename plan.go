@@ -0,0 +1,369 @@
+package rabbids
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// DeclarationPlan describe every exchange, queue and binding rabbids declares for one config.
+// It can be printed (String) or encoded as JSON to be reviewed before a rollout.
+type DeclarationPlan struct {
+	Exchanges []PlannedExchange `json:"exchanges"`
+	Queues    []PlannedQueue    `json:"queues"`
+	Bindings  []PlannedBinding  `json:"bindings"`
+}
+
+// PlannedExchange is one exchange declared by rabbids.
+type PlannedExchange struct {
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	Durable    bool       `json:"durable"`
+	AutoDelete bool       `json:"auto_delete"`
+	Internal   bool       `json:"internal"`
+	Args       amqp.Table `json:"args,omitempty"`
+	// Delay is true for the exchanges of the delay infrastructure.
+	Delay bool `json:"delay,omitempty"`
+}
+
+// PlannedQueue is one queue declared by rabbids.
+type PlannedQueue struct {
+	Name       string     `json:"name"`
+	Durable    bool       `json:"durable"`
+	AutoDelete bool       `json:"auto_delete"`
+	Exclusive  bool       `json:"exclusive"`
+	Args       amqp.Table `json:"args,omitempty"`
+	// Consumers using the queue, empty for the dead letters and the delay infrastructure.
+	Consumers []string `json:"consumers,omitempty"`
+	// DeadLetter is the name of the dead letter config when the queue is a dead letter.
+	DeadLetter string `json:"dead_letter,omitempty"`
+	// Delay is true for the queues of the delay infrastructure.
+	Delay bool `json:"delay,omitempty"`
+}
+
+// PlannedBinding is one binding from an exchange to a queue or to another exchange.
+type PlannedBinding struct {
+	Exchange        string     `json:"exchange"`
+	Destination     string     `json:"destination"`
+	DestinationType string     `json:"destination_type"`
+	RoutingKey      string     `json:"routing_key"`
+	Args            amqp.Table `json:"args,omitempty"`
+}
+
+// Plan returns the declarations of the config without connecting to the broker:
+// the dead letters, the queues and bindings of every consumer and all the exchanges.
+// The delay infrastructure is included when one consumer uses the delayed-requeue nack_strategy or the
+// delayed_retry on_error strategy, the producers declare it on the first message sent with a delay.
+// The queues created by the ttl delay strategy depend on the delays sent and are not included.
+func Plan(config *Config) DeclarationPlan {
+	p := &planner{
+		exchanges: map[string]bool{},
+		queues:    map[string]int{},
+		bindings:  map[string]bool{},
+	}
+
+	consumers := make([]string, 0, len(config.Consumers))
+	for name := range config.Consumers {
+		consumers = append(consumers, name)
+	}
+
+	sort.Strings(consumers)
+
+	var groups []delayGroup
+
+	delayed := map[delayGroup][]string{}
+
+	for _, name := range consumers {
+		cfg := config.Consumers[name]
+
+		if dl, ok := config.DeadLetters[cfg.DeadLetter]; ok {
+			p.queue(config, dl.Queue, "", cfg.DeadLetter)
+		}
+
+		p.queue(config, cfg.Queue, name, "")
+
+		if cfg.usesDelay() {
+			conn := config.Connections[cfg.Connection]
+			opts := conn.delayOptions()
+			group := delayGroup{
+				strategy:    conn.DelayStrategy,
+				prefix:      opts.prefix(),
+				levelPrefix: opts.levelPrefix(),
+				resolution:  opts.resolution(),
+				levels:      opts.levels(),
+				queueType:   opts.QueueType,
+			}
+
+			if _, ok := delayed[group]; !ok {
+				groups = append(groups, group)
+			}
+
+			delayed[group] = append(delayed[group], cfg.Queue.Name)
+		}
+	}
+
+	exchanges := make([]string, 0, len(config.Exchanges))
+	for name := range config.Exchanges {
+		exchanges = append(exchanges, name)
+	}
+
+	sort.Strings(exchanges)
+
+	for _, name := range exchanges {
+		p.exchange(name, config.Exchanges[name])
+	}
+
+	for _, group := range groups {
+		switch group.strategy {
+		case "", DelayStrategyLevels:
+			p.delayInfrastructure(group, delayed[group])
+		case DelayStrategyPlugin:
+			p.pluginDelayInfrastructure(group.prefix, delayed[group])
+		}
+	}
+
+	return p.plan
+}
+
+// delayGroup is the delay infrastructure used by the consumers of one connection.
+type delayGroup struct {
+	strategy    string
+	prefix      string
+	levelPrefix string
+	resolution  time.Duration
+	levels      int
+	queueType   string
+}
+
+type planner struct {
+	plan      DeclarationPlan
+	exchanges map[string]bool
+	queues    map[string]int
+	bindings  map[string]bool
+}
+
+func (p *planner) queue(config *Config, q QueueConfig, consumer, deadLetter string) {
+	if i, ok := p.queues[q.Name]; ok {
+		if consumer != "" {
+			p.plan.Queues[i].Consumers = append(p.plan.Queues[i].Consumers, consumer)
+		}
+
+		return
+	}
+
+	planned := PlannedQueue{
+		Name:       q.Name,
+		Durable:    q.Options.Durable,
+		AutoDelete: q.Options.AutoDelete,
+		Exclusive:  q.Options.Exclusive,
+		Args:       assertRightTableTypes(q.Options.Args),
+		DeadLetter: deadLetter,
+	}
+	if consumer != "" {
+		planned.Consumers = []string{consumer}
+	}
+
+	p.queues[q.Name] = len(p.plan.Queues)
+	p.plan.Queues = append(p.plan.Queues, planned)
+
+	for _, b := range q.Bindings {
+		if ex, ok := config.Exchanges[b.Exchange]; ok {
+			p.exchange(b.Exchange, ex)
+		}
+
+		for _, key := range b.RoutingKeys {
+			p.binding(PlannedBinding{
+				Exchange:        b.Exchange,
+				Destination:     q.Name,
+				DestinationType: "queue",
+				RoutingKey:      key,
+				Args:            assertRightTableTypes(b.Options.Args),
+			})
+		}
+	}
+}
+
+func (p *planner) exchange(name string, ex ExchangeConfig) {
+	if p.exchanges[name] {
+		return
+	}
+
+	p.exchanges[name] = true
+	p.plan.Exchanges = append(p.plan.Exchanges, PlannedExchange{
+		Name:       name,
+		Type:       ex.Type,
+		Durable:    ex.Options.Durable,
+		AutoDelete: ex.Options.AutoDelete,
+		Internal:   ex.Options.Internal,
+		Args:       assertRightTableTypes(ex.Options.Args),
+	})
+}
+
+func (p *planner) binding(b PlannedBinding) {
+	key := fmt.Sprintf("%s|%s|%s|%s", b.Exchange, b.DestinationType, b.Destination, b.RoutingKey)
+	if p.bindings[key] {
+		return
+	}
+
+	p.bindings[key] = true
+	p.plan.Bindings = append(p.plan.Bindings, b)
+}
+
+// delayInfrastructure add the declarations made by delayDelivery.build and delayDelivery.Declare.
+func (p *planner) delayInfrastructure(group delayGroup, queues []string) {
+	delivery := delayDeliveryExchange(group.prefix)
+	bindingKey := "1.#"
+
+	for level := group.levels - 1; level >= 0; level-- {
+		current := delayedLevelName(group.levelPrefix, level)
+		next := delayedLevelName(group.levelPrefix, level-1)
+
+		if level == 0 {
+			next = delivery
+		}
+
+		p.exchanges[current] = true
+		p.plan.Exchanges = append(p.plan.Exchanges, PlannedExchange{
+			Name:    current,
+			Type:    amqp.ExchangeTopic,
+			Durable: true,
+			Delay:   true,
+		})
+		p.plan.Queues = append(p.plan.Queues, PlannedQueue{
+			Name:    current,
+			Durable: true,
+			Args:    delayLevelArgs(group.queueType, level, group.resolution, next),
+			Delay:   true,
+		})
+		p.binding(PlannedBinding{Exchange: current, Destination: current, DestinationType: "queue", RoutingKey: bindingKey})
+
+		bindingKey = "*." + bindingKey
+	}
+
+	bindingKey = "0.#"
+
+	for level := group.levels - 1; level > 0; level-- {
+		p.binding(PlannedBinding{
+			Exchange:        delayedLevelName(group.levelPrefix, level),
+			Destination:     delayedLevelName(group.levelPrefix, level-1),
+			DestinationType: "exchange",
+			RoutingKey:      bindingKey,
+		})
+
+		bindingKey = "*." + bindingKey
+	}
+
+	p.exchanges[delivery] = true
+	p.plan.Exchanges = append(p.plan.Exchanges, PlannedExchange{
+		Name:    delivery,
+		Type:    amqp.ExchangeTopic,
+		Durable: true,
+		Delay:   true,
+	})
+	p.binding(PlannedBinding{
+		Exchange:        delayedLevelName(group.levelPrefix, 0),
+		Destination:     delivery,
+		DestinationType: "exchange",
+		RoutingKey:      bindingKey,
+	})
+
+	for _, q := range queues {
+		p.binding(PlannedBinding{
+			Exchange:        delivery,
+			Destination:     q,
+			DestinationType: "queue",
+			RoutingKey:      "#." + q,
+		})
+	}
+}
+
+// pluginDelayInfrastructure add the declarations made by the plugin delay strategy.
+func (p *planner) pluginDelayInfrastructure(prefix string, queues []string) {
+	exchange := delayedMessageExchange(prefix)
+
+	p.exchanges[exchange] = true
+	p.plan.Exchanges = append(p.plan.Exchanges, PlannedExchange{
+		Name:    exchange,
+		Type:    "x-delayed-message",
+		Durable: true,
+		Args:    amqp.Table{"x-delayed-type": amqp.ExchangeDirect},
+		Delay:   true,
+	})
+
+	for _, q := range queues {
+		p.binding(PlannedBinding{
+			Exchange:        exchange,
+			Destination:     q,
+			DestinationType: "queue",
+			RoutingKey:      q,
+		})
+	}
+}
+
+// String returns the plan in a human-readable format.
+func (p DeclarationPlan) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "exchanges (%d):\n", len(p.Exchanges))
+
+	for _, ex := range p.Exchanges {
+		fmt.Fprintf(&b, "  + %s type=%s durable=%t auto_delete=%t internal=%t%s%s\n",
+			ex.Name, ex.Type, ex.Durable, ex.AutoDelete, ex.Internal, formatArgs(ex.Args), formatDelay(ex.Delay))
+	}
+
+	fmt.Fprintf(&b, "queues (%d):\n", len(p.Queues))
+
+	for _, q := range p.Queues {
+		extra := formatDelay(q.Delay)
+		if q.DeadLetter != "" {
+			extra += fmt.Sprintf(" dead_letter=%s", q.DeadLetter)
+		}
+
+		if len(q.Consumers) > 0 {
+			extra += fmt.Sprintf(" consumers=%s", strings.Join(q.Consumers, ","))
+		}
+
+		fmt.Fprintf(&b, "  + %s durable=%t auto_delete=%t exclusive=%t%s%s\n",
+			q.Name, q.Durable, q.AutoDelete, q.Exclusive, formatArgs(q.Args), extra)
+	}
+
+	fmt.Fprintf(&b, "bindings (%d):\n", len(p.Bindings))
+
+	for _, bind := range p.Bindings {
+		fmt.Fprintf(&b, "  + %s -> %s %s key=%q%s\n",
+			bind.Exchange, bind.DestinationType, bind.Destination, bind.RoutingKey, formatArgs(bind.Args))
+	}
+
+	return b.String()
+}
+
+func formatArgs(args amqp.Table) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	values := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, fmt.Sprintf("%s=%v", k, args[k]))
+	}
+
+	return fmt.Sprintf(" args={%s}", strings.Join(values, ", "))
+}
+
+func formatDelay(delay bool) string {
+	if delay {
+		return " (delay)"
+	}
+
+	return ""
+}
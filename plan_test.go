@@ -0,0 +1,168 @@
+package rabbids
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{
+		Exchanges: map[string]ExchangeConfig{
+			"events":   {Type: "topic", Options: Options{Durable: true}},
+			"fallback": {Type: "topic"},
+		},
+		DeadLetters: map[string]DeadLetter{
+			"fallback": {Queue: QueueConfig{
+				Name:     "fallback",
+				Options:  Options{Durable: true, Args: amqp.Table{"x-message-ttl": 300000}},
+				Bindings: []Binding{{Exchange: "fallback", RoutingKeys: []string{"#"}}},
+			}},
+		},
+		Consumers: map[string]ConsumerConfig{
+			"billing": {
+				DeadLetter: "fallback",
+				Queue: QueueConfig{
+					Name:     "billing",
+					Options:  Options{Durable: true},
+					Bindings: []Binding{{Exchange: "events", RoutingKeys: []string{"*.invoice.*", "*.payment.*"}}},
+				},
+			},
+		},
+	}
+
+	plan := Plan(config)
+
+	require.Equal(t, []PlannedExchange{
+		{Name: "fallback", Type: "topic", Args: amqp.Table{}},
+		{Name: "events", Type: "topic", Durable: true, Args: amqp.Table{}},
+	}, plan.Exchanges)
+	require.Equal(t, []PlannedQueue{
+		{Name: "fallback", Durable: true, Args: amqp.Table{"x-message-ttl": int64(300000)}, DeadLetter: "fallback"},
+		{Name: "billing", Durable: true, Args: amqp.Table{}, Consumers: []string{"billing"}},
+	}, plan.Queues)
+	require.Len(t, plan.Bindings, 3)
+	require.Equal(t, `exchanges (2):
+  + fallback type=topic durable=false auto_delete=false internal=false
+  + events type=topic durable=true auto_delete=false internal=false
+queues (2):
+  + fallback durable=true auto_delete=false exclusive=false args={x-message-ttl=300000} dead_letter=fallback
+  + billing durable=true auto_delete=false exclusive=false consumers=billing
+bindings (3):
+  + fallback -> queue fallback key="#"
+  + events -> queue billing key="*.invoice.*"
+  + events -> queue billing key="*.payment.*"
+`, plan.String())
+
+	b, err := json.Marshal(plan.Queues[1])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":"billing","durable":true,"auto_delete":false,"exclusive":false,"consumers":["billing"]}`, string(b))
+}
+
+func TestPlanWithDelayInfrastructure(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan(&Config{Consumers: map[string]ConsumerConfig{
+		"billing": {Queue: QueueConfig{Name: "billing"}, NackStrategy: NackStrategyDelayedRequeue},
+	}})
+
+	// all the delay levels plus the delivery exchange
+	require.Len(t, plan.Exchanges, maxNumberOfBitsToUse+1)
+	require.Len(t, plan.Queues, maxNumberOfBitsToUse+1)
+	require.Equal(t, PlannedBinding{
+		Exchange:        DelayDeliveryExchange,
+		Destination:     "billing",
+		DestinationType: "queue",
+		RoutingKey:      "#.billing",
+	}, plan.Bindings[len(plan.Bindings)-1])
+}
+
+func TestPlanWithPluginDelayStrategy(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan(&Config{
+		Connections: map[string]Connection{"default": {DelayStrategy: DelayStrategyPlugin}},
+		Consumers: map[string]ConsumerConfig{
+			"billing": {Connection: "default", Queue: QueueConfig{Name: "billing"}, OnError: OnErrorDelayedRetry},
+		},
+	})
+
+	require.Len(t, plan.Exchanges, 1)
+	require.Equal(t, DelayedMessageExchange, plan.Exchanges[0].Name)
+	require.Equal(t, "x-delayed-message", plan.Exchanges[0].Type)
+	require.Len(t, plan.Queues, 1)
+	require.Equal(t, PlannedBinding{
+		Exchange:        DelayedMessageExchange,
+		Destination:     "billing",
+		DestinationType: "queue",
+		RoutingKey:      "billing",
+	}, plan.Bindings[len(plan.Bindings)-1])
+}
+
+func TestPlanWithDelayPrefix(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan(&Config{
+		Connections: map[string]Connection{
+			"default": {},
+			"billing": {DelayPrefix: "billing.delay"},
+		},
+		Consumers: map[string]ConsumerConfig{
+			"billing": {Connection: "billing", Queue: QueueConfig{Name: "billing"}, OnError: OnErrorDelayedRetry},
+			"emails":  {Connection: "default", Queue: QueueConfig{Name: "emails"}, OnError: OnErrorDelayedRetry},
+		},
+	})
+
+	// two complete delay infrastructures, one for each prefix
+	require.Len(t, plan.Exchanges, (maxNumberOfBitsToUse+1)*2)
+	require.Equal(t, "billing.delay-level-27", plan.Exchanges[0].Name)
+	require.Contains(t, plan.Bindings, PlannedBinding{
+		Exchange:        "billing.delay-delivery",
+		Destination:     "billing",
+		DestinationType: "queue",
+		RoutingKey:      "#.billing",
+	})
+	require.Contains(t, plan.Bindings, PlannedBinding{
+		Exchange:        DelayDeliveryExchange,
+		Destination:     "emails",
+		DestinationType: "queue",
+		RoutingKey:      "#.emails",
+	})
+}
+
+func TestPlanWithMaxDelay(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan(&Config{
+		Connections: map[string]Connection{"default": {MaxDelay: time.Hour}},
+		Consumers: map[string]ConsumerConfig{
+			"billing": {Connection: "default", Queue: QueueConfig{Name: "billing"}, OnError: OnErrorDelayedRetry},
+		},
+	})
+
+	// 12 levels for one hour plus the delivery exchange
+	require.Len(t, plan.Exchanges, 13)
+	require.Equal(t, "rabbids.delay-level-11", plan.Exchanges[0].Name)
+}
+
+func TestPlanWithDelayResolution(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan(&Config{
+		Connections: map[string]Connection{"default": {MaxDelay: time.Minute, DelayResolution: 100 * time.Millisecond}},
+		Consumers: map[string]ConsumerConfig{
+			"billing": {Connection: "default", Queue: QueueConfig{Name: "billing"}, OnError: OnErrorDelayedRetry},
+		},
+	})
+
+	// 10 levels for 600 units of 100ms plus the delivery exchange
+	require.Len(t, plan.Exchanges, 11)
+	require.Equal(t, "rabbids.delay-100ms-level-9", plan.Exchanges[0].Name)
+	require.Equal(t, int64(51200), plan.Queues[1].Args["x-message-ttl"])
+	require.Equal(t, DelayDeliveryExchange, plan.Exchanges[10].Name)
+}
@@ -0,0 +1,74 @@
+package rabbids
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// supervisorHeartbeats is the number of missed checks before the supervisor is considered stuck.
+const supervisorHeartbeats = 3
+
+// Live return an error when the supervisor loop is not running,
+// meaning the consumers are not restarted anymore and the process should be restarted.
+func (r *Rabbids) Live() error {
+	interval := time.Duration(atomic.LoadInt64(&r.supervisorInterval))
+	if interval <= 0 {
+		return errors.New("the supervisor is not running")
+	}
+
+	last := time.Unix(0, atomic.LoadInt64(&r.supervisorHeartbeat))
+	if since := time.Since(last); since > supervisorHeartbeats*interval {
+		return fmt.Errorf("the supervisor loop is stuck, the last check was %s ago", since)
+	}
+
+	return nil
+}
+
+// Ready return an error when one connection is closed or one consumer is not consuming,
+// meaning the instance should not receive traffic.
+func (r *Rabbids) Ready() error {
+	if r.isDraining() {
+		return errors.New("the consumers are draining")
+	}
+
+	for _, c := range r.Connections() {
+		if !c.Open {
+			return fmt.Errorf("the connection \"%s\" is closed", c.Name)
+		}
+	}
+
+	for _, c := range r.Consumers() {
+		if !c.Alive {
+			return fmt.Errorf("the consumer \"%s\" is not running: %v", c.Name, c.LastError)
+		}
+	}
+
+	return nil
+}
+
+// Check return an error when the client is not ready or the supervisor is not running,
+// it implements the check signature of the common health libraries like alexliesenfeld/health.
+func (r *Rabbids) Check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := r.Ready(); err != nil {
+		return err
+	}
+
+	return r.Live()
+}
+
+// supervisorCheck record the supervisor heartbeat, called by the supervisor loop on every check.
+func (r *Rabbids) supervisorCheck(interval time.Duration) {
+	atomic.StoreInt64(&r.supervisorHeartbeat, time.Now().UnixNano())
+	atomic.StoreInt64(&r.supervisorInterval, int64(interval))
+}
+
+func (r *Rabbids) supervisorStopped() {
+	atomic.StoreInt64(&r.supervisorInterval, 0)
+}
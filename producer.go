@@ -1,8 +1,11 @@
 package rabbids
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/leveeml/rabbids/serialization"
@@ -13,6 +16,7 @@ import (
 // Producer is an high level rabbitMQ producer instance.
 type Producer struct {
 	mutex         sync.RWMutex
+	connection    string
 	conf          Connection
 	conn          *amqp.Connection
 	ch            *amqp.Channel
@@ -20,12 +24,30 @@ type Producer struct {
 	emit          chan Publishing
 	emitErr       chan PublishingError
 	notifyClose   chan *amqp.Error
-	log           LoggerFN
-	serializer    Serializer
+	log           Logger
+	metrics       Metrics
+	tracer        Tracer
+	hooks         hookSet
+	serializer    SerializerV2
 	declarations  *declarations
+	exMutex       sync.Mutex
 	exDeclared    map[string]struct{}
-	delayDelivery *delayDelivery
+	delayStrategy DelayStrategy
+	delayBypass   delayBypass
+	held          sync.WaitGroup
 	name          string
+	confirmMutex  sync.Mutex
+	confirmCh     *amqp.Channel
+	confirms      chan amqp.Confirmation
+	// confirmOpen is 1 while the confirm channel is open, read without the confirmMutex.
+	// confirmWait is the max time waiting one confirmation.
+	confirmOpen   int32
+	confirmWait   time.Duration
+	closeOnce     sync.Once
+	closeErr      error
+	connectedAt   time.Time
+	auditExchange string
+	reportErr     ErrorReporter
 }
 
 // NewProcucer create a new high level rabbitMQ producer instance
@@ -38,6 +60,8 @@ type Producer struct {
 //                            in the first time the topic is used.
 //   rabbids.WithSerializer - used to set a specific serializer
 //                            the default is the a JSON serializer.
+//   rabbids.WithSerializerV2 - used to set a serializer describing the body with headers,
+//                              like the compressed and encrypted serializers.
 func NewProducer(dsn string, opts ...ProducerOption) (*Producer, error) {
 	p := &Producer{
 		conf: Connection{
@@ -49,10 +73,13 @@ func NewProducer(dsn string, opts ...ProducerOption) (*Producer, error) {
 		emit:          make(chan Publishing, 250),
 		emitErr:       make(chan PublishingError, 250),
 		closed:        make(chan struct{}),
-		log:           NoOPLoggerFN,
+		log:           NoOPLogger{},
+		metrics:       NoOPMetrics{},
+		tracer:        NoOPTracer{},
+		confirmWait:   DefaultConfirmTimeout,
 		serializer:    &serialization.JSON{},
 		exDeclared:    make(map[string]struct{}),
-		delayDelivery: &delayDelivery{},
+		delayStrategy: NewLevelsDelayStrategy(DelayOptions{}),
 		name:          fmt.Sprintf("rabbids.producer.%d", time.Now().Unix()),
 	}
 
@@ -62,7 +89,7 @@ func NewProducer(dsn string, opts ...ProducerOption) (*Producer, error) {
 		}
 	}
 
-	err := p.startConnection()
+	err := p.startConnection(nil)
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +115,12 @@ func (p *Producer) loop() {
 				return // graceful shutdown
 			}
 
+			if p.delayBypass.holds(pub) {
+				p.holdEmit(pub)
+
+				continue
+			}
+
 			err := p.Send(pub)
 			if err != nil {
 				p.tryToEmitErr(pub, err)
@@ -110,46 +143,296 @@ func (p *Producer) EmitErr() <-chan PublishingError { return p.emitErr }
 // In case of connection errors, the send will block and retry until the reconnection is done.
 // It returns an error if the Serializer returned an error OR the connection error persisted after the retries.
 func (p *Producer) Send(m Publishing) error {
+	m, err := p.prepare(m)
+	if err != nil {
+		p.hooks.published(p.name, m, err)
+
+		return err
+	}
+
+	finish := p.tracer.StartPublish(m.Context(), p.name, &m)
+
+	err = retry.Do(func() error {
+		p.mutex.RLock()
+		p.tryToDeclareTopic(m.Exchange)
+
+		err := p.ch.Publish(m.Exchange, m.Key, false, false, m.Publishing)
+		p.mutex.RUnlock()
+
+		return err
+	}, 10, 10*time.Millisecond)
+	p.metrics.MessagePublished(p.name, m.Exchange, err)
+	p.hooks.published(p.name, m, err)
+	finish(err)
+
+	if err == nil {
+		p.audit(m)
+	}
+
+	return err
+}
+
+// SendWithConfirm send a message to rabbitMQ and wait until the broker confirms the message.
+// The messages are published one at a time using a dedicated channel in confirm mode,
+// so it's slower than Send and should be used only when the confirmation is required.
+func (p *Producer) SendWithConfirm(m Publishing) error {
+	m, err := p.prepare(m)
+	if err != nil {
+		p.hooks.published(p.name, m, err)
+
+		return err
+	}
+
+	finish := p.tracer.StartPublish(m.Context(), p.name, &m)
+
+	p.confirmMutex.Lock()
+	defer p.confirmMutex.Unlock()
+
+	err = retry.Do(func() error {
+		p.mutex.RLock()
+		defer p.mutex.RUnlock()
+
+		p.tryToDeclareTopic(m.Exchange)
+
+		ch, err := p.confirmChannel()
+		if err != nil {
+			return err
+		}
+
+		err = ch.Publish(m.Exchange, m.Key, false, false, m.Publishing)
+		if err != nil {
+			p.resetConfirmChannel()
+
+			return err
+		}
+
+		return p.waitConfirm(m)
+	}, 10, 10*time.Millisecond)
+	p.metrics.MessagePublished(p.name, m.Exchange, err)
+	p.hooks.published(p.name, m, err)
+	finish(err)
+
+	if err == nil {
+		p.audit(m)
+	}
+
+	return err
+}
+
+// prepare apply the options and encode the data of the message.
+func (p *Producer) prepare(m Publishing) (Publishing, error) {
 	for _, op := range m.options {
 		op(&m)
 	}
 
-	b, err := p.serializer.Marshal(m.Data)
+	stampTypedMessage(&m)
+	stampCorrelationID(&m)
+
+	if !m.encoded {
+		out, err := p.serializer.Serialize(m.Exchange, m.Key, m.Data)
+		if err != nil {
+			return m, fmt.Errorf("failed to marshal: %w", err)
+		}
+
+		if len(out.Headers) > 0 && m.Headers == nil {
+			m.Headers = amqp.Table{}
+		}
+
+		for k, v := range out.Headers {
+			m.Headers[k] = v
+		}
+
+		m.Body = out.Body
+
+		if m.ContentType == "" {
+			m.ContentType = out.ContentType
+		}
+
+		if out.ContentEncoding != "" {
+			m.ContentEncoding = out.ContentEncoding
+		}
+	}
+
+	err := p.checkQueueTTL(m)
 	if err != nil {
-		return fmt.Errorf("failed to marshal: %w", err)
+		return m, err
 	}
 
-	m.Body = b
-	m.ContentType = p.serializer.Name()
+	if p.delayBypass.applies(m) {
+		if p.delayBypass.hold {
+			time.Sleep(m.Delay)
+		}
+
+		bypassDelay(&m)
+	}
 
 	if m.Delay > 0 {
-		err := p.delayDelivery.Declare(p.ch, m.Key)
+		p.mutex.RLock()
+		err = p.delayStrategy.Prepare(p.ch, m.delayQueue, &m)
+		p.mutex.RUnlock()
+
 		if err != nil {
-			return err
+			return m, err
 		}
+
+		stampDelay(&m, time.Now())
 	}
 
-	return retry.Do(func() error {
-		p.mutex.RLock()
-		p.tryToDeclareTopic(m.Exchange)
+	return m, nil
+}
 
-		err := p.ch.Publish(m.Exchange, m.Key, false, false, m.Publishing)
-		p.mutex.RUnlock()
+// checkQueueTTL return ErrDelayExceeded when the delay of the message is greater than the x-message-ttl
+// of the target queue, from the config: the delay is expected to fit in the lifetime of the queue messages.
+func (p *Producer) checkQueueTTL(m Publishing) error {
+	if m.Delay <= 0 || m.delayQueue == "" {
+		return nil
+	}
 
-		return err
-	}, 10, 10*time.Millisecond)
+	p.exMutex.Lock()
+	d := p.declarations
+	p.exMutex.Unlock()
+
+	if d == nil {
+		return nil
+	}
+
+	ttl, ok := d.queueTTL(m.delayQueue)
+	if !ok || m.Delay <= ttl {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s is greater than the x-message-ttl %s of the queue \"%s\"",
+		ErrDelayExceeded, m.Delay, ttl, m.delayQueue)
+}
+
+// reload replace the declarations and, when it's not nil, the delay strategy of the producer
+// after the config of the client is reloaded.
+func (p *Producer) reload(d *declarations, delay DelayStrategy) {
+	p.exMutex.Lock()
+	p.declarations = d
+	p.exMutex.Unlock()
+
+	if delay == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	p.delayStrategy = delay
+	p.mutex.Unlock()
+}
+
+// DeclareDelayInfrastructure declare the infrastructure of the delay strategy and bind the queues to it,
+// before sending the first message with delay. It does nothing when the strategy isn't a DelayDeclarer.
+func (p *Producer) DeclareDelayInfrastructure(queues ...string) error {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	d, ok := p.delayStrategy.(DelayDeclarer)
+	if !ok {
+		return nil
+	}
+
+	return d.Declare(p.ch, queues...)
+}
+
+// confirmChannel return the channel in confirm mode, opening a new one when needed.
+// It MUST be called with the confirmMutex locked.
+func (p *Producer) confirmChannel() (*amqp.Channel, error) {
+	if p.confirmCh != nil {
+		return p.confirmCh, nil
+	}
+
+	ch, err := p.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the confirm channel: %w", err)
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		_ = ch.Close()
+
+		return nil, fmt.Errorf("failed to put the channel in confirm mode: %w", err)
+	}
+
+	p.confirmCh = ch
+	p.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	atomic.StoreInt32(&p.confirmOpen, 1)
+
+	return ch, nil
+}
+
+// waitConfirm wait the confirmation of the message published, up to the confirmWait.
+// It MUST be called with the confirmMutex locked.
+func (p *Producer) waitConfirm(m Publishing) error {
+	timeout := p.confirmWait
+	if timeout <= 0 {
+		timeout = DefaultConfirmTimeout
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case confirm, ok := <-p.confirms:
+		if !ok {
+			p.resetConfirmChannel()
+
+			return errors.New("the confirm channel was closed before receiving the confirmation")
+		}
+
+		if !confirm.Ack {
+			return fmt.Errorf("the message %s was not confirmed by the broker", m.MessageId)
+		}
+
+		return nil
+	case <-timer.C:
+		// the late confirmation would be received by the next message, so the channel is discarded.
+		p.resetConfirmChannel()
+
+		return fmt.Errorf("timeout after %s waiting the confirmation of the message %s", timeout, m.MessageId)
+	}
+}
+
+// resetConfirmChannel close the confirm channel after a failure, the next message opens a new one.
+// It MUST be called with the confirmMutex locked.
+func (p *Producer) resetConfirmChannel() {
+	if p.confirmCh != nil {
+		_ = p.confirmCh.Close()
+	}
+
+	p.confirmCh = nil
+	p.confirms = nil
+	atomic.StoreInt32(&p.confirmOpen, 0)
 }
 
 // Close will close all the underline channels and close the connection with rabbitMQ.
+// The messages already emitted are sent and the pending confirmations are waited before closing.
 // Any Emit call after calling the Close method will panic.
 func (p *Producer) Close() error {
+	p.closeOnce.Do(func() {
+		p.closeErr = p.close()
+	})
+
+	return p.closeErr
+}
+
+func (p *Producer) close() error {
 	close(p.emit)
 	<-p.closed
+	p.held.Wait()
+
+	p.confirmMutex.Lock()
+	defer p.confirmMutex.Unlock()
 
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
 	if p.ch != nil && p.conn != nil && !p.conn.IsClosed() {
+		if p.confirmCh != nil {
+			if err := p.confirmCh.Close(); err != nil {
+				return fmt.Errorf("error closing the confirm channel: %w", err)
+			}
+		}
+
 		if err := p.ch.Close(); err != nil {
 			return fmt.Errorf("error closing the channel: %w", err)
 		}
@@ -164,6 +447,44 @@ func (p *Producer) Close() error {
 	return nil
 }
 
+// Alive returns true while the producer connection is open.
+// When the connection is lost the producer reconnects in background.
+func (p *Producer) Alive() bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.conn != nil && !p.conn.IsClosed()
+}
+
+// connectionState returns the time since the producer connection was opened and the number of channels open.
+func (p *Producer) connectionState(now time.Time) (time.Duration, int) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	channels := 0
+	if p.ch != nil {
+		channels++
+	}
+
+	channels += int(atomic.LoadInt32(&p.confirmOpen))
+
+	return now.Sub(p.connectedAt), channels
+}
+
+// Check return an error when the producer connection is closed,
+// it implements the check signature of the common health libraries like alexliesenfeld/health.
+func (p *Producer) Check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !p.Alive() {
+		return fmt.Errorf("the producer \"%s\" connection is closed", p.name)
+	}
+
+	return nil
+}
+
 // GetAMQPChannel returns the current connection channel.
 func (p *Producer) GetAMQPChannel() *amqp.Channel {
 	return p.ch
@@ -175,30 +496,41 @@ func (p *Producer) GetAMQPConnection() *amqp.Connection {
 }
 
 func (p *Producer) handleAMPQClose(err error) {
-	p.log("ampq connection closed", Fields{"error": err})
+	p.log.Warn("ampq connection closed", Fields{"error": err})
 
 	for {
-		connErr := p.startConnection()
+		connErr := p.startConnection(func(err error) {
+			p.metrics.ReconnectAttempt(p.name, err)
+		})
 		if connErr == nil {
+			p.metrics.Reconnected(p.name)
+			p.hooks.reconnected(p.name)
+
 			return
 		}
 
-		p.log("ampq reconnection failed", Fields{"error": connErr})
+		p.log.Error("ampq reconnection failed", Fields{"error": connErr})
 		time.Sleep(time.Second)
 	}
 }
 
-func (p *Producer) startConnection() error {
-	p.log("opening a new rabbitmq connection", Fields{})
+func (p *Producer) startConnection(attempt func(error)) error {
+	p.log.Info("opening a new rabbitmq connection", Fields{})
 
-	conn, err := openConnection(p.conf, p.name)
+	conn, err := openConnection(p.conf, p.name, attempt)
 	if err != nil {
 		return err
 	}
 
+	// the confirm channel belongs to the old connection, the next SendWithConfirm opens a new one.
+	p.confirmMutex.Lock()
+	defer p.confirmMutex.Unlock()
+	p.resetConfirmChannel()
+
 	p.mutex.Lock()
 
 	p.conn = conn
+	p.connectedAt = time.Now()
 	p.ch, err = p.conn.Channel()
 	p.notifyClose = p.conn.NotifyClose(make(chan *amqp.Error))
 
@@ -212,18 +544,34 @@ func (p *Producer) tryToEmitErr(m Publishing, err error) {
 	select {
 	case p.emitErr <- data:
 	default:
+		p.reportErr.report(fmt.Errorf("the EmitErr channel is full, dropping the error: %w", err), Fields{
+			"producer":    p.name,
+			"message-id":  m.MessageId,
+			"exchange":    m.Exchange,
+			"routing-key": m.Key,
+		})
 	}
 }
 
+// tryToDeclareTopic declare the exchange in the first time it is used.
+// It MUST be called with the mutex locked, the exMutex guard the exDeclared shared by the senders
+// and the declarations replaced by a reload.
 func (p *Producer) tryToDeclareTopic(ex string) {
-	if p.declarations == nil || ex == "" {
+	if ex == "" {
+		return
+	}
+
+	p.exMutex.Lock()
+	defer p.exMutex.Unlock()
+
+	if p.declarations == nil {
 		return
 	}
 
 	if _, ok := p.exDeclared[ex]; !ok {
 		err := p.declarations.declareExchange(p.ch, ex)
 		if err != nil {
-			p.log("failed declaring a exchange", Fields{"err": err, "ex": ex})
+			p.log.Error("failed declaring a exchange", Fields{"err": err, "ex": ex})
 			return
 		}
 
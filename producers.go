@@ -0,0 +1,100 @@
+package rabbids
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Producer return one producer declared in the config, created by New.
+// The producers are closed by Shutdown.
+func (r *Rabbids) Producer(name string) (*Producer, error) {
+	r.producersMutex.Lock()
+	defer r.producersMutex.Unlock()
+
+	p, ok := r.namedProducers[name]
+	if !ok {
+		return nil, fmt.Errorf("producer \"%s\" did not exist", name)
+	}
+
+	return p, nil
+}
+
+// createNamedProducers create all the producers from the config with the registered options.
+func (r *Rabbids) createNamedProducers() error {
+	r.namedProducers = make(map[string]*Producer, len(r.config.Producers))
+
+	for name, cfg := range r.config.Producers {
+		opts := append([]ProducerOption{WithCustomName(fmt.Sprintf("rabbids.%s", name))}, r.config.ProducerOptions[name]...)
+
+		p, err := r.CreateProducer(cfg.Connection, opts...)
+		if err != nil {
+			for _, created := range r.namedProducers {
+				_ = created.Close()
+			}
+
+			return fmt.Errorf("failed to create the producer \"%s\": %w", name, err)
+		}
+
+		r.namedProducers[name] = p
+	}
+
+	return nil
+}
+
+// producerNames return the names of the producers from the config, sorted.
+func (r *Rabbids) producerNames() []string {
+	r.producersMutex.Lock()
+	defer r.producersMutex.Unlock()
+
+	names := make([]string, 0, len(r.namedProducers))
+	for name := range r.namedProducers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// closeConnections close all the connections opened by the client, used when New fails.
+func (r *Rabbids) closeConnections() {
+	r.connsMutex.Lock()
+	defer r.connsMutex.Unlock()
+
+	for _, conn := range r.conns {
+		_ = conn.Close()
+	}
+}
+
+// checkProducers report the state of the producers from the config.
+// The producers reconnect by themselves, the supervisor only logs and records the state changes.
+func (s *supervisor) checkProducers() {
+	for _, name := range s.rabbids.producerNames() {
+		p, err := s.rabbids.Producer(name)
+		if err != nil {
+			continue
+		}
+
+		alive := p.Alive()
+		s.rabbids.metrics.ProducerAlive(name, alive)
+		s.rabbids.metrics.ProducerBuffer(name, len(p.emit), cap(p.emit))
+
+		since, channels := p.connectionState(time.Now())
+		s.rabbids.metrics.ConnectionState(name, alive, since, channels)
+
+		if dead := s.deadProducers[name]; dead == !alive {
+			continue
+		}
+
+		s.deadProducers[name] = !alive
+
+		if alive {
+			s.rabbids.log.Info("producer reconnected", Fields{"producer-name": name, "transition": transitionRecreated})
+
+			continue
+		}
+
+		s.rabbids.log.Warn("producer found dead, waiting the reconnection", Fields{"producer-name": name, "transition": transitionDead})
+	}
+}
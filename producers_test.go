@@ -0,0 +1,78 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+type producerMetrics struct {
+	NoOPMetrics
+	alive []bool
+}
+
+func (m *producerMetrics) ProducerAlive(_ string, alive bool) {
+	m.alive = append(m.alive, alive)
+}
+
+func TestRabbids_Producer(t *testing.T) {
+	t.Parallel()
+
+	r, err := New(&Config{}, NoOPLoggerFN)
+	require.NoError(t, err)
+
+	_, err = r.Producer("events")
+	require.EqualError(t, err, "producer \"events\" did not exist")
+
+	_, err = New(&Config{Producers: map[string]ProducerConfig{"events": {Connection: "default"}}}, NoOPLoggerFN)
+	require.EqualError(t, err, "failed to create the producer \"events\": connection \"default\" did not exist")
+}
+
+func TestSupervisor_checkProducers(t *testing.T) {
+	t.Parallel()
+
+	var logs []string
+
+	metrics := &producerMetrics{}
+	r := &Rabbids{
+		log:            LoggerFN(func(message string, _ Fields) { logs = append(logs, message) }),
+		metrics:        metrics,
+		namedProducers: map[string]*Producer{"events": {}},
+	}
+	s := &supervisor{rabbids: r, deadProducers: map[string]bool{}}
+
+	s.checkProducers()
+	s.checkProducers()
+
+	require.Equal(t, []bool{false, false}, metrics.alive)
+	require.Equal(t, []string{"producer found dead, waiting the reconnection"}, logs)
+}
+
+func TestProducer_waitConfirm(t *testing.T) {
+	t.Parallel()
+
+	pub := Publishing{}
+	pub.MessageId = "42"
+
+	p := &Producer{confirmWait: 10 * time.Millisecond, confirms: make(chan amqp.Confirmation, 1)}
+	p.confirms <- amqp.Confirmation{Ack: true}
+	require.NoError(t, p.waitConfirm(pub))
+
+	p.confirms <- amqp.Confirmation{Ack: false}
+	require.EqualError(t, p.waitConfirm(pub), "the message 42 was not confirmed by the broker")
+	require.NotNil(t, p.confirms, "a nack keeps the confirm channel")
+
+	p.confirmOpen = 1
+	require.EqualError(t, p.waitConfirm(pub), "timeout after 10ms waiting the confirmation of the message 42")
+	require.Nil(t, p.confirms, "the confirm channel should be discarded after a timeout")
+
+	_, channels := p.connectionState(time.Now())
+	require.Equal(t, 0, channels, "the discarded confirm channel should not be counted")
+
+	p.confirms = make(chan amqp.Confirmation)
+	close(p.confirms)
+	require.EqualError(t, p.waitConfirm(pub), "the confirm channel was closed before receiving the confirmation")
+	require.Nil(t, p.confirms)
+}
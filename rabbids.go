@@ -1,10 +1,17 @@
 package rabbids
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"os"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ivpusic/grpool"
@@ -15,17 +22,71 @@ import (
 
 // Rabbids is the main block used to create and run rabbitMQ consumers and producers.
 type Rabbids struct {
-	conns        map[string]*amqp.Connection
-	config       *Config
-	declarations *declarations
-	log          LoggerFN
-	number       int64
+	conns              map[string]*amqp.Connection
+	connStats          map[string]*connectionStats
+	connsMutex         sync.Mutex
+	dialing            map[string]chan struct{}
+	configMutex        sync.RWMutex
+	config             *Config
+	declarations       *declarations
+	log                Logger
+	number             int64
+	consumers          map[string]*Consumer
+	mutex              sync.RWMutex
+	draining           int32
+	connProducersMutex sync.Mutex
+	connProducers      map[string]*Producer
+	producersMutex     sync.Mutex
+	producers          []*Producer
+	namedProducers     map[string]*Producer
+	delayMutex         sync.Mutex
+	delayStrategies    map[string]DelayStrategy
+	supervisor         *supervisor
+	singletons         map[string]bool
+	verifyTopology     bool
+	metrics            Metrics
+	tracer             Tracer
+	events             chan Event
+	lifecycle          lifecycleHooks
+	hooks              hookSet
+	reportErr          ErrorReporter
+	declaredMutex      sync.Mutex
+	declared           map[string]map[string]struct{}
+	// supervisorHeartbeat is the last supervisor check in unix nano and the supervisorInterval
+	// is the check interval, zero when the supervisor is not running.
+	supervisorHeartbeat int64
+	supervisorInterval  int64
 }
 
-func New(config *Config, log LoggerFN) (*Rabbids, error) {
+// New create a new Rabbids client, opening all the connections declared in the config.
+func New(config *Config, log LoggerFN, opts ...Option) (*Rabbids, error) {
 	setConfigDefaults(config)
 
-	conns := make(map[string]*amqp.Connection)
+	r := &Rabbids{
+		conns:     make(map[string]*amqp.Connection),
+		connStats: map[string]*connectionStats{},
+		config:    config,
+		declarations: &declarations{
+			config: config,
+		},
+		log:           log,
+		number:        0,
+		consumers:     map[string]*Consumer{},
+		connProducers: map[string]*Producer{},
+		metrics:       NoOPMetrics{},
+		tracer:        NoOPTracer{},
+		events:        make(chan Event, EventsBufferSize),
+	}
+
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+
+	r.hooks = append(r.hooks, Hooks{OnDeclare: r.recordDeclared})
+	r.declarations.log = r.log
+	r.declarations.hooks = r.hooks
 
 	for name, cfgConn := range config.Connections {
 		log("opening connection with rabbitMQ", Fields{
@@ -34,34 +95,55 @@ func New(config *Config, log LoggerFN) (*Rabbids, error) {
 			"connection": name,
 		})
 
-		conn, err := openConnection(cfgConn, fmt.Sprintf("rabbids.%s", name))
+		conn, err := openConnection(cfgConn, fmt.Sprintf("rabbids.%s", name), nil)
 		if err != nil {
 			return nil, fmt.Errorf("error opening the connection \"%s\": %w", name, err)
 		}
 
-		conns[name] = conn
+		r.conns[name] = conn
+		r.stats(name).opened(time.Now())
+		r.watchConnection(name, conn)
 	}
 
-	r := &Rabbids{
-		conns:  conns,
-		config: config,
-		declarations: &declarations{
-			config: config,
-			log:    log,
-		},
-		log:    log,
-		number: 0,
+	if r.verifyTopology {
+		if err := r.VerifyTopology(); err != nil {
+			r.closeConnections()
+
+			return nil, err
+		}
+	}
+
+	if err := r.declareDelayOnStart(); err != nil {
+		r.closeConnections()
+
+		return nil, err
+	}
+
+	if err := r.createNamedProducers(); err != nil {
+		r.closeConnections()
+
+		return nil, err
 	}
 
 	return r, nil
 }
 
 // CreateConsumers will iterate over config and create all the consumers.
+// Consumers from groups active in another connection are skipped.
 func (r *Rabbids) CreateConsumers() ([]*Consumer, error) {
+	r.configMutex.RLock()
+	defer r.configMutex.RUnlock()
+
 	var consumers []*Consumer
 
 	for name, cfg := range r.config.Consumers {
 		consumer, err := r.newConsumer(name, cfg)
+		if errors.Is(err, ErrStandby) {
+			r.log.Info("consumer in standby", Fields{"consumer": name, "group": cfg.Stream.Group, "singleton": r.isSingleton(name, cfg)})
+
+			continue
+		}
+
 		if err != nil {
 			return consumers, err
 		}
@@ -74,6 +156,9 @@ func (r *Rabbids) CreateConsumers() ([]*Consumer, error) {
 
 // CreateConsumer create a new consumer for a specific name using the config provided.
 func (r *Rabbids) CreateConsumer(name string) (*Consumer, error) {
+	r.configMutex.RLock()
+	defer r.configMutex.RUnlock()
+
 	cfg, ok := r.config.Consumers[name]
 	if !ok {
 		return nil, fmt.Errorf("consumer \"%s\" did not exist", name)
@@ -83,7 +168,155 @@ func (r *Rabbids) CreateConsumer(name string) (*Consumer, error) {
 }
 
 func (r *Rabbids) newConsumer(name string, cfg ConsumerConfig) (*Consumer, error) {
-	ch, err := r.getChannel(cfg.Connection)
+	switch cfg.NackStrategy {
+	case NackStrategyRequeue, NackStrategyDelayedRequeue:
+	default:
+		return nil, fmt.Errorf("invalid nack strategy \"%s\" for consumer \"%s\"", cfg.NackStrategy, name)
+	}
+
+	switch cfg.OnError {
+	case OnErrorRequeue, OnErrorDeadLetter, OnErrorDelayedRetry, OnErrorDiscard:
+	default:
+		return nil, fmt.Errorf("invalid on_error strategy \"%s\" for consumer \"%s\"", cfg.OnError, name)
+	}
+
+	if cfg.RequeueBackoff != 0 && cfg.RequeueBackoff < 1 {
+		return nil, fmt.Errorf("invalid requeue_backoff %v for consumer \"%s\", it must be at least 1", cfg.RequeueBackoff, name)
+	}
+
+	switch cfg.TimeoutStrategy {
+	case "", TimeoutStrategyWarn, TimeoutStrategyAbort:
+	default:
+		return nil, fmt.Errorf("invalid timeout strategy \"%s\" for consumer \"%s\"", cfg.TimeoutStrategy, name)
+	}
+
+	if member, ok := r.groupMemberOnConnection(name, cfg); ok {
+		return nil, fmt.Errorf("the consumers \"%s\" and \"%s\" from the group \"%s\" can't use the same connection \"%s\"",
+			name, member, cfg.Stream.Group, cfg.Connection)
+	}
+
+	// with auto_ack the broker doesn't wait the handler, there is nothing to pause
+	if cfg.CircuitBreaker.Threshold > 0 && cfg.Options.AutoAck {
+		return nil, fmt.Errorf("the circuit_breaker can't be used with auto_ack for consumer \"%s\"", name)
+	}
+
+	lock := r.consumerLock(name, cfg)
+	if lock != "" {
+		if err := r.acquireLock(name, cfg, lock); err != nil {
+			return nil, err
+		}
+	}
+
+	ch, err := r.openConsumerChannel(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	handler, ok := r.config.Handlers[name]
+	if cfg.Type == ConsumerTypeReprocessor {
+		handler, ok = newReprocessor(cfg.Reprocess, func(p Publishing) error {
+			producer, err := r.connectionProducer(cfg.Connection)
+			if err != nil {
+				return err
+			}
+
+			return producer.SendWithConfirm(p)
+		}), true
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("failed to create the \"%s\" consumer, Handler not registered", name)
+	}
+
+	r.log.Info("consumer created",
+		Fields{
+			"max-workers": cfg.Workers,
+			"consumer":    name,
+		})
+
+	number := atomic.AddInt64(&r.number, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Consumer{
+		queue:           cfg.Queue.Name,
+		name:            name,
+		tag:             consumerTag(cfg.ConsumerTag, name, number),
+		number:          number,
+		createdAt:       time.Now(),
+		events:          r.emit,
+		drained:         make(chan struct{}),
+		workers:         cfg.Workers,
+		prefetch:        int64(cfg.PrefetchCount),
+		prefetchSize:    cfg.PrefetchSize,
+		qosGlobal:       cfg.QosGlobal,
+		opts:            cfg.Options,
+		channel:         ch,
+		t:               tomb.Tomb{},
+		ctx:             ctx,
+		cancel:          cancel,
+		handler:         handler,
+		log:             r.log,
+		metrics:         r.metrics,
+		tracer:          r.tracer,
+		hooks:           r.hooks,
+		reportErr:       r.reportErr,
+		maxAge:          cfg.MaxAge,
+		skipExpired:     cfg.SkipExpired,
+		routingKeys:     newRoutingKeyLimiter(cfg.RoutingKeyMetrics),
+		onErrorStrategy: cfg.OnError,
+		deadLetterEx:    argString(cfg.Queue.Options.Args, "x-dead-letter-exchange"),
+		deadLetterKey:   argString(cfg.Queue.Options.Args, "x-dead-letter-routing-key"),
+		ackBatch: ackBatchConfig{
+			size:     cfg.AckBatchSize,
+			interval: cfg.AckBatchInterval,
+		},
+		stream:       newStreamConsumer(cfg),
+		breaker:      newCircuitBreaker(cfg.CircuitBreaker),
+		lock:         lock,
+		singleton:    r.isSingleton(name, cfg),
+		backpressure: backpressureConfig{threshold: DefaultBackpressureThreshold},
+		timeout: consumerTimeoutConfig{
+			duration: cfg.ConsumerTimeout,
+			strategy: cfg.TimeoutStrategy,
+		},
+		nack: nackConfig{
+			strategy:    cfg.NackStrategy,
+			delay:       cfg.RequeueDelay,
+			jitter:      cfg.RequeueJitter,
+			backoff:     cfg.RequeueBackoff,
+			maxDelay:    cfg.RequeueMaxDelay,
+			maxAttempts: int64(cfg.RequeueMaxAttempts),
+		},
+		producer: func() (*Producer, error) {
+			return r.connectionProducer(cfg.Connection)
+		},
+		redeclare: func() (*amqp.Channel, error) {
+			r.configMutex.RLock()
+			defer r.configMutex.RUnlock()
+
+			return r.openConsumerChannel(name, cfg)
+		},
+	}
+
+	for _, opt := range r.config.ConsumerOptions[name] {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("failed to apply the options for consumer \"%s\": %w", name, err)
+		}
+	}
+
+	// auto ack consumers didn't need the job accounting from the worker pool
+	if !cfg.Options.AutoAck {
+		c.workerPool = grpool.NewPool(cfg.Workers, 0)
+	}
+
+	r.registerConsumer(c)
+
+	return c, nil
+}
+
+// openConsumerChannel open a new channel for one consumer, declaring the queue
+// and the dead letter used by the consumer.
+func (r *Rabbids) openConsumerChannel(name string, cfg ConsumerConfig) (*amqp.Channel, error) {
+	ch, err := r.getConnectionChannel(cfg.Connection, r.consumerConnectionKey(name, cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open the rabbitMQ channel for consumer %s: %w", name, err)
 	}
@@ -100,93 +333,419 @@ func (r *Rabbids) newConsumer(name string, cfg ConsumerConfig) (*Consumer, error
 		return nil, err
 	}
 
-	if err = ch.Qos(cfg.PrefetchCount, 0, false); err != nil {
+	r.emit(Event{Type: EventTopologyDeclared, Consumer: name, Queue: cfg.Queue.Name, Connection: cfg.Connection})
+
+	if err = ch.Qos(cfg.PrefetchCount, cfg.PrefetchSize, cfg.QosGlobal); err != nil {
 		return nil, fmt.Errorf("failed to set QoS: %w", err)
 	}
 
-	handler, ok := r.config.Handlers[name]
+	return ch, nil
+}
+
+// registerConsumer keep track of the last consumer created for each name
+// and carry the restart count and the last error from the previous one.
+func (r *Rabbids) registerConsumer(c *Consumer) {
+	r.mutex.Lock()
+	old, restarted := r.consumers[c.name]
+
+	if restarted {
+		c.restarts = old.restarts + 1
+		c.lastErr = old.LastError()
+	}
+
+	r.consumers[c.name] = c
+	r.mutex.Unlock()
+
+	if restarted {
+		r.emit(Event{Type: EventConsumerRestarted, Consumer: c.name, Queue: c.queue, Err: c.lastErr})
+	}
+}
+
+// Consumers returns the status of all the consumers created by this client, sorted by name.
+func (r *Rabbids) Consumers() []ConsumerStatus {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	status := make([]ConsumerStatus, 0, len(r.consumers))
+	for _, c := range r.consumers {
+		status = append(status, c.Status())
+	}
+
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Name < status[j].Name
+	})
+
+	return status
+}
+
+// Drain cancel the subscription of all the consumers and wait until all the messages
+// already delivered are handled or the context is done.
+// It returns the status of the consumers after the drain, the InFlight field shows the
+// messages still being handled when the context is done.
+// After a drain the supervisor stops recreating dead consumers.
+func (r *Rabbids) Drain(ctx context.Context) ([]ConsumerStatus, error) {
+	atomic.StoreInt32(&r.draining, 1)
+
+	r.mutex.RLock()
+	consumers := make([]*Consumer, 0, len(r.consumers))
+
+	for _, c := range r.consumers {
+		consumers = append(consumers, c)
+	}
+	r.mutex.RUnlock()
+
+	var (
+		wg    sync.WaitGroup
+		errMu sync.Mutex
+		errs  []string
+	)
+
+	for _, c := range consumers {
+		if !c.running() {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(c *Consumer) {
+			defer wg.Done()
+
+			if err := c.Drain(ctx); err != nil {
+				errMu.Lock()
+				errs = append(errs, err.Error())
+				errMu.Unlock()
+			}
+		}(c)
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+
+		return r.Consumers(), fmt.Errorf("failed to drain the consumers: %s", strings.Join(errs, "; "))
+	}
+
+	return r.Consumers(), nil
+}
+
+func (r *Rabbids) isDraining() bool {
+	return atomic.LoadInt32(&r.draining) == 1
+}
+
+// QueueStats have the number of messages and consumers of one queue.
+type QueueStats struct {
+	Name      string
+	Messages  int
+	Consumers int
+}
+
+// QueueStats inspect one queue declared by the config (consumer or dead letter queue)
+// and return the number of messages ready to be delivered and the number of consumers.
+// The inspection is done using a passive declare on a new channel.
+func (r *Rabbids) QueueStats(name string) (QueueStats, error) {
+	connectionName, ok := r.queueConnection(name)
 	if !ok {
-		return nil, fmt.Errorf("failed to create the \"%s\" consumer, Handler not registered", name)
+		return QueueStats{}, fmt.Errorf("queue \"%s\" did not exist in the config", name)
 	}
 
-	r.log("consumer created",
-		Fields{
-			"max-workers": cfg.Workers,
-			"consumer":    name,
-		})
+	ch, err := r.getChannel(connectionName)
+	if err != nil {
+		return QueueStats{}, fmt.Errorf("failed to open the rabbitMQ channel to inspect the queue %s: %w", name, err)
+	}
+
+	defer ch.Close()
+
+	q, err := ch.QueueInspect(name)
+	if err != nil {
+		return QueueStats{}, fmt.Errorf("failed to inspect the queue \"%s\": %w", name, err)
+	}
+
+	return QueueStats{Name: q.Name, Messages: q.Messages, Consumers: q.Consumers}, nil
+}
+
+// queueConnection return the connection name used to declare one queue.
+func (r *Rabbids) queueConnection(queue string) (string, bool) {
+	r.configMutex.RLock()
+	defer r.configMutex.RUnlock()
+
+	for _, cfg := range r.config.Consumers {
+		if cfg.Queue.Name == queue {
+			return cfg.Connection, true
+		}
+	}
+
+	for _, cfg := range r.config.Consumers {
+		dead, ok := r.config.DeadLetters[cfg.DeadLetter]
+		if ok && dead.Queue.Name == queue {
+			return cfg.Connection, true
+		}
+	}
 
-	return &Consumer{
-		queue:      cfg.Queue.Name,
-		name:       name,
-		number:     atomic.AddInt64(&r.number, 1),
-		opts:       cfg.Options,
-		channel:    ch,
-		t:          tomb.Tomb{},
-		handler:    handler,
-		workerPool: grpool.NewPool(cfg.Workers, 0),
-		log:        r.log,
-	}, nil
+	return "", false
 }
 
-// CreateConsumer create a new consumer using the connection inside the config.
+// CreateProducer create a new producer using the connection inside the config.
+// The producers created by the client are closed by Shutdown.
 func (r *Rabbids) CreateProducer(connectionName string, customOpts ...ProducerOption) (*Producer, error) {
+	p, err := r.newProducer(connectionName, customOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	r.producersMutex.Lock()
+	r.producers = append(r.producers, p)
+	r.producersMutex.Unlock()
+
+	return p, nil
+}
+
+func (r *Rabbids) newProducer(connectionName string, customOpts ...ProducerOption) (*Producer, error) {
+	r.configMutex.RLock()
+	defer r.configMutex.RUnlock()
+
 	conn, exists := r.config.Connections[connectionName]
 	if !exists {
 		return nil, fmt.Errorf("connection \"%s\" did not exist", connectionName)
 	}
 
+	delay, err := r.delayStrategy(connectionName, conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the producer for connection \"%s\": %w", connectionName, err)
+	}
+
 	opts := []ProducerOption{
-		withConnection(conn),
-		WithLogger(r.log),
+		withConnection(connectionName, conn),
+		WithDelayStrategy(delay),
+		WithProducerLogger(r.log),
+		withMetrics(r.metrics),
+		WithProducerTracer(r.tracer),
+		withHooks(r.hooks),
+		WithProducerErrorReporter(r.reportErr),
 		withDeclarations(r.declarations),
 	}
 
+	if conn.DelayBypass > 0 {
+		opts = append(opts, WithDelayBypass(conn.DelayBypass, conn.DelayBypassMode))
+	}
+
 	return NewProducer("", append(opts, customOpts...)...)
 }
 
+// connectionProducer return the producer used internally by the consumers of one connection
+// (replies and republished messages). The producer is created on the first use.
+func (r *Rabbids) connectionProducer(connectionName string) (*Producer, error) {
+	r.connProducersMutex.Lock()
+	defer r.connProducersMutex.Unlock()
+
+	if p, ok := r.connProducers[connectionName]; ok {
+		return p, nil
+	}
+
+	p, err := r.newProducer(connectionName, WithCustomName(fmt.Sprintf("rabbids.%s.producer", connectionName)))
+	if err != nil {
+		return nil, err
+	}
+
+	r.connProducers[connectionName] = p
+
+	return p, nil
+}
+
+// Close all the connections opened by the client and the producers used internally by the consumers.
+// The consumers MUST be stopped before calling Close.
+func (r *Rabbids) Close() error {
+	for _, fn := range r.lifecycle.onShutdown {
+		fn()
+	}
+
+	r.connProducersMutex.Lock()
+	defer r.connProducersMutex.Unlock()
+
+	for name, p := range r.connProducers {
+		if err := p.Close(); err != nil {
+			return fmt.Errorf("failed to close the producer for connection \"%s\": %w", name, err)
+		}
+
+		delete(r.connProducers, name)
+	}
+
+	r.connsMutex.Lock()
+	defer r.connsMutex.Unlock()
+
+	for name, conn := range r.conns {
+		if conn.IsClosed() {
+			continue
+		}
+
+		if err := conn.Close(); err != nil {
+			return fmt.Errorf("failed to close the connection \"%s\": %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// getChannel open a channel on the TCP connection of the connection config,
+// the config is read locked because it can be replaced by a reload.
 func (r *Rabbids) getChannel(connectionName string) (*amqp.Channel, error) {
-	_, ok := r.conns[connectionName]
+	r.configMutex.RLock()
+	cfgConn, err := r.connectionConfig(connectionName)
+	r.configMutex.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.openChannel(cfgConn, connectionName)
+}
+
+// getConnectionChannel open a channel on one of the TCP connections created for the connection config.
+// The key identifies the TCP connection, so consumers isolated from the others use keys different from the name.
+// It MUST be called with the configMutex locked.
+func (r *Rabbids) getConnectionChannel(connectionName, key string) (*amqp.Channel, error) {
+	cfgConn, err := r.connectionConfig(connectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.openChannel(cfgConn, key)
+}
+
+// connectionConfig return the config of one connection. It MUST be called with the configMutex locked.
+func (r *Rabbids) connectionConfig(connectionName string) (Connection, error) {
+	cfgConn, ok := r.config.Connections[connectionName]
 	if !ok {
 		available := []string{}
-		for name := range r.conns {
+		for name := range r.config.Connections {
 			available = append(available, name)
 		}
 
-		return nil, fmt.Errorf(
+		return cfgConn, fmt.Errorf(
 			"connection (%s) did not exist, connections names available: %s",
 			connectionName,
 			strings.Join(available, ", "))
 	}
 
-	var ch *amqp.Channel
-	var errCH error
+	return cfgConn, nil
+}
 
-	conn := r.conns[connectionName]
-	ch, errCH = conn.Channel()
-	// Reconnect the connection when receive an connection closed error
-	if errCH != nil && errCH.Error() == amqp.ErrClosed.Error() {
-		cfgConn := r.config.Connections[connectionName]
-		r.log("reopening one connection closed",
-			Fields{
-				"sleep":      cfgConn.Sleep,
-				"timeout":    cfgConn.Timeout,
-				"connection": connectionName,
-			},
-		)
+// openChannel open a channel on the TCP connection identified by the key,
+// opening the connection when it doesn't exist or was closed.
+func (r *Rabbids) openChannel(cfgConn Connection, key string) (*amqp.Channel, error) {
+	conn, err := r.connection(cfgConn, key)
+	if err != nil {
+		return nil, err
+	}
 
-		conn, err := openConnection(cfgConn, fmt.Sprintf("rabbids.%s", connectionName))
+	ch, err := conn.Channel()
+	// Reconnect the connection when it was closed after the check
+	if err != nil && err.Error() == amqp.ErrClosed.Error() {
+		conn, err = r.connection(cfgConn, key)
 		if err != nil {
-			return nil, fmt.Errorf("error reopening the connection \"%s\": %w", connectionName, err)
+			return nil, err
+		}
+
+		ch, err = conn.Channel()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.connsMutex.Lock()
+	r.stats(key).track(ch)
+	r.connsMutex.Unlock()
+
+	return ch, nil
+}
+
+// connection returns the TCP connection identified by the key, dialing it when it doesn't exist or was closed.
+// The dial happens outside the connsMutex, so the readers are not blocked by the retries, and only
+// one dial per key runs at a time: the other callers wait it and check the connection again.
+func (r *Rabbids) connection(cfgConn Connection, key string) (*amqp.Connection, error) {
+	r.connsMutex.Lock()
+
+	for {
+		conn, ok := r.conns[key]
+		if ok && !conn.IsClosed() {
+			r.connsMutex.Unlock()
+
+			return conn, nil
+		}
+
+		dialing, inProgress := r.dialing[key]
+		if !inProgress {
+			break
 		}
 
-		r.conns[connectionName] = conn
-		ch, errCH = conn.Channel()
+		r.connsMutex.Unlock()
+		<-dialing
+		r.connsMutex.Lock()
+	}
+
+	if r.dialing == nil {
+		r.dialing = map[string]chan struct{}{}
 	}
 
-	return ch, errCH
+	done := make(chan struct{})
+	r.dialing[key] = done
+	_, reopen := r.conns[key]
+	stats := r.stats(key)
+
+	r.connsMutex.Unlock()
+
+	message := "reopening one connection closed"
+	if !reopen {
+		message = "opening a new connection with rabbitMQ"
+	}
+
+	r.log.Info(message,
+		Fields{
+			"sleep":      cfgConn.Sleep,
+			"timeout":    cfgConn.Timeout,
+			"connection": key,
+		},
+	)
+
+	var attempt func(error)
+	if reopen {
+		attempt = func(err error) {
+			atomic.AddInt64(&stats.attempts, 1)
+			r.metrics.ReconnectAttempt(key, err)
+		}
+	}
+
+	conn, err := openConnection(cfgConn, fmt.Sprintf("rabbids.%s", key), attempt)
+
+	r.connsMutex.Lock()
+	delete(r.dialing, key)
+	close(done)
+
+	if err == nil {
+		r.conns[key] = conn
+		stats.opened(time.Now())
+		r.watchConnection(key, conn)
+	}
+
+	r.connsMutex.Unlock()
+
+	if err != nil {
+		return nil, fmt.Errorf("error opening the connection \"%s\": %w", key, err)
+	}
+
+	if reopen {
+		r.metrics.Reconnected(key)
+		r.hooks.reconnected(key)
+		r.emit(Event{Type: EventConnectionRestored, Connection: key})
+	}
+
+	return conn, nil
 }
 
-func openConnection(config Connection, name string) (*amqp.Connection, error) {
+// openConnection dial with rabbitMQ, retrying 5 times. The attempt function, when not nil,
+// is called after every dial with its error.
+func openConnection(config Connection, name string, attempt func(error)) (*amqp.Connection, error) {
 	var conn *amqp.Connection
 
 	id, err := uuid.NewRandom()
@@ -204,10 +763,17 @@ func openConnection(config Connection, name string) (*amqp.Connection, error) {
 				"information":     "https://github.com/EmpregoLigado/rabbids",
 				"product":         "Rabbids",
 				"version":         Version,
+				"platform":        "Go " + runtime.Version(),
+				"hostname":        hostname(),
+				"pid":             int64(os.Getpid()),
 				"id":              id.String(),
-				"connection_name": name,
+				"connection_name": connectionName(config.ConnectionName, name),
 			},
 		})
+		if attempt != nil {
+			attempt(err)
+		}
+
 		return err
 	}, 5, config.Sleep)
 
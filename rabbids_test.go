@@ -0,0 +1,101 @@
+package rabbids
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_queueConnection(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{config: &Config{
+		DeadLetters: map[string]DeadLetter{
+			"fallback": {Queue: QueueConfig{Name: "fallback-queue"}},
+		},
+		Consumers: map[string]ConsumerConfig{
+			"consumer1": {Connection: "conn1", DeadLetter: "fallback", Queue: QueueConfig{Name: "queue1"}},
+			"consumer2": {Connection: "conn2", Queue: QueueConfig{Name: "queue2"}},
+		},
+	}}
+
+	conn, ok := r.queueConnection("queue2")
+	require.True(t, ok)
+	require.Equal(t, "conn2", conn)
+
+	conn, ok = r.queueConnection("fallback-queue")
+	require.True(t, ok)
+	require.Equal(t, "conn1", conn)
+
+	_, ok = r.queueConnection("unknown")
+	require.False(t, ok)
+}
+
+func Test_consumerConnectionKey(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{config: &Config{
+		Connections: map[string]Connection{
+			"default": {ChannelsPerConnection: 2},
+			"other":   {},
+		},
+		Consumers: map[string]ConsumerConfig{
+			"a":        {Connection: "default"},
+			"b":        {Connection: "default"},
+			"c":        {Connection: "default"},
+			"critical": {Connection: "default", DedicatedConnection: true},
+			"d":        {Connection: "other"},
+		},
+	}}
+
+	tests := []struct {
+		consumer string
+		key      string
+	}{
+		{"a", "default"},
+		{"b", "default"},
+		{"c", "default.1"},
+		{"critical", "default.critical"},
+		{"d", "other"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.key, r.consumerConnectionKey(tt.consumer, r.config.Consumers[tt.consumer]), tt.consumer)
+	}
+}
+
+func TestRabbids_Drain(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	busy, d, started := drainingConsumer("busy", release)
+	defer busy.workerPool.Release()
+
+	dead := &Consumer{name: "dead", log: NoOPLogger{}}
+	dead.t.Kill(errors.New("channel closed"))
+
+	r := &Rabbids{consumers: map[string]*Consumer{"busy": busy, "dead": dead}}
+
+	d <- amqp.Delivery{}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	status, err := r.Drain(ctx)
+	require.EqualError(t, err, "failed to drain the consumers: context deadline exceeded")
+	require.True(t, r.isDraining())
+	require.Len(t, status, 2)
+	require.Equal(t, "busy", status[0].Name)
+	require.Equal(t, int64(1), status[0].InFlight)
+	require.Equal(t, "dead", status[1].Name)
+	require.False(t, status[1].Alive)
+	require.False(t, dead.isDraining(), "the dead consumers are not drained")
+
+	close(release)
+	busy.Kill()
+}
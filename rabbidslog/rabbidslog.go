@@ -0,0 +1,22 @@
+// Package rabbidslog has the adapters of the popular logging packages to the rabbids.Logger interface:
+//
+//	rab, err := rabbids.New(config, nil, rabbids.WithLeveledLogger(rabbidslog.Zap(logger)))
+package rabbidslog
+
+import (
+	"sort"
+
+	"github.com/leveeml/rabbids"
+)
+
+// sortedKeys returns the names of the fields sorted, so the fields are always logged in the same order.
+func sortedKeys(fields rabbids.Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
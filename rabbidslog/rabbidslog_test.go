@@ -0,0 +1,38 @@
+package rabbidslog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/leveeml/rabbids"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZap(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	l := Zap(zap.New(core))
+
+	l.Debug("declaring queue", rabbids.Fields{"queue": "events"})
+	l.Warn("dropping an expired message", rabbids.Fields{"consumer": "events", "message-id": "1"})
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	require.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	require.Equal(t, "dropping an expired message", entries[0].Message)
+	require.Equal(t, map[string]interface{}{"consumer": "events", "message-id": "1"}, entries[0].ContextMap())
+}
+
+func TestZerolog(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := Zerolog(zerolog.New(&buf).Level(zerolog.InfoLevel))
+
+	l.Debug("declaring queue", rabbids.Fields{"queue": "events"})
+	l.Error("failed to handle the message", rabbids.Fields{"consumer": "events"})
+
+	require.JSONEq(t, `{"level":"error","consumer":"events","message":"failed to handle the message"}`, buf.String())
+}
@@ -0,0 +1,45 @@
+//go:build go1.21
+// +build go1.21
+
+package rabbidslog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/leveeml/rabbids"
+)
+
+// Slog returns a rabbids.Logger using the log/slog logger.
+func Slog(l *slog.Logger) rabbids.Logger {
+	return slogLogger{l: l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Debug(message string, fields rabbids.Fields) {
+	s.log(slog.LevelDebug, message, fields)
+}
+
+func (s slogLogger) Info(message string, fields rabbids.Fields) {
+	s.log(slog.LevelInfo, message, fields)
+}
+
+func (s slogLogger) Warn(message string, fields rabbids.Fields) {
+	s.log(slog.LevelWarn, message, fields)
+}
+
+func (s slogLogger) Error(message string, fields rabbids.Fields) {
+	s.log(slog.LevelError, message, fields)
+}
+
+func (s slogLogger) log(level slog.Level, message string, fields rabbids.Fields) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, k := range sortedKeys(fields) {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+
+	s.l.LogAttrs(context.Background(), level, message, attrs...)
+}
@@ -0,0 +1,25 @@
+//go:build go1.21
+// +build go1.21
+
+package rabbidslog
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/leveeml/rabbids"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlog(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := Slog(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	l.Debug("declaring queue", rabbids.Fields{"queue": "events"})
+	l.Info("consumer created", rabbids.Fields{"consumer": "events", "max-workers": 2})
+
+	require.Contains(t, buf.String(), `"level":"INFO","msg":"consumer created","consumer":"events","max-workers":2}`)
+	require.NotContains(t, buf.String(), "declaring queue")
+}
@@ -0,0 +1,40 @@
+package rabbidslog
+
+import (
+	"github.com/leveeml/rabbids"
+	"go.uber.org/zap"
+)
+
+// Zap returns a rabbids.Logger using the zap logger.
+func Zap(l *zap.Logger) rabbids.Logger {
+	return zapLogger{l: l}
+}
+
+type zapLogger struct {
+	l *zap.Logger
+}
+
+func (z zapLogger) Debug(message string, fields rabbids.Fields) {
+	z.l.Debug(message, zapFields(fields)...)
+}
+
+func (z zapLogger) Info(message string, fields rabbids.Fields) {
+	z.l.Info(message, zapFields(fields)...)
+}
+
+func (z zapLogger) Warn(message string, fields rabbids.Fields) {
+	z.l.Warn(message, zapFields(fields)...)
+}
+
+func (z zapLogger) Error(message string, fields rabbids.Fields) {
+	z.l.Error(message, zapFields(fields)...)
+}
+
+func zapFields(fields rabbids.Fields) []zap.Field {
+	zf := make([]zap.Field, 0, len(fields))
+	for _, k := range sortedKeys(fields) {
+		zf = append(zf, zap.Any(k, fields[k]))
+	}
+
+	return zf
+}
@@ -0,0 +1,35 @@
+package rabbidslog
+
+import (
+	"github.com/leveeml/rabbids"
+	"github.com/rs/zerolog"
+)
+
+// Zerolog returns a rabbids.Logger using the zerolog logger.
+func Zerolog(l zerolog.Logger) rabbids.Logger {
+	return zeroLogger{l: l}
+}
+
+type zeroLogger struct {
+	l zerolog.Logger
+}
+
+func (z zeroLogger) Debug(message string, fields rabbids.Fields) {
+	logEvent(z.l.Debug(), message, fields)
+}
+
+func (z zeroLogger) Info(message string, fields rabbids.Fields) {
+	logEvent(z.l.Info(), message, fields)
+}
+
+func (z zeroLogger) Warn(message string, fields rabbids.Fields) {
+	logEvent(z.l.Warn(), message, fields)
+}
+
+func (z zeroLogger) Error(message string, fields rabbids.Fields) {
+	logEvent(z.l.Error(), message, fields)
+}
+
+func logEvent(e *zerolog.Event, message string, fields rabbids.Fields) {
+	e.Fields(map[string]interface{}(fields)).Msg(message)
+}
@@ -0,0 +1,316 @@
+package rabbidsotel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/leveeml/rabbids"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+)
+
+const (
+	// ExchangeKey is the attribute with the exchange of the messages published.
+	ExchangeKey = attribute.Key("rabbids.exchange")
+	// StatusKey is the attribute with the status of the messages published, success or error.
+	StatusKey = attribute.Key("rabbids.status")
+	// ConnectionKey is the attribute with the name of the connection opened again.
+	ConnectionKey = attribute.Key("rabbids.connection")
+	// QueueKey is the attribute with the name of the delay level or dead letter queue.
+	QueueKey = attribute.Key("rabbids.queue")
+	// RoutingKeyKey is the attribute with the routing key of the messages handled, see RoutingKeyHandled.
+	RoutingKeyKey = attribute.Key("rabbids.routing_key")
+)
+
+// MetricsOption represents an option function to change the Metrics on creation time.
+type MetricsOption func(*Metrics)
+
+// WithMeterProvider set the provider used to create the meter, the global provider is used by default.
+func WithMeterProvider(provider metric.MeterProvider) MetricsOption {
+	return func(m *Metrics) {
+		m.provider = provider
+	}
+}
+
+// WithMetrics returns the rabbids option recording the metrics of the client with OpenTelemetry,
+// the same metrics exported by the rabbids/metrics package to prometheus.
+func WithMetrics(opts ...MetricsOption) rabbids.Option {
+	return func(r *rabbids.Rabbids) error {
+		m, err := NewMetrics(opts...)
+		if err != nil {
+			return err
+		}
+
+		return rabbids.WithMetrics(m)(r)
+	}
+}
+
+// Metrics implements the rabbids.Metrics interface using the OpenTelemetry metrics API.
+// The counters and histograms are recorded when the events happen and the state reported by the
+// supervisor, like the consumers aliveness, is observed by gauges on every collection.
+type Metrics struct {
+	provider        metric.MeterProvider
+	published       metric.Int64Counter
+	handled         metric.Int64Counter
+	byRoutingKey    metric.Int64Counter
+	handlerDuration metric.Float64Histogram
+	expired         metric.Int64Counter
+	reconnects      metric.Int64Counter
+	attempts        metric.Int64Counter
+	restarts        metric.Int64Counter
+	recovery        metric.Float64Histogram
+	lag             metric.Float64Histogram
+	gauges          map[string]metric.Int64GaugeObserver
+	mutex           sync.Mutex
+	values          map[gaugeValue]int64
+}
+
+// gaugeValue identify the last value reported to one gauge.
+type gaugeValue struct {
+	gauge string
+	attr  attribute.KeyValue
+}
+
+const (
+	gaugeConsumerUp       = "rabbids.consumer.up"
+	gaugeSaturated        = "rabbids.consumer.saturated"
+	gaugeStuck            = "rabbids.consumer.stuck"
+	gaugeInFlight         = "rabbids.consumer.messages_in_flight"
+	gaugeWorkers          = "rabbids.consumer.workers"
+	gaugeProducerUp       = "rabbids.producer.up"
+	gaugeBufferPending    = "rabbids.producer.buffer_pending"
+	gaugeBufferCapacity   = "rabbids.producer.buffer_capacity"
+	gaugeDelayBacklog     = "rabbids.delay.backlog_messages"
+	gaugeDeadLetters      = "rabbids.dead_letter.messages"
+	gaugeConnectionUp     = "rabbids.connection.up"
+	gaugeSinceReconnect   = "rabbids.connection.seconds_since_reconnect"
+	gaugeChannels         = "rabbids.connection.channels"
+	statusSuccess         = "success"
+	statusError           = "error"
+	secondsUnit           = "s"
+	defaultInstrumentUnit = "1"
+)
+
+var gaugeDescriptions = map[string]string{
+	gaugeConsumerUp:     "1 when the consumer is alive.",
+	gaugeSaturated:      "1 when all the workers of the consumer are busy for longer than the backpressure threshold.",
+	gaugeStuck:          "1 when the consumer has messages in flight and no progress for longer than the watchdog threshold.",
+	gaugeInFlight:       "Messages being handled by the consumer workers.",
+	gaugeWorkers:        "Number of workers of the consumer.",
+	gaugeProducerUp:     "1 when the producer connection is open.",
+	gaugeBufferPending:  "Messages waiting inside the Emit channel of the producer.",
+	gaugeBufferCapacity: "Capacity of the Emit channel of the producer.",
+	gaugeDelayBacklog:   "Messages inside each delay level queue.",
+	gaugeDeadLetters:    "Messages inside each dead letter queue.",
+	gaugeConnectionUp:   "1 when the connection is open, 0 while reconnecting.",
+	gaugeSinceReconnect: "Seconds since the connection was opened, or opened again after being lost.",
+	gaugeChannels:       "Channels open on the connection.",
+}
+
+// NewMetrics create the instruments of all the rabbids metrics.
+func NewMetrics(opts ...MetricsOption) (*Metrics, error) {
+	m := &Metrics{
+		provider: global.GetMeterProvider(),
+		gauges:   map[string]metric.Int64GaugeObserver{},
+		values:   map[gaugeValue]int64{},
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	meter := m.provider.Meter(instrumentationName)
+	counters := []struct {
+		target      *metric.Int64Counter
+		name        string
+		description string
+	}{
+		{&m.published, "rabbids.messages.published", "Messages published by the producers, by status."},
+		{&m.handled, "rabbids.messages.handled", "Messages handled by the consumers, by outcome."},
+		{
+			&m.byRoutingKey, "rabbids.messages.handled_by_routing_key",
+			"Messages handled by the consumers with routing_key_metrics, by routing key and outcome.",
+		},
+		{&m.expired, "rabbids.messages.expired", "Expired messages dropped by the consumers."},
+		{&m.reconnects, "rabbids.reconnects", "Connections opened again after being lost."},
+		{&m.attempts, "rabbids.reconnect.attempts", "Dials made to open the connections again after being lost, by status."},
+		{&m.restarts, "rabbids.consumer.restarts", "Dead consumers recreated by the supervisor."},
+	}
+
+	for _, c := range counters {
+		counter, err := meter.NewInt64Counter(c.name,
+			metric.WithDescription(c.description), metric.WithUnit(defaultInstrumentUnit))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the %s counter: %w", c.name, err)
+		}
+
+		*c.target = counter
+	}
+
+	histograms := []struct {
+		target      *metric.Float64Histogram
+		name        string
+		description string
+	}{
+		{&m.handlerDuration, "rabbids.handler.duration", "Time spent inside the consumer handlers."},
+		{&m.recovery, "rabbids.consumer.recovery", "Time since the consumer was found dead until it was recreated."},
+		{&m.lag, "rabbids.consumer.lag", "Age of the messages received by the consumers, using the message timestamp."},
+	}
+
+	for _, h := range histograms {
+		histogram, err := meter.NewFloat64Histogram(h.name,
+			metric.WithDescription(h.description), metric.WithUnit(secondsUnit))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the %s histogram: %w", h.name, err)
+		}
+
+		*h.target = histogram
+	}
+
+	batch := meter.NewBatchObserver(m.observe)
+
+	for name, description := range gaugeDescriptions {
+		gauge, err := batch.NewInt64GaugeObserver(name,
+			metric.WithDescription(description), metric.WithUnit(defaultInstrumentUnit))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the %s gauge: %w", name, err)
+		}
+
+		m.gauges[name] = gauge
+	}
+
+	return m, nil
+}
+
+// observe report the last value of all the gauges.
+func (m *Metrics) observe(_ context.Context, result metric.BatchObserverResult) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for v, value := range m.values {
+		result.Observe([]attribute.KeyValue{v.attr}, m.gauges[v.gauge].Observation(value))
+	}
+}
+
+func (m *Metrics) set(gauge string, attr attribute.KeyValue, value int64) {
+	m.mutex.Lock()
+	m.values[gaugeValue{gauge: gauge, attr: attr}] = value
+	m.mutex.Unlock()
+}
+
+// MessagePublished implements rabbids.Metrics.
+func (m *Metrics) MessagePublished(producer, exchange string, err error) {
+	status := statusSuccess
+	if err != nil {
+		status = statusError
+	}
+
+	m.published.Add(context.Background(), 1,
+		ProducerKey.String(producer), ExchangeKey.String(exchange), StatusKey.String(status))
+}
+
+// MessageHandled implements rabbids.Metrics.
+func (m *Metrics) MessageHandled(consumer string, outcome rabbids.Outcome, duration time.Duration) {
+	ctx := context.Background()
+	m.handled.Add(ctx, 1, ConsumerKey.String(consumer), OutcomeKey.String(string(outcome)))
+	m.handlerDuration.Record(ctx, duration.Seconds(), ConsumerKey.String(consumer))
+}
+
+// MessageExpired implements rabbids.Metrics.
+func (m *Metrics) MessageExpired(consumer string) {
+	m.expired.Add(context.Background(), 1, ConsumerKey.String(consumer))
+}
+
+// Reconnected implements rabbids.Metrics.
+func (m *Metrics) Reconnected(connection string) {
+	m.reconnects.Add(context.Background(), 1, ConnectionKey.String(connection))
+}
+
+// ConsumerRestarted implements rabbids.Metrics.
+func (m *Metrics) ConsumerRestarted(consumer string, timeToRecover time.Duration) {
+	ctx := context.Background()
+	m.restarts.Add(ctx, 1, ConsumerKey.String(consumer))
+	m.recovery.Record(ctx, timeToRecover.Seconds(), ConsumerKey.String(consumer))
+}
+
+// ConsumerAlive implements rabbids.Metrics.
+func (m *Metrics) ConsumerAlive(consumer string, alive bool) {
+	m.set(gaugeConsumerUp, ConsumerKey.String(consumer), boolToInt(alive))
+}
+
+// ConsumerSaturated implements rabbids.Metrics.
+func (m *Metrics) ConsumerSaturated(consumer string, saturated bool) {
+	m.set(gaugeSaturated, ConsumerKey.String(consumer), boolToInt(saturated))
+}
+
+// ConsumerStuck implements rabbids.Metrics.
+func (m *Metrics) ConsumerStuck(consumer string, stuck bool) {
+	m.set(gaugeStuck, ConsumerKey.String(consumer), boolToInt(stuck))
+}
+
+// ConsumerWorkers implements rabbids.Metrics.
+func (m *Metrics) ConsumerWorkers(consumer string, inFlight, workers int) {
+	m.set(gaugeInFlight, ConsumerKey.String(consumer), int64(inFlight))
+	m.set(gaugeWorkers, ConsumerKey.String(consumer), int64(workers))
+}
+
+// ProducerAlive implements rabbids.Metrics.
+func (m *Metrics) ProducerAlive(producer string, alive bool) {
+	m.set(gaugeProducerUp, ProducerKey.String(producer), boolToInt(alive))
+}
+
+// ProducerBuffer implements rabbids.Metrics.
+func (m *Metrics) ProducerBuffer(producer string, pending, capacity int) {
+	m.set(gaugeBufferPending, ProducerKey.String(producer), int64(pending))
+	m.set(gaugeBufferCapacity, ProducerKey.String(producer), int64(capacity))
+}
+
+// DelayBacklog implements rabbids.Metrics.
+func (m *Metrics) DelayBacklog(queue string, messages int) {
+	m.set(gaugeDelayBacklog, QueueKey.String(queue), int64(messages))
+}
+
+// DeadLetterDepth implements rabbids.Metrics.
+func (m *Metrics) DeadLetterDepth(queue string, messages int) {
+	m.set(gaugeDeadLetters, QueueKey.String(queue), int64(messages))
+}
+
+// ReconnectAttempt implements rabbids.Metrics.
+func (m *Metrics) ReconnectAttempt(connection string, err error) {
+	status := statusSuccess
+	if err != nil {
+		status = statusError
+	}
+
+	m.attempts.Add(context.Background(), 1, ConnectionKey.String(connection), StatusKey.String(status))
+}
+
+// ConnectionState implements rabbids.Metrics.
+func (m *Metrics) ConnectionState(connection string, connected bool, sinceReconnect time.Duration, channels int) {
+	attr := ConnectionKey.String(connection)
+	m.set(gaugeConnectionUp, attr, boolToInt(connected))
+	m.set(gaugeSinceReconnect, attr, int64(sinceReconnect.Seconds()))
+	m.set(gaugeChannels, attr, int64(channels))
+}
+
+// MessageLag implements rabbids.Metrics.
+func (m *Metrics) MessageLag(consumer string, lag time.Duration) {
+	m.lag.Record(context.Background(), lag.Seconds(), ConsumerKey.String(consumer))
+}
+
+// RoutingKeyHandled implements rabbids.Metrics.
+func (m *Metrics) RoutingKeyHandled(consumer, routingKey string, outcome rabbids.Outcome) {
+	m.byRoutingKey.Add(context.Background(), 1,
+		ConsumerKey.String(consumer), RoutingKeyKey.String(routingKey), OutcomeKey.String(string(outcome)))
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
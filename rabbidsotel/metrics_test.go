@@ -0,0 +1,57 @@
+package rabbidsotel
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leveeml/rabbids"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/metrictest"
+)
+
+var _ rabbids.Metrics = &Metrics{}
+
+func TestMetrics(t *testing.T) {
+	provider := metrictest.NewMeterProvider()
+	m, err := NewMetrics(WithMeterProvider(provider))
+	require.NoError(t, err)
+
+	m.MessagePublished("producer", "events", nil)
+	m.MessagePublished("producer", "events", errors.New("closed"))
+	m.MessageHandled("consumer", rabbids.OutcomeAck, 20*time.Millisecond)
+	m.MessageLag("consumer", 1500*time.Millisecond)
+	m.RoutingKeyHandled("consumer", "user.created", rabbids.OutcomeNack)
+	m.ConsumerWorkers("consumer", 3, 5)
+	m.ConsumerAlive("consumer", true)
+	m.ConsumerAlive("consumer", false)
+
+	provider.RunAsyncInstruments()
+
+	got := map[string][]metrictest.Measured{}
+	for _, measured := range metrictest.AsStructs(provider.MeasurementBatches) {
+		got[measured.Name] = append(got[measured.Name], measured)
+	}
+
+	published := got["rabbids.messages.published"]
+	require.Len(t, published, 2)
+	require.Equal(t, attribute.StringValue("success"), published[0].Labels[StatusKey])
+	require.Equal(t, attribute.StringValue("error"), published[1].Labels[StatusKey])
+
+	require.Len(t, got["rabbids.messages.handled"], 1)
+	require.Equal(t, attribute.StringValue("ack"), got["rabbids.messages.handled"][0].Labels[OutcomeKey])
+	require.Len(t, got["rabbids.handler.duration"], 1)
+	require.InDelta(t, 0.02, got["rabbids.handler.duration"][0].Number.AsFloat64(), 0.0001)
+	require.Len(t, got["rabbids.consumer.lag"], 1)
+	require.InDelta(t, 1.5, got["rabbids.consumer.lag"][0].Number.AsFloat64(), 0.0001)
+	require.Len(t, got["rabbids.messages.handled_by_routing_key"], 1)
+	require.Equal(t, attribute.StringValue("user.created"),
+		got["rabbids.messages.handled_by_routing_key"][0].Labels[RoutingKeyKey])
+
+	require.Len(t, got[gaugeInFlight], 1)
+	require.Equal(t, int64(3), got[gaugeInFlight][0].Number.AsInt64())
+	require.Equal(t, int64(5), got[gaugeWorkers][0].Number.AsInt64())
+	require.Len(t, got[gaugeConsumerUp], 1)
+	require.Equal(t, int64(0), got[gaugeConsumerUp][0].Number.AsInt64())
+}
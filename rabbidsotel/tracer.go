@@ -0,0 +1,183 @@
+// Package rabbidsotel instruments the rabbids producers and consumers with OpenTelemetry tracing,
+// following the messaging semantic conventions, and records the rabbids metrics with the OpenTelemetry metrics API.
+//
+// The producers start one span for each message published and inject the span context inside the
+// message headers, the consumers extract it and start a child span around the handler execution:
+//
+//	rab, err := rabbids.New(config, logger, rabbidsotel.WithTracing(), rabbidsotel.WithMetrics())
+package rabbidsotel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leveeml/rabbids"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/leveeml/rabbids/rabbidsotel"
+
+	// ProducerKey is the attribute with the name of the rabbids producer.
+	ProducerKey = attribute.Key("rabbids.producer")
+	// ConsumerKey is the attribute with the name of the rabbids consumer.
+	ConsumerKey = attribute.Key("rabbids.consumer")
+	// OutcomeKey is the attribute with the outcome of the message handled by the consumer.
+	OutcomeKey = attribute.Key("rabbids.outcome")
+)
+
+// Option represents an option function to change the Tracer on creation time.
+type Option func(*Tracer)
+
+// WithTracerProvider set the provider used to create the tracer, the global provider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(t *Tracer) {
+		t.provider = provider
+	}
+}
+
+// WithPropagator set the propagator used to send the span context inside the message headers,
+// the global propagator is used by default.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(t *Tracer) {
+		t.propagator = propagator
+	}
+}
+
+// WithTracing returns the rabbids option tracing all the producers and consumers created by the client.
+func WithTracing(opts ...Option) rabbids.Option {
+	return rabbids.WithTracer(NewTracer(opts...))
+}
+
+// Tracer implements the rabbids.Tracer interface using OpenTelemetry.
+type Tracer struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	tracer     trace.Tracer
+}
+
+// NewTracer create a Tracer, use rabbids.WithProducerTracer to trace the producers created with NewProducer.
+func NewTracer(opts ...Option) *Tracer {
+	t := &Tracer{
+		provider:   otel.GetTracerProvider(),
+		propagator: otel.GetTextMapPropagator(),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	t.tracer = t.provider.Tracer(instrumentationName)
+
+	return t
+}
+
+// StartPublish start a producer span, child of the Publishing context, and inject it inside the message headers.
+func (t *Tracer) StartPublish(ctx context.Context, producer string, p *rabbids.Publishing) func(err error) {
+	attrs := append(messageAttributes(p.Exchange, p.Key, p.MessageId, p.CorrelationId, len(p.Body)),
+		ProducerKey.String(producer))
+
+	ctx, span := t.tracer.Start(ctx, spanName(p.Exchange, p.Key, "send"),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+	)
+
+	if p.Headers == nil {
+		p.Headers = amqp.Table{}
+	}
+
+	t.propagator.Inject(ctx, headersCarrier(p.Headers))
+
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}
+}
+
+// StartHandle start a consumer span, child of the span context extracted from the message headers.
+func (t *Tracer) StartHandle(consumer string, m rabbids.Message) (context.Context, func(rabbids.Outcome, error)) {
+	ctx := t.propagator.Extract(m.Context(), headersCarrier(m.Headers))
+	attrs := append(messageAttributes(m.Exchange, m.RoutingKey, m.MessageId, m.CorrelationId, len(m.Body)),
+		semconv.MessagingOperationProcess,
+		ConsumerKey.String(consumer))
+
+	ctx, span := t.tracer.Start(ctx, spanName(m.Exchange, m.RoutingKey, "process"),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	)
+
+	return ctx, func(outcome rabbids.Outcome, err error) {
+		span.SetAttributes(OutcomeKey.String(string(outcome)))
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}
+}
+
+func messageAttributes(exchange, key, id, correlationID string, size int) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKey.String("rabbitmq"),
+		semconv.MessagingProtocolKey.String("AMQP"),
+		semconv.MessagingProtocolVersionKey.String("0.9.1"),
+		semconv.MessagingDestinationKindTopic,
+		semconv.MessagingDestinationKey.String(exchange),
+		semconv.MessagingRabbitmqRoutingKeyKey.String(key),
+		semconv.MessagingMessagePayloadSizeBytesKey.Int(size),
+	}
+
+	if id != "" {
+		attrs = append(attrs, semconv.MessagingMessageIDKey.String(id))
+	}
+
+	if correlationID != "" {
+		attrs = append(attrs, semconv.MessagingConversationIDKey.String(correlationID))
+	}
+
+	return attrs
+}
+
+// spanName returns the "<destination> <operation>" name, the messages sent with the default exchange
+// are routed to the queue with the routing key name, so the key is the destination.
+func spanName(exchange, key, operation string) string {
+	if exchange == "" {
+		return fmt.Sprintf("%s %s", key, operation)
+	}
+
+	return fmt.Sprintf("%s %s", exchange, operation)
+}
+
+// headersCarrier adapts the message headers to the propagation.TextMapCarrier interface.
+type headersCarrier amqp.Table
+
+func (c headersCarrier) Get(key string) string {
+	v, _ := c[key].(string)
+
+	return v
+}
+
+func (c headersCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c headersCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
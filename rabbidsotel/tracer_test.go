@@ -0,0 +1,64 @@
+package rabbidsotel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leveeml/rabbids"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ rabbids.Tracer = &Tracer{}
+
+func TestTracerPropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := NewTracer(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+		WithPropagator(propagation.TraceContext{}),
+	)
+
+	pub := rabbids.NewPublishing("events", "user.created", nil)
+	pub.Headers = nil
+	pub.CorrelationId = "123"
+
+	finish := tracer.StartPublish(context.Background(), "producer", &pub)
+	finish(nil)
+	require.Contains(t, pub.Headers, "traceparent")
+
+	m := rabbids.Message{Delivery: amqp.Delivery{
+		Exchange:   pub.Exchange,
+		RoutingKey: pub.Key,
+		MessageId:  pub.MessageId,
+		Headers:    pub.Headers,
+	}}
+
+	ctx, done := tracer.StartHandle("consumer", m)
+	require.True(t, trace.SpanContextFromContext(ctx).IsValid())
+	done(rabbids.OutcomeNack, errors.New("failed"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	send, process := spans[0], spans[1]
+	require.Equal(t, "events send", send.Name())
+	require.Equal(t, trace.SpanKindProducer, send.SpanKind())
+	require.Contains(t, send.Attributes(), ProducerKey.String("producer"))
+	require.Equal(t, "events process", process.Name())
+	require.Equal(t, trace.SpanKindConsumer, process.SpanKind())
+	require.Equal(t, send.SpanContext().TraceID(), process.SpanContext().TraceID())
+	require.Equal(t, send.SpanContext().SpanID(), process.Parent().SpanID())
+	require.Contains(t, process.Attributes(), OutcomeKey.String("nack"))
+	require.Equal(t, codes.Error, process.Status().Code)
+}
+
+func TestSpanName(t *testing.T) {
+	require.Equal(t, "events send", spanName("events", "user.created", "send"))
+	require.Equal(t, "replies send", spanName("", "replies", "send"))
+}
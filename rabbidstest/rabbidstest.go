@@ -0,0 +1,160 @@
+// Package rabbidstest provides utilities to test the rabbids MessageHandlers
+// without a running RabbitMQ.
+package rabbidstest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/leveeml/rabbids"
+	"github.com/streadway/amqp"
+)
+
+// MessageOption is used to change the delivery used by the Message created with NewMessage.
+type MessageOption func(*amqp.Delivery)
+
+// WithHeaders set the message headers.
+func WithHeaders(headers amqp.Table) MessageOption {
+	return func(d *amqp.Delivery) {
+		d.Headers = headers
+	}
+}
+
+// WithContentType set the message content type.
+func WithContentType(contentType string) MessageOption {
+	return func(d *amqp.Delivery) {
+		d.ContentType = contentType
+	}
+}
+
+// WithRouting set the exchange and routing key used to publish the message.
+func WithRouting(exchange, key string) MessageOption {
+	return func(d *amqp.Delivery) {
+		d.Exchange = exchange
+		d.RoutingKey = key
+	}
+}
+
+// WithMessageID set the message id.
+func WithMessageID(id string) MessageOption {
+	return func(d *amqp.Delivery) {
+		d.MessageId = id
+	}
+}
+
+// WithTimestamp set the message timestamp.
+func WithTimestamp(timestamp time.Time) MessageOption {
+	return func(d *amqp.Delivery) {
+		d.Timestamp = timestamp
+	}
+}
+
+// WithRedelivered mark the message as redelivered by the broker.
+func WithRedelivered() MessageOption {
+	return func(d *amqp.Delivery) {
+		d.Redelivered = true
+	}
+}
+
+// WithDelivery change any other field of the delivery.
+func WithDelivery(fn func(*amqp.Delivery)) MessageOption {
+	return fn
+}
+
+// NewMessage create a Message with the body, like the ones received by the consumers, and
+// return the Acknowledger used to check the Ack, Nack and Reject calls made by the handler.
+func NewMessage(body []byte, opts ...MessageOption) (rabbids.Message, *Acknowledger) {
+	ack := &Acknowledger{}
+	d := amqp.Delivery{
+		Acknowledger: ack,
+		Body:         body,
+		Headers:      amqp.Table{},
+		DeliveryTag:  1,
+		Timestamp:    time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return rabbids.Message{Delivery: d}, ack
+}
+
+// Call is one call made to the Acknowledger.
+type Call struct {
+	Method   string
+	Tag      uint64
+	Multiple bool
+	Requeue  bool
+}
+
+// Acknowledger is an amqp.Acknowledger stub recording every call.
+// It is safe for concurrent use.
+type Acknowledger struct {
+	mutex sync.Mutex
+	calls []Call
+	// Err is returned by all the methods when not nil.
+	Err error
+}
+
+func (a *Acknowledger) Ack(tag uint64, multiple bool) error {
+	return a.record(Call{Method: "Ack", Tag: tag, Multiple: multiple})
+}
+
+func (a *Acknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	return a.record(Call{Method: "Nack", Tag: tag, Multiple: multiple, Requeue: requeue})
+}
+
+func (a *Acknowledger) Reject(tag uint64, requeue bool) error {
+	return a.record(Call{Method: "Reject", Tag: tag, Requeue: requeue})
+}
+
+func (a *Acknowledger) record(c Call) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.calls = append(a.calls, c)
+
+	return a.Err
+}
+
+// Calls return all the calls made in order.
+func (a *Acknowledger) Calls() []Call {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return append([]Call(nil), a.calls...)
+}
+
+// Acked return true when the message was acked.
+func (a *Acknowledger) Acked() bool {
+	return a.has(func(c Call) bool { return c.Method == "Ack" })
+}
+
+// Nacked return true when the message was rejected by Nack or Reject.
+func (a *Acknowledger) Nacked() bool {
+	return a.has(func(c Call) bool { return c.Method == "Nack" || c.Method == "Reject" })
+}
+
+// Requeued return true when the message was rejected with requeue.
+func (a *Acknowledger) Requeued() bool {
+	return a.has(func(c Call) bool { return c.Method != "Ack" && c.Requeue })
+}
+
+// Settled return true when the handler made any call to the Acknowledger.
+func (a *Acknowledger) Settled() bool {
+	return a.has(func(Call) bool { return true })
+}
+
+func (a *Acknowledger) has(fn func(Call) bool) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, c := range a.calls {
+		if fn(c) {
+			return true
+		}
+	}
+
+	return false
+}
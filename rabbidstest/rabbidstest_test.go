@@ -0,0 +1,52 @@
+package rabbidstest_test
+
+import (
+	"testing"
+
+	"github.com/leveeml/rabbids"
+	"github.com/leveeml/rabbids/rabbidstest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMessage(t *testing.T) {
+	t.Parallel()
+
+	handler := rabbids.MessageHandlerFunc(func(m rabbids.Message) {
+		var v struct {
+			Requeue bool `json:"requeue"`
+		}
+
+		if err := m.Decode(&v); err != nil || v.Requeue {
+			_ = m.Nack(false, true)
+			return
+		}
+
+		_ = m.Ack(false)
+	})
+
+	tests := []struct {
+		name     string
+		body     string
+		acked    bool
+		requeued bool
+	}{
+		{"acked message", `{"requeue": false}`, true, false},
+		{"requeued message", `{"requeue": true}`, false, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, ack := rabbidstest.NewMessage([]byte(tt.body), rabbidstest.WithContentType("application/json"))
+			handler.Handle(m)
+
+			require.True(t, ack.Settled())
+			require.Equal(t, tt.acked, ack.Acked())
+			require.Equal(t, tt.requeued, ack.Requeued())
+			require.Equal(t, tt.requeued, ack.Nacked())
+			require.Len(t, ack.Calls(), 1)
+		})
+	}
+}
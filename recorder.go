@@ -0,0 +1,137 @@
+package rabbids
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The directions of the messages recorded by the MessageRecorder.
+const (
+	DirectionPublished = "published"
+	DirectionConsumed  = "consumed"
+)
+
+// RecordedMessage is one message kept by the MessageRecorder, the body is truncated to the max body size.
+type RecordedMessage struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	// Name of the producer or consumer.
+	Name          string                 `json:"name"`
+	Exchange      string                 `json:"exchange"`
+	RoutingKey    string                 `json:"routing_key"`
+	MessageID     string                 `json:"message_id,omitempty"`
+	CorrelationID string                 `json:"correlation_id,omitempty"`
+	ContentType   string                 `json:"content_type,omitempty"`
+	Headers       map[string]interface{} `json:"headers,omitempty"`
+	Body          string                 `json:"body"`
+	BodySize      int                    `json:"body_size"`
+	Truncated     bool                   `json:"truncated,omitempty"`
+	// Error is the publishing error, when the message failed to be published.
+	Error string `json:"error,omitempty"`
+}
+
+// MessageRecorder keep the last messages published and consumed in memory, for debugging in production
+// without enabling the broker firehose tracer. Register it with WithHooks(recorder.Hooks()) and
+// expose it in an admin port, it implements the http.Handler interface returning the messages as JSON.
+type MessageRecorder struct {
+	mutex    sync.Mutex
+	messages []RecordedMessage
+	next     int
+	full     bool
+	maxBody  int
+	now      func() time.Time
+}
+
+// NewMessageRecorder create a recorder keeping the last size messages, with the body truncated to maxBody bytes.
+func NewMessageRecorder(size, maxBody int) *MessageRecorder {
+	if size < 1 {
+		size = 1
+	}
+
+	return &MessageRecorder{messages: make([]RecordedMessage, size), maxBody: maxBody, now: time.Now}
+}
+
+// Hooks returns the hooks recording the messages published and consumed.
+func (r *MessageRecorder) Hooks() Hooks {
+	return Hooks{
+		OnPublish: func(producer string, p Publishing) {
+			r.record(r.published(producer, p))
+		},
+		OnPublishError: func(producer string, p Publishing, err error) {
+			m := r.published(producer, p)
+			m.Error = err.Error()
+			r.record(m)
+		},
+		OnConsume: func(consumer string, m Message) {
+			r.record(r.recorded(DirectionConsumed, consumer, m.Exchange, m.RoutingKey, m.Headers, m.Body, RecordedMessage{
+				MessageID:     m.MessageId,
+				CorrelationID: m.CorrelationId,
+				ContentType:   m.ContentType,
+			}))
+		},
+	}
+}
+
+func (r *MessageRecorder) published(producer string, p Publishing) RecordedMessage {
+	return r.recorded(DirectionPublished, producer, p.Exchange, p.Key, p.Headers, p.Body, RecordedMessage{
+		MessageID:     p.MessageId,
+		CorrelationID: p.CorrelationId,
+		ContentType:   p.ContentType,
+	})
+}
+
+func (r *MessageRecorder) recorded(direction, name, exchange, key string, headers map[string]interface{},
+	body []byte, m RecordedMessage) RecordedMessage {
+	m.Time = r.now()
+	m.Direction = direction
+	m.Name = name
+	m.Exchange = exchange
+	m.RoutingKey = key
+	m.BodySize = len(body)
+
+	if len(headers) > 0 {
+		m.Headers = make(map[string]interface{}, len(headers))
+		for k, v := range headers {
+			m.Headers[k] = v
+		}
+	}
+
+	if len(body) > r.maxBody {
+		body = body[:r.maxBody]
+		m.Truncated = true
+	}
+
+	m.Body = string(body)
+
+	return m
+}
+
+func (r *MessageRecorder) record(m RecordedMessage) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.messages[r.next] = m
+	r.next = (r.next + 1) % len(r.messages)
+	r.full = r.full || r.next == 0
+}
+
+// Messages returns the messages recorded, the oldest first.
+func (r *MessageRecorder) Messages() []RecordedMessage {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.full {
+		return append([]RecordedMessage{}, r.messages[:r.next]...)
+	}
+
+	return append(append([]RecordedMessage{}, r.messages[r.next:]...), r.messages[:r.next]...)
+}
+
+// ServeHTTP returns the messages recorded as JSON.
+func (r *MessageRecorder) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(r.Messages())
+}
@@ -0,0 +1,53 @@
+package rabbids
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageRecorder(t *testing.T) {
+	t.Parallel()
+
+	r := NewMessageRecorder(2, 5)
+	hooks := r.Hooks()
+
+	pub := NewPublishing("events", "user.created", nil)
+	pub.Body = []byte(`{"name":"foo"}`)
+
+	hooks.OnPublish("producer", pub)
+	hooks.OnPublishError("producer", pub, errors.New("connection closed"))
+	hooks.OnConsume("consumer", Message{Delivery: amqp.Delivery{
+		Exchange:   "events",
+		RoutingKey: "user.created",
+		MessageId:  pub.MessageId,
+		Body:       []byte("ok"),
+	}})
+
+	messages := r.Messages()
+	require.Len(t, messages, 2, "only the last messages are kept")
+
+	require.Equal(t, DirectionPublished, messages[0].Direction)
+	require.Equal(t, "connection closed", messages[0].Error)
+	require.Equal(t, `{"nam`, messages[0].Body)
+	require.Equal(t, 14, messages[0].BodySize)
+	require.True(t, messages[0].Truncated)
+
+	require.Equal(t, DirectionConsumed, messages[1].Direction)
+	require.Equal(t, "consumer", messages[1].Name)
+	require.Equal(t, "ok", messages[1].Body)
+	require.False(t, messages[1].Truncated)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/rabbids/messages", nil))
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var got []RecordedMessage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 2)
+	require.Equal(t, "user.created", got[1].RoutingKey)
+}
@@ -0,0 +1,69 @@
+package rabbids
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// minChannelRecoveryInterval is the min time between two channel recoveries of one consumer,
+// a channel closed again in less time is handled by the supervisor recreating the consumer.
+const minChannelRecoveryInterval = time.Second
+
+// canRecoverChannel returns true when only the consumer channel was closed by the broker
+// with a soft error (like a failed declare or the consumer timeout) and the connection is still usable.
+func (c *Consumer) canRecoverChannel(err error) bool {
+	var amqpErr *amqp.Error
+	if !errors.As(err, &amqpErr) || amqpErr == nil || !amqpErr.Recover {
+		return false
+	}
+
+	if c.redeclare == nil || c.isDraining() || !c.t.Alive() {
+		return false
+	}
+
+	return time.Since(c.lastRecovery) >= minChannelRecoveryInterval
+}
+
+// recoverChannel open a new channel for the consumer, without recreating the consumer or the connection,
+// and start consuming again. The messages in flight are requeued by the broker.
+func (c *Consumer) recoverChannel(cause error) (<-chan amqp.Delivery, <-chan *amqp.Error, error) {
+	c.lastRecovery = time.Now()
+	c.log.Warn("the consumer channel was closed, opening a new channel", Fields{"consumer": c.name, "error": cause})
+
+	// the batched acks use the delivery tags of the closed channel,
+	// so the batcher is reset only after all the messages in flight are handled.
+	if c.acks != nil {
+		c.workerPool.WaitAll()
+	}
+
+	ch, err := c.redeclare()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to recover the channel closed by %s: %w", cause, err)
+	}
+
+	c.setChannel(ch)
+
+	if c.acks != nil {
+		c.acks.reset(ch)
+	}
+
+	// stream consumers resume after the last checkpoint, not from the configured offset.
+	c.checkpoint()
+
+	args, err := c.consumeArgs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d, closed, err := c.subscribe(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.emit(Event{Type: EventChannelRecovered, Consumer: c.name, Queue: c.queue, Err: cause})
+
+	return d, closed, nil
+}
@@ -0,0 +1,39 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumer_canRecoverChannel(t *testing.T) {
+	t.Parallel()
+
+	redeclare := func() (*amqp.Channel, error) { return nil, nil }
+	soft := &amqp.Error{Code: amqp.PreconditionFailed, Recover: true}
+
+	tests := []struct {
+		name     string
+		consumer *Consumer
+		err      error
+		expected bool
+	}{
+		{"soft channel error", &Consumer{redeclare: redeclare}, soft, true},
+		{"channel closed by the client", &Consumer{redeclare: redeclare}, (*amqp.Error)(nil), false},
+		{"hard connection error", &Consumer{redeclare: redeclare}, &amqp.Error{Code: amqp.ConnectionForced}, false},
+		{"other errors", &Consumer{redeclare: redeclare}, errors.New("internal channel closed"), false},
+		{"without redeclare", &Consumer{}, soft, false},
+		{"draining", &Consumer{redeclare: redeclare, draining: 1}, soft, false},
+		{"recovered recently", &Consumer{redeclare: redeclare, lastRecovery: time.Now()}, soft, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.consumer.canRecoverChannel(tt.err))
+		})
+	}
+}
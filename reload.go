@@ -0,0 +1,266 @@
+package rabbids
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// ConfigDiff describe the changes between two configs.
+type ConfigDiff struct {
+	AddedConsumers   []string
+	RemovedConsumers []string
+	ChangedConsumers []string
+	AddedExchanges   []string
+	ChangedExchanges []string
+}
+
+// Empty return true when the configs have the same consumers and exchanges.
+func (d ConfigDiff) Empty() bool {
+	return len(d.AddedConsumers)+len(d.RemovedConsumers)+len(d.ChangedConsumers)+
+		len(d.AddedExchanges)+len(d.ChangedExchanges) == 0
+}
+
+// DiffConfig compare the consumers and exchanges of two configs, a consumer is changed when
+// its config, its dead letter or the exchanges used by its bindings are different.
+func DiffConfig(old, updated *Config) ConfigDiff {
+	d := ConfigDiff{}
+
+	for name, ex := range updated.Exchanges {
+		oldEx, ok := old.Exchanges[name]
+
+		switch {
+		case !ok:
+			d.AddedExchanges = append(d.AddedExchanges, name)
+		case !reflect.DeepEqual(oldEx, ex):
+			d.ChangedExchanges = append(d.ChangedExchanges, name)
+		}
+	}
+
+	for name, cfg := range updated.Consumers {
+		oldCfg, ok := old.Consumers[name]
+
+		switch {
+		case !ok:
+			d.AddedConsumers = append(d.AddedConsumers, name)
+		case !reflect.DeepEqual(oldCfg, cfg) || consumerTopologyChanged(old, updated, cfg):
+			d.ChangedConsumers = append(d.ChangedConsumers, name)
+		}
+	}
+
+	for name := range old.Consumers {
+		if _, ok := updated.Consumers[name]; !ok {
+			d.RemovedConsumers = append(d.RemovedConsumers, name)
+		}
+	}
+
+	for _, names := range [][]string{
+		d.AddedConsumers, d.RemovedConsumers, d.ChangedConsumers, d.AddedExchanges, d.ChangedExchanges,
+	} {
+		sort.Strings(names)
+	}
+
+	return d
+}
+
+func consumerTopologyChanged(old, updated *Config, cfg ConsumerConfig) bool {
+	if !reflect.DeepEqual(old.DeadLetters[cfg.DeadLetter], updated.DeadLetters[cfg.DeadLetter]) {
+		return true
+	}
+
+	for _, b := range cfg.Queue.Bindings {
+		if !reflect.DeepEqual(old.Exchanges[b.Exchange], updated.Exchanges[b.Exchange]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reload apply a new config to the running client: the topology of the new and changed consumers
+// is declared, the removed and changed consumers are stopped and the supervisor creates the new ones
+// using the new config, without counting them as restarts. The handlers and consumer options not registered
+// in the new config are kept, and the producers use the new declarations and delay options.
+// When the topology declaration fails the old config is kept.
+func (r *Rabbids) Reload(config *Config) error {
+	setConfigDefaults(config)
+
+	r.configMutex.RLock()
+	old := r.config
+	r.configMutex.RUnlock()
+
+	for name, h := range old.Handlers {
+		if _, ok := config.Handlers[name]; !ok {
+			config.RegisterHandler(name, h, old.ConsumerOptions[name]...)
+		}
+	}
+
+	diff := DiffConfig(old, config)
+	decl := &declarations{config: config, log: r.log, hooks: r.hooks}
+
+	delays, err := changedDelayStrategies(old, config)
+	if err != nil {
+		return fmt.Errorf("failed to reload the config: %w", err)
+	}
+
+	for _, name := range append(diff.AddedConsumers, diff.ChangedConsumers...) {
+		if err := r.declareConsumerTopology(decl, name, config.Consumers[name]); err != nil {
+			return fmt.Errorf("failed to reload the config: %w", err)
+		}
+	}
+
+	r.configMutex.Lock()
+	r.config = config
+	r.declarations = decl
+	r.configMutex.Unlock()
+
+	r.reloadProducers(decl, delays)
+
+	r.log.Info("config reloaded", Fields{
+		"added-consumers":   diff.AddedConsumers,
+		"removed-consumers": diff.RemovedConsumers,
+		"changed-consumers": diff.ChangedConsumers,
+		"added-exchanges":   diff.AddedExchanges,
+		"changed-exchanges": diff.ChangedExchanges,
+	})
+
+	removed := r.unregisterConsumers(diff.RemovedConsumers)
+	changed := r.unregisterConsumers(diff.ChangedConsumers)
+
+	for _, c := range removed {
+		c.Kill()
+	}
+
+	// the changed consumers are created again as new ones, not restarted, using the same handler
+	for _, c := range changed {
+		atomic.StoreInt32(&c.reloaded, 1)
+		c.stop()
+	}
+
+	return nil
+}
+
+// unregisterConsumers remove the consumers from the client, returning the ones removed.
+func (r *Rabbids) unregisterConsumers(names []string) []*Consumer {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	consumers := []*Consumer{}
+
+	for _, name := range names {
+		if c, ok := r.consumers[name]; ok {
+			consumers = append(consumers, c)
+			delete(r.consumers, name)
+		}
+	}
+
+	return consumers
+}
+
+// changedDelayStrategies create the delay strategies of the connections with new delay options.
+func changedDelayStrategies(old, config *Config) (map[string]DelayStrategy, error) {
+	delays := map[string]DelayStrategy{}
+
+	for name, conn := range config.Connections {
+		oldConn, ok := old.Connections[name]
+		if ok && oldConn.DelayStrategy == conn.DelayStrategy && oldConn.delayOptions() == conn.delayOptions() {
+			continue
+		}
+
+		s, err := newDelayStrategy(conn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delay options for connection \"%s\": %w", name, err)
+		}
+
+		delays[name] = s
+	}
+
+	return delays, nil
+}
+
+// reloadProducers replace the declarations of the producers created by the client
+// and the delay strategy of the producers using the connections with new delay options.
+func (r *Rabbids) reloadProducers(decl *declarations, delays map[string]DelayStrategy) {
+	r.delayMutex.Lock()
+	if r.delayStrategies == nil {
+		r.delayStrategies = map[string]DelayStrategy{}
+	}
+
+	for name, s := range delays {
+		r.delayStrategies[name] = s
+	}
+	r.delayMutex.Unlock()
+
+	r.producersMutex.Lock()
+	producers := append([]*Producer{}, r.producers...)
+	r.producersMutex.Unlock()
+
+	r.connProducersMutex.Lock()
+	for _, p := range r.connProducers {
+		producers = append(producers, p)
+	}
+	r.connProducersMutex.Unlock()
+
+	for _, p := range producers {
+		p.reload(decl, delays[p.connection])
+	}
+}
+
+func (r *Rabbids) declareConsumerTopology(decl *declarations, name string, cfg ConsumerConfig) error {
+	cfgConn, ok := decl.config.Connections[cfg.Connection]
+	if !ok {
+		return fmt.Errorf("connection \"%s\" used by the consumer %s did not exist", cfg.Connection, name)
+	}
+
+	ch, err := r.openChannel(cfgConn, cfg.Connection)
+	if err != nil {
+		return fmt.Errorf("failed to open the rabbitMQ channel for consumer %s: %w", name, err)
+	}
+
+	defer ch.Close()
+
+	if len(cfg.DeadLetter) > 0 {
+		if err := decl.declareDeadLetters(ch, cfg.DeadLetter); err != nil {
+			return err
+		}
+	}
+
+	return decl.declareQueue(ch, cfg.Queue)
+}
+
+// hasConsumer return true when the consumer exists in the current config.
+func (r *Rabbids) hasConsumer(name string) bool {
+	r.configMutex.RLock()
+	defer r.configMutex.RUnlock()
+
+	_, ok := r.config.Consumers[name]
+
+	return ok
+}
+
+// consumerNames return the names of the consumers in the current config.
+func (r *Rabbids) consumerNames() []string {
+	r.configMutex.RLock()
+	defer r.configMutex.RUnlock()
+
+	names := make([]string, 0, len(r.config.Consumers))
+	for name := range r.config.Consumers {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// checkInterval return the supervisor check interval of the consumer or the default when not set.
+func (r *Rabbids) checkInterval(name string, defaultInterval time.Duration) time.Duration {
+	r.configMutex.RLock()
+	defer r.configMutex.RUnlock()
+
+	if interval := r.config.Consumers[name].CheckInterval; interval > 0 {
+		return interval
+	}
+
+	return defaultInterval
+}
@@ -0,0 +1,163 @@
+package rabbids
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffConfig(t *testing.T) {
+	t.Parallel()
+
+	old := &Config{
+		Exchanges: map[string]ExchangeConfig{
+			"events":  {Type: "topic"},
+			"billing": {Type: "topic"},
+		},
+		Consumers: map[string]ConsumerConfig{
+			"same":    {Queue: QueueConfig{Name: "same"}},
+			"workers": {Workers: 1, Queue: QueueConfig{Name: "workers"}},
+			"binding": {Queue: QueueConfig{Name: "binding", Bindings: []Binding{{Exchange: "billing"}}}},
+			"removed": {Queue: QueueConfig{Name: "removed"}},
+		},
+	}
+	updated := &Config{
+		Exchanges: map[string]ExchangeConfig{
+			"events":  {Type: "topic"},
+			"billing": {Type: "fanout"},
+			"audit":   {Type: "fanout"},
+		},
+		Consumers: map[string]ConsumerConfig{
+			"same":    {Queue: QueueConfig{Name: "same"}},
+			"workers": {Workers: 5, Queue: QueueConfig{Name: "workers"}},
+			"binding": {Queue: QueueConfig{Name: "binding", Bindings: []Binding{{Exchange: "billing"}}}},
+			"added":   {Queue: QueueConfig{Name: "added"}},
+		},
+	}
+
+	require.Equal(t, ConfigDiff{
+		AddedConsumers:   []string{"added"},
+		RemovedConsumers: []string{"removed"},
+		ChangedConsumers: []string{"binding", "workers"},
+		AddedExchanges:   []string{"audit"},
+		ChangedExchanges: []string{"billing"},
+	}, DiffConfig(old, updated))
+	require.True(t, DiffConfig(old, old).Empty())
+}
+
+func TestRabbids_ReloadWithQueueStats(t *testing.T) {
+	t.Parallel()
+
+	newConfig := func() *Config {
+		return &Config{
+			Connections: map[string]Connection{"default": {DSN: "amqp://localhost:5672"}},
+			Consumers: map[string]ConsumerConfig{
+				"billing": {Connection: "missing", Queue: QueueConfig{Name: "billing"}},
+			},
+		}
+	}
+	r := &Rabbids{config: newConfig(), log: NoOPLogger{}, consumers: map[string]*Consumer{}}
+	setConfigDefaults(r.config)
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 500; i++ {
+			require.NoError(t, r.Reload(newConfig()))
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 500; i++ {
+			_, err := r.QueueStats("billing")
+			require.EqualError(t, err, "failed to open the rabbitMQ channel to inspect the queue billing: "+
+				"connection (missing) did not exist, connections names available: default")
+		}
+	}()
+
+	wg.Wait()
+}
+
+func TestRabbids_ReloadProducers(t *testing.T) {
+	t.Parallel()
+
+	old := &Config{
+		Connections: map[string]Connection{
+			"default": {DSN: "amqp://localhost:5672"},
+			"other":   {DSN: "amqp://localhost:5672"},
+		},
+		Consumers: map[string]ConsumerConfig{
+			"removed": {Connection: "default", Queue: QueueConfig{Name: "removed"}},
+		},
+	}
+	setConfigDefaults(old)
+
+	oldDelay := NewLevelsDelayStrategy(DelayOptions{})
+	otherDelay := NewLevelsDelayStrategy(DelayOptions{})
+	handler := &closeRecorder{MessageHandlerFunc: func(m Message) {}}
+	removed := &Consumer{name: "removed", handler: handler}
+	p := &Producer{connection: "default", declarations: &declarations{config: old}, delayStrategy: oldDelay}
+	other := &Producer{connection: "other", declarations: &declarations{config: old}, delayStrategy: otherDelay}
+
+	r := &Rabbids{
+		config:          old,
+		log:             NoOPLogger{},
+		consumers:       map[string]*Consumer{"removed": removed},
+		producers:       []*Producer{p},
+		connProducers:   map[string]*Producer{"other": other},
+		delayStrategies: map[string]DelayStrategy{"default": oldDelay, "other": otherDelay},
+	}
+
+	updated := &Config{
+		Connections: map[string]Connection{
+			"default": {DSN: "amqp://localhost:5672", DelayPrefix: "billing"},
+			"other":   {DSN: "amqp://localhost:5672"},
+		},
+	}
+	require.NoError(t, r.Reload(updated))
+
+	require.Same(t, updated, p.declarations.config)
+	require.Same(t, updated, other.declarations.config)
+	require.NotEqual(t, oldDelay, p.delayStrategy, "the delay options of the connection changed")
+	require.Equal(t, r.delayStrategies["default"], p.delayStrategy)
+	require.Equal(t, otherDelay, other.delayStrategy)
+	require.Empty(t, r.Consumers())
+
+	require.EqualError(t, r.Reload(&Config{
+		Connections: map[string]Connection{"default": {DelayStrategy: "unknown"}},
+	}), `failed to reload the config: invalid delay options for connection "default": invalid delay strategy "unknown"`)
+}
+
+func TestSupervisor_reloadedConsumers(t *testing.T) {
+	t.Parallel()
+
+	metrics := &aliveMetrics{alive: map[string]bool{}}
+	changed := &Consumer{name: "changed", reloaded: 1}
+	changed.t.Kill(errors.New("killed by the reload"))
+
+	s := &supervisor{
+		rabbids: &Rabbids{
+			config:    &Config{Consumers: map[string]ConsumerConfig{"changed": {}}},
+			log:       NoOPLogger{},
+			metrics:   metrics,
+			consumers: map[string]*Consumer{},
+		},
+		consumers: map[string]*Consumer{"changed": changed},
+		restarts:  map[string]*restartState{"changed": {attempts: 2, next: time.Now().Add(-time.Second)}},
+	}
+
+	s.restartDeadConsumers()
+
+	require.NotContains(t, s.consumers, "changed", "the consumer fails to be created without the handler")
+	require.Empty(t, metrics.alive, "the reloaded consumer is not checked as a dead one")
+	require.Zero(t, s.state("changed").attempts, "the reload is not a restart attempt")
+}
@@ -0,0 +1,122 @@
+package rabbids
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// ReplayOptions control how the messages are moved from a dead letter queue.
+type ReplayOptions struct {
+	// Limit is the max number of messages replayed, zero means all the messages inside the queue.
+	Limit int
+	// RateLimit is the max number of messages replayed per second, zero means unlimited.
+	RateLimit int
+	// Filter select the messages to replay, the other messages are kept inside the dead letter queue.
+	Filter func(Message) bool
+}
+
+// ReplayDeadLetter move the messages from a dead letter queue back to the exchange and routing key
+// where they were originally published and return the number of messages replayed.
+// The replay stops when the queue is empty, the limit is reached or the context is done.
+func (r *Rabbids) ReplayDeadLetter(ctx context.Context, dlqName string, opts ReplayOptions) (int, error) {
+	connectionName, ok := r.queueConnection(dlqName)
+	if !ok {
+		return 0, fmt.Errorf("queue \"%s\" did not exist in the config", dlqName)
+	}
+
+	producer, err := r.connectionProducer(connectionName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create the producer to replay the queue \"%s\": %w", dlqName, err)
+	}
+
+	ch, err := r.getChannel(connectionName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open the rabbitMQ channel to replay the queue %s: %w", dlqName, err)
+	}
+
+	// the filtered messages are not acked and go back to the queue when the channel is closed.
+	defer ch.Close()
+
+	get := func() (amqp.Delivery, bool, error) {
+		return ch.Get(dlqName, false)
+	}
+
+	return r.replay(ctx, dlqName, opts, get, producer.SendWithConfirm)
+}
+
+// replay move the messages received with the get function to their original destination using the send function.
+func (r *Rabbids) replay(
+	ctx context.Context,
+	dlqName string,
+	opts ReplayOptions,
+	get func() (amqp.Delivery, bool, error),
+	send func(Publishing) error,
+) (int, error) {
+	var tick <-chan time.Time
+
+	if opts.RateLimit > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(opts.RateLimit))
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	replayed := 0
+
+	for opts.Limit == 0 || replayed < opts.Limit {
+		if err := ctx.Err(); err != nil {
+			return replayed, err
+		}
+
+		d, ok, err := get()
+		if err != nil {
+			return replayed, fmt.Errorf("failed to get a message from the queue \"%s\": %w", dlqName, err)
+		}
+
+		if !ok {
+			return replayed, nil
+		}
+
+		m := Message{Delivery: d, ctx: ctx}
+		if opts.Filter != nil && !opts.Filter(m) {
+			continue
+		}
+
+		exchange, key := originalDestination(d, "", "")
+		if exchange == "" && key == "" {
+			r.log.Warn("skipping the message without the original exchange and routing key", Fields{
+				"queue":      dlqName,
+				"message-id": d.MessageId,
+			})
+
+			continue
+		}
+
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				return replayed, ctx.Err()
+			}
+		}
+
+		pub := NewPublishing(exchange, key, nil)
+		pub.Publishing = publishingFromDelivery(d)
+		pub.encoded = true
+
+		if err := send(pub); err != nil {
+			return replayed, fmt.Errorf("failed to replay the message from the queue \"%s\": %w", dlqName, err)
+		}
+
+		if err := d.Ack(false); err != nil {
+			return replayed, fmt.Errorf("failed to ack the message from the queue \"%s\": %w", dlqName, err)
+		}
+
+		replayed++
+	}
+
+	return replayed, nil
+}
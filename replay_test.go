@@ -0,0 +1,147 @@
+package rabbids
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRabbids_replay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		opts      ReplayOptions
+		sendErr   error
+		replayed  int
+		acks      int
+		remaining int
+		err       string
+	}{
+		{name: "all the messages", replayed: 4, acks: 4},
+		{name: "with a limit", opts: ReplayOptions{Limit: 2}, replayed: 2, acks: 2, remaining: 3},
+		{
+			name: "with a filter",
+			opts: ReplayOptions{Filter: func(m Message) bool {
+				return m.Headers["tenant"] == "acme"
+			}},
+			replayed: 2,
+			acks:     2,
+		},
+		{
+			name:      "with a publishing error",
+			sendErr:   errors.New("not confirmed"),
+			remaining: 4,
+			err:       `failed to replay the message from the queue "fallback": not confirmed`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ack := &fakeAcknowledger{}
+			queue := replayDeliveries(ack)
+			get := func() (amqp.Delivery, bool, error) {
+				if len(queue) == 0 {
+					return amqp.Delivery{}, false, nil
+				}
+
+				d := queue[0]
+				queue = queue[1:]
+
+				return d, true, nil
+			}
+
+			var published []Publishing
+
+			send := func(pub Publishing) error {
+				if tt.sendErr != nil {
+					return tt.sendErr
+				}
+
+				published = append(published, pub)
+
+				return nil
+			}
+
+			r := &Rabbids{log: NoOPLogger{}}
+			replayed, err := r.replay(context.Background(), "fallback", tt.opts, get, send)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tt.replayed, replayed)
+			require.Len(t, published, tt.replayed)
+			require.Equal(t, tt.acks, ack.acks)
+			require.Len(t, queue, tt.remaining)
+
+			for _, pub := range published {
+				require.Equal(t, "event_bus", pub.Exchange)
+				require.Equal(t, "service.sms.send", pub.Key)
+			}
+		})
+	}
+}
+
+func TestRabbids_replayRateLimit(t *testing.T) {
+	t.Parallel()
+
+	ack := &fakeAcknowledger{}
+	queue := replayDeliveries(ack)
+	get := func() (amqp.Delivery, bool, error) {
+		if len(queue) == 0 {
+			return amqp.Delivery{}, false, nil
+		}
+
+		d := queue[0]
+		queue = queue[1:]
+
+		return d, true, nil
+	}
+
+	r := &Rabbids{log: NoOPLogger{}}
+	start := time.Now()
+
+	replayed, err := r.replay(context.Background(), "fallback", ReplayOptions{RateLimit: 50}, get, func(Publishing) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4, replayed)
+	require.True(t, time.Since(start) >= 4*20*time.Millisecond, "expecting one message every 20ms")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	queue = replayDeliveries(ack)
+	replayed, err = r.replay(ctx, "fallback", ReplayOptions{RateLimit: 1}, get, func(Publishing) error {
+		return nil
+	})
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, 0, replayed)
+}
+
+// replayDeliveries return four messages with the original destination and one without it.
+func replayDeliveries(ack amqp.Acknowledger) []amqp.Delivery {
+	deliveries := []amqp.Delivery{}
+
+	for _, tenant := range []string{"acme", "globex", "acme", "globex"} {
+		deliveries = append(deliveries, amqp.Delivery{
+			Acknowledger: ack,
+			Headers: amqp.Table{
+				OriginalExchangeHeader:   "event_bus",
+				OriginalRoutingKeyHeader: "service.sms.send",
+				"tenant":                 tenant,
+			},
+		})
+	}
+
+	return append(deliveries, amqp.Delivery{Acknowledger: ack, Headers: amqp.Table{"tenant": "acme"}})
+}
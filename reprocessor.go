@@ -0,0 +1,120 @@
+package rabbids
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// ConsumerTypeReprocessor is the consumer type used to reprocess the messages from a dead letter queue.
+// The reprocessor didn't need a registered handler, it republishes every message to the
+// original exchange and routing key at a controlled rate.
+const ConsumerTypeReprocessor = "reprocessor"
+
+// ReprocessConfig describe how a reprocessor consumer republishes the messages.
+type ReprocessConfig struct {
+	// Exchange used to republish the messages, when empty the original exchange is used.
+	Exchange string `mapstructure:"exchange"`
+	// RoutingKey used to republish the messages, when empty the original routing key is used.
+	RoutingKey string `mapstructure:"routing_key"`
+	// Rate is the max number of messages republished per second, zero means unlimited.
+	Rate int `mapstructure:"rate"`
+	// StripDeathHeaders remove the x-death and x-first-death-* headers before republishing.
+	StripDeathHeaders bool `mapstructure:"strip_death_headers"`
+}
+
+// reprocessor is the handler used by the reprocessor consumers.
+type reprocessor struct {
+	config  ReprocessConfig
+	publish func(Publishing) error
+	ticker  *time.Ticker
+	once    sync.Once
+}
+
+func newReprocessor(config ReprocessConfig, publish func(Publishing) error) *reprocessor {
+	r := &reprocessor{config: config, publish: publish}
+	if config.Rate > 0 {
+		r.ticker = time.NewTicker(time.Second / time.Duration(config.Rate))
+	}
+
+	return r
+}
+
+func (r *reprocessor) Handle(m Message) {
+	_ = r.HandleErr(m)
+}
+
+// HandleErr republish the message and ack it only after the publishing is confirmed.
+// Errors are handled by the consumer on_error strategy.
+func (r *reprocessor) HandleErr(m Message) error {
+	exchange, key := r.destination(m)
+	if exchange == "" && key == "" {
+		return errors.New("failed to find the original exchange and routing key of the message")
+	}
+
+	if r.ticker != nil {
+		<-r.ticker.C
+	}
+
+	pub := NewPublishing(exchange, key, nil)
+	pub.Publishing = publishingFromDelivery(m.Delivery)
+	pub.encoded = true
+
+	if r.config.StripDeathHeaders {
+		for k := range pub.Headers {
+			if k == "x-death" || strings.HasPrefix(k, "x-first-death-") || strings.HasPrefix(k, "x-last-death-") {
+				delete(pub.Headers, k)
+			}
+		}
+	}
+
+	if err := r.publish(pub); err != nil {
+		return fmt.Errorf("failed to republish the message: %w", err)
+	}
+
+	return m.Ack(false)
+}
+
+func (r *reprocessor) Close() {
+	r.once.Do(func() {
+		if r.ticker != nil {
+			r.ticker.Stop()
+		}
+	})
+}
+
+// destination return the exchange and routing key used to republish the message.
+func (r *reprocessor) destination(m Message) (string, string) {
+	return originalDestination(m.Delivery, r.config.Exchange, r.config.RoutingKey)
+}
+
+// originalDestination return the exchange and routing key where one dead lettered message was
+// published, using the given exchange and key, the headers added by rabbids or the first x-death entry, in this order.
+func originalDestination(d amqp.Delivery, exchange, key string) (string, string) {
+	if v, ok := d.Headers[OriginalExchangeHeader].(string); ok && exchange == "" {
+		exchange = v
+	}
+
+	if v, ok := d.Headers[OriginalRoutingKeyHeader].(string); ok && key == "" {
+		key = v
+	}
+
+	ds := deaths(d.Headers)
+	if len(ds) == 0 {
+		return exchange, key
+	}
+
+	if exchange == "" {
+		exchange = ds[0].Exchange
+	}
+
+	if key == "" && len(ds[0].RoutingKeys) > 0 {
+		key = ds[0].RoutingKeys[0]
+	}
+
+	return exchange, key
+}
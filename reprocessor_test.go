@@ -0,0 +1,82 @@
+package rabbids
+
+import (
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReprocessorDestination(t *testing.T) {
+	t.Parallel()
+
+	deaths := []interface{}{
+		amqp.Table{"exchange": "event_bus", "routing-keys": []interface{}{"service.send"}, "reason": "rejected"},
+	}
+
+	tests := []struct {
+		name     string
+		config   ReprocessConfig
+		headers  amqp.Table
+		exchange string
+		key      string
+	}{
+		{"without headers", ReprocessConfig{}, amqp.Table{}, "", ""},
+		{"from the x-death header", ReprocessConfig{}, amqp.Table{"x-death": deaths}, "event_bus", "service.send"},
+		{
+			"from the rabbids headers",
+			ReprocessConfig{},
+			amqp.Table{"x-death": deaths, OriginalExchangeHeader: "other", OriginalRoutingKeyHeader: "other.key"},
+			"other", "other.key",
+		},
+		{
+			"from the config",
+			ReprocessConfig{Exchange: "retry", RoutingKey: "retry.key"},
+			amqp.Table{"x-death": deaths},
+			"retry", "retry.key",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := newReprocessor(tt.config, nil)
+			exchange, key := r.destination(Message{Delivery: amqp.Delivery{Headers: tt.headers}})
+			require.Equal(t, tt.exchange, exchange)
+			require.Equal(t, tt.key, key)
+		})
+	}
+}
+
+func TestReprocessorHandleErr(t *testing.T) {
+	t.Parallel()
+
+	var published Publishing
+
+	ack := &fakeAcknowledger{}
+	r := newReprocessor(ReprocessConfig{StripDeathHeaders: true}, func(p Publishing) error {
+		published = p
+		return nil
+	})
+	defer r.Close()
+
+	err := r.HandleErr(Message{Delivery: amqp.Delivery{
+		Acknowledger: ack,
+		Body:         []byte(`{"foo": "bar"}`),
+		Headers: amqp.Table{
+			"x-death": []interface{}{
+				amqp.Table{"exchange": "event_bus", "routing-keys": []interface{}{"service.send"}},
+			},
+			"x-first-death-reason": "rejected",
+			"foo":                  "bar",
+		},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, "event_bus", published.Exchange)
+	require.Equal(t, "service.send", published.Key)
+	require.Equal(t, amqp.Table{"foo": "bar"}, published.Headers)
+	require.Equal(t, []byte(`{"foo": "bar"}`), published.Body)
+	require.Equal(t, 1, ack.acks)
+}
@@ -0,0 +1,137 @@
+package rabbids
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// RequeueCountHeader is the header used to count how many times one message was requeued
+// by the delayed-requeue strategy.
+const RequeueCountHeader = "x-rabbids-requeue-count"
+
+// delayedRequeueAcknowledger replace the requeue of one message by a new publishing using the delay
+// infrastructure. The original message is acked only after the broker confirms the new one,
+// if the publishing fails the message is requeued by the broker as usual.
+type delayedRequeueAcknowledger struct {
+	amqp.Acknowledger
+	delivery amqp.Delivery
+	queue    string
+	nack     nackConfig
+	producer func() (*Producer, error)
+	log      Logger
+}
+
+func (a *delayedRequeueAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	if !requeue || multiple {
+		return a.Acknowledger.Nack(tag, multiple, requeue)
+	}
+
+	return a.requeue(tag, func(requeue bool) error {
+		return a.Acknowledger.Nack(tag, multiple, requeue)
+	})
+}
+
+func (a *delayedRequeueAcknowledger) Reject(tag uint64, requeue bool) error {
+	if !requeue {
+		return a.Acknowledger.Reject(tag, requeue)
+	}
+
+	return a.requeue(tag, func(requeue bool) error {
+		return a.Acknowledger.Reject(tag, requeue)
+	})
+}
+
+func (a *delayedRequeueAcknowledger) requeue(tag uint64, fallback func(requeue bool) error) error {
+	count := requeueCount(a.delivery.Headers)
+	if a.nack.maxAttempts > 0 && count >= a.nack.maxAttempts {
+		a.log.Warn("the message reached the max requeue attempts, rejecting without requeue", Fields{
+			"queue":      a.queue,
+			"message-id": a.delivery.MessageId,
+			"attempts":   count,
+		})
+
+		return fallback(false)
+	}
+
+	delay := backoffDelay(a.nack.delay, a.nack.backoff, a.nack.maxDelay, count)
+	if a.nack.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(a.nack.jitter)))
+	}
+
+	err := a.publish(delay)
+	if err != nil {
+		a.log.Error("failed to requeue the message with delay, requeueing on the broker", Fields{
+			"error":      err,
+			"queue":      a.queue,
+			"message-id": a.delivery.MessageId,
+		})
+
+		return fallback(true)
+	}
+
+	return a.Acknowledger.Ack(tag, false)
+}
+
+// backoffDelay return the delay of the next requeue, multiplying the delay by the backoff factor
+// for every previous requeue, up to the max delay.
+func backoffDelay(delay time.Duration, backoff float64, max time.Duration, count int64) time.Duration {
+	if backoff <= 1 || count <= 0 {
+		return delay
+	}
+
+	next := float64(delay) * math.Pow(backoff, float64(count))
+	if max > 0 && next > float64(max) {
+		return max
+	}
+
+	if next > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(next)
+}
+
+func (a *delayedRequeueAcknowledger) publish(delay time.Duration) error {
+	p, err := a.producer()
+	if err != nil {
+		return err
+	}
+
+	pub := NewDelayedPublishing(a.queue, delay, nil)
+	pub.Publishing = publishingFromDelivery(a.delivery)
+	pub.encoded = true
+	pub.Headers[RequeueCountHeader] = requeueCount(a.delivery.Headers) + 1
+
+	return p.SendWithConfirm(pub)
+}
+
+// publishingFromDelivery copy the body and the properties from one delivery to be published again.
+func publishingFromDelivery(d amqp.Delivery) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+
+	return amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		UserId:          d.UserId,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+}
+
+func requeueCount(headers amqp.Table) int64 {
+	return int64Value(headers[RequeueCountHeader])
+}
@@ -0,0 +1,95 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_delayedRequeueAcknowledger(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reject without requeue is passed to the broker", func(t *testing.T) {
+		t.Parallel()
+
+		ack := &fakeAcknowledger{}
+		a := &delayedRequeueAcknowledger{Acknowledger: ack, log: NoOPLogger{}}
+
+		require.NoError(t, a.Nack(1, false, false))
+		require.NoError(t, a.Reject(1, false))
+		require.Equal(t, 1, ack.nacks)
+		require.Equal(t, 1, ack.rejects)
+		require.Equal(t, 0, ack.acks)
+	})
+
+	t.Run("requeue on the broker when the publishing fails", func(t *testing.T) {
+		t.Parallel()
+
+		ack := &fakeAcknowledger{}
+		a := &delayedRequeueAcknowledger{
+			Acknowledger: ack,
+			queue:        "foo",
+			log:          NoOPLogger{},
+			producer: func() (*Producer, error) {
+				return nil, errors.New("connection closed")
+			},
+		}
+
+		require.NoError(t, a.Nack(1, false, true))
+		require.Equal(t, 1, ack.nacks)
+		require.Equal(t, 0, ack.acks)
+	})
+}
+
+func Test_delayedRequeueAcknowledgerMaxAttempts(t *testing.T) {
+	t.Parallel()
+
+	ack := &fakeAcknowledger{}
+	a := &delayedRequeueAcknowledger{
+		Acknowledger: ack,
+		delivery:     amqp.Delivery{Headers: amqp.Table{RequeueCountHeader: int32(3)}},
+		queue:        "foo",
+		nack:         nackConfig{maxAttempts: 3},
+		log:          NoOPLogger{},
+		producer: func() (*Producer, error) {
+			t.Fatal("the message must not be published again")
+			return nil, nil
+		},
+	}
+
+	require.NoError(t, a.Reject(1, true))
+	require.Equal(t, 1, ack.rejects)
+	require.Equal(t, 0, ack.acks)
+}
+
+func Test_backoffDelay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		backoff float64
+		max     time.Duration
+		count   int64
+		want    time.Duration
+	}{
+		{"without backoff", 0, 0, 5, time.Second},
+		{"first requeue", 2, time.Minute, 0, time.Second},
+		{"third requeue", 2, time.Minute, 3, 8 * time.Second},
+		{"fractional factor", 1.5, time.Minute, 2, 2250 * time.Millisecond},
+		{"max delay", 2, time.Minute, 10, time.Minute},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, backoffDelay(time.Second, tt.backoff, tt.max, tt.count), tt.name)
+	}
+}
+
+func Test_requeueCount(t *testing.T) {
+	t.Parallel()
+
+	require.EqualValues(t, 0, requeueCount(amqp.Table{}))
+	require.EqualValues(t, 3, requeueCount(amqp.Table{RequeueCountHeader: int32(3)}))
+	require.EqualValues(t, 4, requeueCount(amqp.Table{RequeueCountHeader: int64(4)}))
+}
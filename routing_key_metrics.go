@@ -0,0 +1,50 @@
+package rabbids
+
+import "sync"
+
+// RoutingKeyOther is the routing key reported by RoutingKeyHandled for the keys received
+// after the consumer reached the routing_key_metrics limit.
+const RoutingKeyOther = "_other"
+
+// routingKeyLimiter bound the cardinality of the routing key metrics,
+// the first max routing keys received are reported and the others are grouped as RoutingKeyOther.
+type routingKeyLimiter struct {
+	mutex sync.Mutex
+	max   int
+	seen  map[string]struct{}
+}
+
+func newRoutingKeyLimiter(max int) *routingKeyLimiter {
+	if max <= 0 {
+		return nil
+	}
+
+	return &routingKeyLimiter{max: max, seen: make(map[string]struct{}, max)}
+}
+
+// label returns the routing key used in the metrics.
+func (l *routingKeyLimiter) label(key string) string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.seen[key]; ok {
+		return key
+	}
+
+	if len(l.seen) >= l.max {
+		return RoutingKeyOther
+	}
+
+	l.seen[key] = struct{}{}
+
+	return key
+}
+
+// recordRoutingKey report the outcome of the message by routing key, when enabled by routing_key_metrics.
+func (c *Consumer) recordRoutingKey(key string, outcome Outcome) {
+	if c.routingKeys == nil {
+		return
+	}
+
+	c.metrics.RoutingKeyHandled(c.name, c.routingKeys.label(key), outcome)
+}
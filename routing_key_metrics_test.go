@@ -0,0 +1,44 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumer_recordRoutingKey(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, newRoutingKeyLimiter(0), "disabled by default")
+
+	m := &routingKeyMetrics{}
+	c := &Consumer{
+		name:        "users",
+		log:         NoOPLogger{},
+		metrics:     m,
+		routingKeys: newRoutingKeyLimiter(2),
+		handler:     MessageHandlerFunc(func(m Message) { _ = m.Ack(false) }),
+	}
+
+	for _, key := range []string{"user.created", "user.deleted", "user.updated", "user.created"} {
+		c.handle(amqp.Delivery{RoutingKey: key, Acknowledger: &fakeAcknowledger{}}, time.Now())
+	}
+
+	require.Equal(t, []string{
+		"user.created:ack",
+		"user.deleted:ack",
+		RoutingKeyOther + ":ack",
+		"user.created:ack",
+	}, m.handled)
+}
+
+type routingKeyMetrics struct {
+	NoOPMetrics
+	handled []string
+}
+
+func (m *routingKeyMetrics) RoutingKeyHandled(_, routingKey string, outcome Outcome) {
+	m.handled = append(m.handled, routingKey+":"+string(outcome))
+}
@@ -0,0 +1,99 @@
+package rabbids
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultSupervisorInterval is the interval between the supervisor checks used by Run.
+	DefaultSupervisorInterval = time.Second
+	// DefaultDrainTimeout is the max time Run waits for the shutdown of the client.
+	DefaultDrainTimeout = 30 * time.Second
+)
+
+// RunOption represents an option to change the behavior of Run and RunContext.
+type RunOption func(*runner)
+
+type runner struct {
+	log               LoggerFN
+	options           []Option
+	interval          time.Duration
+	supervisorOptions []SupervisorOption
+	drainTimeout      time.Duration
+}
+
+// RunWithLogger set the logger used by the Rabbids client.
+func RunWithLogger(log LoggerFN) RunOption {
+	return func(r *runner) {
+		r.log = log
+	}
+}
+
+// RunWithOptions set the options used to create the Rabbids client.
+func RunWithOptions(opts ...Option) RunOption {
+	return func(r *runner) {
+		r.options = append(r.options, opts...)
+	}
+}
+
+// RunWithSupervisor change the interval and the options of the supervisor.
+func RunWithSupervisor(interval time.Duration, opts ...SupervisorOption) RunOption {
+	return func(r *runner) {
+		r.interval = interval
+		r.supervisorOptions = append(r.supervisorOptions, opts...)
+	}
+}
+
+// RunWithDrainTimeout change the max time to wait for the messages in flight and the producers on shutdown.
+func RunWithDrainTimeout(timeout time.Duration) RunOption {
+	return func(r *runner) {
+		r.drainTimeout = timeout
+	}
+}
+
+// Run create the Rabbids client, start all the consumers with a supervisor and block
+// until a SIGINT or SIGTERM is received. On shutdown the client is stopped using Shutdown.
+func Run(config *Config, opts ...RunOption) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+
+	defer signal.Stop(sig)
+
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return RunContext(ctx, config, opts...)
+}
+
+// RunContext works like Run but the consumers run until the context is done.
+func RunContext(ctx context.Context, config *Config, opts ...RunOption) error {
+	run := &runner{
+		log:          NoOPLoggerFN,
+		interval:     DefaultSupervisorInterval,
+		drainTimeout: DefaultDrainTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(run)
+	}
+
+	r, err := New(config, run.log, run.options...)
+	if err != nil {
+		return fmt.Errorf("failed to create the rabbids client: %w", err)
+	}
+
+	return r.start(ctx, run.interval, run.drainTimeout, run.supervisorOptions...)
+}
@@ -0,0 +1,38 @@
+package rabbids
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunContext(t *testing.T) {
+	t.Parallel()
+
+	var shutdown bool
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- RunContext(ctx, &Config{},
+			RunWithSupervisor(10*time.Millisecond),
+			RunWithDrainTimeout(time.Second),
+			RunWithOptions(WithOnShutdown(func() {
+				shutdown = true
+			})),
+		)
+	}()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+		require.True(t, shutdown)
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunContext did not return after the context was done")
+	}
+}
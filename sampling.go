@@ -0,0 +1,73 @@
+package rabbids
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/streadway/amqp"
+)
+
+type samplingConfig struct {
+	fraction float64
+	exchange string
+	key      string
+}
+
+// WithSampling make the consumer call the handler with only a fraction (between 0 and 1) of the messages.
+// The other messages are acked without calling the handler, useful to canary a new handler
+// implementation against production traffic.
+func WithSampling(fraction float64) ConsumerOption {
+	return WithSamplingTee(fraction, "", "")
+}
+
+// WithSamplingTee works like WithSampling but the messages not sampled are published
+// to the exchange and routing key and acked after the broker confirms them,
+// so they can be processed by another consumer.
+func WithSamplingTee(fraction float64, exchange, key string) ConsumerOption {
+	return func(c *Consumer) error {
+		if fraction < 0 || fraction > 1 {
+			return fmt.Errorf("invalid sampling fraction %v, the value must be between 0 and 1", fraction)
+		}
+
+		c.sampling = &samplingConfig{fraction: fraction, exchange: exchange, key: key}
+
+		return nil
+	}
+}
+
+// skipSample return true when the message was not sampled and was already acked or teed.
+func (c *Consumer) skipSample(d amqp.Delivery) bool {
+	if c.sampling == nil || rand.Float64() < c.sampling.fraction {
+		return false
+	}
+
+	if c.sampling.exchange != "" || c.sampling.key != "" {
+		pub := NewPublishing(c.sampling.exchange, c.sampling.key, nil)
+		pub.Publishing = publishingFromDelivery(d)
+		pub.encoded = true
+
+		if err := c.publishConfirmed(pub); err != nil {
+			c.log.Error("failed to tee the message not sampled, handling the message", Fields{
+				"consumer":   c.name,
+				"message-id": d.MessageId,
+				"error":      err,
+			})
+
+			return false
+		}
+	}
+
+	if c.opts.AutoAck {
+		return true
+	}
+
+	if err := d.Ack(false); err != nil {
+		c.log.Error("failed to ack the message not sampled", Fields{
+			"consumer":   c.name,
+			"message-id": d.MessageId,
+			"error":      err,
+		})
+	}
+
+	return true
+}
@@ -0,0 +1,59 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumer_sampling(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		fraction float64
+		handled  int
+		acks     int
+		err      string
+	}{
+		{name: "all the messages", fraction: 1, handled: 10, acks: 10},
+		{name: "none of the messages", fraction: 0, handled: 0, acks: 10},
+		{name: "invalid fraction", fraction: 1.5, err: "invalid sampling fraction 1.5, the value must be between 0 and 1"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handled := 0
+			c := &Consumer{
+				name:    "sampling",
+				log:     NoOPLogger{},
+				metrics: NoOPMetrics{},
+				handler: MessageHandlerFunc(func(m Message) {
+					handled++
+					_ = m.Ack(false)
+				}),
+			}
+
+			err := WithSampling(tt.fraction)(c)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			ack := &fakeAcknowledger{}
+			for i := 0; i < 10; i++ {
+				c.handle(amqp.Delivery{Acknowledger: ack}, time.Now())
+			}
+
+			require.Equal(t, tt.handled, handled)
+			require.Equal(t, tt.acks, ack.acks)
+		})
+	}
+}
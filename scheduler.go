@@ -0,0 +1,239 @@
+package rabbids
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// ScheduleConfig describe one message published on a cron schedule by the Scheduler.
+type ScheduleConfig struct {
+	// Cron is the schedule, a cron expression with five fields (minute hour day-of-month month day-of-week)
+	// or one of the descriptors @yearly, @monthly, @weekly, @daily, @hourly and @every <duration>.
+	Cron string `mapstructure:"cron"`
+	// Timezone is the location used by the cron expression, by default the local time.
+	Timezone string `mapstructure:"timezone"`
+	// Producer is the name of the producer, from the producers config, used to publish the message.
+	Producer string `mapstructure:"producer"`
+	// Exchange, RoutingKey, Data and Headers describe the message published,
+	// unless a function is registered with Config.RegisterSchedule.
+	Exchange   string                 `mapstructure:"exchange"`
+	RoutingKey string                 `mapstructure:"routing_key"`
+	Data       interface{}            `mapstructure:"data"`
+	Headers    map[string]interface{} `mapstructure:"headers"`
+	// AllReplicas publish the message from every process running the scheduler.
+	// By default only the replica holding the schedule lock publishes the message.
+	AllReplicas bool `mapstructure:"all_replicas"`
+}
+
+// Scheduler publish the messages from the schedules config using the cron expressions.
+// Create one with Rabbids.NewScheduler and call Run.
+type Scheduler struct {
+	jobs []*scheduledJob
+	log  Logger
+	now  func() time.Time
+}
+
+type scheduledJob struct {
+	name       string
+	schedule   *cronSchedule
+	location   *time.Location
+	publishing func() Publishing
+	send       func(Publishing) error
+	// lock return ErrStandby when another replica holds the lock of the schedule, nil for the AllReplicas schedules.
+	lock func() error
+	next time.Time
+}
+
+// scheduleLockName return the name of the exclusive queue used as a lock by one schedule.
+func scheduleLockName(name string) string {
+	return fmt.Sprintf("rabbids.schedule.%s", name)
+}
+
+// NewScheduler create a Scheduler with all the schedules from the config.
+// The messages are published by the producers from the config, created by New.
+func (r *Rabbids) NewScheduler() (*Scheduler, error) {
+	r.configMutex.RLock()
+	defer r.configMutex.RUnlock()
+
+	s := &Scheduler{log: r.log, now: time.Now}
+
+	names := make([]string, 0, len(r.config.Schedules))
+	for name := range r.config.Schedules {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		cfg := r.config.Schedules[name]
+
+		job, err := r.newScheduledJob(name, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the schedule \"%s\": %w", name, err)
+		}
+
+		s.jobs = append(s.jobs, job)
+	}
+
+	return s, nil
+}
+
+func (r *Rabbids) newScheduledJob(name string, cfg ScheduleConfig) (*scheduledJob, error) {
+	schedule, err := parseCron(cfg.Cron)
+	if err != nil {
+		return nil, err
+	}
+
+	location := time.Local
+	if cfg.Timezone != "" {
+		location, err = time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone \"%s\": %w", cfg.Timezone, err)
+		}
+	}
+
+	producerCfg, ok := r.config.Producers[cfg.Producer]
+	if !ok {
+		return nil, fmt.Errorf("producer \"%s\" did not exist", cfg.Producer)
+	}
+
+	job := &scheduledJob{
+		name:       name,
+		schedule:   schedule,
+		location:   location,
+		publishing: r.config.SchedulePublishings[name],
+		send: func(m Publishing) error {
+			p, err := r.Producer(cfg.Producer)
+			if err != nil {
+				return err
+			}
+
+			return p.Send(m)
+		},
+	}
+
+	if job.publishing == nil {
+		job.publishing = func() Publishing {
+			m := NewPublishing(cfg.Exchange, cfg.RoutingKey, cfg.Data)
+			for k, v := range cfg.Headers {
+				m.Headers[k] = v
+			}
+
+			return m
+		}
+	}
+
+	if !cfg.AllReplicas {
+		job.lock = func() error {
+			return r.acquireScheduleLock(producerCfg.Connection, scheduleLockName(name))
+		}
+	}
+
+	return job, nil
+}
+
+// acquireScheduleLock declare the exclusive lock queue of one schedule. The declaration succeeds again
+// while the connection holding the lock is open, so the lock is checked before every publishing.
+func (r *Rabbids) acquireScheduleLock(connectionName, lock string) error {
+	ch, err := r.getChannel(connectionName)
+	if err != nil {
+		return fmt.Errorf("failed to open the channel for the schedule lock: %w", err)
+	}
+
+	_, err = ch.QueueDeclare(lock, false, false, true, false, nil)
+
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.ResourceLocked {
+		return ErrStandby
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to declare the schedule lock \"%s\": %w", lock, err)
+	}
+
+	return ch.Close()
+}
+
+// Run publish the scheduled messages until the context is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if len(s.jobs) == 0 {
+		<-ctx.Done()
+
+		return nil
+	}
+
+	for _, job := range s.jobs {
+		job.next = job.schedule.next(s.now().In(job.location))
+	}
+
+	for {
+		job := s.nextJob()
+		if job == nil {
+			s.log.Warn("no schedule will run again, stopping the scheduler", Fields{})
+			<-ctx.Done()
+
+			return nil
+		}
+
+		timer := time.NewTimer(job.next.Sub(s.now()))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil
+		case <-timer.C:
+		}
+
+		s.fire(job)
+		job.next = job.schedule.next(s.now().In(job.location))
+	}
+}
+
+// nextJob return the job with the closest next time.
+func (s *Scheduler) nextJob() *scheduledJob {
+	var next *scheduledJob
+
+	for _, job := range s.jobs {
+		if job.next.IsZero() {
+			continue
+		}
+
+		if next == nil || job.next.Before(next.next) {
+			next = job
+		}
+	}
+
+	return next
+}
+
+// fire publish the message of one job, when the replica holds the schedule lock.
+func (s *Scheduler) fire(job *scheduledJob) {
+	if job.lock != nil {
+		err := job.lock()
+		if errors.Is(err, ErrStandby) {
+			s.log.Debug("schedule skipped, the lock is held by another replica", Fields{"schedule": job.name})
+
+			return
+		}
+
+		if err != nil {
+			s.log.Error("failed to acquire the schedule lock", Fields{"schedule": job.name, "error": err})
+
+			return
+		}
+	}
+
+	if err := job.send(job.publishing()); err != nil {
+		s.log.Error("failed to publish the scheduled message", Fields{"schedule": job.name, "error": err})
+
+		return
+	}
+
+	s.log.Debug("scheduled message published", Fields{"schedule": job.name})
+}
@@ -0,0 +1,115 @@
+package rabbids
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRabbids_NewScheduler(t *testing.T) {
+	t.Parallel()
+
+	r, err := New(&Config{}, NoOPLoggerFN)
+	require.NoError(t, err)
+
+	r.config.Producers = map[string]ProducerConfig{"events": {Connection: "default"}}
+	r.config.Schedules = map[string]ScheduleConfig{
+		"report": {Cron: "@daily", Producer: "events", Exchange: "reports", RoutingKey: "daily"},
+	}
+
+	s, err := r.NewScheduler()
+	require.NoError(t, err)
+	require.Len(t, s.jobs, 1)
+	require.NotNil(t, s.jobs[0].lock, "the schedules are locked by default")
+
+	m := s.jobs[0].publishing()
+	require.Equal(t, "reports", m.Exchange)
+	require.Equal(t, "daily", m.Key)
+
+	r.config.RegisterSchedule("report", func() Publishing {
+		return NewPublishing("custom", "key", nil)
+	})
+	r.config.Schedules["report"] = ScheduleConfig{Cron: "@daily", Producer: "events", AllReplicas: true}
+
+	s, err = r.NewScheduler()
+	require.NoError(t, err)
+	require.Nil(t, s.jobs[0].lock)
+	require.Equal(t, "custom", s.jobs[0].publishing().Exchange)
+
+	r.config.Schedules["report"] = ScheduleConfig{Cron: "@daily", Producer: "foo"}
+	_, err = r.NewScheduler()
+	require.EqualError(t, err, `failed to create the schedule "report": producer "foo" did not exist`)
+
+	r.config.Schedules["report"] = ScheduleConfig{Cron: "@daily", Producer: "events", Timezone: "Mars/Olympus"}
+	_, err = r.NewScheduler()
+	require.Error(t, err)
+}
+
+func TestScheduler_fire(t *testing.T) {
+	t.Parallel()
+
+	var sent int
+
+	job := &scheduledJob{
+		name:       "report",
+		publishing: func() Publishing { return NewPublishing("", "key", nil) },
+		send: func(Publishing) error {
+			sent++
+			return nil
+		},
+		lock: func() error { return ErrStandby },
+	}
+	s := &Scheduler{jobs: []*scheduledJob{job}, log: NoOPLogger{}}
+
+	s.fire(job)
+	require.Equal(t, 0, sent, "the replica without the lock must not publish")
+
+	job.lock = func() error { return errors.New("connection closed") }
+	s.fire(job)
+	require.Equal(t, 0, sent)
+
+	job.lock = func() error { return nil }
+	s.fire(job)
+	require.Equal(t, 1, sent)
+}
+
+func TestScheduler_Run(t *testing.T) {
+	t.Parallel()
+
+	var sent int32
+
+	now := time.Date(2020, 10, 12, 10, 32, 15, 0, time.UTC)
+	s := &Scheduler{
+		log: NoOPLogger{},
+		now: func() time.Time { return now },
+		jobs: []*scheduledJob{
+			{
+				name:       "never",
+				schedule:   &cronSchedule{month: 1 << 2, dom: 1 << 31},
+				location:   time.UTC,
+				publishing: func() Publishing { return Publishing{} },
+				send:       func(Publishing) error { return errors.New("unexpected") },
+			},
+			{
+				name:       "every",
+				schedule:   &cronSchedule{every: 200 * time.Millisecond},
+				location:   time.UTC,
+				publishing: func() Publishing { return Publishing{} },
+				send: func(Publishing) error {
+					atomic.AddInt32(&sent, 1)
+					return nil
+				},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, s.Run(ctx))
+	require.EqualValues(t, 2, atomic.LoadInt32(&sent))
+}
@@ -0,0 +1,179 @@
+package serialization
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// AvroSchemaIDHeader is the header with the schema ID, used by the Avro serializer created WithSchemaIDHeader.
+const AvroSchemaIDHeader = "x-avro-schema-id"
+
+// avroMagicByte is the first byte of the Confluent wire format, followed by the schema ID in 4 bytes.
+const avroMagicByte = 0
+
+// Avro implements the rabbids.Serializer interface using the Avro binary format and a schema registry.
+// By default the body uses the Confluent wire format: a zero byte, the schema ID in four bytes (big endian)
+// and the Avro data. The values are validated against the schema when they are marshaled and unmarshaled.
+type Avro struct {
+	registry *SchemaRegistry
+	subject  string
+	schema   string
+	parsed   *avroSchema
+	header   bool
+}
+
+// AvroOption is used to customize the Avro serializer.
+type AvroOption func(*Avro)
+
+// WithSchemaIDHeader send the schema ID in the AvroSchemaIDHeader instead of the body,
+// when the serializer is used by a rabbids producer.
+func WithSchemaIDHeader() AvroOption {
+	return func(a *Avro) {
+		a.header = true
+	}
+}
+
+// NewAvro create an Avro serializer for the schema, registered under the subject on the first Marshal.
+func NewAvro(registry *SchemaRegistry, subject, schema string, opts ...AvroOption) (*Avro, error) {
+	parsed, err := parseAvroSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Avro{registry: registry, subject: subject, schema: schema, parsed: parsed}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a, nil
+}
+
+// Marshal returns the data in the Avro wire format or an error when the data doesn't match the schema.
+// The data is converted using encoding/json first, so the json tags are used as the field names.
+func (a *Avro) Marshal(v interface{}) ([]byte, error) {
+	id, data, err := a.encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(wireHeader(id), data...), nil
+}
+
+// Serialize implements the SerializerV2 interface used by the rabbids producers. It returns the Avro data
+// with the AvroSchemaIDHeader when the serializer was created WithSchemaIDHeader, or the same as Marshal.
+func (a *Avro) Serialize(exchange, key string, v interface{}) (Serialized, error) {
+	if !a.header {
+		return serialize(a, v)
+	}
+
+	id, data, err := a.encode(v)
+	if err != nil {
+		return Serialized{}, err
+	}
+
+	return Serialized{
+		Body:        data,
+		ContentType: a.Name(),
+		Headers:     map[string]interface{}{AvroSchemaIDHeader: int32(id)},
+	}, nil
+}
+
+// Name returns the name of the serialization used.
+// This value is used as the ContentType value on the message.
+func (a *Avro) Name() string {
+	return "avro/binary"
+}
+
+// Unmarshal parses a body in the Avro wire format into the value pointed by v,
+// using the schema from the registry. It returns an error when the body doesn't match the schema.
+func (a *Avro) Unmarshal(data []byte, v interface{}) error {
+	return a.UnmarshalWithHeaders(data, nil, v)
+}
+
+// UnmarshalWithHeaders parses the body into the value pointed by v, using the schema ID
+// from the AvroSchemaIDHeader when it exists or from the wire format.
+func (a *Avro) UnmarshalWithHeaders(data []byte, headers map[string]interface{}, v interface{}) error {
+	id, ok := headerSchemaID(headers)
+	if !ok {
+		if len(data) < 5 || data[0] != avroMagicByte {
+			return errors.New("the body isn't in the avro wire format")
+		}
+
+		id, data = int(binary.BigEndian.Uint32(data[1:5])), data[5:]
+	}
+
+	schema, err := a.registry.parsedSchema(id)
+	if err != nil {
+		return err
+	}
+
+	r := bytes.NewReader(data)
+
+	value, err := schema.decode(r)
+	if err != nil {
+		return fmt.Errorf("the body doesn't match the schema %d: %w", id, err)
+	}
+
+	if r.Len() > 0 {
+		return fmt.Errorf("the body doesn't match the schema %d: %d bytes left", id, r.Len())
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// encode validate and encode the value, registering the schema when needed.
+func (a *Avro) encode(v interface{}) (int, []byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var value interface{}
+	if err = dec.Decode(&value); err != nil {
+		return 0, nil, err
+	}
+
+	var buf bytes.Buffer
+	if err = a.parsed.encode(&buf, value); err != nil {
+		return 0, nil, fmt.Errorf("the data doesn't match the schema of the subject \"%s\": %w", a.subject, err)
+	}
+
+	id, err := a.registry.Register(a.subject, a.schema)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return id, buf.Bytes(), nil
+}
+
+func wireHeader(id int) []byte {
+	b := make([]byte, 5)
+	b[0] = avroMagicByte
+	binary.BigEndian.PutUint32(b[1:], uint32(id))
+
+	return b
+}
+
+func headerSchemaID(headers map[string]interface{}) (int, bool) {
+	switch id := headers[AvroSchemaIDHeader].(type) {
+	case int:
+		return id, true
+	case int32:
+		return int(id), true
+	case int64:
+		return int(id), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,514 @@
+package serialization
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// avroSchema is a parsed Avro schema. The logical types are handled as the underlying type.
+type avroSchema struct {
+	typ      string
+	name     string
+	fields   []avroField
+	symbols  []string
+	items    *avroSchema
+	values   *avroSchema
+	branches []*avroSchema
+	size     int
+}
+
+type avroField struct {
+	name       string
+	schema     *avroSchema
+	def        interface{}
+	hasDefault bool
+}
+
+var avroPrimitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+// parseAvroSchema parse one Avro schema in the JSON format.
+func parseAvroSchema(schema string) (*avroSchema, error) {
+	dec := json.NewDecoder(strings.NewReader(schema))
+	dec.UseNumber()
+
+	var raw interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %w", err)
+	}
+
+	p := avroParser{named: map[string]*avroSchema{}}
+
+	return p.parse(raw, "")
+}
+
+type avroParser struct {
+	named map[string]*avroSchema
+}
+
+//nolint:funlen,gocyclo
+func (p avroParser) parse(raw interface{}, namespace string) (*avroSchema, error) {
+	switch v := raw.(type) {
+	case string:
+		if avroPrimitives[v] {
+			return &avroSchema{typ: v}, nil
+		}
+
+		if s, ok := p.named[v]; ok {
+			return s, nil
+		}
+
+		if s, ok := p.named[fullName(v, namespace)]; ok {
+			return s, nil
+		}
+
+		return nil, fmt.Errorf("unknown avro type \"%s\"", v)
+	case []interface{}:
+		s := &avroSchema{typ: "union"}
+
+		for _, b := range v {
+			branch, err := p.parse(b, namespace)
+			if err != nil {
+				return nil, err
+			}
+
+			s.branches = append(s.branches, branch)
+		}
+
+		return s, nil
+	case map[string]interface{}:
+		typ, _ := v["type"].(string)
+		if typ == "" {
+			return p.parse(v["type"], namespace)
+		}
+
+		s := &avroSchema{typ: typ}
+
+		switch typ {
+		case "record", "error", "enum", "fixed":
+			s.typ = strings.Replace(typ, "error", "record", 1)
+
+			name, _ := v["name"].(string)
+			if name == "" {
+				return nil, fmt.Errorf("the avro %s must have a name", typ)
+			}
+
+			if ns, ok := v["namespace"].(string); ok && !strings.Contains(name, ".") {
+				namespace = ns
+			}
+
+			s.name = fullName(name, namespace)
+			p.named[s.name] = s
+		}
+
+		switch s.typ {
+		case "record":
+			fields, _ := v["fields"].([]interface{})
+			for _, f := range fields {
+				fm, ok := f.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("invalid field in the avro record \"%s\"", s.name)
+				}
+
+				name, _ := fm["name"].(string)
+
+				fs, err := p.parse(fm["type"], namespace)
+				if err != nil {
+					return nil, fmt.Errorf("invalid field \"%s\": %w", name, err)
+				}
+
+				def, hasDefault := fm["default"]
+				s.fields = append(s.fields, avroField{name: name, schema: fs, def: def, hasDefault: hasDefault})
+			}
+		case "enum":
+			symbols, _ := v["symbols"].([]interface{})
+			for _, sym := range symbols {
+				str, _ := sym.(string)
+				s.symbols = append(s.symbols, str)
+			}
+		case "fixed":
+			size, _ := v["size"].(json.Number)
+
+			n, err := size.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("invalid size of the avro fixed \"%s\"", s.name)
+			}
+
+			s.size = int(n)
+		case "array":
+			items, err := p.parse(v["items"], namespace)
+			if err != nil {
+				return nil, err
+			}
+
+			s.items = items
+		case "map":
+			values, err := p.parse(v["values"], namespace)
+			if err != nil {
+				return nil, err
+			}
+
+			s.values = values
+		default:
+			if !avroPrimitives[typ] {
+				return p.parse(typ, namespace)
+			}
+		}
+
+		return s, nil
+	default:
+		return nil, fmt.Errorf("invalid avro schema %v", raw)
+	}
+}
+
+func fullName(name, namespace string) string {
+	if namespace == "" || strings.Contains(name, ".") {
+		return name
+	}
+
+	return namespace + "." + name
+}
+
+// encode write the value, with the types produced by a json.Decoder using UseNumber, in the Avro binary format.
+// It returns an error when the value doesn't match the schema.
+//
+//nolint:funlen,gocyclo
+func (s *avroSchema) encode(buf *bytes.Buffer, v interface{}) error {
+	switch s.typ {
+	case "null":
+		if v != nil {
+			return fmt.Errorf("expected null, got %T", v)
+		}
+	case "boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("expected boolean, got %T", v)
+		}
+
+		if b {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case "int", "long":
+		n, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("expected %s, got %T", s.typ, v)
+		}
+
+		i, err := n.Int64()
+		if err != nil {
+			return fmt.Errorf("expected %s, got %s", s.typ, n)
+		}
+
+		if s.typ == "int" && (i < math.MinInt32 || i > math.MaxInt32) {
+			return fmt.Errorf("the value %d overflows the int type", i)
+		}
+
+		writeLong(buf, i)
+	case "float", "double":
+		n, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("expected %s, got %T", s.typ, v)
+		}
+
+		f, err := n.Float64()
+		if err != nil {
+			return fmt.Errorf("expected %s, got %s", s.typ, n)
+		}
+
+		if s.typ == "float" {
+			_ = binary.Write(buf, binary.LittleEndian, math.Float32bits(float32(f)))
+		} else {
+			_ = binary.Write(buf, binary.LittleEndian, math.Float64bits(f))
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected string, got %T", v)
+		}
+
+		writeLong(buf, int64(len(str)))
+		buf.WriteString(str)
+	case "bytes", "fixed":
+		b, err := avroBytes(v)
+		if err != nil {
+			return err
+		}
+
+		if s.typ == "fixed" {
+			if len(b) != s.size {
+				return fmt.Errorf("expected %d bytes for the fixed \"%s\", got %d", s.size, s.name, len(b))
+			}
+		} else {
+			writeLong(buf, int64(len(b)))
+		}
+
+		buf.Write(b)
+	case "enum":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected a symbol of the enum \"%s\", got %T", s.name, v)
+		}
+
+		for i, sym := range s.symbols {
+			if sym == str {
+				writeLong(buf, int64(i))
+				return nil
+			}
+		}
+
+		return fmt.Errorf("\"%s\" is not a symbol of the enum \"%s\"", str, s.name)
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok && v != nil {
+			return fmt.Errorf("expected array, got %T", v)
+		}
+
+		if len(items) > 0 {
+			writeLong(buf, int64(len(items)))
+
+			for i, item := range items {
+				if err := s.items.encode(buf, item); err != nil {
+					return fmt.Errorf("item %d: %w", i, err)
+				}
+			}
+		}
+
+		writeLong(buf, 0)
+	case "map":
+		values, ok := v.(map[string]interface{})
+		if !ok && v != nil {
+			return fmt.Errorf("expected map, got %T", v)
+		}
+
+		if len(values) > 0 {
+			writeLong(buf, int64(len(values)))
+
+			for k, value := range values {
+				writeLong(buf, int64(len(k)))
+				buf.WriteString(k)
+
+				if err := s.values.encode(buf, value); err != nil {
+					return fmt.Errorf("key \"%s\": %w", k, err)
+				}
+			}
+		}
+
+		writeLong(buf, 0)
+	case "union":
+		for i, branch := range s.branches {
+			var b bytes.Buffer
+			if branch.encode(&b, v) != nil {
+				continue
+			}
+
+			writeLong(buf, int64(i))
+			buf.Write(b.Bytes())
+
+			return nil
+		}
+
+		return fmt.Errorf("the value %v didn't match any type of the union", v)
+	case "record":
+		record, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected the record \"%s\", got %T", s.name, v)
+		}
+
+		for _, f := range s.fields {
+			value, ok := record[f.name]
+			if !ok {
+				if !f.hasDefault {
+					return fmt.Errorf("missing the field \"%s\" of the record \"%s\"", f.name, s.name)
+				}
+
+				value = f.def
+			}
+
+			if err := f.schema.encode(buf, value); err != nil {
+				return fmt.Errorf("field \"%s\": %w", f.name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// decode read one value in the Avro binary format.
+// The records and maps are returned as map[string]interface{} and the bytes as []byte.
+//
+//nolint:funlen,gocyclo
+func (s *avroSchema) decode(r *bytes.Reader) (interface{}, error) {
+	switch s.typ {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		return b == 1, nil
+	case "int", "long":
+		return binary.ReadVarint(r)
+	case "float":
+		var bits uint32
+		err := binary.Read(r, binary.LittleEndian, &bits)
+
+		return math.Float32frombits(bits), err
+	case "double":
+		var bits uint64
+		err := binary.Read(r, binary.LittleEndian, &bits)
+
+		return math.Float64frombits(bits), err
+	case "string":
+		b, err := readBytes(r)
+
+		return string(b), err
+	case "bytes":
+		return readBytes(r)
+	case "fixed":
+		b := make([]byte, s.size)
+		_, err := io.ReadFull(r, b)
+
+		return b, err
+	case "enum":
+		i, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if i < 0 || int(i) >= len(s.symbols) {
+			return nil, fmt.Errorf("invalid symbol index %d of the enum \"%s\"", i, s.name)
+		}
+
+		return s.symbols[i], nil
+	case "array":
+		items := []interface{}{}
+		err := readBlocks(r, func() error {
+			item, err := s.items.decode(r)
+			items = append(items, item)
+
+			return err
+		})
+
+		return items, err
+	case "map":
+		values := map[string]interface{}{}
+		err := readBlocks(r, func() error {
+			k, err := readBytes(r)
+			if err != nil {
+				return err
+			}
+
+			values[string(k)], err = s.values.decode(r)
+
+			return err
+		})
+
+		return values, err
+	case "union":
+		i, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if i < 0 || int(i) >= len(s.branches) {
+			return nil, fmt.Errorf("invalid union index %d", i)
+		}
+
+		return s.branches[i].decode(r)
+	case "record":
+		record := make(map[string]interface{}, len(s.fields))
+
+		for _, f := range s.fields {
+			v, err := f.schema.decode(r)
+			if err != nil {
+				return nil, fmt.Errorf("field \"%s\": %w", f.name, err)
+			}
+
+			record[f.name] = v
+		}
+
+		return record, nil
+	}
+
+	return nil, fmt.Errorf("unknown avro type \"%s\"", s.typ)
+}
+
+// avroBytes accept the bytes as []byte or as a base64 string, the format used by encoding/json.
+func avroBytes(v interface{}) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case string:
+		decoded, err := base64.StdEncoding.DecodeString(b)
+		if err != nil {
+			return nil, fmt.Errorf("expected base64 bytes: %w", err)
+		}
+
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("expected bytes, got %T", v)
+	}
+}
+
+// writeLong write the number using the zig-zag variable length encoding, the same used by binary.PutVarint.
+func writeLong(buf *bytes.Buffer, n int64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutVarint(b[:], n)])
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 || n > int64(r.Len()) {
+		return nil, errors.New("invalid avro length")
+	}
+
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+
+	return b, err
+}
+
+// readBlocks read the blocks of arrays and maps, a negative count is followed by the size of the block.
+func readBlocks(r *bytes.Reader, item func() error) error {
+	for {
+		count, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return nil
+		}
+
+		if count < 0 {
+			count = -count
+
+			if _, err := binary.ReadVarint(r); err != nil {
+				return err
+			}
+		}
+
+		for i := int64(0); i < count; i++ {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
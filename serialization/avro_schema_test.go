@@ -0,0 +1,211 @@
+package serialization
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvroSchemaEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		value   string
+		want    interface{}
+		encoded []byte
+	}{
+		{
+			name:    "union with null",
+			schema:  `["null", "string"]`,
+			value:   `null`,
+			want:    nil,
+			encoded: []byte{0},
+		},
+		{
+			name:    "union with the second branch",
+			schema:  `["null", "string"]`,
+			value:   `"a"`,
+			want:    "a",
+			encoded: []byte{2, 2, 'a'},
+		},
+		{
+			name:    "union uses the first branch matching the value",
+			schema:  `["long", "double"]`,
+			value:   `1.5`,
+			want:    1.5,
+			encoded: []byte{2, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f},
+		},
+		{
+			name:    "bytes",
+			schema:  `"bytes"`,
+			value:   `"AAEC"`,
+			want:    []byte{0, 1, 2},
+			encoded: []byte{6, 0, 1, 2},
+		},
+		{
+			name:    "empty bytes",
+			schema:  `{"type": "bytes"}`,
+			value:   `""`,
+			want:    []byte{},
+			encoded: []byte{0},
+		},
+		{
+			name:    "fixed",
+			schema:  `{"type": "fixed", "name": "MD5", "size": 3}`,
+			value:   `"AAEC"`,
+			want:    []byte{0, 1, 2},
+			encoded: []byte{0, 1, 2},
+		},
+		{
+			name: "named type referenced in the same namespace",
+			schema: `{"type": "record", "name": "Pair", "namespace": "rabbids.test", "fields": [
+				{"name": "a", "type": {"type": "fixed", "name": "Hash", "size": 2}},
+				{"name": "b", "type": "Hash"},
+				{"name": "c", "type": "rabbids.test.Hash"}
+			]}`,
+			value:   `{"a": "AAE=", "b": "AgM=", "c": "BAU="}`,
+			want:    map[string]interface{}{"a": []byte{0, 1}, "b": []byte{2, 3}, "c": []byte{4, 5}},
+			encoded: []byte{0, 1, 2, 3, 4, 5},
+		},
+		{
+			name: "named type from another namespace",
+			schema: `{"type": "record", "name": "Event", "namespace": "a", "fields": [
+				{"name": "status", "type": {"type": "enum", "name": "b.Status", "symbols": ["ON", "OFF"]}},
+				{"name": "previous", "type": ["null", "b.Status"]}
+			]}`,
+			value:   `{"status": "OFF", "previous": "ON"}`,
+			want:    map[string]interface{}{"status": "OFF", "previous": "ON"},
+			encoded: []byte{2, 2, 0},
+		},
+		{
+			name: "recursive record",
+			schema: `{"type": "record", "name": "Node", "fields": [
+				{"name": "value", "type": "int"},
+				{"name": "next", "type": ["null", "Node"], "default": null}
+			]}`,
+			value: `{"value": 1, "next": {"value": 2}}`,
+			want: map[string]interface{}{
+				"value": int64(1),
+				"next":  map[string]interface{}{"value": int64(2), "next": nil},
+			},
+			encoded: []byte{2, 2, 4, 0},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseAvroSchema(tt.schema)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, s.encode(&buf, jsonValue(t, tt.value)))
+			require.Equal(t, tt.encoded, buf.Bytes())
+
+			r := bytes.NewReader(buf.Bytes())
+			got, err := s.decode(r)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Zero(t, r.Len())
+		})
+	}
+}
+
+func TestAvroSchemaEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		err    string
+	}{
+		{
+			name:   "union without a matching branch",
+			schema: `["null", "int"]`,
+			value:  `"a"`,
+			err:    "the value a didn't match any type of the union",
+		},
+		{
+			name:   "fixed with the wrong size",
+			schema: `{"type": "fixed", "name": "MD5", "size": 16}`,
+			value:  `"AAEC"`,
+			err:    `expected 16 bytes for the fixed "MD5", got 3`,
+		},
+		{
+			name:   "bytes not in base64",
+			schema: `"bytes"`,
+			value:  `"not base64!"`,
+			err:    "expected base64 bytes: illegal base64 data at input byte 3",
+		},
+		{
+			name:   "bytes with a number",
+			schema: `"bytes"`,
+			value:  `1`,
+			err:    "expected bytes, got json.Number",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseAvroSchema(tt.schema)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.EqualError(t, s.encode(&buf, jsonValue(t, tt.value)), tt.err)
+		})
+	}
+}
+
+func TestAvroSchemaDecodeErrors(t *testing.T) {
+	union, err := parseAvroSchema(`["null", "string"]`)
+	require.NoError(t, err)
+
+	_, err = union.decode(bytes.NewReader([]byte{4}))
+	require.EqualError(t, err, "invalid union index 2")
+
+	b, err := parseAvroSchema(`"bytes"`)
+	require.NoError(t, err)
+
+	_, err = b.decode(bytes.NewReader([]byte{8, 1}))
+	require.EqualError(t, err, "invalid avro length")
+
+	fixed, err := parseAvroSchema(`{"type": "fixed", "name": "MD5", "size": 4}`)
+	require.NoError(t, err)
+
+	_, err = fixed.decode(bytes.NewReader([]byte{1, 2}))
+	require.Error(t, err)
+
+	_, err = parseAvroSchema(`{"type": "fixed", "name": "MD5"}`)
+	require.EqualError(t, err, `invalid size of the avro fixed "MD5"`)
+}
+
+func TestSchemaRegistryTimeout(t *testing.T) {
+	require.Equal(t, DefaultRegistryTimeout, NewSchemaRegistry("http://localhost", nil).client.Timeout)
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	registry := NewSchemaRegistry(srv.URL, &http.Client{Timeout: 50 * time.Millisecond})
+
+	_, err := registry.Schema(1)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to fetch the schema 1")
+}
+
+func jsonValue(t *testing.T, value string) interface{} {
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.UseNumber()
+
+	var v interface{}
+	require.NoError(t, dec.Decode(&v))
+
+	return v
+}
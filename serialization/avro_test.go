@@ -0,0 +1,152 @@
+package serialization
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const userSchema = `{
+	"type": "record",
+	"name": "User",
+	"namespace": "rabbids.test",
+	"fields": [
+		{"name": "name", "type": "string"},
+		{"name": "age", "type": "int"},
+		{"name": "score", "type": "double"},
+		{"name": "active", "type": "boolean"},
+		{"name": "email", "type": ["null", "string"], "default": null},
+		{"name": "role", "type": {"type": "enum", "name": "Role", "symbols": ["ADMIN", "USER"]}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "attrs", "type": {"type": "map", "values": "long"}},
+		{"name": "avatar", "type": "bytes"},
+		{"name": "manager", "type": ["null", "User"], "default": null}
+	]
+}`
+
+type user struct {
+	Name    string           `json:"name"`
+	Age     int              `json:"age"`
+	Score   float64          `json:"score"`
+	Active  bool             `json:"active"`
+	Email   *string          `json:"email"`
+	Role    string           `json:"role"`
+	Tags    []string         `json:"tags"`
+	Attrs   map[string]int64 `json:"attrs"`
+	Avatar  []byte           `json:"avatar"`
+	Manager *user            `json:"manager,omitempty"`
+}
+
+func newTestRegistry(t *testing.T) (*SchemaRegistry, *int32, func()) {
+	var calls int32
+
+	schemas := map[int]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/subjects/users-value/versions":
+			var body struct {
+				Schema string `json:"schema"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			schemas[7] = body.Schema
+			_, _ = w.Write([]byte(`{"id": 7}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/schemas/ids/7":
+			_ = json.NewEncoder(w).Encode(map[string]string{"schema": schemas[7]})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error_code": 40403, "message": "Schema not found"}`))
+		}
+	}))
+
+	return NewSchemaRegistry(srv.URL+"/", srv.Client()), &calls, srv.Close
+}
+
+func TestAvro(t *testing.T) {
+	registry, calls, closeRegistry := newTestRegistry(t)
+	defer closeRegistry()
+
+	avro, err := NewAvro(registry, "users-value", userSchema)
+	require.NoError(t, err)
+	require.Equal(t, "avro/binary", avro.Name())
+
+	email := "john@example.com"
+	in := user{
+		Name:    "John",
+		Age:     42,
+		Score:   9.5,
+		Active:  true,
+		Email:   &email,
+		Role:    "ADMIN",
+		Tags:    []string{"a", "b"},
+		Attrs:   map[string]int64{"logins": -3},
+		Avatar:  []byte{0, 1, 2},
+		Manager: &user{Name: "Mary", Role: "USER", Tags: []string{}, Attrs: map[string]int64{}, Avatar: []byte{}},
+	}
+
+	b, err := avro.Marshal(in)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 0, 0, 7}, b[:5])
+
+	var out user
+	require.NoError(t, avro.Unmarshal(b, &out))
+	require.Equal(t, in, out)
+
+	_, err = avro.Marshal(in)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(calls), "the schema must be registered once")
+
+	// the schema is fetched from the registry by the consumers
+	consumer, err := NewAvro(NewSchemaRegistry(registry.url, nil), "users-value", userSchema)
+	require.NoError(t, err)
+	require.NoError(t, consumer.Unmarshal(b, &out))
+	require.NoError(t, consumer.Unmarshal(b, &out))
+	require.Equal(t, int32(2), atomic.LoadInt32(calls))
+
+	_, err = avro.Marshal(map[string]interface{}{"name": "John", "age": "42"})
+	require.EqualError(t, err, `the data doesn't match the schema of the subject "users-value": field "age": expected int, got string`)
+
+	_, err = avro.Marshal(map[string]interface{}{"name": "John"})
+	require.EqualError(t, err, `the data doesn't match the schema of the subject "users-value": missing the field "age" of the record "rabbids.test.User"`)
+
+	require.EqualError(t, avro.Unmarshal([]byte("{}"), &out), "the body isn't in the avro wire format")
+	require.Error(t, avro.Unmarshal(append(b, 1), &out))
+	require.Error(t, avro.Unmarshal(b[:len(b)-1], &out))
+	require.EqualError(t, avro.Unmarshal([]byte{0, 0, 0, 0, 9, 1}, &out),
+		"failed to fetch the schema 9: schema registry returned 404: Schema not found")
+}
+
+func TestAvroWithSchemaIDHeader(t *testing.T) {
+	registry, _, closeRegistry := newTestRegistry(t)
+	defer closeRegistry()
+
+	avro, err := NewAvro(registry, "users-value", `{"type": "record", "name": "Event", "fields": [{"name": "id", "type": "long"}]}`,
+		WithSchemaIDHeader())
+	require.NoError(t, err)
+
+	serialized, err := avro.Serialize("events", "event.created", map[string]int{"id": 1})
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, serialized.Body)
+	require.Equal(t, "avro/binary", serialized.ContentType)
+	require.Equal(t, map[string]interface{}{AvroSchemaIDHeader: int32(7)}, serialized.Headers)
+
+	var out map[string]int
+	require.NoError(t, avro.UnmarshalWithHeaders(serialized.Body, serialized.Headers, &out))
+	require.Equal(t, map[string]int{"id": 1}, out)
+}
+
+func TestParseAvroSchemaErrors(t *testing.T) {
+	_, err := parseAvroSchema(`{"type": "record", "fields": []}`)
+	require.EqualError(t, err, "the avro record must have a name")
+
+	_, err = parseAvroSchema(`{"type": "record", "name": "A", "fields": [{"name": "b", "type": "B"}]}`)
+	require.EqualError(t, err, `invalid field "b": unknown avro type "B"`)
+
+	_, err = parseAvroSchema(`{`)
+	require.Error(t, err)
+}
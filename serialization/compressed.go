@@ -0,0 +1,109 @@
+package serialization
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// The compression algorithms supported by Compressed, used as the Content-Encoding of the messages.
+const (
+	Gzip    = "gzip"
+	Deflate = "deflate"
+)
+
+// CompressedSerializer compress the data encoded by another serializer.
+type CompressedSerializer struct {
+	inner SerializerV2
+	algo  string
+}
+
+// Compressed return a serializer compressing the data serialized by the inner serializer, using Gzip or Deflate.
+// The rabbids producers set the Content-Encoding of the messages with the algorithm,
+// the consumers decompress the body inside Message.Decode or with the rabbids.Decompressor transformer.
+func Compressed(inner SerializerV2, algo string) *CompressedSerializer {
+	return &CompressedSerializer{inner: inner, algo: algo}
+}
+
+// Serialize returns the data serialized by the inner serializer and compressed, keeping the content type
+// and the headers of the inner serializer. The algorithm is added after the content encoding of the inner serializer.
+func (c *CompressedSerializer) Serialize(exchange, key string, v interface{}) (Serialized, error) {
+	out, err := c.inner.Serialize(exchange, key, v)
+	if err != nil {
+		return out, err
+	}
+
+	if out.Body, err = c.compress(out.Body); err != nil {
+		return out, err
+	}
+
+	out.ContentEncoding = appendEncoding(out.ContentEncoding, c.algo)
+
+	return out, nil
+}
+
+func (c *CompressedSerializer) compress(data []byte) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+
+	switch c.algo {
+	case Gzip:
+		w = gzip.NewWriter(&buf)
+	case Deflate:
+		var err error
+		if w, err = flate.NewWriter(&buf, flate.DefaultCompression); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression \"%s\"", c.algo)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress return the data decompressed using the content encoding, Gzip or Deflate.
+// The data is returned without changes for other encodings.
+func Decompress(encoding string, data []byte) ([]byte, error) {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+
+	switch encoding {
+	case Gzip:
+		if r, err = gzip.NewReader(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("failed to decompress: %w", err)
+		}
+	case Deflate:
+		r = flate.NewReader(bytes.NewReader(data))
+	default:
+		return data, nil
+	}
+
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress: %w", err)
+	}
+
+	return b, nil
+}
+
+// IsCompressed return true for the content encodings decompressed by Decompress.
+func IsCompressed(encoding string) bool {
+	return encoding == Gzip || encoding == Deflate
+}
@@ -0,0 +1,39 @@
+package serialization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressed(t *testing.T) {
+	for _, algo := range []string{Gzip, Deflate} {
+		out, err := Compressed(&JSON{}, algo).Serialize("ex", "key", map[string]string{"foo": "bar"})
+		require.NoError(t, err)
+		require.Equal(t, "application/json", out.ContentType)
+		require.Equal(t, algo, out.ContentEncoding)
+		require.Nil(t, out.Headers)
+		require.True(t, IsCompressed(algo))
+
+		data, err := Decompress(algo, out.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"foo":"bar"}`, string(data))
+	}
+
+	_, err := Compressed(&JSON{}, "br").Serialize("ex", "key", "value")
+	require.EqualError(t, err, `unsupported compression "br"`)
+
+	data, err := DecodeBody(nil, "identity", []byte("plain"), nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("plain"), data)
+
+	require.Equal(t, []string{"gzip", "aes-gcm"}, ContentEncodings(" GZIP ,aes-gcm,"))
+	require.Empty(t, ContentEncodings(""))
+
+	data, err = Decompress("", []byte("plain"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("plain"), data)
+
+	_, err = Decompress(Gzip, []byte("plain"))
+	require.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package serialization
+
+import (
+	"errors"
+	"strings"
+)
+
+// ContentEncodings return the encodings of the Content-Encoding in the order they were applied.
+// The encodings are separated by commas, like the HTTP Content-Encoding.
+func ContentEncodings(encoding string) []string {
+	var encodings []string
+
+	for _, e := range strings.Split(encoding, ",") {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" {
+			encodings = append(encodings, e)
+		}
+	}
+
+	return encodings
+}
+
+// appendEncoding add the encoding applied after the encodings of the Content-Encoding.
+func appendEncoding(encoding, next string) string {
+	if encoding == "" {
+		return next
+	}
+
+	return encoding + ", " + next
+}
+
+// DecodeBody remove the encodings of the body in the reverse order they were applied:
+// the body is decrypted before it is decompressed when the data was compressed and then encrypted.
+// The keys are used to decrypt the messages encrypted by the EncryptedSerializer, nil when the messages are
+// not encrypted. Unknown encodings are ignored.
+func DecodeBody(keys KeyProvider, encoding string, body []byte, headers map[string]interface{}) ([]byte, error) {
+	encodings := ContentEncodings(encoding)
+
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var err error
+
+		switch {
+		case encodings[i] == AESGCM:
+			if keys == nil {
+				return nil, errors.New("the body is encrypted and no key provider was registered")
+			}
+
+			body, err = Decrypt(keys, body, headers)
+		case IsCompressed(encodings[i]):
+			body, err = Decompress(encodings[i], body)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
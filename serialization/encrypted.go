@@ -0,0 +1,199 @@
+package serialization
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// AESGCM is the Content-Encoding added by the encrypted serializer.
+const AESGCM = "aes-gcm"
+
+// The headers used by the encrypted serializer to describe the encryption of the body.
+const (
+	EncryptionKeyIDHeader   = "x-encryption-key-id"
+	EncryptionNonceHeader   = "x-encryption-nonce"
+	EncryptionDataKeyHeader = "x-encryption-data-key"
+)
+
+// KeyProvider provide the data keys used to encrypt each message, protected by a master key.
+// It can be backed by a KMS (like the GenerateDataKey and Decrypt operations of AWS KMS) or by static keys.
+type KeyProvider interface {
+	// DataKey return a new AES key, the same key encrypted by the master key and the ID of the master key.
+	DataKey() (keyID string, key, encryptedKey []byte, err error)
+	// DecryptKey return the data key encrypted by the master key with the ID.
+	DecryptKey(keyID string, encryptedKey []byte) ([]byte, error)
+}
+
+// StaticKeyProvider is a KeyProvider using master keys defined by the application.
+// The current key is used to encrypt and all the keys are used to decrypt, to allow the rotation of the keys.
+type StaticKeyProvider struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewStaticKeyProvider create a StaticKeyProvider with AES keys of 16, 24 or 32 bytes, by ID.
+func NewStaticKeyProvider(current string, keys map[string][]byte) (*StaticKeyProvider, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("the current key \"%s\" didn't exist", current)
+	}
+
+	p := &StaticKeyProvider{current: current, keys: map[string]cipher.AEAD{}}
+
+	for id, key := range keys {
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key \"%s\": %w", id, err)
+		}
+
+		p.keys[id] = aead
+	}
+
+	return p, nil
+}
+
+// DataKey return a new 32 bytes data key encrypted with the current key.
+func (p *StaticKeyProvider) DataKey() (string, []byte, []byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", nil, nil, err
+	}
+
+	encrypted, err := seal(p.keys[p.current], key)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return p.current, key, encrypted, nil
+}
+
+// DecryptKey return the data key encrypted with the key ID.
+func (p *StaticKeyProvider) DecryptKey(keyID string, encryptedKey []byte) ([]byte, error) {
+	aead, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key \"%s\"", keyID)
+	}
+
+	return open(aead, encryptedKey)
+}
+
+// EncryptedSerializer encrypt the data encoded by another serializer with AES-GCM,
+// using a new data key for each message (envelope encryption).
+type EncryptedSerializer struct {
+	inner SerializerV2
+	keys  KeyProvider
+}
+
+// Encrypted return a serializer encrypting the data serialized by the inner serializer.
+// The key ID, the nonce and the encrypted data key are sent in the message headers.
+// The consumers decrypt the body with Decrypt or the rabbids.NewDecryptor transformer.
+func Encrypted(inner SerializerV2, keys KeyProvider) *EncryptedSerializer {
+	return &EncryptedSerializer{inner: inner, keys: keys}
+}
+
+// Serialize returns the encrypted data with the headers of the encryption, keeping the content type
+// and the headers of the inner serializer. AESGCM is added after the content encoding of the inner serializer,
+// like "gzip, aes-gcm", so the body is decrypted before it is decompressed.
+func (e *EncryptedSerializer) Serialize(exchange, key string, v interface{}) (Serialized, error) {
+	out, err := e.inner.Serialize(exchange, key, v)
+	if err != nil {
+		return out, err
+	}
+
+	keyID, dataKey, encryptedKey, err := e.keys.DataKey()
+	if err != nil {
+		return out, fmt.Errorf("failed to create the data key: %w", err)
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return out, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return out, err
+	}
+
+	if out.Headers == nil {
+		out.Headers = map[string]interface{}{}
+	}
+
+	out.Headers[EncryptionKeyIDHeader] = keyID
+	out.Headers[EncryptionNonceHeader] = nonce
+	out.Headers[EncryptionDataKeyHeader] = encryptedKey
+	out.Body = aead.Seal(nil, nonce, out.Body, nil)
+	out.ContentEncoding = appendEncoding(out.ContentEncoding, AESGCM)
+
+	return out, nil
+}
+
+// IsEncrypted return true when the headers describe a body encrypted by the EncryptedSerializer.
+// The content encoding of the message also ends with AESGCM.
+func IsEncrypted(headers map[string]interface{}) bool {
+	_, ok := headers[EncryptionKeyIDHeader]
+
+	return ok
+}
+
+// Decrypt return the body encrypted by the EncryptedSerializer, using the encryption headers.
+func Decrypt(keys KeyProvider, body []byte, headers map[string]interface{}) ([]byte, error) {
+	keyID, _ := headers[EncryptionKeyIDHeader].(string)
+	nonce, _ := headers[EncryptionNonceHeader].([]byte)
+	encryptedKey, _ := headers[EncryptionDataKeyHeader].([]byte)
+
+	if keyID == "" || len(nonce) == 0 || len(encryptedKey) == 0 {
+		return nil, errors.New("the encryption headers are missing")
+	}
+
+	key, err := keys.DecryptKey(keyID, encryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the data key: %w", err)
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid encryption nonce")
+	}
+
+	data, err := aead.Open(nil, nonce, body, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	return data, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypt the data with a random nonce, placed before the encrypted data.
+func seal(aead cipher.AEAD, data []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func open(aead cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("invalid encrypted data")
+	}
+
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+}
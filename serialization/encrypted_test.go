@@ -0,0 +1,78 @@
+package serialization
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncrypted(t *testing.T) {
+	old, err := NewStaticKeyProvider("old", map[string][]byte{"old": bytes.Repeat([]byte("o"), 16)})
+	require.NoError(t, err)
+
+	keys, err := NewStaticKeyProvider("new", map[string][]byte{
+		"old": bytes.Repeat([]byte("o"), 16),
+		"new": bytes.Repeat([]byte("n"), 32),
+	})
+	require.NoError(t, err)
+
+	out, err := Encrypted(Compressed(&JSON{}, Gzip), keys).Serialize("ex", "key", map[string]string{"ssn": "123"})
+	require.NoError(t, err)
+	require.Equal(t, "application/json", out.ContentType)
+	require.Equal(t, "gzip, aes-gcm", out.ContentEncoding)
+	require.True(t, IsEncrypted(out.Headers))
+	require.Equal(t, "new", out.Headers[EncryptionKeyIDHeader])
+	require.NotContains(t, string(out.Body), "123")
+
+	data, err := Decrypt(keys, out.Body, out.Headers)
+	require.NoError(t, err)
+
+	data, err = Decompress(Gzip, data)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"ssn":"123"}`, string(data))
+
+	data, err = DecodeBody(keys, out.ContentEncoding, out.Body, out.Headers)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"ssn":"123"}`, string(data))
+
+	_, err = DecodeBody(nil, out.ContentEncoding, out.Body, out.Headers)
+	require.EqualError(t, err, "the body is encrypted and no key provider was registered")
+
+	// the encrypted body is compressed when the compression is the outer serializer
+	out, err = Compressed(Encrypted(&JSON{}, keys), Deflate).Serialize("ex", "key", "value")
+	require.NoError(t, err)
+	require.Equal(t, "aes-gcm, deflate", out.ContentEncoding)
+
+	data, err = DecodeBody(keys, out.ContentEncoding, out.Body, out.Headers)
+	require.NoError(t, err)
+	require.Equal(t, `"value"`, string(data))
+
+	// the messages encrypted with the old key are still decrypted after the rotation
+	out, err = Encrypted(&JSON{}, old).Serialize("ex", "key", "value")
+	require.NoError(t, err)
+
+	body, headers := out.Body, out.Headers
+
+	data, err = Decrypt(keys, body, headers)
+	require.NoError(t, err)
+	require.Equal(t, `"value"`, string(data))
+
+	_, err = Decrypt(keys, append(body, 1), headers)
+	require.Error(t, err)
+
+	_, err = Decrypt(old, body, map[string]interface{}{})
+	require.EqualError(t, err, "the encryption headers are missing")
+
+	headers[EncryptionKeyIDHeader] = "unknown"
+	_, err = Decrypt(keys, body, headers)
+	require.EqualError(t, err, `failed to decrypt the data key: unknown key "unknown"`)
+}
+
+func TestNewStaticKeyProviderErrors(t *testing.T) {
+	_, err := NewStaticKeyProvider("a", map[string][]byte{})
+	require.EqualError(t, err, `the current key "a" didn't exist`)
+
+	_, err = NewStaticKeyProvider("a", map[string][]byte{"a": []byte("short")})
+	require.EqualError(t, err, `invalid key "a": crypto/aes: invalid key size 5`)
+}
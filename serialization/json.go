@@ -1,13 +1,51 @@
 package serialization
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// JSONEngine is the json implementation used by the JSON serializer, encoding/json by default.
+// It's implemented by jsoniter (jsoniter.ConfigFastest or a custom config) and can wrap other packages,
+// like segmentio/encoding/json.
+type JSONEngine interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
 
 // JSON implements the rabbids.Serializer interface.
-type JSON struct{}
+// The zero value uses encoding/json with the default options.
+type JSON struct {
+	// Engine replace encoding/json. The engine options, like omitting the empty fields or escaping HTML,
+	// are defined by the engine config and the options below are ignored.
+	Engine JSONEngine
+	// DisableHTMLEscape keep the characters &, < and > inside the strings without escaping them.
+	DisableHTMLEscape bool
+	// Indent the json with the string, for each level.
+	Indent string
+}
 
 // Marshal returns the data in json format or an error.
 func (j *JSON) Marshal(v interface{}) ([]byte, error) {
-	return json.Marshal(v)
+	if j.Engine != nil {
+		return j.Engine.Marshal(v)
+	}
+
+	if !j.DisableHTMLEscape && j.Indent == "" {
+		return json.Marshal(v)
+	}
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(!j.DisableHTMLEscape)
+	enc.SetIndent("", j.Indent)
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
 }
 
 // Name returns the name of the serialization used.
@@ -15,3 +53,17 @@ func (j *JSON) Marshal(v interface{}) ([]byte, error) {
 func (j *JSON) Name() string {
 	return "application/json"
 }
+
+// Serialize implements the SerializerV2 interface, returning the json data with the content type.
+func (j *JSON) Serialize(exchange, key string, v interface{}) (Serialized, error) {
+	return serialize(j, v)
+}
+
+// Unmarshal parses the json data into the value pointed by v.
+func (j *JSON) Unmarshal(data []byte, v interface{}) error {
+	if j.Engine != nil {
+		return j.Engine.Unmarshal(data, v)
+	}
+
+	return json.Unmarshal(data, v)
+}
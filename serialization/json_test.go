@@ -0,0 +1,52 @@
+package serialization
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingEngine struct {
+	marshal, unmarshal int
+}
+
+func (e *countingEngine) Marshal(v interface{}) ([]byte, error) {
+	e.marshal++
+
+	return json.Marshal(v)
+}
+
+func (e *countingEngine) Unmarshal(data []byte, v interface{}) error {
+	e.unmarshal++
+
+	return json.Unmarshal(data, v)
+}
+
+func TestJSON(t *testing.T) {
+	data := map[string]string{"html": "<b>&</b>"}
+
+	b, err := (&JSON{}).Marshal(data)
+	require.NoError(t, err)
+	require.Equal(t, `{"html":"\u003cb\u003e\u0026\u003c/b\u003e"}`, string(b))
+
+	b, err = (&JSON{DisableHTMLEscape: true}).Marshal(data)
+	require.NoError(t, err)
+	require.Equal(t, `{"html":"<b>&</b>"}`, string(b))
+
+	b, err = (&JSON{Indent: "  "}).Marshal(data)
+	require.NoError(t, err)
+	require.Equal(t, "{\n  \"html\": \"\\u003cb\\u003e\\u0026\\u003c/b\\u003e\"\n}", string(b))
+
+	engine := &countingEngine{}
+	s := &JSON{Engine: engine}
+
+	b, err = s.Marshal(data)
+	require.NoError(t, err)
+
+	var out map[string]string
+	require.NoError(t, s.Unmarshal(b, &out))
+	require.Equal(t, data, out)
+	require.Equal(t, 1, engine.marshal)
+	require.Equal(t, 1, engine.unmarshal)
+}
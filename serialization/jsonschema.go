@@ -0,0 +1,420 @@
+package serialization
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonSchema is a JSON Schema supporting the validation keywords used to describe messages:
+// type, enum, const, properties, required, additionalProperties, items, the length and range limits,
+// pattern, allOf, anyOf, oneOf, not and the local references ($ref to #/definitions or #/$defs).
+type jsonSchema struct {
+	root *jsonSchema
+	raw  map[string]interface{}
+	// always is used by the boolean schemas, true accepts everything and false nothing.
+	always  *bool
+	pattern *regexp.Regexp
+	props   map[string]*jsonSchema
+	addl    *jsonSchema
+	items   *jsonSchema
+	subs    map[string][]*jsonSchema
+	not     *jsonSchema
+	defs    map[string]*jsonSchema
+}
+
+func parseJSONSchema(schema string) (*jsonSchema, error) {
+	dec := json.NewDecoder(strings.NewReader(schema))
+	dec.UseNumber()
+
+	var raw interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid json schema: %w", err)
+	}
+
+	return compileJSONSchema(raw, nil)
+}
+
+//nolint:gocyclo
+func compileJSONSchema(raw interface{}, root *jsonSchema) (*jsonSchema, error) {
+	s := &jsonSchema{root: root}
+	if root == nil {
+		s.root = s
+	}
+
+	switch v := raw.(type) {
+	case bool:
+		s.always = &v
+
+		return s, nil
+	case map[string]interface{}:
+		s.raw = v
+	default:
+		return nil, fmt.Errorf("invalid json schema %v", raw)
+	}
+
+	compile := func(r interface{}) (*jsonSchema, error) {
+		return compileJSONSchema(r, s.root)
+	}
+
+	for _, key := range []string{"definitions", "$defs"} {
+		defs, _ := s.raw[key].(map[string]interface{})
+		for name, def := range defs {
+			compiled, err := compile(def)
+			if err != nil {
+				return nil, fmt.Errorf("invalid definition \"%s\": %w", name, err)
+			}
+
+			if s.defs == nil {
+				s.defs = map[string]*jsonSchema{}
+			}
+
+			s.defs["#/"+key+"/"+name] = compiled
+		}
+	}
+
+	if p, ok := s.raw["pattern"].(string); ok {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern \"%s\": %w", p, err)
+		}
+
+		s.pattern = re
+	}
+
+	if props, ok := s.raw["properties"].(map[string]interface{}); ok {
+		s.props = map[string]*jsonSchema{}
+
+		for name, p := range props {
+			compiled, err := compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid property \"%s\": %w", name, err)
+			}
+
+			s.props[name] = compiled
+		}
+	}
+
+	for key, target := range map[string]**jsonSchema{"additionalProperties": &s.addl, "items": &s.items, "not": &s.not} {
+		if r, ok := s.raw[key]; ok {
+			compiled, err := compile(r)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", key, err)
+			}
+
+			*target = compiled
+		}
+	}
+
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		list, _ := s.raw[key].([]interface{})
+		for _, r := range list {
+			compiled, err := compile(r)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", key, err)
+			}
+
+			if s.subs == nil {
+				s.subs = map[string][]*jsonSchema{}
+			}
+
+			s.subs[key] = append(s.subs[key], compiled)
+		}
+	}
+
+	return s, nil
+}
+
+// validate return an error describing the first violation of the schema, the path is the location of the value.
+//
+//nolint:funlen,gocyclo
+func (s *jsonSchema) validate(v interface{}, path string) error {
+	if s.always != nil {
+		if !*s.always {
+			return fmt.Errorf("%s: no value is allowed", path)
+		}
+
+		return nil
+	}
+
+	if ref, ok := s.raw["$ref"].(string); ok {
+		target, ok := s.root.defs[ref]
+		if ref == "#" {
+			target, ok = s.root, true
+		}
+
+		if !ok {
+			return fmt.Errorf("%s: unknown reference \"%s\"", path, ref)
+		}
+
+		return target.validate(v, path)
+	}
+
+	if t, ok := s.raw["type"]; ok && !matchesJSONType(t, v) {
+		return fmt.Errorf("%s: expected %v, got %s", path, t, jsonTypeOf(v))
+	}
+
+	if enum, ok := s.raw["enum"].([]interface{}); ok {
+		found := false
+
+		for _, e := range enum {
+			if jsonEqual(e, v) {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%s: the value must be one of %v", path, enum)
+		}
+	}
+
+	if c, ok := s.raw["const"]; ok && !jsonEqual(c, v) {
+		return fmt.Errorf("%s: the value must be %v", path, c)
+	}
+
+	switch value := v.(type) {
+	case string:
+		n := float64(utf8.RuneCountInString(value))
+		if min, ok := s.number("minLength"); ok && n < min {
+			return fmt.Errorf("%s: the length must be at least %v", path, min)
+		}
+
+		if max, ok := s.number("maxLength"); ok && n > max {
+			return fmt.Errorf("%s: the length must be at most %v", path, max)
+		}
+
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			return fmt.Errorf("%s: the value must match the pattern \"%s\"", path, s.pattern)
+		}
+	case json.Number:
+		n, _ := value.Float64()
+		if min, ok := s.number("minimum"); ok && n < min {
+			return fmt.Errorf("%s: the value must be at least %v", path, min)
+		}
+
+		if max, ok := s.number("maximum"); ok && n > max {
+			return fmt.Errorf("%s: the value must be at most %v", path, max)
+		}
+
+		if min, ok := s.number("exclusiveMinimum"); ok && n <= min {
+			return fmt.Errorf("%s: the value must be greater than %v", path, min)
+		}
+
+		if max, ok := s.number("exclusiveMaximum"); ok && n >= max {
+			return fmt.Errorf("%s: the value must be less than %v", path, max)
+		}
+
+		if m, ok := s.number("multipleOf"); ok && m > 0 && math.Abs(math.Remainder(n, m)) > 1e-9 {
+			return fmt.Errorf("%s: the value must be a multiple of %v", path, m)
+		}
+	case []interface{}:
+		n := float64(len(value))
+		if min, ok := s.number("minItems"); ok && n < min {
+			return fmt.Errorf("%s: expected at least %v items", path, min)
+		}
+
+		if max, ok := s.number("maxItems"); ok && n > max {
+			return fmt.Errorf("%s: expected at most %v items", path, max)
+		}
+
+		if unique, _ := s.raw["uniqueItems"].(bool); unique {
+			for i := range value {
+				for j := i + 1; j < len(value); j++ {
+					if jsonEqual(value[i], value[j]) {
+						return fmt.Errorf("%s: the items must be unique", path)
+					}
+				}
+			}
+		}
+
+		if s.items != nil {
+			for i, item := range value {
+				if err := s.items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		if err := s.validateObject(value, path); err != nil {
+			return err
+		}
+	}
+
+	return s.validateSubschemas(v, path)
+}
+
+func (s *jsonSchema) validateObject(value map[string]interface{}, path string) error {
+	required, _ := s.raw["required"].([]interface{})
+	for _, r := range required {
+		name, _ := r.(string)
+		if _, ok := value[name]; !ok {
+			return fmt.Errorf("%s: missing the required property \"%s\"", path, name)
+		}
+	}
+
+	n := float64(len(value))
+	if min, ok := s.number("minProperties"); ok && n < min {
+		return fmt.Errorf("%s: expected at least %v properties", path, min)
+	}
+
+	if max, ok := s.number("maxProperties"); ok && n > max {
+		return fmt.Errorf("%s: expected at most %v properties", path, max)
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := s.props[name]
+		if !ok {
+			prop = s.addl
+		}
+
+		if prop == nil {
+			continue
+		}
+
+		if prop.always != nil && !*prop.always && !ok {
+			return fmt.Errorf("%s: the property \"%s\" is not allowed", path, name)
+		}
+
+		if err := prop.validate(value[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *jsonSchema) validateSubschemas(v interface{}, path string) error {
+	for _, sub := range s.subs["allOf"] {
+		if err := sub.validate(v, path); err != nil {
+			return err
+		}
+	}
+
+	if anyOf := s.subs["anyOf"]; len(anyOf) > 0 {
+		matched := false
+
+		for _, sub := range anyOf {
+			if sub.validate(v, path) == nil {
+				matched = true
+
+				break
+			}
+		}
+
+		if !matched {
+			return fmt.Errorf("%s: the value must match at least one schema of anyOf", path)
+		}
+	}
+
+	if oneOf := s.subs["oneOf"]; len(oneOf) > 0 {
+		matches := 0
+
+		for _, sub := range oneOf {
+			if sub.validate(v, path) == nil {
+				matches++
+			}
+		}
+
+		if matches != 1 {
+			return fmt.Errorf("%s: the value must match exactly one schema of oneOf, matched %d", path, matches)
+		}
+	}
+
+	if s.not != nil && s.not.validate(v, path) == nil {
+		return fmt.Errorf("%s: the value must not match the schema of not", path)
+	}
+
+	return nil
+}
+
+func (s *jsonSchema) number(key string) (float64, bool) {
+	n, ok := s.raw[key].(json.Number)
+	if !ok {
+		return 0, false
+	}
+
+	f, err := n.Float64()
+
+	return f, err == nil
+}
+
+func matchesJSONType(t, v interface{}) bool {
+	switch typ := t.(type) {
+	case string:
+		actual := jsonTypeOf(v)
+
+		return actual == typ || (typ == "number" && actual == "integer")
+	case []interface{}:
+		for _, option := range typ {
+			if matchesJSONType(option, v) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func jsonTypeOf(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+
+		if f, err := value.Float64(); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// jsonEqual compare two values decoded from json, the numbers are compared by value.
+func jsonEqual(a, b interface{}) bool {
+	na, okA := a.(json.Number)
+	nb, okB := b.(json.Number)
+
+	if okA && okB {
+		fa, _ := na.Float64()
+		fb, _ := nb.Float64()
+
+		return fa == fb
+	}
+
+	ba, errA := json.Marshal(a)
+	bb, errB := json.Marshal(b)
+
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+
+	return bytes.Equal(ba, bb)
+}
@@ -0,0 +1,39 @@
+package serialization
+
+import "fmt"
+
+// Passthrough implements the rabbids.Serializer interface publishing the data without changes.
+// The data must be a []byte or a string, use rabbids.WithContentType to set the real content type.
+type Passthrough struct {
+	// BytesOnly reject the data that isn't a []byte, used to bridge binary payloads
+	// like images or protobuf messages from other systems.
+	BytesOnly bool
+}
+
+// Marshal returns the data as it is or an error for other types.
+func (p *Passthrough) Marshal(v interface{}) ([]byte, error) {
+	if data, ok := v.([]byte); ok {
+		return data, nil
+	}
+
+	if p.BytesOnly {
+		return nil, fmt.Errorf("the passthrough serializer expects []byte, got %T", v)
+	}
+
+	if data, ok := v.(string); ok {
+		return []byte(data), nil
+	}
+
+	return nil, fmt.Errorf("the passthrough serializer expects []byte or string, got %T", v)
+}
+
+// Name returns the name of the serialization used.
+// This value is used as the ContentType value on the message.
+func (p *Passthrough) Name() string {
+	return "application/octet-stream"
+}
+
+// Serialize implements the SerializerV2 interface, returning the data with the content type.
+func (p *Passthrough) Serialize(exchange, key string, v interface{}) (Serialized, error) {
+	return serialize(p, v)
+}
@@ -0,0 +1,153 @@
+package serialization
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRegistryTimeout is the timeout of the requests to the schema registry when no client is used.
+const DefaultRegistryTimeout = 10 * time.Second
+
+// SchemaRegistry is a client for a Confluent-compatible schema registry.
+// The schemas and IDs are cached, the registry is only called once for each schema.
+type SchemaRegistry struct {
+	url     string
+	client  *http.Client
+	mutex   sync.RWMutex
+	schemas map[int]*avroSchema
+	ids     map[string]int
+}
+
+// NewSchemaRegistry create a schema registry client using the base URL of the registry.
+// A client with the DefaultRegistryTimeout is used when the client is nil.
+func NewSchemaRegistry(registryURL string, client *http.Client) *SchemaRegistry {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultRegistryTimeout}
+	}
+
+	return &SchemaRegistry{
+		url:     strings.TrimSuffix(registryURL, "/"),
+		client:  client,
+		schemas: map[int]*avroSchema{},
+		ids:     map[string]int{},
+	}
+}
+
+// Register register the schema under the subject and return the schema ID.
+// Registering the same schema again returns the same ID.
+func (r *SchemaRegistry) Register(subject, schema string) (int, error) {
+	key := subject + "\x00" + schema
+
+	r.mutex.RLock()
+	id, ok := r.ids[key]
+	r.mutex.RUnlock()
+
+	if ok {
+		return id, nil
+	}
+
+	parsed, err := parseAvroSchema(schema)
+	if err != nil {
+		return 0, err
+	}
+
+	var resp struct {
+		ID int `json:"id"`
+	}
+
+	err = r.do(http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", map[string]string{"schema": schema}, &resp)
+	if err != nil {
+		return 0, fmt.Errorf("failed to register the schema of the subject \"%s\": %w", subject, err)
+	}
+
+	r.mutex.Lock()
+	r.ids[key] = resp.ID
+	r.schemas[resp.ID] = parsed
+	r.mutex.Unlock()
+
+	return resp.ID, nil
+}
+
+// Schema return the schema of the ID from the registry.
+func (r *SchemaRegistry) Schema(id int) (string, error) {
+	var resp struct {
+		Schema string `json:"schema"`
+	}
+
+	err := r.do(http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &resp)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the schema %d: %w", id, err)
+	}
+
+	return resp.Schema, nil
+}
+
+// parsedSchema return the parsed schema of the ID, using the cache.
+func (r *SchemaRegistry) parsedSchema(id int) (*avroSchema, error) {
+	r.mutex.RLock()
+	s, ok := r.schemas[id]
+	r.mutex.RUnlock()
+
+	if ok {
+		return s, nil
+	}
+
+	schema, err := r.Schema(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err = parseAvroSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %d: %w", id, err)
+	}
+
+	r.mutex.Lock()
+	r.schemas[id] = s
+	r.mutex.Unlock()
+
+	return s, nil
+}
+
+func (r *SchemaRegistry) do(method, path string, body, out interface{}) error {
+	var reqBody bytes.Buffer
+
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, r.url+path, &reqBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var registryErr struct {
+			Code    int    `json:"error_code"`
+			Message string `json:"message"`
+		}
+
+		_ = json.NewDecoder(resp.Body).Decode(&registryErr)
+
+		return fmt.Errorf("schema registry returned %d: %s", resp.StatusCode, registryErr.Message)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
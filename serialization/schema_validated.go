@@ -0,0 +1,89 @@
+package serialization
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrSchemaValidation is returned when the data doesn't match the JSON Schema of the destination.
+var ErrSchemaValidation = errors.New("the data doesn't match the json schema")
+
+// SchemaValidated validate the data against a JSON Schema, selected by the exchange and routing key
+// of the message, before serializing it with the inner serializer. The data is validated using its json
+// representation, so any inner serializer can be used.
+type SchemaValidated struct {
+	inner   SerializerV2
+	mutex   sync.RWMutex
+	schemas map[schemaRoute]*jsonSchema
+}
+
+type schemaRoute struct {
+	exchange, key string
+}
+
+// NewSchemaValidated create a serializer validating the data before serializing it with the inner serializer.
+// Use Add to set the schemas, the messages sent to destinations without a schema are not validated.
+func NewSchemaValidated(inner SerializerV2) *SchemaValidated {
+	return &SchemaValidated{inner: inner, schemas: map[schemaRoute]*jsonSchema{}}
+}
+
+// Add set the JSON Schema of the messages sent to the exchange with the routing key.
+// An empty routing key is used for all the messages of the exchange without a schema for the routing key.
+func (s *SchemaValidated) Add(exchange, routingKey, schema string) error {
+	parsed, err := parseJSONSchema(schema)
+	if err != nil {
+		return fmt.Errorf("failed to add the schema of \"%s/%s\": %w", exchange, routingKey, err)
+	}
+
+	s.mutex.Lock()
+	s.schemas[schemaRoute{exchange, routingKey}] = parsed
+	s.mutex.Unlock()
+
+	return nil
+}
+
+// Serialize returns an error wrapping ErrSchemaValidation when the data doesn't match the schema
+// of the exchange and routing key, or the data serialized by the inner serializer.
+func (s *SchemaValidated) Serialize(exchange, key string, v interface{}) (Serialized, error) {
+	if err := s.Validate(exchange, key, v); err != nil {
+		return Serialized{}, err
+	}
+
+	return s.inner.Serialize(exchange, key, v)
+}
+
+// Validate check the data against the schema of the exchange and routing key.
+func (s *SchemaValidated) Validate(exchange, key string, v interface{}) error {
+	s.mutex.RLock()
+	schema, ok := s.schemas[schemaRoute{exchange, key}]
+	if !ok {
+		schema, ok = s.schemas[schemaRoute{exchange, ""}]
+	}
+	s.mutex.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return err
+	}
+
+	if err := schema.validate(value, "$"); err != nil {
+		return fmt.Errorf("%w of \"%s/%s\": %s", ErrSchemaValidation, exchange, key, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package serialization
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const orderSchema = `{
+	"type": "object",
+	"required": ["id", "items"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "pattern": "^ord-[0-9]+$"},
+		"status": {"enum": ["new", "paid"]},
+		"total": {"type": "number", "minimum": 0},
+		"items": {"type": "array", "minItems": 1, "items": {"$ref": "#/definitions/item"}},
+		"coupon": {"type": ["string", "null"], "maxLength": 5}
+	},
+	"definitions": {
+		"item": {
+			"type": "object",
+			"required": ["sku", "qty"],
+			"properties": {"sku": {"type": "string"}, "qty": {"type": "integer", "exclusiveMinimum": 0}}
+		}
+	}
+}`
+
+func TestSchemaValidated(t *testing.T) {
+	s := NewSchemaValidated(Compressed(&JSON{}, Gzip))
+	require.NoError(t, s.Add("orders", "order.created", orderSchema))
+	require.NoError(t, s.Add("orders", "", `{"type": "object", "required": ["id"]}`))
+	require.Error(t, s.Add("orders", "bad", `{"pattern": "("}`))
+
+	valid := map[string]interface{}{
+		"id":     "ord-1",
+		"status": "new",
+		"total":  10.5,
+		"items":  []map[string]interface{}{{"sku": "a", "qty": 2}},
+		"coupon": nil,
+	}
+
+	out, err := s.Serialize("orders", "order.created", valid)
+	require.NoError(t, err)
+	require.Equal(t, "application/json", out.ContentType)
+	require.Equal(t, Gzip, out.ContentEncoding)
+	require.Nil(t, out.Headers)
+
+	data, err := Decompress(Gzip, out.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"ord-1"`)
+
+	tests := []struct {
+		change func(map[string]interface{})
+		err    string
+	}{
+		{func(m map[string]interface{}) { delete(m, "id") }, `$: missing the required property "id"`},
+		{func(m map[string]interface{}) { m["id"] = "1" }, `$.id: the value must match the pattern "^ord-[0-9]+$"`},
+		{func(m map[string]interface{}) { m["status"] = "lost" }, `$.status: the value must be one of [new paid]`},
+		{func(m map[string]interface{}) { m["total"] = -1 }, `$.total: the value must be at least 0`},
+		{func(m map[string]interface{}) { m["items"] = []int{} }, `$.items: expected at least 1 items`},
+		{
+			func(m map[string]interface{}) { m["items"] = []map[string]interface{}{{"sku": "a", "qty": 1.5}} },
+			`$.items[0].qty: expected integer, got number`,
+		},
+		{func(m map[string]interface{}) { m["coupon"] = 10 }, `$.coupon: expected [string null], got integer`},
+		{func(m map[string]interface{}) { m["extra"] = true }, `$: the property "extra" is not allowed`},
+	}
+
+	for _, tt := range tests {
+		m := map[string]interface{}{}
+		for k, v := range valid {
+			m[k] = v
+		}
+
+		tt.change(m)
+
+		_, err := s.Serialize("orders", "order.created", m)
+		require.True(t, errors.Is(err, ErrSchemaValidation))
+		require.EqualError(t, err, `the data doesn't match the json schema of "orders/order.created": `+tt.err)
+	}
+
+	// the exchange schema is used for the other routing keys
+	_, err = s.Serialize("orders", "order.paid", map[string]string{"total": "1"})
+	require.EqualError(t, err, `the data doesn't match the json schema of "orders/order.paid": $: missing the required property "id"`)
+
+	// destinations without a schema aren't validated
+	_, err = s.Serialize("users", "user.created", "anything")
+	require.NoError(t, err)
+
+	// the destination is passed by the outer serializers
+	_, err = Compressed(s, Deflate).Serialize("orders", "order.paid", map[string]string{"total": "1"})
+	require.True(t, errors.Is(err, ErrSchemaValidation))
+}
+
+func TestJSONSchemaCombinations(t *testing.T) {
+	schema, err := parseJSONSchema(`{
+		"oneOf": [{"type": "integer"}, {"type": "number", "multipleOf": 0.5}],
+		"not": {"const": 3}
+	}`)
+	require.NoError(t, err)
+
+	require.NoError(t, schema.validate(jsonNumber("2.5"), "$"))
+	require.EqualError(t, schema.validate(jsonNumber("2"), "$"), "$: the value must match exactly one schema of oneOf, matched 2")
+	require.EqualError(t, schema.validate(jsonNumber("0.3"), "$"), "$: the value must match exactly one schema of oneOf, matched 0")
+
+	schema, err = parseJSONSchema(`{"anyOf": [{"type": "string"}, {"type": "array", "uniqueItems": true}]}`)
+	require.NoError(t, err)
+	require.NoError(t, schema.validate("value", "$"))
+	require.EqualError(t, schema.validate(true, "$"), "$: the value must match at least one schema of anyOf")
+}
+
+func jsonNumber(n string) interface{} {
+	return json.Number(n)
+}
@@ -0,0 +1,32 @@
+package serialization
+
+// Serialized is the body encoded by a SerializerV2 with the message properties describing it.
+type Serialized struct {
+	Body            []byte
+	ContentType     string
+	ContentEncoding string
+	Headers         map[string]interface{}
+}
+
+// SerializerV2 is the same interface as rabbids.SerializerV2, implemented by all the serializers of this package.
+// The wrappers, like Compressed and Encrypted, receive the destination of the message and pass it
+// to the inner serializer.
+type SerializerV2 interface {
+	Serialize(exchange, key string, data interface{}) (Serialized, error)
+}
+
+// Serializer is the same interface as rabbids.Serializer.
+type Serializer interface {
+	Marshal(interface{}) ([]byte, error)
+	Name() string
+}
+
+// serialize return the data marshaled by the serializer with its name as the content type.
+func serialize(s Serializer, v interface{}) (Serialized, error) {
+	b, err := s.Marshal(v)
+	if err != nil {
+		return Serialized{}, err
+	}
+
+	return Serialized{Body: b, ContentType: s.Name()}, nil
+}
@@ -0,0 +1,45 @@
+package serialization
+
+import "fmt"
+
+// Text implements the rabbids.Serializer interface for plain text payloads.
+type Text struct{}
+
+// Marshal returns the text of a string, a []byte or a fmt.Stringer, or an error for other types.
+func (t *Text) Marshal(v interface{}) ([]byte, error) {
+	switch data := v.(type) {
+	case string:
+		return []byte(data), nil
+	case []byte:
+		return data, nil
+	case fmt.Stringer:
+		return []byte(data.String()), nil
+	default:
+		return nil, fmt.Errorf("the text serializer expects a string, got %T", v)
+	}
+}
+
+// Name returns the name of the serialization used.
+// This value is used as the ContentType value on the message.
+func (t *Text) Name() string {
+	return "text/plain"
+}
+
+// Serialize implements the SerializerV2 interface, returning the text with the content type.
+func (t *Text) Serialize(exchange, key string, v interface{}) (Serialized, error) {
+	return serialize(t, v)
+}
+
+// Unmarshal set the text into the *string or *[]byte pointed by v.
+func (t *Text) Unmarshal(data []byte, v interface{}) error {
+	switch out := v.(type) {
+	case *string:
+		*out = string(data)
+	case *[]byte:
+		*out = append((*out)[:0], data...)
+	default:
+		return fmt.Errorf("the text serializer decodes into *string or *[]byte, got %T", v)
+	}
+
+	return nil
+}
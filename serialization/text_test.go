@@ -0,0 +1,36 @@
+package serialization
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestText(t *testing.T) {
+	s := &Text{}
+	require.Equal(t, "text/plain", s.Name())
+
+	for _, v := range []interface{}{"deploy done", []byte("deploy done")} {
+		b, err := s.Marshal(v)
+		require.NoError(t, err)
+		require.Equal(t, "deploy done", string(b))
+	}
+
+	b, err := s.Marshal(time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "1s", string(b))
+
+	_, err = s.Marshal(10)
+	require.EqualError(t, err, "the text serializer expects a string, got int")
+
+	var str string
+	require.NoError(t, s.Unmarshal([]byte("hello"), &str))
+	require.Equal(t, "hello", str)
+
+	var raw []byte
+	require.NoError(t, s.Unmarshal([]byte("hello"), &raw))
+	require.Equal(t, []byte("hello"), raw)
+
+	require.Error(t, s.Unmarshal([]byte("hello"), new(int)))
+}
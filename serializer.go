@@ -0,0 +1,42 @@
+package rabbids
+
+import "github.com/leveeml/rabbids/serialization"
+
+// Serialized is the body encoded by a SerializerV2 with the message properties describing it.
+type Serialized = serialization.Serialized
+
+// SerializerV2 encode the data of the messages. Unlike Serializer, it receives the destination of the message
+// and describes the body with the content type, the content encoding and headers, as needed
+// by the compressed and encrypted formats. All the serializers of the serialization package implement it.
+// Use AdaptSerializer to use a Serializer where a SerializerV2 is expected.
+type SerializerV2 = serialization.SerializerV2
+
+// SerializerV2Func implements the SerializerV2 interface.
+type SerializerV2Func func(exchange, key string, data interface{}) (Serialized, error)
+
+func (f SerializerV2Func) Serialize(exchange, key string, data interface{}) (Serialized, error) {
+	return f(exchange, key, data)
+}
+
+// AdaptSerializer return a SerializerV2 using the Marshal and Name methods of the Serializer.
+// Serializers implementing SerializerV2 are returned without changes.
+func AdaptSerializer(s Serializer) SerializerV2 {
+	if v2, ok := s.(SerializerV2); ok {
+		return v2
+	}
+
+	return serializerAdapter{s}
+}
+
+type serializerAdapter struct {
+	s Serializer
+}
+
+func (a serializerAdapter) Serialize(exchange, key string, data interface{}) (Serialized, error) {
+	b, err := a.s.Marshal(data)
+	if err != nil {
+		return Serialized{}, err
+	}
+
+	return Serialized{Body: b, ContentType: a.s.Name()}, nil
+}
@@ -0,0 +1,86 @@
+package rabbids
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// setSupervisor register the supervisor running the consumers of this client.
+func (r *Rabbids) setSupervisor(s *supervisor) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.supervisor = s
+}
+
+// Shutdown stop the client in order, bounded by the context:
+// cancel the consumer subscriptions and wait for the handlers (Drain), stop the supervisor and the consumers,
+// flush the messages emitted and the confirmations pending of the producers created by CreateProducer
+// and at last close all the connections.
+// The steps after the drain are executed even when the context is done, the errors are combined in the returned error.
+func (r *Rabbids) Shutdown(ctx context.Context) error {
+	var errs []string
+
+	if _, err := r.Drain(ctx); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	r.mutex.Lock()
+	s := r.supervisor
+	r.supervisor = nil
+	consumers := make([]*Consumer, 0, len(r.consumers))
+
+	for _, c := range r.consumers {
+		consumers = append(consumers, c)
+	}
+	r.mutex.Unlock()
+
+	if s != nil {
+		s.Stop()
+	}
+
+	for _, c := range consumers {
+		c.Kill()
+	}
+
+	r.producersMutex.Lock()
+	producers := r.producers
+	r.producers = nil
+	r.producersMutex.Unlock()
+
+	for _, p := range producers {
+		if err := closeProducer(ctx, p); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to close the producer \"%s\": %s", p.name, err))
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+
+		return fmt.Errorf("failed to shutdown the client: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// closeProducer close the producer, waiting the messages already emitted until the context is done.
+func closeProducer(ctx context.Context, p *Producer) error {
+	done := make(chan error, 1)
+
+	go func() {
+		done <- p.Close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,32 @@
+package rabbids
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRabbids_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+
+	r, err := New(&Config{}, NoOPLoggerFN, WithOnShutdown(func() {
+		calls = append(calls, "close")
+	}))
+	require.NoError(t, err)
+
+	// a consumer created but never started must not block the shutdown
+	r.registerConsumer(&Consumer{name: "idle", log: NoOPLogger{}})
+	_, err = StartSupervisor(r, time.Millisecond)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, r.Shutdown(ctx))
+	require.Equal(t, []string{"close"}, calls)
+	require.EqualError(t, r.Live(), "the supervisor is not running")
+}
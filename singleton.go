@@ -0,0 +1,29 @@
+package rabbids
+
+import "fmt"
+
+// Singleton run the consumer in only one instance across a fleet: the consumer holds an exclusive
+// lock queue on its connection and the consumers from the other instances stay in standby,
+// created by the supervisor when the active one stops.
+// It works like the `singleton` field of the consumer config and emits the
+// EventLeadershipAcquired and EventLeadershipLost events.
+func Singleton(consumerName string) Option {
+	return func(r *Rabbids) error {
+		if r.singletons == nil {
+			r.singletons = map[string]bool{}
+		}
+
+		r.singletons[consumerName] = true
+
+		return nil
+	}
+}
+
+// singletonLockName return the name of the exclusive queue used as a lock by one singleton consumer.
+func singletonLockName(consumer string) string {
+	return fmt.Sprintf("rabbids.singleton.%s", consumer)
+}
+
+func (r *Rabbids) isSingleton(name string, cfg ConsumerConfig) bool {
+	return cfg.Singleton || r.singletons[name]
+}
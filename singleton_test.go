@@ -0,0 +1,60 @@
+package rabbids
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRabbids_consumerLock(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{}
+	require.NoError(t, Singleton("billing")(r))
+
+	tests := []struct {
+		name     string
+		consumer string
+		cfg      ConsumerConfig
+		expected string
+	}{
+		{"regular consumer", "regular", ConsumerConfig{}, ""},
+		{"singleton from config", "reports", ConsumerConfig{Singleton: true}, "rabbids.singleton.reports"},
+		{"singleton from option", "billing", ConsumerConfig{}, "rabbids.singleton.billing"},
+		{
+			"stream group",
+			"events",
+			ConsumerConfig{Queue: QueueConfig{Name: "events-0"}, Stream: StreamConfig{Group: "audit"}},
+			"rabbids.group.audit.events-0",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, r.consumerLock(tt.consumer, tt.cfg))
+		})
+	}
+}
+
+func TestRabbids_groupMembersOnTheSameConnection(t *testing.T) {
+	t.Parallel()
+
+	stream := QueueConfig{Name: "events-0"}
+	r := &Rabbids{config: &Config{Consumers: map[string]ConsumerConfig{
+		"events-a": {Connection: "default", Queue: stream, Stream: StreamConfig{Group: "audit"}},
+		"events-b": {Connection: "default", Queue: stream, Stream: StreamConfig{Group: "audit"}},
+		"events-c": {Connection: "other", Queue: stream, Stream: StreamConfig{Group: "audit"}},
+		"events-d": {Connection: "default", Queue: QueueConfig{Name: "events-1"}, Stream: StreamConfig{Group: "audit"}},
+	}}}
+	setConfigDefaults(r.config)
+
+	_, err := r.newConsumer("events-b", r.config.Consumers["events-b"])
+	require.EqualError(t, err, `the consumers "events-b" and "events-a" from the group "audit" can't use the same connection "default"`)
+
+	_, ok := r.groupMemberOnConnection("events-c", r.config.Consumers["events-c"])
+	require.False(t, ok, "the members with other connections hold their own lock")
+
+	_, ok = r.groupMemberOnConnection("events-d", r.config.Consumers["events-d"])
+	require.False(t, ok, "every stream of the group has its own lock")
+}
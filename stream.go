@@ -0,0 +1,259 @@
+package rabbids
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// StreamOffsetHeader is the header with the offset of the messages received from a stream queue.
+const StreamOffsetHeader = "x-stream-offset"
+
+// StreamConfig describe how a consumer reads from a stream queue (declared with x-queue-type: stream).
+type StreamConfig struct {
+	// Offset is where the consumer starts to read the stream when there is no checkpoint:
+	// first, last, next, a numeric offset, a RFC3339 timestamp or an interval like 1h or 7D.
+	Offset string `mapstructure:"offset"`
+	// CheckpointEvery is the number of processed messages between two checkpoints, the default is every message.
+	CheckpointEvery int `mapstructure:"checkpoint_every"`
+	// Group is the name of the consumer group. Only one consumer of the group is active for each stream
+	// and the offsets are shared by the group, so many instances can share a stream without duplicates.
+	Group string `mapstructure:"group"`
+}
+
+// OffsetStore persist the last offset processed by the stream consumers,
+// so a restarted consumer resumes from where it left off.
+type OffsetStore interface {
+	// Offset return the last offset saved for the consumer, false when the consumer doesn't have a checkpoint.
+	Offset(consumer string) (int64, bool, error)
+	// SaveOffset save the last offset processed by the consumer.
+	SaveOffset(consumer string, offset int64) error
+}
+
+// WithOffsetStore set the store used to checkpoint the offsets of a stream consumer.
+func WithOffsetStore(store OffsetStore) ConsumerOption {
+	return func(c *Consumer) error {
+		c.stream.store = store
+
+		return nil
+	}
+}
+
+// MemoryOffsetStore is an OffsetStore keeping the offsets in memory,
+// useful to keep the offset between restarts of the consumer inside the same process.
+type MemoryOffsetStore struct {
+	mutex   sync.RWMutex
+	offsets map[string]int64
+}
+
+// NewMemoryOffsetStore create an empty MemoryOffsetStore.
+func NewMemoryOffsetStore() *MemoryOffsetStore {
+	return &MemoryOffsetStore{offsets: map[string]int64{}}
+}
+
+func (s *MemoryOffsetStore) Offset(consumer string) (int64, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	offset, ok := s.offsets[consumer]
+
+	return offset, ok, nil
+}
+
+func (s *MemoryOffsetStore) SaveOffset(consumer string, offset int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.offsets[consumer] = offset
+
+	return nil
+}
+
+type streamConsumer struct {
+	enabled bool
+	offset  string
+	group   string
+	every   int64
+	store   OffsetStore
+	mutex   sync.Mutex
+	// last and saved are -1 until the first message is processed or saved.
+	last  int64
+	saved int64
+	// pending count the messages in flight by offset, the checkpoint stays below them
+	// because the workers can finish the messages out of order.
+	pending map[int64]int
+}
+
+func newStreamConsumer(cfg ConsumerConfig) streamConsumer {
+	return streamConsumer{
+		enabled: argString(cfg.Queue.Options.Args, "x-queue-type") == "stream",
+		offset:  cfg.Stream.Offset,
+		group:   cfg.Stream.Group,
+		every:   int64(cfg.Stream.CheckpointEvery),
+		last:    -1,
+		saved:   -1,
+		pending: map[int64]int{},
+	}
+}
+
+// consumeArgs return the arguments used to start consuming, adding the stream offset for stream queues.
+// The stream is read after the checkpoint, or after the offsets already processed when the channel
+// is recovered without an OffsetStore, and from the configured offset otherwise.
+func (c *Consumer) consumeArgs() (amqp.Table, error) {
+	if !c.stream.enabled {
+		return c.opts.Args, nil
+	}
+
+	args := amqp.Table{}
+	for k, v := range c.opts.Args {
+		args[k] = v
+	}
+
+	c.stream.mutex.Lock()
+	defer c.stream.mutex.Unlock()
+
+	if c.stream.store != nil {
+		offset, ok, err := c.stream.store.Offset(c.offsetKey())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the stream offset for consumer \"%s\": %w", c.name, err)
+		}
+
+		if ok {
+			c.stream.last, c.stream.saved = offset, offset
+			args[StreamOffsetHeader] = offset + 1
+
+			return args, nil
+		}
+	}
+
+	if committed := c.committedOffset(); committed >= 0 {
+		args[StreamOffsetHeader] = committed + 1
+
+		return args, nil
+	}
+
+	if c.stream.offset != "" {
+		args[StreamOffsetHeader] = parseStreamOffset(c.stream.offset)
+	}
+
+	return args, nil
+}
+
+// offsetKey return the key used to save the offsets, shared by all the consumers of a group.
+func (c *Consumer) offsetKey() string {
+	if c.stream.group != "" {
+		return fmt.Sprintf("%s.%s", c.stream.group, c.queue)
+	}
+
+	return c.name
+}
+
+// parseStreamOffset convert the offset from the config to the type expected by the broker.
+func parseStreamOffset(offset string) interface{} {
+	if n, err := strconv.ParseInt(offset, 10, 64); err == nil {
+		return n
+	}
+
+	if t, err := time.Parse(time.RFC3339, offset); err == nil {
+		return t
+	}
+
+	return offset
+}
+
+// receiveOffset mark the offset of the message received as in flight, until trackOffset is called.
+func (c *Consumer) receiveOffset(d amqp.Delivery) {
+	if !c.stream.enabled {
+		return
+	}
+
+	offset, ok := d.Headers[StreamOffsetHeader].(int64)
+	if !ok {
+		return
+	}
+
+	c.stream.mutex.Lock()
+	defer c.stream.mutex.Unlock()
+
+	if c.stream.pending == nil {
+		c.stream.pending = map[int64]int{}
+	}
+
+	c.stream.pending[offset]++
+}
+
+// trackOffset keep the offset of the processed message and checkpoint it when needed.
+func (c *Consumer) trackOffset(d amqp.Delivery) {
+	if !c.stream.enabled {
+		return
+	}
+
+	offset, ok := d.Headers[StreamOffsetHeader].(int64)
+	if !ok {
+		return
+	}
+
+	c.stream.mutex.Lock()
+	defer c.stream.mutex.Unlock()
+
+	if n := c.stream.pending[offset]; n > 1 {
+		c.stream.pending[offset] = n - 1
+	} else {
+		delete(c.stream.pending, offset)
+	}
+
+	if offset > c.stream.last {
+		c.stream.last = offset
+	}
+
+	if c.committedOffset()-c.stream.saved >= c.stream.every {
+		c.saveOffset()
+	}
+}
+
+// committedOffset returns the highest offset processed with all the previous messages processed.
+func (c *Consumer) committedOffset() int64 {
+	committed := c.stream.last
+
+	for offset := range c.stream.pending {
+		if offset-1 < committed {
+			committed = offset - 1
+		}
+	}
+
+	return committed
+}
+
+// checkpoint save the last processed offset, used when the consumer stops.
+func (c *Consumer) checkpoint() {
+	if !c.stream.enabled {
+		return
+	}
+
+	c.stream.mutex.Lock()
+	defer c.stream.mutex.Unlock()
+
+	c.saveOffset()
+}
+
+func (c *Consumer) saveOffset() {
+	committed := c.committedOffset()
+	if c.stream.store == nil || committed <= c.stream.saved {
+		return
+	}
+
+	if err := c.stream.store.SaveOffset(c.offsetKey(), committed); err != nil {
+		c.log.Error("failed to save the stream offset", Fields{
+			"consumer": c.name,
+			"offset":   committed,
+			"error":    err,
+		})
+
+		return
+	}
+
+	c.stream.saved = committed
+}
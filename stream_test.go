@@ -0,0 +1,131 @@
+package rabbids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStreamOffset(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		offset   string
+		expected interface{}
+	}{
+		{"first", "first"},
+		{"next", "next"},
+		{"1h", "1h"},
+		{"100", int64(100)},
+		{"2020-10-01T10:00:00Z", time.Date(2020, 10, 1, 10, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, parseStreamOffset(tt.offset), tt.offset)
+	}
+}
+
+func TestConsumer_streamOffsets(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryOffsetStore()
+	cfg := ConsumerConfig{
+		Queue:  QueueConfig{Options: Options{Args: amqp.Table{"x-queue-type": "stream"}}},
+		Stream: StreamConfig{Offset: "first", CheckpointEvery: 2},
+	}
+	c := &Consumer{name: "stream", log: NoOPLogger{}, opts: cfg.Queue.Options, stream: newStreamConsumer(cfg)}
+	require.NoError(t, WithOffsetStore(store)(c))
+
+	args, err := c.consumeArgs()
+	require.NoError(t, err)
+	require.Equal(t, amqp.Table{"x-queue-type": "stream", StreamOffsetHeader: "first"}, args)
+
+	for i := int64(0); i < 3; i++ {
+		c.trackOffset(amqp.Delivery{Headers: amqp.Table{StreamOffsetHeader: i}})
+	}
+
+	offset, ok, err := store.Offset("stream")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, int64(1), offset, "the checkpoint should happen every 2 messages")
+
+	c.checkpoint()
+
+	offset, _, _ = store.Offset("stream")
+	require.Equal(t, int64(2), offset)
+
+	restarted := &Consumer{name: "stream", log: NoOPLogger{}, opts: cfg.Queue.Options, stream: newStreamConsumer(cfg)}
+	require.NoError(t, WithOffsetStore(store)(restarted))
+
+	args, err = restarted.consumeArgs()
+	require.NoError(t, err)
+	require.Equal(t, int64(3), args[StreamOffsetHeader], "should resume after the last checkpoint")
+}
+
+func TestConsumer_streamOffsetsOutOfOrder(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryOffsetStore()
+	cfg := ConsumerConfig{Queue: QueueConfig{Options: Options{Args: amqp.Table{"x-queue-type": "stream"}}}}
+	c := &Consumer{name: "stream", log: NoOPLogger{}, opts: cfg.Queue.Options, stream: newStreamConsumer(cfg)}
+	require.NoError(t, WithOffsetStore(store)(c))
+
+	delivery := func(offset int64) amqp.Delivery {
+		return amqp.Delivery{Headers: amqp.Table{StreamOffsetHeader: offset}}
+	}
+
+	for i := int64(0); i < 3; i++ {
+		c.receiveOffset(delivery(i))
+	}
+
+	c.trackOffset(delivery(0))
+	c.trackOffset(delivery(2))
+
+	offset, _, _ := store.Offset("stream")
+	require.Equal(t, int64(0), offset, "the offset 1 is still in flight")
+
+	c.checkpoint()
+
+	offset, _, _ = store.Offset("stream")
+	require.Equal(t, int64(0), offset, "the checkpoint must not skip the offset 1")
+
+	c.trackOffset(delivery(1))
+
+	offset, _, _ = store.Offset("stream")
+	require.Equal(t, int64(2), offset)
+}
+
+func TestConsumer_streamArgsAfterRecovery(t *testing.T) {
+	t.Parallel()
+
+	cfg := ConsumerConfig{
+		Queue:  QueueConfig{Options: Options{Args: amqp.Table{"x-queue-type": "stream"}}},
+		Stream: StreamConfig{Offset: "first"},
+	}
+	c := &Consumer{name: "stream", log: NoOPLogger{}, opts: cfg.Queue.Options, stream: newStreamConsumer(cfg)}
+
+	for i := int64(10); i < 13; i++ {
+		d := amqp.Delivery{Headers: amqp.Table{StreamOffsetHeader: i}}
+		c.receiveOffset(d)
+		c.trackOffset(d)
+	}
+
+	c.receiveOffset(amqp.Delivery{Headers: amqp.Table{StreamOffsetHeader: int64(13)}})
+
+	args, err := c.consumeArgs()
+	require.NoError(t, err)
+	require.Equal(t, int64(13), args[StreamOffsetHeader], "the recovered channel must not read from the configured offset")
+}
+
+func TestConsumer_offsetKey(t *testing.T) {
+	t.Parallel()
+
+	c := &Consumer{name: "stream-consumer", queue: "events-0"}
+	require.Equal(t, "stream-consumer", c.offsetKey())
+
+	c.stream.group = "billing"
+	require.Equal(t, "billing.events-0", c.offsetKey(), "the offsets should be shared by the group")
+	require.Equal(t, "rabbids.group.billing.events-0", groupLockName("billing", "events-0"))
+}
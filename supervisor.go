@@ -1,6 +1,12 @@
 package rabbids
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,18 +17,105 @@ type supervisor struct {
 	rabbids        *Rabbids
 	consumers      map[string]*Consumer
 	close          chan struct{}
+	done           chan struct{}
+	commands       chan consumerCommand
+	policy         RestartPolicy
+	restarts       map[string]*restartState
+	stopOnce       sync.Once
+	// restartConcurrency is the max number of consumers created at the same time
+	// and restartStagger the max random wait before creating each one.
+	restartConcurrency int
+	restartStagger     time.Duration
+	watchdog           watchdogConfig
+	deadProducers      map[string]bool
+	delayBacklog       delayBacklogConfig
+	deadLetterMonitor  deadLetterMonitorConfig
+}
+
+// RestartPolicy control how the supervisor restarts the dead consumers.
+// The zero value restarts the consumers forever on every check.
+type RestartPolicy struct {
+	// MaxRestarts is the max number of restarts of one consumer inside the Window,
+	// after that the supervisor gives up restarting it. Zero means unlimited.
+	MaxRestarts int
+	// Window is the period used to count the restarts.
+	Window time.Duration
+	// Backoff is the min time between two restarts of one consumer,
+	// doubled for every restart inside the Window.
+	Backoff time.Duration
+	// MaxBackoff is the max time between two restarts.
+	MaxBackoff time.Duration
+	// OnGiveUp is called when the supervisor gives up restarting one consumer.
+	OnGiveUp func(consumer string, err error)
+}
+
+// SupervisorOption represents an option to change the supervisor behavior.
+type SupervisorOption func(*supervisor)
+
+// WithRestartPolicy set the policy used to restart the dead consumers.
+func WithRestartPolicy(p RestartPolicy) SupervisorOption {
+	return func(s *supervisor) {
+		s.policy = p
+	}
+}
+
+// WithRestartLimit limit the number of consumers recreated at the same time and wait a random
+// time up to stagger before recreating each one. Useful to recover smoothly when a broker
+// with hundreds of consumers restarts. By default the consumers are recreated one at a time without waiting.
+func WithRestartLimit(concurrency int, stagger time.Duration) SupervisorOption {
+	return func(s *supervisor) {
+		s.restartConcurrency = concurrency
+		s.restartStagger = stagger
+	}
+}
+
+const (
+	// maxTrackedRestarts limit the restarts kept in memory for each consumer.
+	maxTrackedRestarts = 100
+	// maxBackoffExponent avoid overflows when doubling the backoff.
+	maxBackoffExponent = 20
+)
+
+type restartState struct {
+	// deadSince is when the supervisor found the consumer dead, used to measure the time to recover.
+	deadSince time.Time
+	lastCheck time.Time
+	restarts  []time.Time
+	next      time.Time
+	gaveUp    bool
+	// killed consumers are not restarted until RestartConsumer is called.
+	killed bool
+	// attempts is the number of restart attempts since the consumer was found dead.
+	attempts int
+	// stuck is true while the watchdog reports the consumer as stuck.
+	stuck bool
+}
+
+// consumerCommand is a request to kill or restart one consumer handled by the supervisor loop.
+type consumerCommand struct {
+	name    string
+	restart bool
+	done    chan error
 }
 
 // StartSupervisor init a new supervisor that will start all the consumers from Rabbids
 // and check if the consumers are alive, if not alive it will be restarted.
 // It returns the stop function to gracefully shutdown the consumers and
 // an error if fail to create the consumers the first time.
-func StartSupervisor(rabbids *Rabbids, intervalChecks time.Duration) (stop func(), err error) {
+func StartSupervisor(rabbids *Rabbids, intervalChecks time.Duration, opts ...SupervisorOption) (stop func(), err error) {
 	s := &supervisor{
 		checkAliveness: intervalChecks,
 		rabbids:        rabbids,
 		consumers:      map[string]*Consumer{},
 		close:          make(chan struct{}),
+		done:           make(chan struct{}),
+		commands:       make(chan consumerCommand),
+		restarts:       map[string]*restartState{},
+		deadProducers:  map[string]bool{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
 
 	cs, err := s.rabbids.CreateConsumers()
@@ -36,55 +129,430 @@ func StartSupervisor(rabbids *Rabbids, intervalChecks time.Duration) (stop func(
 	}
 
 	go s.loop()
+	rabbids.setSupervisor(s)
 
 	return s.Stop, nil
 }
 
+// StartSupervisorContext works like StartSupervisor but the consumers run until the context is done,
+// blocking until all of them are stopped. It returns an error only when the consumers can't be created,
+// so it can be used with signal.NotifyContext or inside an errgroup.
+func StartSupervisorContext(ctx context.Context, rabbids *Rabbids, intervalChecks time.Duration, opts ...SupervisorOption) error {
+	stop, err := StartSupervisor(rabbids, intervalChecks, opts...)
+	if err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	stop()
+
+	return nil
+}
+
 func (s *supervisor) loop() {
-	ticker := time.NewTicker(s.checkAliveness)
+	interval := s.tick()
+	timer := time.NewTimer(interval)
+	s.rabbids.supervisorCheck(interval)
 
 	for {
 		select {
 		case <-s.close:
+			timer.Stop()
+			s.rabbids.supervisorStopped()
+
 			for name, c := range s.consumers {
 				c.Kill()
 				delete(s.consumers, name)
 			}
+			close(s.done)
 			s.close <- struct{}{}
 
 			return
-		case <-ticker.C:
+		case cmd := <-s.commands:
+			cmd.done <- s.handleCommand(cmd)
+		case <-timer.C:
 			s.restartDeadConsumers()
+			s.checkProducers()
+			s.checkConnections(time.Now())
+			s.checkDelayBacklog(time.Now())
+			s.checkDeadLetters(time.Now())
+
+			interval = s.tick()
+			timer.Reset(interval)
+			s.rabbids.supervisorCheck(interval)
 		}
 	}
 }
 
 // Stop all the running consumers.
 func (s *supervisor) Stop() {
-	s.close <- struct{}{}
-	<-s.close
+	s.stopOnce.Do(func() {
+		s.close <- struct{}{}
+		<-s.close
+	})
 }
 
 func (s *supervisor) restartDeadConsumers() {
+	if s.rabbids.isDraining() {
+		return
+	}
+
+	now := time.Now()
+
+	var dead, missing []string
+
 	for name, c := range s.consumers {
-		if !c.Alive() {
-			s.rabbids.log("recreating one consumer", Fields{
-				"consumer-name": name,
-			})
+		if !s.due(name, now) {
+			continue
+		}
+
+		// consumers removed from the config by a reload
+		if !s.rabbids.hasConsumer(name) {
+			c.Kill()
+			delete(s.consumers, name)
+			delete(s.restarts, name)
+
+			continue
+		}
+
+		if s.state(name).killed {
+			continue
+		}
+
+		// consumers changed by a reload are created again with the new config, like the missing ones
+		if c.isReloaded() {
+			delete(s.consumers, name)
+			delete(s.restarts, name)
 
-			nc, err := s.rabbids.CreateConsumer(name)
-			if err != nil {
-				s.rabbids.log("error recreating one consumer", Fields{
-					"consumer-name": name,
-					"error":         err,
-				})
+			continue
+		}
+
+		alive := c.Alive()
+		s.rabbids.metrics.ConsumerAlive(name, alive)
+		s.rabbids.metrics.ConsumerWorkers(name, int(atomic.LoadInt64(&c.inFlight)), c.workers)
+		s.logTransition("consumer checked", name, transitionChecked, Fields{"alive": alive})
+
+		if alive {
+			s.checkStuck(name, c)
+		}
+
+		if !alive {
+			state := s.state(name)
+			if state.deadSince.IsZero() {
+				state.deadSince = now
+				s.logTransition("consumer found dead", name, transitionDead, Fields{"reason": c.LastError()})
+			}
 
+			if !s.allowRestart(name, c, now) {
 				continue
 			}
 
-			delete(s.consumers, name)
-			s.consumers[name] = nc
-			nc.Run()
+			state.attempts++
+			s.logTransition("recreating one consumer", name, transitionRecreating, Fields{
+				"reason":     "dead",
+				"dead-since": state.deadSince,
+			})
+
+			dead = append(dead, name)
+		}
+	}
+
+	// consumers in standby or added by a reload are created when missing.
+	for _, name := range s.rabbids.consumerNames() {
+		if _, ok := s.consumers[name]; !ok {
+			missing = append(missing, name)
 		}
 	}
+
+	created := s.createConsumers(append(dead, missing...))
+
+	for _, name := range dead {
+		res := created[name]
+		if res.err != nil {
+			s.logTransition("error recreating one consumer", name, transitionFailed, Fields{"reason": res.err})
+			s.reportFailure(name, transitionFailed, res.err)
+
+			continue
+		}
+
+		state := s.state(name)
+		s.consumers[name] = res.consumer
+		res.consumer.Run()
+
+		timeToRecover := time.Since(state.deadSince)
+		s.rabbids.metrics.ConsumerRestarted(name, timeToRecover)
+		s.logTransition("consumer recreated", name, transitionRecreated, Fields{"time-to-recover": timeToRecover})
+		state.deadSince = time.Time{}
+		state.attempts = 0
+
+		if state.stuck {
+			state.stuck = false
+			s.rabbids.metrics.ConsumerStuck(name, false)
+		}
+	}
+
+	for _, name := range missing {
+		res := created[name]
+		if res.err != nil {
+			if !errors.Is(res.err, ErrStandby) {
+				s.logTransition("error creating one consumer", name, transitionFailed, Fields{"reason": res.err})
+				s.reportFailure(name, transitionFailed, res.err)
+			}
+
+			continue
+		}
+
+		s.consumers[name] = res.consumer
+		res.consumer.Run()
+		s.logTransition("consumer created", name, transitionRecreated, Fields{"reason": "missing"})
+	}
+}
+
+// The supervisor transitions logged for every decision, in the "transition" field.
+const (
+	transitionChecked    = "checked"
+	transitionDead       = "found-dead"
+	transitionRecreating = "recreating"
+	transitionRecreated  = "recreated"
+	transitionFailed     = "failed"
+	transitionGaveUp     = "gave-up"
+	transitionKilled     = "killed"
+	transitionStuck      = "stuck"
+	transitionUnstuck    = "unstuck"
+)
+
+// logTransition log one supervisor decision with the consumer name,
+// the transition and the number of the restart attempt, the level depends on the transition.
+func (s *supervisor) logTransition(message, name, transition string, fields Fields) {
+	f := Fields{
+		"consumer-name": name,
+		"transition":    transition,
+		"attempt":       s.state(name).attempts,
+	}
+
+	for k, v := range fields {
+		f[k] = v
+	}
+
+	switch transition {
+	case transitionChecked:
+		s.rabbids.log.Debug(message, f)
+	case transitionDead, transitionStuck:
+		s.rabbids.log.Warn(message, f)
+	case transitionFailed, transitionGaveUp:
+		s.rabbids.log.Error(message, f)
+	default:
+		s.rabbids.log.Info(message, f)
+	}
+}
+
+// reportFailure send the consumers the supervisor failed to restart to the ErrorReporter.
+func (s *supervisor) reportFailure(name, transition string, err error) {
+	s.rabbids.reportErr.report(err, Fields{
+		"consumer-name": name,
+		"transition":    transition,
+		"attempt":       s.state(name).attempts,
+	})
+}
+
+type createResult struct {
+	consumer *Consumer
+	err      error
+}
+
+// createConsumers create the consumers using at most restartConcurrency goroutines,
+// waiting a random stagger before creating each one to avoid flooding the broker with declarations.
+func (s *supervisor) createConsumers(names []string) map[string]createResult {
+	results := make(map[string]createResult, len(names))
+	if len(names) == 0 {
+		return results
+	}
+
+	concurrency := s.restartConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+	)
+
+	sem := make(chan struct{}, concurrency)
+
+	for _, name := range names {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(name string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if s.restartStagger > 0 {
+				time.Sleep(time.Duration(rand.Int63n(int64(s.restartStagger))))
+			}
+
+			c, err := s.rabbids.CreateConsumer(name)
+
+			mutex.Lock()
+			results[name] = createResult{consumer: c, err: err}
+			mutex.Unlock()
+		}(name)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// send a command to the supervisor loop, returning false when the supervisor is stopped.
+func (s *supervisor) send(name string, restart bool) (bool, error) {
+	cmd := consumerCommand{name: name, restart: restart, done: make(chan error, 1)}
+
+	select {
+	case s.commands <- cmd:
+		return true, <-cmd.done
+	case <-s.done:
+		return false, nil
+	}
+}
+
+// handleCommand kill or restart one consumer, the killed consumers are kept
+// in the supervisor without being restarted. The handler is not closed, it's used
+// again by the recreated consumer and closed when the supervisor stops.
+func (s *supervisor) handleCommand(cmd consumerCommand) error {
+	c, ok := s.consumers[cmd.name]
+	if !ok && !cmd.restart {
+		return fmt.Errorf("consumer \"%s\" is not running", cmd.name)
+	}
+
+	if ok {
+		c.stop()
+	}
+
+	state := s.state(cmd.name)
+	if !cmd.restart {
+		state.killed = true
+		s.logTransition("consumer killed", cmd.name, transitionKilled, Fields{"reason": "command"})
+
+		return nil
+	}
+
+	s.logTransition("recreating one consumer", cmd.name, transitionRecreating, Fields{"reason": "command"})
+
+	nc, err := s.rabbids.CreateConsumer(cmd.name)
+	if err != nil {
+		s.logTransition("error recreating one consumer", cmd.name, transitionFailed, Fields{"reason": err})
+
+		return err
+	}
+
+	state.killed = false
+	state.gaveUp = false
+	state.restarts = nil
+	state.next = time.Time{}
+	s.consumers[cmd.name] = nc
+	nc.Run()
+	s.logTransition("consumer recreated", cmd.name, transitionRecreated, Fields{"reason": "command"})
+
+	return nil
+}
+
+// tick return the interval between the checks, the smallest check interval of all the consumers.
+func (s *supervisor) tick() time.Duration {
+	tick := s.checkAliveness
+
+	for _, name := range s.rabbids.consumerNames() {
+		if interval := s.rabbids.checkInterval(name, s.checkAliveness); interval < tick {
+			tick = interval
+		}
+	}
+
+	return tick
+}
+
+// due return true when the consumer check interval passed since the last check.
+func (s *supervisor) due(name string, now time.Time) bool {
+	state := s.state(name)
+	if now.Sub(state.lastCheck) < s.rabbids.checkInterval(name, s.checkAliveness) {
+		return false
+	}
+
+	state.lastCheck = now
+
+	return true
+}
+
+func (s *supervisor) state(name string) *restartState {
+	state, ok := s.restarts[name]
+	if !ok {
+		state = &restartState{}
+		s.restarts[name] = state
+	}
+
+	return state
+}
+
+// allowRestart apply the restart policy, recording the restart when allowed.
+func (s *supervisor) allowRestart(name string, c *Consumer, now time.Time) bool {
+	state := s.state(name)
+
+	if state.gaveUp || now.Before(state.next) {
+		return false
+	}
+
+	if s.policy.Window > 0 {
+		recent := state.restarts[:0]
+
+		for _, t := range state.restarts {
+			if now.Sub(t) < s.policy.Window {
+				recent = append(recent, t)
+			}
+		}
+
+		state.restarts = recent
+	}
+
+	if s.policy.MaxRestarts > 0 && len(state.restarts) >= s.policy.MaxRestarts {
+		state.gaveUp = true
+		err := c.LastError()
+
+		s.logTransition("giving up restarting one consumer", name, transitionGaveUp, Fields{
+			"restarts": len(state.restarts),
+			"window":   s.policy.Window,
+			"reason":   err,
+		})
+
+		s.reportFailure(name, transitionGaveUp, fmt.Errorf("gave up restarting the consumer after %d restarts: %v",
+			len(state.restarts), err))
+
+		if s.policy.OnGiveUp != nil {
+			s.policy.OnGiveUp(name, err)
+		}
+
+		return false
+	}
+
+	state.restarts = append(state.restarts, now)
+	if len(state.restarts) > maxTrackedRestarts && s.policy.MaxRestarts < maxTrackedRestarts {
+		state.restarts = state.restarts[1:]
+	}
+
+	if s.policy.Backoff > 0 {
+		exp := len(state.restarts) - 1
+		if exp > maxBackoffExponent {
+			exp = maxBackoffExponent
+		}
+
+		backoff := s.policy.Backoff << uint(exp)
+		if s.policy.MaxBackoff > 0 && (backoff > s.policy.MaxBackoff || backoff <= 0) {
+			backoff = s.policy.MaxBackoff
+		}
+
+		state.next = now.Add(backoff)
+	}
+
+	return true
 }
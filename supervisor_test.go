@@ -0,0 +1,270 @@
+package rabbids
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ivpusic/grpool"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartSupervisorContext(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{config: &Config{}, log: NoOPLogger{}, consumers: map[string]*Consumer{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- StartSupervisorContext(ctx, r, time.Millisecond)
+	}()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the supervisor should stop when the context is canceled")
+	}
+}
+
+func TestSupervisor_allowRestart(t *testing.T) {
+	t.Parallel()
+
+	var gaveUp string
+
+	s := &supervisor{
+		rabbids:  &Rabbids{log: NoOPLogger{}},
+		restarts: map[string]*restartState{},
+	}
+	WithRestartPolicy(RestartPolicy{
+		MaxRestarts: 3,
+		Window:      time.Minute,
+		Backoff:     time.Second,
+		MaxBackoff:  3 * time.Second,
+		OnGiveUp:    func(consumer string, _ error) { gaveUp = consumer },
+	})(s)
+
+	c := &Consumer{name: "crashing"}
+	now := time.Now()
+
+	tests := []struct {
+		after   time.Duration
+		allowed bool
+	}{
+		{0, true},
+		{500 * time.Millisecond, false}, // backoff of 1s
+		{time.Second, true},
+		{2 * time.Second, false}, // backoff of 2s
+		{3 * time.Second, true},
+		{6 * time.Second, false}, // max restarts inside the window
+		{2 * time.Minute, false}, // the supervisor gave up
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.allowed, s.allowRestart("crashing", c, now.Add(tt.after)), "after %s", tt.after)
+	}
+
+	require.Equal(t, "crashing", gaveUp)
+}
+
+func TestRabbids_Live(t *testing.T) {
+	t.Parallel()
+
+	r := &Rabbids{config: &Config{}, log: NoOPLogger{}, consumers: map[string]*Consumer{}}
+	require.EqualError(t, r.Live(), "the supervisor is not running")
+
+	stop, err := StartSupervisor(r, time.Millisecond)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return r.Live() == nil }, time.Second, time.Millisecond)
+
+	stop()
+	require.EqualError(t, r.Live(), "the supervisor is not running")
+}
+
+type aliveMetrics struct {
+	NoOPMetrics
+	alive map[string]bool
+}
+
+func (m *aliveMetrics) ConsumerAlive(consumer string, alive bool) {
+	m.alive[consumer] = alive
+}
+
+func TestSupervisor_restartDeadConsumersMetrics(t *testing.T) {
+	t.Parallel()
+
+	metrics := &aliveMetrics{alive: map[string]bool{}}
+	dead := &Consumer{name: "dead"}
+	dead.t.Kill(errors.New("channel closed"))
+
+	s := &supervisor{
+		rabbids: &Rabbids{
+			config:    &Config{Consumers: map[string]ConsumerConfig{"alive": {}, "dead": {}}},
+			log:       NoOPLogger{},
+			metrics:   metrics,
+			consumers: map[string]*Consumer{},
+		},
+		consumers: map[string]*Consumer{"alive": {name: "alive"}, "dead": dead},
+		restarts:  map[string]*restartState{},
+	}
+
+	s.restartDeadConsumers()
+
+	require.Equal(t, map[string]bool{"alive": true, "dead": false}, metrics.alive)
+	require.False(t, s.restarts["dead"].deadSince.IsZero(), "the consumer failed to restart and still dead")
+}
+
+func TestSupervisor_checkIntervals(t *testing.T) {
+	t.Parallel()
+
+	s := &supervisor{
+		checkAliveness: time.Second,
+		rabbids: &Rabbids{config: &Config{Consumers: map[string]ConsumerConfig{
+			"fast":    {CheckInterval: 10 * time.Millisecond},
+			"default": {},
+		}}},
+		restarts: map[string]*restartState{},
+	}
+
+	require.Equal(t, 10*time.Millisecond, s.tick())
+
+	now := time.Now()
+	require.True(t, s.due("fast", now))
+	require.True(t, s.due("default", now))
+	require.True(t, s.due("fast", now.Add(10*time.Millisecond)))
+	require.False(t, s.due("default", now.Add(10*time.Millisecond)))
+	require.True(t, s.due("default", now.Add(time.Second)))
+}
+
+func TestSupervisor_createConsumers(t *testing.T) {
+	t.Parallel()
+
+	s := &supervisor{
+		rabbids: &Rabbids{config: &Config{}, log: NoOPLogger{}, consumers: map[string]*Consumer{}},
+	}
+	WithRestartLimit(2, 5*time.Millisecond)(s)
+
+	names := []string{"first", "second", "third", "fourth", "fifth"}
+	results := s.createConsumers(names)
+
+	require.Len(t, results, len(names))
+
+	for _, name := range names {
+		require.EqualError(t, results[name].err, "consumer \""+name+"\" did not exist")
+		require.Nil(t, results[name].consumer)
+	}
+
+	require.Empty(t, s.createConsumers(nil))
+}
+
+type stuckMetrics struct {
+	NoOPMetrics
+	stuck []bool
+}
+
+func (m *stuckMetrics) ConsumerStuck(_ string, stuck bool) {
+	m.stuck = append(m.stuck, stuck)
+}
+
+func TestSupervisor_checkStuck(t *testing.T) {
+	t.Parallel()
+
+	metrics := &stuckMetrics{}
+	s := &supervisor{
+		rabbids:  &Rabbids{log: NoOPLogger{}, metrics: metrics},
+		restarts: map[string]*restartState{},
+	}
+	WithWatchdog(10*time.Millisecond, false)(s)
+
+	c := &Consumer{name: "wedged", log: NoOPLogger{}}
+	s.checkStuck("wedged", c)
+	require.False(t, c.Stuck(10*time.Millisecond), "idle consumers are not stuck")
+
+	c.startJob()
+	s.checkStuck("wedged", c)
+	require.False(t, c.Stuck(10*time.Millisecond))
+
+	time.Sleep(20 * time.Millisecond)
+	s.checkStuck("wedged", c)
+	s.checkStuck("wedged", c)
+	require.True(t, c.Stuck(10*time.Millisecond))
+
+	c.finishJob()
+	s.checkStuck("wedged", c)
+
+	require.Equal(t, []bool{true, false}, metrics.stuck)
+}
+
+func TestConsumer_recycle(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	c := &Consumer{
+		name:    "wedged",
+		workers: 1,
+		ctx:     ctx,
+		cancel:  cancel,
+		handler: MessageHandlerFunc(func(m Message) {
+			close(started)
+			<-m.Context().Done()
+			close(canceled)
+		}),
+		workerPool: grpool.NewPool(1, 0),
+		log:        NoOPLogger{},
+		metrics:    NoOPMetrics{},
+		tracer:     NoOPTracer{},
+	}
+	defer c.workerPool.Release()
+
+	d := make(chan amqp.Delivery, 1)
+	closed := make(chan *amqp.Error)
+	returned := make(chan error, 1)
+
+	go func() { returned <- c.consumeLoop(d, closed) }()
+
+	d <- amqp.Delivery{}
+	<-started
+
+	c.recycle()
+	<-canceled
+
+	// the broker closes the deliveries and the channel closed by the recycle
+	close(closed)
+	close(d)
+
+	require.Equal(t, ErrConsumerStuck, <-returned)
+}
+
+func TestSupervisor_logTransition(t *testing.T) {
+	t.Parallel()
+
+	var (
+		message string
+		fields  Fields
+	)
+
+	s := &supervisor{
+		rabbids: &Rabbids{log: LoggerFN(func(m string, f Fields) {
+			message, fields = m, f
+		})},
+		restarts: map[string]*restartState{"crashing": {attempts: 2}},
+	}
+
+	err := errors.New("channel closed")
+	s.logTransition("error recreating one consumer", "crashing", transitionFailed, Fields{"reason": err})
+
+	require.Equal(t, "error recreating one consumer", message)
+	require.Equal(t, Fields{
+		"consumer-name": "crashing",
+		"transition":    "failed",
+		"attempt":       2,
+		"reason":        err,
+	}, fields)
+}
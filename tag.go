@@ -0,0 +1,44 @@
+package rabbids
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// consumerTag create the consumer tag replacing the placeholders of the template.
+func consumerTag(template, name string, number int64) string {
+	if template == "" {
+		template = DefaultConsumerTag
+	}
+
+	return expandInstance(template, "{name}", name, "{worker}", strconv.FormatInt(number, 10))
+}
+
+// connectionName create the name of one connection replacing the placeholders of the template,
+// the name is shown by the broker management UI.
+func connectionName(template, name string) string {
+	if template == "" {
+		return name
+	}
+
+	return expandInstance(template, "{name}", name)
+}
+
+// expandInstance replace the {hostname} and {pid} placeholders, identifying the process,
+// and the other placeholders passed as old, new pairs.
+func expandInstance(template string, oldnew ...string) string {
+	return strings.NewReplacer(append(oldnew,
+		"{hostname}", hostname(),
+		"{pid}", strconv.Itoa(os.Getpid()),
+	)...).Replace(template)
+}
+
+func hostname() string {
+	h, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+
+	return h
+}
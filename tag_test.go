@@ -0,0 +1,42 @@
+package rabbids
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_consumerTag(t *testing.T) {
+	t.Parallel()
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	pid := strconv.Itoa(os.Getpid())
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"", "rabbitmq-billing-3"},
+		{DefaultConsumerTag, "rabbitmq-billing-3"},
+		{"{hostname}/{pid}/{name}-{worker}", hostname + "/" + pid + "/billing-3"},
+		{"static-tag", "static-tag"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, consumerTag(tt.template, "billing", 3), "template %q", tt.template)
+	}
+}
+
+func Test_connectionName(t *testing.T) {
+	t.Parallel()
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	require.Equal(t, "rabbids.default", connectionName("", "rabbids.default"))
+	require.Equal(t, "billing-api@"+hostname+" rabbids.default", connectionName("billing-api@{hostname} {name}", "rabbids.default"))
+}
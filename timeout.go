@@ -0,0 +1,69 @@
+package rabbids
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// DefaultConsumerTimeout is the default delivery acknowledgement timeout used by RabbitMQ 3.8.15+.
+const DefaultConsumerTimeout = 30 * time.Minute
+
+// Strategies used when a handler takes too long and the broker consumer timeout is close.
+const (
+	// TimeoutStrategyWarn only log a warning about the message.
+	TimeoutStrategyWarn = "warn"
+	// TimeoutStrategyAbort cancel the message context, so the handler can stop and the message
+	// follows the on_error strategy before the broker closes the channel.
+	TimeoutStrategyAbort = "abort"
+)
+
+// consumerTimeoutThreshold is the fraction of the consumer timeout elapsed before applying the timeout strategy.
+const consumerTimeoutThreshold = 0.9
+
+type consumerTimeoutConfig struct {
+	duration time.Duration
+	strategy string
+}
+
+// queueConsumerTimeout return the timeout defined by the x-consumer-timeout queue argument (in milliseconds).
+func queueConsumerTimeout(args amqp.Table) (time.Duration, bool) {
+	return argMilliseconds(args, "x-consumer-timeout")
+}
+
+// watchTimeout apply the timeout strategy when the handler still processing the message
+// close to the broker consumer timeout. The broker timeout starts on the delivery, so the time
+// waiting for a worker counts. The returned function MUST be called after the handler finishes.
+func (c *Consumer) watchTimeout(ctx context.Context, d amqp.Delivery, received time.Time) (context.Context, func()) {
+	if c.opts.AutoAck || c.timeout.strategy == "" || c.timeout.duration <= 0 {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	threshold := time.Duration(float64(c.timeout.duration) * consumerTimeoutThreshold)
+	timer := time.AfterFunc(threshold-time.Since(received), func() {
+		c.log.Warn("the handler is taking too long and the message is close to the broker consumer timeout", Fields{
+			"consumer":         c.name,
+			"message-id":       d.MessageId,
+			"consumer-timeout": c.timeout.duration,
+			"strategy":         c.timeout.strategy,
+		})
+
+		if c.timeout.strategy == TimeoutStrategyAbort {
+			cancel()
+		}
+	})
+
+	return ctx, func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+// isConsumerTimeout return true when the channel was closed by the broker because one delivery
+// was not acknowledged before the consumer timeout.
+func isConsumerTimeout(err *amqp.Error) bool {
+	return err != nil && err.Code == amqp.PreconditionFailed && strings.Contains(err.Reason, "timed out")
+}
@@ -0,0 +1,115 @@
+package rabbids
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueConsumerTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		args    amqp.Table
+		timeout time.Duration
+		ok      bool
+	}{
+		{"without the argument", amqp.Table{}, 0, false},
+		{"with an int", amqp.Table{"x-consumer-timeout": 60000}, time.Minute, true},
+		{"with an int64", amqp.Table{"x-consumer-timeout": int64(1000)}, time.Second, true},
+		{"with an invalid type", amqp.Table{"x-consumer-timeout": "1000"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			timeout, ok := queueConsumerTimeout(tt.args)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.timeout, timeout)
+		})
+	}
+}
+
+func TestConsumer_watchTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		strategy string
+		canceled bool
+	}{
+		{TimeoutStrategyWarn, false},
+		{TimeoutStrategyAbort, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.strategy, func(t *testing.T) {
+			t.Parallel()
+
+			c := &Consumer{
+				name:    "timeout",
+				log:     NoOPLogger{},
+				timeout: consumerTimeoutConfig{duration: 10 * time.Millisecond, strategy: tt.strategy},
+			}
+
+			ctx, stop := c.watchTimeout(context.Background(), amqp.Delivery{}, time.Now())
+			defer stop()
+
+			select {
+			case <-ctx.Done():
+				require.True(t, tt.canceled, "the context should not be canceled")
+			case <-time.After(50 * time.Millisecond):
+				require.False(t, tt.canceled, "the context should be canceled")
+			}
+		})
+	}
+}
+
+func TestConsumer_watchTimeoutFromReceipt(t *testing.T) {
+	t.Parallel()
+
+	c := &Consumer{
+		name:    "timeout",
+		log:     NoOPLogger{},
+		timeout: consumerTimeoutConfig{duration: time.Second, strategy: TimeoutStrategyAbort},
+	}
+
+	// the message waited a worker for more than the threshold
+	ctx, stop := c.watchTimeout(context.Background(), amqp.Delivery{}, time.Now().Add(-time.Second))
+	defer stop()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("the context should be canceled, the timeout starts on the delivery")
+	}
+}
+
+func TestConsumer_watchTimeoutDisabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := &Consumer{name: "timeout", log: NoOPLogger{}, timeout: consumerTimeoutConfig{duration: time.Millisecond}}
+
+	got, stop := c.watchTimeout(ctx, amqp.Delivery{}, time.Now().Add(-time.Second))
+	defer stop()
+
+	require.Equal(t, ctx, got, "the handlers are watched only with a timeout strategy")
+}
+
+func TestIsConsumerTimeout(t *testing.T) {
+	t.Parallel()
+
+	require.False(t, isConsumerTimeout(nil))
+	require.False(t, isConsumerTimeout(&amqp.Error{Code: amqp.ChannelError, Reason: "closed"}))
+	require.True(t, isConsumerTimeout(&amqp.Error{
+		Code:   amqp.PreconditionFailed,
+		Reason: "PRECONDITION_FAILED - delivery acknowledgement on channel 1 timed out",
+	}))
+}
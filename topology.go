@@ -0,0 +1,50 @@
+package rabbids
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/streadway/amqp"
+)
+
+// isNotFound returns true when the broker closed the channel because one queue or exchange
+// did not exist, which happens after restoring a broker from scratch.
+func isNotFound(err error) bool {
+	var amqpErr *amqp.Error
+
+	return errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound
+}
+
+// startConsume start consuming the queue. When the queue did not exist the consumer opens
+// a new channel, declaring the topology again, and retries once.
+func (c *Consumer) startConsume(args amqp.Table) (<-chan amqp.Delivery, error) {
+	d, err := c.amqpChannel().Consume(c.queue, c.tag,
+		c.opts.AutoAck,
+		c.opts.Exclusive,
+		c.opts.NoLocal,
+		c.opts.NoWait,
+		args)
+	if err == nil || !isNotFound(err) || c.redeclare == nil {
+		return d, err
+	}
+
+	c.log.Warn("queue not found, declaring the consumer topology again", Fields{
+		"consumer": c.name,
+		"queue":    c.queue,
+		"error":    err,
+	})
+
+	ch, rerr := c.redeclare()
+	if rerr != nil {
+		return nil, fmt.Errorf("failed to declare the topology again after %s: %w", err, rerr)
+	}
+
+	c.setChannel(ch)
+
+	return ch.Consume(c.queue, c.tag,
+		c.opts.AutoAck,
+		c.opts.Exclusive,
+		c.opts.NoLocal,
+		c.opts.NoWait,
+		args)
+}
@@ -0,0 +1,34 @@
+package rabbids
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_isNotFound(t *testing.T) {
+	t.Parallel()
+
+	notFound := &amqp.Error{Code: amqp.NotFound, Reason: "NOT_FOUND - no queue 'events'"}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"not found", notFound, true},
+		{"wrapped not found", fmt.Errorf("failed to consume: %w", notFound), true},
+		{"resource locked", &amqp.Error{Code: amqp.ResourceLocked}, false},
+		{"other error", errors.New("failed"), false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, isNotFound(tt.err))
+		})
+	}
+}
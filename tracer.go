@@ -0,0 +1,72 @@
+package rabbids
+
+import "context"
+
+// Tracer is the interface used by rabbids to trace the messages published and handled.
+// The rabbidsotel package has an OpenTelemetry implementation.
+type Tracer interface {
+	// StartPublish is called by the producers after the message is encoded and before it's published,
+	// with the context of the Publishing. The headers of the message can be changed to propagate the trace.
+	// The function returned is called with the result of the publishing.
+	StartPublish(ctx context.Context, producer string, p *Publishing) func(err error)
+	// StartHandle is called by the consumers before handling the message, returning the context used
+	// by the handler. The function returned is called with the outcome after the message is handled.
+	StartHandle(consumer string, m Message) (context.Context, func(outcome Outcome, err error))
+}
+
+// NoOPTracer is a Tracer implementation that does nothing.
+type NoOPTracer struct{}
+
+func (NoOPTracer) StartPublish(ctx context.Context, producer string, p *Publishing) func(err error) {
+	return func(error) {}
+}
+
+func (NoOPTracer) StartHandle(consumer string, m Message) (context.Context, func(outcome Outcome, err error)) {
+	return m.Context(), func(Outcome, error) {}
+}
+
+// WithTracer set the Tracer used by all the consumers and producers created by the Rabbids client.
+func WithTracer(t Tracer) Option {
+	return func(r *Rabbids) error {
+		r.tracer = t
+
+		return nil
+	}
+}
+
+// WithProducerTracer set the Tracer used by the producer, for the producers created with NewProducer.
+func WithProducerTracer(t Tracer) ProducerOption {
+	return func(p *Producer) error {
+		p.tracer = t
+
+		return nil
+	}
+}
+
+// trace start tracing the handling of the message when the consumer has a Tracer,
+// returning the message with the context of the trace.
+func (c *Consumer) trace(m Message) (Message, func(Outcome, error)) {
+	if c.tracer == nil {
+		return m, func(Outcome, error) {}
+	}
+
+	ctx, finish := c.tracer.StartHandle(c.name, m)
+
+	return m.WithContext(ctx), finish
+}
+
+// WithPublishingContext set the context of the Publishing, used by the Tracer as the parent of the publishing.
+func WithPublishingContext(ctx context.Context) PublishingOption {
+	return func(p *Publishing) {
+		p.ctx = ctx
+	}
+}
+
+// Context returns the context of the Publishing, set by WithPublishingContext.
+func (p Publishing) Context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+
+	return p.ctx
+}
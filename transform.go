@@ -0,0 +1,138 @@
+package rabbids
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/leveeml/rabbids/serialization"
+	"github.com/streadway/amqp"
+)
+
+// Transformer change the message before it is validated and passed to the handler,
+// used to decompress or decrypt the payloads encoded by the producers.
+// Messages that fail to be transformed are sent to the dead letter with the error inside the ErrorHeader.
+// Transform is called by the worker goroutines, so it MUST be safe for concurrent use.
+type Transformer interface {
+	Transform(m Message) (Message, error)
+}
+
+// TransformerFunc implements the Transformer interface.
+type TransformerFunc func(m Message) (Message, error)
+
+func (f TransformerFunc) Transform(m Message) (Message, error) {
+	return f(m)
+}
+
+// WithTransformer add a transformer to the consumer, transformers are called in the order they are added.
+func WithTransformer(t Transformer) ConsumerOption {
+	return func(c *Consumer) error {
+		c.transformers = append(c.transformers, t)
+
+		return nil
+	}
+}
+
+// GzipDecompressor decompress the messages with the Content-Encoding gzip.
+// Other messages are passed without changes.
+var GzipDecompressor = TransformerFunc(func(m Message) (Message, error) {
+	if m.ContentEncoding != "gzip" {
+		return m, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(m.Body))
+	if err != nil {
+		return m, fmt.Errorf("failed to decompress the message: %w", err)
+	}
+
+	defer r.Close()
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return m, fmt.Errorf("failed to decompress the message: %w", err)
+	}
+
+	m.Body = body
+	m.ContentEncoding = ""
+
+	return m, nil
+})
+
+// Decompressor decompress the messages with the last Content-Encoding gzip or deflate,
+// used by the producers with the serialization.Compressed serializer, removing it from the Content-Encoding.
+// Other messages are passed without changes.
+var Decompressor = TransformerFunc(func(m Message) (Message, error) {
+	encoding, rest, ok := lastEncoding(m.ContentEncoding)
+	if !ok || !serialization.IsCompressed(encoding) {
+		return m, nil
+	}
+
+	body, err := serialization.Decompress(encoding, m.Body)
+	if err != nil {
+		return m, fmt.Errorf("failed to decompress the message: %w", err)
+	}
+
+	m.Body = body
+	m.ContentEncoding = rest
+
+	return m, nil
+})
+
+// NewDecryptor return a transformer decrypting the messages encrypted by the serialization.Encrypted serializer,
+// with the last Content-Encoding serialization.AESGCM, removing it and the encryption headers.
+// Other messages are passed without changes.
+func NewDecryptor(keys serialization.KeyProvider) Transformer {
+	return TransformerFunc(func(m Message) (Message, error) {
+		encoding, rest, ok := lastEncoding(m.ContentEncoding)
+		if !ok || encoding != serialization.AESGCM {
+			return m, nil
+		}
+
+		body, err := serialization.Decrypt(keys, m.Body, m.Headers)
+		if err != nil {
+			return m, fmt.Errorf("failed to decrypt the message: %w", err)
+		}
+
+		headers := make(amqp.Table, len(m.Headers))
+		for k, v := range m.Headers {
+			headers[k] = v
+		}
+
+		delete(headers, serialization.EncryptionKeyIDHeader)
+		delete(headers, serialization.EncryptionNonceHeader)
+		delete(headers, serialization.EncryptionDataKeyHeader)
+
+		m.Body = body
+		m.Headers = headers
+		m.ContentEncoding = rest
+
+		return m, nil
+	})
+}
+
+// lastEncoding return the last encoding applied to the message and the previous encodings.
+func lastEncoding(contentEncoding string) (string, string, bool) {
+	encodings := serialization.ContentEncodings(contentEncoding)
+	if len(encodings) == 0 {
+		return "", "", false
+	}
+
+	last := len(encodings) - 1
+
+	return encodings[last], strings.Join(encodings[:last], ", "), true
+}
+
+func (c *Consumer) transform(m Message) (Message, error) {
+	for _, t := range c.transformers {
+		nm, err := t.Transform(m)
+		if err != nil {
+			return m, fmt.Errorf("failed to transform the message: %w", err)
+		}
+
+		m = nm
+	}
+
+	return m, nil
+}
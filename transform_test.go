@@ -0,0 +1,99 @@
+package rabbids
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/leveeml/rabbids/serialization"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzipDecompressor(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(`{"foo":"bar"}`))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	m, err := GzipDecompressor(Message{Delivery: amqp.Delivery{ContentEncoding: "gzip", Body: buf.Bytes()}})
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"bar"}`, string(m.Body))
+	require.Empty(t, m.ContentEncoding)
+
+	m, err = GzipDecompressor(Message{Delivery: amqp.Delivery{Body: []byte("plain")}})
+	require.NoError(t, err)
+	require.Equal(t, "plain", string(m.Body))
+
+	_, err = GzipDecompressor(Message{Delivery: amqp.Delivery{ContentEncoding: "gzip", Body: []byte("plain")}})
+	require.Error(t, err)
+}
+
+func TestDecompressor(t *testing.T) {
+	t.Parallel()
+
+	p := &Producer{serializer: serialization.Compressed(&serialization.JSON{}, serialization.Deflate)}
+
+	pub, err := p.prepare(NewPublishing("ex", "key", map[string]string{"foo": "bar"}))
+	require.NoError(t, err)
+	require.Equal(t, "deflate", pub.ContentEncoding)
+	require.Equal(t, "application/json", pub.ContentType)
+
+	m := Message{Delivery: amqp.Delivery{ContentType: pub.ContentType, ContentEncoding: pub.ContentEncoding, Body: pub.Body}}
+
+	var v map[string]string
+	require.NoError(t, m.Decode(&v))
+	require.Equal(t, map[string]string{"foo": "bar"}, v)
+
+	m, err = Decompressor(m)
+	require.NoError(t, err)
+	require.Empty(t, m.ContentEncoding)
+	require.JSONEq(t, `{"foo":"bar"}`, string(m.Body))
+
+	m, err = Decompressor(Message{Delivery: amqp.Delivery{Body: []byte("plain")}})
+	require.NoError(t, err)
+	require.Equal(t, []byte("plain"), m.Body)
+}
+
+func TestNewDecryptor(t *testing.T) {
+	t.Parallel()
+
+	keys, err := serialization.NewStaticKeyProvider("k1", map[string][]byte{"k1": bytes.Repeat([]byte("k"), 32)})
+	require.NoError(t, err)
+
+	p := &Producer{serializer: serialization.Encrypted(serialization.Compressed(&serialization.JSON{}, serialization.Gzip), keys)}
+
+	pub, err := p.prepare(NewPublishing("ex", "key", map[string]string{"foo": "bar"}))
+	require.NoError(t, err)
+	require.Equal(t, "gzip, aes-gcm", pub.ContentEncoding)
+
+	m := Message{Delivery: amqp.Delivery{
+		ContentType:     pub.ContentType,
+		ContentEncoding: pub.ContentEncoding,
+		Headers:         pub.Headers,
+		Body:            pub.Body,
+	}}
+
+	// the body is decompressed only after it is decrypted
+	same, err := Decompressor(m)
+	require.NoError(t, err)
+	require.Equal(t, m, same)
+
+	m, err = NewDecryptor(keys).Transform(m)
+	require.NoError(t, err)
+	require.Empty(t, m.Headers)
+	require.Equal(t, "gzip", m.ContentEncoding)
+
+	var v map[string]string
+	require.NoError(t, m.Decode(&v))
+	require.Equal(t, map[string]string{"foo": "bar"}, v)
+
+	plain := Message{Delivery: amqp.Delivery{Body: []byte("plain")}}
+	m, err = NewDecryptor(keys).Transform(plain)
+	require.NoError(t, err)
+	require.Equal(t, plain, m)
+}
@@ -0,0 +1,85 @@
+package rabbids
+
+import "fmt"
+
+// Validator is used by the consumers to validate the messages before calling the handler.
+// Invalid messages are sent to the queue dead letter exchange with the validation error
+// inside the ErrorHeader, without calling the handler.
+// Validate is called by the worker goroutines, so it MUST be safe for concurrent use.
+type Validator interface {
+	Validate(m Message) error
+}
+
+// ValidatorFunc implements the Validator interface.
+type ValidatorFunc func(m Message) error
+
+func (f ValidatorFunc) Validate(m Message) error {
+	return f(m)
+}
+
+// WithValidator add a validator to the consumer, validators are called in the order they are added.
+func WithValidator(v Validator) ConsumerOption {
+	return func(c *Consumer) error {
+		c.validators = append(c.validators, v)
+
+		return nil
+	}
+}
+
+func (c *Consumer) validate(m Message) error {
+	for _, v := range c.validators {
+		if err := v.Validate(m); err != nil {
+			return fmt.Errorf("invalid message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deadLetter publish the message to the queue dead letter exchange with the error inside the headers
+// and ack the original message after the broker confirms the copy. Without a dead letter exchange, or when the publishing fails,
+// the message is rejected without requeue.
+func (c *Consumer) deadLetter(m Message, err error) {
+	if c.opts.AutoAck {
+		return
+	}
+
+	if c.deadLetterEx != "" {
+		key := c.deadLetterKey
+		if key == "" {
+			key = m.RoutingKey
+		}
+
+		pub := NewPublishing(c.deadLetterEx, key, nil)
+		pub.Publishing = publishingFromDelivery(m.Delivery)
+		pub.encoded = true
+		pub.Headers[ConsumerHeader] = c.name
+		pub.Headers[ErrorHeader] = err.Error()
+		pub.Headers[OriginalExchangeHeader] = m.Exchange
+		pub.Headers[OriginalRoutingKeyHeader] = m.RoutingKey
+
+		perr := c.publishConfirmed(pub)
+		if perr == nil {
+			ackErr := m.Ack(false)
+			if ackErr != nil {
+				c.log.Error("failed to ack the invalid message", Fields{"consumer": c.name, "error": ackErr})
+			}
+
+			// for the broker the message is acked, but for the application the message was dead lettered
+			if r, ok := m.Acknowledger.(*outcomeRecorder); ok {
+				r.record(OutcomeNack, ackErr)
+			}
+
+			return
+		}
+
+		c.log.Error("failed to publish the invalid message to the dead letter", Fields{
+			"consumer": c.name,
+			"error":    perr,
+		})
+	}
+
+	if nackErr := m.Nack(false, false); nackErr != nil {
+		c.log.Error("failed to reject the invalid message", Fields{"consumer": c.name, "error": nackErr})
+	}
+}
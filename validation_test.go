@@ -0,0 +1,49 @@
+package rabbids
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumer_validate(t *testing.T) {
+	t.Parallel()
+
+	var (
+		called  bool
+		outcome Outcome
+		err     error
+	)
+
+	c := &Consumer{
+		name:         "validation",
+		log:          NoOPLogger{},
+		deadLetterEx: "fallback",
+		metrics:      NoOPMetrics{},
+		handler:      MessageHandlerFunc(func(m Message) { called = true }),
+		auditHooks: []AuditHook{func(_ Message, o Outcome, _ time.Duration, e error) {
+			outcome, err = o, e
+		}},
+	}
+	require.NoError(t, WithValidator(ValidatorFunc(func(m Message) error {
+		if m.ContentType != "application/json" {
+			return errors.New("only json is accepted")
+		}
+
+		return nil
+	}))(c))
+
+	ack := &fakeAcknowledger{}
+	c.handle(amqp.Delivery{Acknowledger: ack, ContentType: "text/plain"}, time.Now())
+
+	require.False(t, called, "the handler should not be called with invalid messages")
+	require.Equal(t, fakeAcknowledger{nacks: 1}, *ack, "without a producer the message is rejected")
+	require.Equal(t, OutcomeNack, outcome)
+	require.EqualError(t, err, "invalid message: only json is accepted")
+
+	c.handle(amqp.Delivery{Acknowledger: ack, ContentType: "application/json"}, time.Now())
+	require.True(t, called)
+}
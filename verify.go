@@ -0,0 +1,218 @@
+package rabbids
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/streadway/amqp"
+)
+
+// TopologyMismatch describe one queue or exchange declared in the broker with properties
+// different from the config.
+type TopologyMismatch struct {
+	// Kind is "queue" or "exchange".
+	Kind string
+	Name string
+	// Reason is the difference reported by the broker, like
+	// "inequivalent arg 'durable' for queue 'events' in vhost '/': received 'false' but current is 'true'".
+	Reason string
+}
+
+// TopologyError is returned by VerifyTopology with all the mismatches found.
+type TopologyError struct {
+	Mismatches []TopologyMismatch
+}
+
+func (e *TopologyError) Error() string {
+	diffs := make([]string, 0, len(e.Mismatches))
+
+	for _, m := range e.Mismatches {
+		diffs = append(diffs, fmt.Sprintf("%s \"%s\": %s", m.Kind, m.Name, m.Reason))
+	}
+
+	return fmt.Sprintf("the broker topology differs from the config: %s", strings.Join(diffs, "; "))
+}
+
+// WithTopologyVerification make New verify the topology using VerifyTopology,
+// failing fast when the queues or exchanges in the broker differ from the config.
+func WithTopologyVerification() Option {
+	return func(r *Rabbids) error {
+		r.verifyTopology = true
+
+		return nil
+	}
+}
+
+// VerifyTopology check every queue and exchange from the config already declared in the broker,
+// returning a *TopologyError with all the differences, instead of failing with PRECONDITION_FAILED
+// when the consumers are created. The queues and exchanges not found are declared later as usual.
+func (r *Rabbids) VerifyTopology() error {
+	r.configMutex.RLock()
+	defer r.configMutex.RUnlock()
+
+	v := &topologyVerifier{rabbids: r, verified: map[string]bool{}}
+
+	consumers := make([]string, 0, len(r.config.Consumers))
+	for name := range r.config.Consumers {
+		consumers = append(consumers, name)
+	}
+
+	sort.Strings(consumers)
+
+	for _, name := range consumers {
+		cfg := r.config.Consumers[name]
+
+		if dl, ok := r.config.DeadLetters[cfg.DeadLetter]; ok {
+			if err := v.queue(cfg.Connection, dl.Queue); err != nil {
+				return err
+			}
+		}
+
+		if err := v.queue(cfg.Connection, cfg.Queue); err != nil {
+			return err
+		}
+	}
+
+	// the exchanges not used by the consumers are verified using the first connection.
+	connections := make([]string, 0, len(r.config.Connections))
+	for name := range r.config.Connections {
+		connections = append(connections, name)
+	}
+
+	sort.Strings(connections)
+
+	if len(connections) > 0 {
+		for name, ex := range r.config.Exchanges {
+			if err := v.exchange(connections[0], name, ex); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(v.mismatches) == 0 {
+		return nil
+	}
+
+	sort.Slice(v.mismatches, func(i, j int) bool {
+		if v.mismatches[i].Kind != v.mismatches[j].Kind {
+			return v.mismatches[i].Kind < v.mismatches[j].Kind
+		}
+
+		return v.mismatches[i].Name < v.mismatches[j].Name
+	})
+
+	return &TopologyError{Mismatches: v.mismatches}
+}
+
+type topologyVerifier struct {
+	rabbids    *Rabbids
+	verified   map[string]bool
+	mismatches []TopologyMismatch
+}
+
+func (v *topologyVerifier) queue(connection string, q QueueConfig) error {
+	// exclusive queues are owned by one connection and can't be verified.
+	if q.Options.Exclusive {
+		return nil
+	}
+
+	args := assertRightTableTypes(q.Options.Args)
+
+	err := v.check(connection, "queue", q.Name, func(ch *amqp.Channel) error {
+		_, err := ch.QueueDeclarePassive(q.Name, q.Options.Durable, q.Options.AutoDelete, false, false, args)
+
+		return err
+	}, func(ch *amqp.Channel) error {
+		_, err := ch.QueueDeclare(q.Name, q.Options.Durable, q.Options.AutoDelete, false, false, args)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, b := range q.Bindings {
+		ex, ok := v.rabbids.config.Exchanges[b.Exchange]
+		if !ok {
+			continue
+		}
+
+		if err := v.exchange(connection, b.Exchange, ex); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *topologyVerifier) exchange(connection, name string, ex ExchangeConfig) error {
+	args := assertRightTableTypes(ex.Options.Args)
+
+	return v.check(connection, "exchange", name, func(ch *amqp.Channel) error {
+		return ch.ExchangeDeclarePassive(name, ex.Type, ex.Options.Durable, ex.Options.AutoDelete, ex.Options.Internal, false, args)
+	}, func(ch *amqp.Channel) error {
+		return ch.ExchangeDeclare(name, ex.Type, ex.Options.Durable, ex.Options.AutoDelete, ex.Options.Internal, false, args)
+	})
+}
+
+// check verify one declaration once, the default and the amq.* declarations are skipped.
+// The passive declare check if the declaration exists and then it's declared again with the config:
+// declaring the same properties is a no-op but different properties are rejected by the broker
+// with PRECONDITION_FAILED.
+func (v *topologyVerifier) check(connection, kind, name string, passive, declare func(*amqp.Channel) error) error {
+	key := kind + "." + name
+	if v.verified[key] || name == "" || strings.HasPrefix(name, "amq.") {
+		return nil
+	}
+
+	v.verified[key] = true
+
+	exists, reason, err := v.try(connection, passive)
+	if err == nil && exists {
+		_, reason, err = v.try(connection, declare)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to verify the %s \"%s\": %w", kind, name, err)
+	}
+
+	if reason != "" {
+		v.mismatches = append(v.mismatches, TopologyMismatch{Kind: kind, Name: name, Reason: reason})
+	}
+
+	return nil
+}
+
+// try run the declaration using a new channel, because the errors close the channel.
+// It returns false when the declaration was not found or differs from the config,
+// with the reason from the broker for the differences.
+func (v *topologyVerifier) try(connection string, fn func(*amqp.Channel) error) (bool, string, error) {
+	// VerifyTopology holds the configMutex
+	ch, err := v.rabbids.getConnectionChannel(connection, connection)
+	if err != nil {
+		return false, "", err
+	}
+
+	err = fn(ch)
+	if err == nil {
+		return true, "", ch.Close()
+	}
+
+	var amqpErr *amqp.Error
+	if !errors.As(err, &amqpErr) {
+		_ = ch.Close()
+
+		return false, "", err
+	}
+
+	switch amqpErr.Code {
+	case amqp.NotFound, amqp.ResourceLocked:
+		return false, "", nil
+	case amqp.PreconditionFailed:
+		return false, amqpErr.Reason, nil
+	}
+
+	return false, "", err
+}
@@ -0,0 +1,28 @@
+package rabbids
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopologyError(t *testing.T) {
+	t.Parallel()
+
+	err := &TopologyError{Mismatches: []TopologyMismatch{
+		{Kind: "exchange", Name: "events", Reason: "inequivalent arg 'type' for exchange 'events' in vhost '/': received 'topic' but current is 'direct'"},
+		{Kind: "queue", Name: "billing", Reason: "inequivalent arg 'durable' for queue 'billing' in vhost '/': received 'false' but current is 'true'"},
+	}}
+
+	require.EqualError(t, err, "the broker topology differs from the config: "+
+		"exchange \"events\": inequivalent arg 'type' for exchange 'events' in vhost '/': received 'topic' but current is 'direct'; "+
+		"queue \"billing\": inequivalent arg 'durable' for queue 'billing' in vhost '/': received 'false' but current is 'true'")
+}
+
+func TestRabbids_VerifyTopologyWithoutDeclarations(t *testing.T) {
+	t.Parallel()
+
+	r, err := New(&Config{}, NoOPLoggerFN, WithTopologyVerification())
+	require.NoError(t, err)
+	require.NoError(t, r.VerifyTopology())
+}
@@ -0,0 +1,104 @@
+package rabbids
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrConsumerStuck is the error of the consumers recycled by the watchdog.
+var ErrConsumerStuck = errors.New("the consumer was recycled by the watchdog, no progress with messages in flight")
+
+type watchdogConfig struct {
+	threshold time.Duration
+	recycle   bool
+}
+
+// WithWatchdog check on every supervisor check if the consumers are stuck: with messages
+// in flight and no message started or finished for longer than the threshold.
+// The stuck consumers are logged and reported with Metrics.ConsumerStuck. With recycle,
+// the context of the messages in flight is canceled and the channel of the stuck consumer is closed,
+// so the broker requeue the messages, the consumer dies with ErrConsumerStuck and the supervisor
+// recreates it. The handlers ignoring the context keep running and their messages can be handled twice.
+func WithWatchdog(threshold time.Duration, recycle bool) SupervisorOption {
+	return func(s *supervisor) {
+		s.watchdog = watchdogConfig{threshold: threshold, recycle: recycle}
+	}
+}
+
+// startJob and finishJob track the messages in flight and the last time the consumer made progress.
+func (c *Consumer) startJob() {
+	atomic.AddInt64(&c.inFlight, 1)
+	atomic.StoreInt64(&c.lastProgress, time.Now().UnixNano())
+}
+
+func (c *Consumer) finishJob() {
+	atomic.AddInt64(&c.inFlight, -1)
+	atomic.StoreInt64(&c.lastProgress, time.Now().UnixNano())
+}
+
+// Stuck returns true if the consumer has messages in flight and no message
+// started or finished for longer than the threshold.
+func (c *Consumer) Stuck(threshold time.Duration) bool {
+	if atomic.LoadInt64(&c.inFlight) <= 0 {
+		return false
+	}
+
+	return time.Since(time.Unix(0, atomic.LoadInt64(&c.lastProgress))) > threshold
+}
+
+// recycle cancel the context of the messages in flight and close the consumer channel,
+// the consumer dies with ErrConsumerStuck without waiting for the stuck workers.
+func (c *Consumer) recycle() {
+	atomic.StoreInt32(&c.recycled, 1)
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	ch := c.amqpChannel()
+	if ch == nil {
+		return
+	}
+
+	if err := ch.Close(); err != nil {
+		c.log.Error("failed to close the channel of a stuck consumer", Fields{"consumer": c.name, "error": err})
+	}
+}
+
+func (c *Consumer) isRecycled() bool {
+	return atomic.LoadInt32(&c.recycled) == 1
+}
+
+// checkStuck apply the watchdog to one alive consumer.
+func (s *supervisor) checkStuck(name string, c *Consumer) {
+	if s.watchdog.threshold <= 0 {
+		return
+	}
+
+	state := s.state(name)
+	stuck := c.Stuck(s.watchdog.threshold)
+
+	if stuck == state.stuck {
+		return
+	}
+
+	state.stuck = stuck
+	s.rabbids.metrics.ConsumerStuck(name, stuck)
+
+	if !stuck {
+		s.logTransition("consumer recovered from stuck", name, transitionUnstuck, nil)
+
+		return
+	}
+
+	s.logTransition("consumer stuck, no progress with messages in flight", name, transitionStuck, Fields{
+		"in-flight": atomic.LoadInt64(&c.inFlight),
+		"threshold": s.watchdog.threshold,
+		"recycle":   s.watchdog.recycle,
+	})
+
+	if s.watchdog.recycle {
+		c.recycle()
+	}
+}